@@ -0,0 +1,126 @@
+// reencrypt 使用当前主密钥重新加密已保存的敏感数据
+//
+// 首次启用加密（迁移历史明文数据）或轮换主密钥时运行：
+//
+//	ENCRYPTION_MASTER_KEYS="1:<旧密钥>,2:<新密钥>" ENCRYPTION_KEY_VERSION=2 go run ./cmd/reencrypt
+//
+// 全部数据重新加密完成后即可从 ENCRYPTION_MASTER_KEYS 中移除旧版本主密钥
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/config"
+	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+	"github.com/yourusername/kyx-quota-bridge/pkg/cache"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+	"github.com/yourusername/kyx-quota-bridge/pkg/encryption"
+)
+
+func main() {
+	batchSize := flag.Int("batch", 500, "number of used keys re-encrypted per batch")
+	flag.Parse()
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to load configuration")
+	}
+
+	keyring, err := encryption.ParseKeyring(cfg.Encryption.MasterKeys, cfg.Encryption.KeyVersion)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid encryption master keys")
+	}
+	if keyring == nil {
+		logger.Fatal("ENCRYPTION_MASTER_KEYS is required")
+	}
+
+	db, err := database.New(&database.Config{
+		Host:            cfg.Database.Host,
+		Port:            cfg.Database.Port,
+		User:            cfg.Database.User,
+		Password:        cfg.Database.Password,
+		DBName:          cfg.Database.DBName,
+		SSLMode:         cfg.Database.SSLMode,
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+	}, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to connect to database")
+	}
+	defer db.Close()
+
+	// 重新加密后需要清除管理员配置缓存
+	redisClient, err := cache.New(&cache.Config{
+		Host:       cfg.Redis.Host,
+		Port:       cfg.Redis.Port,
+		Password:   cfg.Redis.Password,
+		DB:         cfg.Redis.DB,
+		PoolSize:   cfg.Redis.PoolSize,
+		MaxRetries: cfg.Redis.MaxRetries,
+	}, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to connect to Redis")
+	}
+	defer redisClient.Close()
+
+	ctx := context.Background()
+	keyRepo := repository.NewKeyRepository(db, keyring, logger)
+	adminConfigRepo := repository.NewAdminConfigRepository(db, redisClient, keyring, logger)
+	donateJobRepo := repository.NewDonateJobRepository(db, keyring, logger)
+	adminRepo := repository.NewAdminRepository(db, keyring, logger)
+	linuxDoTokenRepo := repository.NewLinuxDoTokenRepository(db, keyring, logger)
+
+	// 1. 已使用的Key
+	totalKeys := 0
+	for {
+		count, err := keyRepo.ReencryptBatch(ctx, *batchSize)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to re-encrypt used keys")
+		}
+		if count == 0 {
+			break
+		}
+		totalKeys += count
+		logger.WithField("re_encrypted", totalKeys).Info("Re-encrypting used keys")
+	}
+
+	// 2. 管理员配置中的 session / keys_authorization
+	configUpdated, err := adminConfigRepo.ReencryptSecrets(ctx)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to re-encrypt admin config secrets")
+	}
+
+	// 3. 未结束的投喂任务
+	jobs, err := donateJobRepo.ReencryptKeys(ctx)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to re-encrypt donate job keys")
+	}
+
+	// 4. 管理员两步验证密钥
+	totpSecrets, err := adminRepo.ReencryptTOTPSecrets(ctx)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to re-encrypt admin totp secrets")
+	}
+
+	// 5. Linux.do 刷新令牌
+	linuxDoTokens, err := linuxDoTokenRepo.ReencryptTokens(ctx)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to re-encrypt linux.do refresh tokens")
+	}
+
+	logger.WithFields(logrus.Fields{
+		"key_version":    keyring.CurrentVersion(),
+		"used_keys":      totalKeys,
+		"admin_config":   configUpdated,
+		"donate_jobs":    jobs,
+		"totp_secrets":   totpSecrets,
+		"linuxdo_tokens": linuxDoTokens,
+	}).Info("Re-encryption completed")
+}
@@ -5,18 +5,22 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // 领取时区在精简镜像中也可用
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"github.com/yourusername/kyx-quota-bridge/internal/config"
 	"github.com/yourusername/kyx-quota-bridge/internal/handler"
 	"github.com/yourusername/kyx-quota-bridge/internal/middleware"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
 	"github.com/yourusername/kyx-quota-bridge/internal/repository"
 	"github.com/yourusername/kyx-quota-bridge/internal/service"
 	"github.com/yourusername/kyx-quota-bridge/pkg/cache"
 	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+	"github.com/yourusername/kyx-quota-bridge/pkg/encryption"
 )
 
 var (
@@ -41,6 +45,9 @@ func main() {
 		logger.WithError(err).Fatal("Failed to load configuration")
 	}
 	logger.Info("Configuration loaded successfully")
+	for _, warning := range cfg.Warnings() {
+		logger.Warn(warning)
+	}
 
 	// 根据配置设置日志级别
 	setLogLevel(logger, cfg.Log.Level)
@@ -78,18 +85,38 @@ func main() {
 	defer redisClient.Close()
 	logger.Info("Redis connected successfully")
 
+	// 敏感数据加密密钥环（未配置主密钥时以明文保存）
+	keyring, err := encryption.ParseKeyring(cfg.Encryption.MasterKeys, cfg.Encryption.KeyVersion)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid encryption master keys")
+	}
+
 	// 5. 初始化仓库层
 	userRepo := repository.NewUserRepository(db, logger)
 	sessionRepo := repository.NewSessionRepository(db, redisClient, logger)
 	claimRepo := repository.NewClaimRepository(db, logger)
 	donateRepo := repository.NewDonateRepository(db, logger)
-	keyRepo := repository.NewKeyRepository(db, logger)
-	adminConfigRepo := repository.NewAdminConfigRepository(db, redisClient, logger)
+	keyRepo := repository.NewKeyRepository(db, keyring, logger)
+	adminConfigRepo := repository.NewAdminConfigRepository(db, redisClient, keyring, logger)
+	quotaGrantRepo := repository.NewQuotaGrantRepository(db, logger)
+	donateJobRepo := repository.NewDonateJobRepository(db, keyring, logger)
+	adminRepo := repository.NewAdminRepository(db, keyring, logger)
+	auditLogRepo := repository.NewAuditLogRepository(db, logger)
+	accessTokenRepo := repository.NewAccessTokenRepository(db, logger)
+	linuxDoTokenRepo := repository.NewLinuxDoTokenRepository(db, keyring, logger)
+	identityRepo := repository.NewIdentityRepository(db, logger)
+	banRepo := repository.NewBanRepository(db, logger)
+	bindingRepo := repository.NewBindingRepository(db, logger)
+	groupTierRepo := repository.NewGroupTierRepository(db, logger)
+	alertRepo := repository.NewAlertRepository(db, logger)
 	logger.Info("Repositories initialized")
 
 	// 6. 初始化服务层
 	cacheService := service.NewCacheService(redisClient, logger)
 
+	// 用户封禁、暂停和观察名单
+	banService := service.NewBanService(banRepo, userRepo, sessionRepo, cacheService, logger)
+
 	// KyxClient
 	kyxClient := service.NewKyxClient(service.KyxClientConfig{
 		BaseURL: cfg.Kyx.APIBase,
@@ -97,6 +124,20 @@ func main() {
 		Timeout: 30 * time.Second,
 	}, logger)
 
+	// 系统告警和公益站 Session 健康检查（Session 失效时自动重新登录，失败时告警）
+	alertService := service.NewAlertService(alertRepo, cfg.Kyx.AlertWebhookURL, logger)
+	kyxSessionMonitor := service.NewKyxSessionMonitor(
+		kyxClient,
+		adminConfigRepo,
+		alertService,
+		service.KyxSessionMonitorConfig{
+			Interval:      time.Duration(cfg.Kyx.SessionCheckIntervalMinutes) * time.Minute,
+			LoginUsername: cfg.Kyx.LoginUsername,
+			LoginPassword: cfg.Kyx.LoginPassword,
+		},
+		logger,
+	)
+
 	// LinuxDoClient
 	linuxDoClient := service.NewLinuxDoClient(service.LinuxDoClientConfig{
 		ClientID:     cfg.LinuxDo.ClientID,
@@ -106,29 +147,129 @@ func main() {
 		Timeout:      30 * time.Second,
 	}, logger)
 
+	// LinuxDoVerifier
+	linuxDoVerifier := service.NewLinuxDoVerifier(
+		linuxDoClient,
+		linuxDoTokenRepo,
+		userRepo,
+		sessionRepo,
+		cacheService,
+		service.LinuxDoVerifierConfig{
+			Interval:     time.Duration(cfg.LinuxDo.VerifyIntervalHours) * time.Hour,
+			ActiveWindow: time.Duration(cfg.LinuxDo.VerifyActiveDays) * 24 * time.Hour,
+		},
+		logger,
+	)
+
+	// 身份提供方（Linux.do 始终启用，OIDC 按配置启用）
+	identityProviders := []service.IdentityProvider{service.NewLinuxDoProvider(linuxDoClient)}
+	if cfg.OIDC.Enabled() {
+		identityProviders = append(identityProviders, service.NewOIDCProvider(service.OIDCProviderConfig{
+			Name:         cfg.OIDC.Name,
+			DisplayName:  cfg.OIDC.DisplayName,
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURI:  cfg.OIDC.RedirectURI,
+			Scopes:       strings.Fields(cfg.OIDC.Scopes),
+			Timeout:      30 * time.Second,
+		}, logger))
+	}
+
 	// AuthService
 	authService := service.NewAuthService(
-		linuxDoClient,
+		identityProviders,
 		sessionRepo,
 		userRepo,
+		identityRepo,
+		adminRepo,
 		cacheService,
+		linuxDoVerifier,
+		banService,
 		service.AuthServiceConfig{
-			JWTSecret:      cfg.Admin.JWTSecret,
-			AdminPassword:  cfg.Admin.Password,
-			SessionTimeout: time.Duration(cfg.Admin.SessionExpire) * time.Hour,
+			JWTSecret:            cfg.Admin.JWTSecret,
+			SessionTimeout:       time.Duration(cfg.Admin.SessionExpire) * time.Hour,
+			DefaultAdminUsername: cfg.Admin.Username,
 		},
 		logger,
 	)
 
+	// 领取周期
+	claimPeriod, err := service.NewClaimPeriod(cfg.Kyx.ClaimPeriod, cfg.Kyx.ClaimTimezone, cfg.Kyx.ClaimRollingHours)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid claim period configuration")
+	}
+
+	// 投喂策略默认值（admin_config 未配置时生效）
+	donateDefaults := model.DonatePolicy{
+		QuotaPerKey:          cfg.Kyx.DonateQuotaPerKey,
+		MaxKeysPerDay:        cfg.Kyx.MaxDonateKeysPerDay,
+		MaxSubmissionsPerDay: cfg.Kyx.MaxDonateSubmissionsPerDay,
+		MaxKeysPerSubmission: cfg.Kyx.MaxKeysPerSubmission,
+	}
+
+	// 剩余额度阈值策略默认值（admin_config 未配置时生效）
+	gateDefaults := model.ClaimGatePolicy{
+		QuotaThreshold: cfg.Kyx.MinQuotaThreshold,
+		Mode:           cfg.Kyx.ClaimThresholdMode,
+	}
+
+	// Linux.do 信任等级策略默认值
+	trustDefaults := model.TrustPolicy{
+		MinBindLevel:   cfg.Kyx.MinBindTrustLevel,
+		MinClaimLevel:  cfg.Kyx.MinClaimTrustLevel,
+		MinDonateLevel: cfg.Kyx.MinDonateTrustLevel,
+	}
+
+	// 首次绑定奖励策略默认值（FIRST_BIND_BONUS_QUOTA 为 0 时不发放）
+	bonusDefaults := model.BindBonusPolicy{
+		Enabled: cfg.Kyx.FirstBindBonusQuota > 0,
+		Quota:   cfg.Kyx.FirstBindBonusQuota,
+	}
+
+	// 用户组档位策略默认值（档位只能由管理员配置，默认用户组为 admin_config.group_id）
+	tierDefaults := model.GroupTierPolicy{
+		DemoteInactiveDays: cfg.Kyx.GroupDemoteInactiveDays,
+		DefaultGroupID:     1,
+	}
+
+	// 投喂Key上游校验器（只有显式关闭时跳过校验，配置校验已保证开启时 ModelScope API 已配置）
+	var keyVerifier service.KeyVerifier
+	if cfg.Kyx.KeyVerifyDisabled {
+		logger.Warn("Key verification disabled, donated keys are accepted without upstream verification")
+	} else {
+		keyVerifier = service.NewModelScopeKeyVerifier(cfg.Kyx.ModelScopeAPIBase, cfg.Kyx.KeyVerifyModel, cfg.Kyx.KeyVerifyTimeout, logger)
+	}
+
+	// QuotaGrantService
+	quotaGrantService := service.NewQuotaGrantService(
+		quotaGrantRepo,
+		claimRepo,
+		kyxClient,
+		cacheService,
+		logger,
+	)
+
 	// UserService
 	userService := service.NewUserService(
 		userRepo,
 		claimRepo,
 		donateRepo,
+		bindingRepo,
 		adminConfigRepo,
 		kyxClient,
 		linuxDoClient,
+		quotaGrantService,
 		cacheService,
+		banService,
+		gateDefaults,
+		trustDefaults,
+		bonusDefaults,
+		claimPeriod,
+		service.BindChallengeConfig{
+			Enabled: cfg.Kyx.BindChallenge,
+			TTL:     time.Duration(cfg.Kyx.BindChallengeTTL) * time.Minute,
+		},
 		logger,
 	)
 
@@ -138,18 +279,64 @@ func main() {
 		userRepo,
 		adminConfigRepo,
 		kyxClient,
+		kyxSessionMonitor,
+		quotaGrantService,
 		cacheService,
+		banService,
+		gateDefaults,
+		trustDefaults,
+		claimPeriod,
+		logger,
+	)
+
+	// GroupTierService（按投喂量自动调整公益站用户组）
+	groupTierService := service.NewGroupTierService(
+		groupTierRepo,
+		adminConfigRepo,
+		kyxClient,
+		tierDefaults,
+		time.Duration(cfg.Kyx.GroupTierIntervalMinutes)*time.Minute,
 		logger,
 	)
 
 	// DonateService
+	keyFilter := cache.NewBloomFilter(redisClient, model.CacheKeyKeysBloom, cfg.Kyx.KeyFilterCapacity, cfg.Kyx.KeyFilterFPRate)
 	donateService := service.NewDonateService(
 		donateRepo,
+		donateJobRepo,
 		keyRepo,
 		userRepo,
 		adminConfigRepo,
 		kyxClient,
+		kyxSessionMonitor,
+		quotaGrantService,
+		groupTierService,
 		cacheService,
+		banService,
+		keyFilter,
+		donateDefaults,
+		trustDefaults,
+		service.KeyVerifyConfig{
+			Verifier:    keyVerifier,
+			Concurrency: cfg.Kyx.KeyVerifyConcurrency,
+			Timeout:     cfg.Kyx.KeyVerifyTimeout,
+		},
+		cfg.Kyx.DonateWorkers,
+		logger,
+	)
+
+	// UserPurger（软删除的用户超过保留期后彻底清除）
+	userPurger := service.NewUserPurger(
+		userRepo,
+		claimRepo,
+		donateRepo,
+		donateJobRepo,
+		quotaGrantRepo,
+		keyRepo,
+		bindingRepo,
+		service.UserPurgerConfig{
+			Retention: time.Duration(cfg.Admin.DeletedUserRetentionDays) * 24 * time.Hour,
+		},
 		logger,
 	)
 
@@ -161,25 +348,54 @@ func main() {
 		donateRepo,
 		keyRepo,
 		sessionRepo,
+		adminRepo,
+		accessTokenRepo,
+		quotaGrantRepo,
 		kyxClient,
+		kyxSessionMonitor,
+		alertService,
+		linuxDoVerifier,
+		userPurger,
 		cacheService,
+		donateDefaults,
+		gateDefaults,
+		trustDefaults,
+		bonusDefaults,
+		tierDefaults,
+		claimPeriod,
 		logger,
 	)
 
+	// BindingService（解绑申请和绑定历史）
+	bindingService := service.NewBindingService(
+		bindingRepo,
+		userRepo,
+		cacheService,
+		time.Duration(cfg.Kyx.UnbindCooldownHours)*time.Hour,
+		logger,
+	)
+
+	// AuditService
+	auditService := service.NewAuditService(auditLogRepo, logger)
+
+	// AccessTokenService
+	accessTokenService := service.NewAccessTokenService(accessTokenRepo, userRepo, logger)
+
 	logger.Info("Services initialized")
 
 	// 7. 初始化处理器层
-	authHandler := handler.NewAuthHandler(authService, logger)
-	userHandler := handler.NewUserHandler(userService, quotaService, donateService, logger)
-	adminHandler := handler.NewAdminHandler(adminService, userService, quotaService, donateService, logger)
+	authHandler := handler.NewAuthHandler(authService, accessTokenService, logger)
+	userHandler := handler.NewUserHandler(userService, quotaService, donateService, bindingService, groupTierService, logger)
+	adminHandler := handler.NewAdminHandler(adminService, userService, quotaService, donateService, quotaGrantService, auditService, banService, bindingService, groupTierService, logger)
 	logger.Info("Handlers initialized")
 
 	// 8. 初始化中间件
-	authMiddleware := middleware.NewAuthMiddleware(authService, logger)
+	authMiddleware := middleware.NewAuthMiddleware(authService, accessTokenService, banService, logger)
 	corsMiddleware := middleware.DefaultCORS(logger)
 	loggerMiddleware := middleware.NewLoggerMiddleware(logger)
 	recoveryMiddleware := middleware.DefaultRecovery(logger)
 	rateLimitMiddleware := middleware.NewRateLimitMiddleware(cacheService, logger)
+	auditMiddleware := middleware.NewAuditMiddleware(auditService, logger)
 	logger.Info("Middlewares initialized")
 
 	// 9. 初始化管理员配置（如果不存在）
@@ -187,6 +403,21 @@ func main() {
 		logger.WithError(err).Warn("Failed to initialize default config")
 	}
 
+	// 管理员表为空时创建首个 owner 账号
+	if err := authService.BootstrapAdmin(context.Background(), cfg.Admin.Username, cfg.Admin.Password); err != nil {
+		logger.WithError(err).Warn("Failed to bootstrap admin account")
+	}
+
+	// 启动后台任务
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	quotaGrantService.Start(workerCtx)
+	donateService.Start(workerCtx)
+	linuxDoVerifier.Start(workerCtx)
+	userPurger.Start(workerCtx)
+	groupTierService.Start(workerCtx)
+	kyxSessionMonitor.Start(workerCtx)
+
 	// 10. 设置Gin模式
 	if cfg.Server.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
@@ -198,6 +429,7 @@ func main() {
 	router := setupRouter(
 		cfg,
 		logger,
+		donateService,
 		authHandler,
 		userHandler,
 		adminHandler,
@@ -206,6 +438,7 @@ func main() {
 		loggerMiddleware,
 		recoveryMiddleware,
 		rateLimitMiddleware,
+		auditMiddleware,
 	)
 
 	// 12. 创建HTTP服务器
@@ -239,6 +472,15 @@ func main() {
 		logger.WithError(err).Error("Server forced to shutdown")
 	}
 
+	// 停止后台任务（中断的投喂任务会在下次启动后从已保存的进度继续）
+	stopWorkers()
+	donateService.Stop()
+	quotaGrantService.Stop()
+	linuxDoVerifier.Stop()
+	userPurger.Stop()
+	groupTierService.Stop()
+	kyxSessionMonitor.Stop()
+
 	logger.Info("Server exited successfully")
 }
 
@@ -285,6 +527,7 @@ func setLogLevel(logger *logrus.Logger, level string) {
 func setupRouter(
 	cfg *config.Config,
 	logger *logrus.Logger,
+	donateService *service.DonateService,
 	authHandler *handler.AuthHandler,
 	userHandler *handler.UserHandler,
 	adminHandler *handler.AdminHandler,
@@ -293,6 +536,7 @@ func setupRouter(
 	loggerMiddleware *middleware.LoggerMiddleware,
 	recoveryMiddleware *middleware.RecoveryMiddleware,
 	rateLimitMiddleware *middleware.RateLimitMiddleware,
+	auditMiddleware *middleware.AuditMiddleware,
 ) *gin.Engine {
 	router := gin.New()
 
@@ -326,10 +570,12 @@ func setupRouter(
 		// 认证路由（无需认证）
 		auth := api.Group("/auth")
 		{
+			auth.GET("/providers", authHandler.GetProviders)
 			auth.GET("/url", authHandler.GetAuthURL)
 			auth.GET("/callback", authHandler.HandleCallback)
 			auth.GET("/check", authHandler.CheckAuth)
-			auth.POST("/admin/login", rateLimitMiddleware.LoginRateLimit(), authHandler.AdminLogin)
+			auth.POST("/admin/login", auditMiddleware.Handler(), rateLimitMiddleware.LoginRateLimit(), authHandler.AdminLogin)
+			auth.POST("/admin/login/otp", auditMiddleware.Handler(), rateLimitMiddleware.LoginRateLimit(), authHandler.AdminLoginOTP)
 		}
 
 		// 需要用户认证的路由
@@ -341,58 +587,135 @@ func setupRouter(
 			authenticated.POST("/auth/refresh", authHandler.RefreshSession)
 			authenticated.GET("/auth/me", authHandler.GetCurrentUser)
 
+			// 登录设备
+			authenticated.GET("/user/sessions", authHandler.ListSessions)
+			authenticated.DELETE("/user/sessions", authHandler.RevokeAllSessions)
+			authenticated.DELETE("/user/sessions/:id", authHandler.RevokeSession)
+
+			// 个人访问令牌管理（仅限会话登录）
+			authenticated.GET("/user/tokens", authHandler.ListAccessTokens)
+			authenticated.POST("/user/tokens", authHandler.CreateAccessToken)
+			authenticated.DELETE("/user/tokens/:id", authHandler.RevokeAccessToken)
+
+			// 第三方身份关联
+			authenticated.GET("/user/identities", authHandler.ListIdentities)
+			authenticated.POST("/user/identities/:provider", authHandler.LinkIdentity)
+			authenticated.DELETE("/user/identities/:provider", authHandler.UnlinkIdentity)
+
 			// 用户相关
 			authenticated.POST("/user/bind", userHandler.BindAccount)
-			authenticated.GET("/user/bind/status", userHandler.CheckBindStatus)
-			authenticated.GET("/user/quota", userHandler.GetQuota)
+			authenticated.POST("/user/bind/challenge", userHandler.CreateBindChallenge)
 			authenticated.GET("/user/profile", userHandler.GetProfile)
-			authenticated.GET("/user/statistics", userHandler.GetStatistics)
+
+			// 解绑申请
+			authenticated.POST("/user/unbind", userHandler.RequestUnbind)
+			authenticated.GET("/user/unbind", userHandler.GetUnbindRequest)
+			authenticated.DELETE("/user/unbind", userHandler.CancelUnbind)
+		}
+
+		// 同时接受个人访问令牌的用户路由（Authorization: Bearer <token>，按 scope 授权）
+		quotaRead := authMiddleware.RequireAuth(model.TokenScopeQuotaRead)
+		claimScope := authMiddleware.RequireAuth(model.TokenScopeClaim)
+		donateScope := authMiddleware.RequireAuth(model.TokenScopeDonate)
+		userRateLimit := rateLimitMiddleware.RateLimitByUser(120, time.Minute)
+		{
+			api.GET("/user/bind/status", quotaRead, userRateLimit, userHandler.CheckBindStatus)
+			api.GET("/user/quota", quotaRead, userRateLimit, userHandler.GetQuota)
+			api.GET("/user/statistics", quotaRead, userRateLimit, userHandler.GetStatistics)
 
 			// 领取记录
-			authenticated.GET("/user/claims", userHandler.GetClaimHistory)
-			authenticated.POST("/user/claim", userHandler.ClaimQuota)
+			api.GET("/user/claims", quotaRead, userRateLimit, userHandler.GetClaimHistory)
+			api.POST("/user/claim", claimScope, userRateLimit, userHandler.ClaimQuota)
 
 			// 投喂记录
-			authenticated.GET("/user/donates", userHandler.GetDonateHistory)
-			authenticated.POST("/user/donate", rateLimitMiddleware.DonateRateLimit(), userHandler.DonateKeys)
+			api.GET("/user/donates", quotaRead, userRateLimit, userHandler.GetDonateHistory)
+			api.POST("/user/donate", donateScope, userRateLimit, rateLimitMiddleware.DonateRateLimit(donateService.MaxSubmissionsPerDay), userHandler.DonateKeys)
+			api.GET("/user/donate/jobs/:id", donateScope, userRateLimit, userHandler.GetDonateJob)
 		}
 
-		// 管理员路由
+		// 管理员路由（按角色授权：viewer 查看，operator 维护，owner 配置、删除和账号管理）
+		viewer := authMiddleware.RequireRole(model.AdminRoleViewer, model.AdminRoleOperator, model.AdminRoleOwner)
+		operator := authMiddleware.RequireRole(model.AdminRoleOperator, model.AdminRoleOwner)
+		owner := authMiddleware.RequireRole(model.AdminRoleOwner)
+
+		// 全部管理员路由写入审计日志
 		admin := api.Group("/admin")
-		admin.Use(authMiddleware.RequireAdmin())
+		admin.Use(auditMiddleware.Handler())
 		{
 			// 配置管理
-			admin.GET("/config", adminHandler.GetConfig)
-			admin.PUT("/config", adminHandler.UpdateConfig)
+			admin.GET("/config", owner, adminHandler.GetConfig)
+			admin.PUT("/config", owner, adminHandler.UpdateConfig)
 
 			// 统计信息
-			admin.GET("/stats", adminHandler.GetSystemStats)
-			admin.GET("/dashboard", adminHandler.GetDashboard)
+			admin.GET("/stats", viewer, adminHandler.GetSystemStats)
+			admin.GET("/dashboard", viewer, adminHandler.GetDashboard)
 
 			// 用户管理
-			admin.GET("/users", adminHandler.ListUsers)
-			admin.GET("/statistics", adminHandler.GetAllStatistics)
-			admin.DELETE("/users/:linux_do_id", adminHandler.DeleteUser)
+			admin.GET("/users", viewer, adminHandler.ListUsers)
+			admin.GET("/statistics", viewer, adminHandler.GetAllStatistics)
+			admin.GET("/users/deleted", viewer, adminHandler.ListDeletedUsers)
+			admin.DELETE("/users/:linux_do_id", owner, adminHandler.DeleteUser)
+			admin.POST("/users/:linux_do_id/restore", operator, adminHandler.RestoreUser)
+			admin.POST("/users/:linux_do_id/claim/reset", operator, adminHandler.ResetUserClaim)
+			admin.DELETE("/users/:linux_do_id/sessions", operator, adminHandler.ForceLogoutUser)
+			admin.GET("/users/:linux_do_id/status", viewer, adminHandler.GetUserStatus)
+			admin.GET("/users/:linux_do_id/bindings", viewer, adminHandler.GetBindingHistory)
+
+			// 解绑申请审批
+			admin.GET("/unbind-requests", viewer, adminHandler.ListUnbindRequests)
+			admin.POST("/unbind-requests/:id/approve", operator, adminHandler.ApproveUnbind)
+			admin.POST("/unbind-requests/:id/reject", operator, adminHandler.RejectUnbind)
+
+			// 用户组自动调整记录
+			admin.GET("/group-changes", viewer, adminHandler.ListGroupChanges)
+
+			// 封禁、暂停和观察名单
+			admin.GET("/bans", viewer, adminHandler.ListBans)
+			admin.POST("/bans", operator, adminHandler.CreateBan)
+			admin.POST("/bans/:id/lift", operator, adminHandler.LiftBan)
 
 			// 记录管理
-			admin.GET("/claims", adminHandler.ListAllClaims)
-			admin.GET("/donates", adminHandler.ListAllDonates)
-			admin.GET("/activity", adminHandler.GetRecentActivity)
+			admin.GET("/claims", viewer, adminHandler.ListAllClaims)
+			admin.GET("/donates", viewer, adminHandler.ListAllDonates)
+			admin.GET("/activity", viewer, adminHandler.GetRecentActivity)
+
+			// 额度发放
+			admin.GET("/grants", viewer, adminHandler.ListQuotaGrants)
+			admin.POST("/grants/:id/replay", operator, adminHandler.ReplayQuotaGrant)
+			admin.POST("/grants/:id/confirm", operator, adminHandler.ConfirmQuotaGrant)
 
 			// 维护操作
-			admin.POST("/maintenance/sessions", adminHandler.CleanExpiredSessions)
-			admin.POST("/maintenance/keys", adminHandler.CleanOldKeys)
-			admin.POST("/cache/clear", adminHandler.ClearCache)
+			admin.POST("/maintenance/sessions", operator, adminHandler.CleanExpiredSessions)
+			admin.POST("/maintenance/keys", owner, adminHandler.CleanOldKeys)
+			admin.POST("/maintenance/keys/filter", operator, adminHandler.RebuildKeyFilter)
+			admin.POST("/cache/clear", operator, adminHandler.ClearCache)
 
 			// 测试工具
-			admin.GET("/test/kyx", adminHandler.TestKyxConnection)
-			admin.GET("/test/session", adminHandler.ValidateKyxSession)
+			admin.GET("/test/kyx", operator, adminHandler.TestKyxConnection)
+			admin.GET("/test/session", operator, adminHandler.ValidateKyxSession)
 
 			// 健康状态
-			admin.GET("/health", adminHandler.GetHealthStatus)
+			admin.GET("/health", viewer, adminHandler.GetHealthStatus)
 
 			// 数据导出
-			admin.GET("/export", adminHandler.ExportData)
+			admin.GET("/export", owner, adminHandler.ExportData)
+
+			// 管理员账号
+			admin.GET("/admins", owner, adminHandler.ListAdmins)
+			admin.POST("/admins", owner, adminHandler.CreateAdmin)
+			admin.PUT("/admins/:id", owner, adminHandler.UpdateAdmin)
+			admin.DELETE("/admins/:id", owner, adminHandler.DeleteAdmin)
+			admin.DELETE("/admins/:id/2fa", owner, adminHandler.ResetAdminTOTP)
+
+			// 审计日志
+			admin.GET("/audit", owner, adminHandler.ListAuditLogs)
+			admin.GET("/audit/verify", owner, adminHandler.VerifyAuditLog)
+
+			// 当前管理员的两步验证
+			admin.POST("/me/2fa/setup", viewer, authHandler.SetupAdminTOTP)
+			admin.POST("/me/2fa/enable", viewer, authHandler.EnableAdminTOTP)
+			admin.POST("/me/2fa/recovery-codes", viewer, authHandler.RegenerateRecoveryCodes)
+			admin.DELETE("/me/2fa", viewer, authHandler.DisableAdminTOTP)
 		}
 	}
 
@@ -92,12 +92,14 @@ if [ ! -f .env ]; then
     REDIS_PASSWORD=$(generate_password 32)
     ADMIN_PASSWORD=$(generate_password 24)
     JWT_SECRET=$(generate_password 64)
+    ENCRYPTION_MASTER_KEYS="1:$(openssl rand -base64 32)"
 
     # 替换密码
     sed -i "s/DB_PASSWORD=.*/DB_PASSWORD=$DB_PASSWORD/" .env
     sed -i "s/REDIS_PASSWORD=.*/REDIS_PASSWORD=$REDIS_PASSWORD/" .env
     sed -i "s/ADMIN_PASSWORD=.*/ADMIN_PASSWORD=$ADMIN_PASSWORD/" .env
     sed -i "s/JWT_SECRET=.*/JWT_SECRET=$JWT_SECRET/" .env
+    sed -i "s|^ENCRYPTION_MASTER_KEYS=.*|ENCRYPTION_MASTER_KEYS=$ENCRYPTION_MASTER_KEYS|" .env
 
     print_success "密码已自动生成并保存到 .env 文件"
 
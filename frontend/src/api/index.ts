@@ -24,6 +24,8 @@ export type {
   UserStats,
   ClaimRecord,
   DonateRecord,
+  DonateJob,
+  DonateJobItem,
   DonatedKey,
   AdminConfig,
   SystemStats,
@@ -52,7 +54,8 @@ export type {
  * - getUserQuota() - 获取用户额度
  * - bindAccount() - 绑定账号
  * - claimDailyQuota() - 每日领取
- * - donateKeys() - 投喂 Keys
+ * - donateKeys() - 投喂 Keys（异步任务）
+ * - getDonateJob() - 查询投喂任务状态
  * - getUserClaimRecords() - 获取领取记录
  * - getUserDonateRecords() - 获取投喂记录
  *
@@ -10,6 +10,7 @@ import type {
   BindAccountForm,
   ClaimRecord,
   DonateRecord,
+  DonateJob,
   DonateForm,
   PaginationParams,
   PaginatedResponse
@@ -49,18 +50,19 @@ export const claimDailyQuota = () => {
 /**
  * 投喂 ModelScope Keys
  * @param data - 投喂表单数据（Keys 数组）
- * @returns 投喂结果，包含验证和处理信息
+ * @returns 排队中的投喂任务，需通过 getDonateJob 轮询处理结果
  */
 export const donateKeys = (data: DonateForm) => {
-  return request.post<{
-    valid_count: number
-    invalid_count: number
-    total_quota_added: number
-    message: string
-  }>('/user/donate', data, {
-    showSuccessMsg: true,
-    successMsg: '投喂成功'
-  })
+  return request.post<DonateJob>('/user/donate', data)
+}
+
+/**
+ * 获取投喂任务状态
+ * @param id - 投喂任务 ID
+ * @returns 投喂任务及每个 Key 的处理进度
+ */
+export const getDonateJob = (id: number) => {
+  return request.get<DonateJob>(`/user/donate/jobs/${id}`)
 }
 
 /**
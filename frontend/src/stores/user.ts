@@ -12,6 +12,7 @@ import {
   bindAccount,
   claimDailyQuota,
   donateKeys,
+  getDonateJob,
   getUserClaimRecords,
   getUserDonateRecords
 } from '@/api/user'
@@ -20,11 +21,16 @@ import type {
   UserStats,
   ClaimRecord,
   DonateRecord,
+  DonateJob,
   BindAccountForm,
   DonateForm,
   PaginationParams
 } from '@/types'
 
+// 投喂任务轮询间隔和最长等待时间（毫秒）
+const DONATE_JOB_POLL_INTERVAL = 2000
+const DONATE_JOB_POLL_TIMEOUT = 5 * 60 * 1000
+
 export const useUserStore = defineStore('user', () => {
   // ==================== State ====================
 
@@ -194,34 +200,59 @@ export const useUserStore = defineStore('user', () => {
     }
   }
 
+  /**
+   * 轮询投喂任务直到完成或失败，超时返回最后一次查询到的任务
+   */
+  const waitDonateJob = async (job: DonateJob): Promise<DonateJob> => {
+    const deadline = Date.now() + DONATE_JOB_POLL_TIMEOUT
+    let current = job
+
+    while (current.status !== 'completed' && current.status !== 'failed' && Date.now() < deadline) {
+      await new Promise((resolve) => setTimeout(resolve, DONATE_JOB_POLL_INTERVAL))
+      const { data } = await getDonateJob(current.id)
+      if (data.success && data.data) {
+        current = data.data
+      }
+    }
+
+    return current
+  }
+
   /**
    * 投喂 Keys
+   * 提交后任务在后台处理，轮询任务状态并返回最终结果，失败时返回 null
    */
-  const donate = async (form: DonateForm): Promise<boolean> => {
+  const donate = async (form: DonateForm): Promise<DonateJob | null> => {
     try {
       donateLoading.value = true
 
       const { data } = await donateKeys(form)
 
-      if (data.success) {
-        message.success(data.message || '投喂成功')
-
-        // 重新获取额度信息和统计信息
-        await fetchUserQuota()
-        await fetchUserStats()
+      if (!data.success || !data.data) {
+        message.error(data.message || '投喂失败')
+        return null
+      }
 
-        // 刷新投喂记录
-        await fetchDonateRecords({ page: 1, page_size: donatePagination.value.pageSize })
+      const job = await waitDonateJob(data.data)
 
-        return true
-      } else {
-        message.error(data.message || '投喂失败')
-        return false
+      if (job.status === 'failed') {
+        message.error(job.last_error?.Valid ? `投喂失败：${job.last_error.String}` : '投喂失败')
+      } else if (job.status !== 'completed') {
+        message.info('投喂任务仍在处理中，稍后可在投喂记录中查看结果')
       }
+
+      // 重新获取额度信息和统计信息
+      await fetchUserQuota()
+      await fetchUserStats()
+
+      // 刷新投喂记录
+      await fetchDonateRecords({ page: 1, page_size: donatePagination.value.pageSize })
+
+      return job.status === 'failed' ? null : job
     } catch (error: any) {
       console.error('Donate keys failed:', error)
       message.error(error.message || '投喂失败')
-      return false
+      return null
     } finally {
       donateLoading.value = false
     }
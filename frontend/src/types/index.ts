@@ -87,6 +87,31 @@ export interface DonateRecord {
   created_at?: string
 }
 
+/**
+ * 投喂任务中单个 Key 的处理进度
+ */
+export interface DonateJobItem {
+  key: string // 脱敏后的 Key
+  status: 'pending' | 'rejected' | 'valid' | 'pushed' | 'push_failed' | 'duplicate'
+  reason?: string
+}
+
+/**
+ * 异步投喂任务（提交后轮询直到 completed 或 failed）
+ */
+export interface DonateJob {
+  id: number
+  status: 'queued' | 'processing' | 'completed' | 'failed'
+  items: DonateJobItem[]
+  total_keys: number
+  processed_keys: number
+  valid_keys: number
+  quota_added: number
+  last_error?: { String: string; Valid: boolean }
+  created_at: string
+  updated_at: string
+}
+
 /**
  * 投喂的 Key 信息
  */
@@ -179,6 +204,7 @@ export interface OAuthCallback {
  * 登录表单
  */
 export interface LoginForm {
+  username?: string // 为空时使用初始管理员（ADMIN_USERNAME）
   password: string
 }
 
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -10,13 +11,15 @@ import (
 
 // Config 全局配置结构
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	LinuxDo  LinuxDoConfig
-	Kyx      KyxConfig
-	Admin    AdminConfig
-	Log      LogConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	Redis      RedisConfig
+	LinuxDo    LinuxDoConfig
+	OIDC       OIDCConfig
+	Kyx        KyxConfig
+	Admin      AdminConfig
+	Encryption EncryptionConfig
+	Log        LogConfig
 }
 
 // ServerConfig 服务器配置
@@ -60,24 +63,96 @@ type LinuxDoConfig struct {
 	AuthURL      string `mapstructure:"auth_url"`
 	TokenURL     string `mapstructure:"token_url"`
 	UserInfoURL  string `mapstructure:"user_info_url"`
+
+	// 账号定期校验
+	VerifyIntervalHours int `mapstructure:"verify_interval_hours"` // 校验间隔（小时），0 表示不校验
+	VerifyActiveDays    int `mapstructure:"verify_active_days"`    // 只校验最近多少天内活跃的用户
+}
+
+// OIDCConfig 通用 OIDC 登录配置（可选，配置 issuer 和客户端后启用）
+type OIDCConfig struct {
+	Name         string `mapstructure:"name"` // 提供方名称，用于路由和身份记录
+	DisplayName  string `mapstructure:"display_name"`
+	Issuer       string `mapstructure:"issuer"`
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+	RedirectURI  string `mapstructure:"redirect_uri"`
+	Scopes       string `mapstructure:"scopes"` // 空格分隔
 }
 
 // KyxConfig 公益站API配置
 type KyxConfig struct {
-	APIBase             string `mapstructure:"api_base"`
-	ModelScopeAPIBase   string `mapstructure:"modelscope_api_base"`
-	DefaultClaimQuota   int64  `mapstructure:"default_claim_quota"`
-	DonateQuotaPerKey   int64  `mapstructure:"donate_quota_per_key"`
-	MinQuotaThreshold   int64  `mapstructure:"min_quota_threshold"`
-	MaxDonateKeysPerDay int    `mapstructure:"max_donate_keys_per_day"`
-	FirstBindBonusQuota int64  `mapstructure:"first_bind_bonus_quota"`
+	APIBase                    string `mapstructure:"api_base"`
+	ModelScopeAPIBase          string `mapstructure:"modelscope_api_base"`
+	DefaultClaimQuota          int64  `mapstructure:"default_claim_quota"`
+	DonateQuotaPerKey          int64  `mapstructure:"donate_quota_per_key"`
+	MinQuotaThreshold          int64  `mapstructure:"min_quota_threshold"` // 剩余额度超过该值时限制领取，0 表示不限制
+	MaxDonateKeysPerDay        int    `mapstructure:"max_donate_keys_per_day"`
+	MaxDonateSubmissionsPerDay int    `mapstructure:"max_donate_submissions_per_day"`
+	MaxKeysPerSubmission       int    `mapstructure:"max_keys_per_submission"`
+	FirstBindBonusQuota        int64  `mapstructure:"first_bind_bonus_quota"`
+
+	// 投喂Key上游校验（关闭后不校验Key是否可用，所有格式正确的Key都会发放额度）
+	KeyVerifyDisabled    bool          `mapstructure:"key_verify_disabled"`
+	KeyVerifyModel       string        `mapstructure:"key_verify_model"` // 校验时调用的模型
+	KeyVerifyConcurrency int           `mapstructure:"key_verify_concurrency"`
+	KeyVerifyTimeout     time.Duration `mapstructure:"key_verify_timeout"`
+
+	// 异步投喂任务
+	DonateWorkers int `mapstructure:"donate_workers"`
+
+	// Linux.do 信任等级限制（0 表示不限制）
+	MinBindTrustLevel   int `mapstructure:"min_bind_trust_level"`
+	MinClaimTrustLevel  int `mapstructure:"min_claim_trust_level"`
+	MinDonateTrustLevel int `mapstructure:"min_donate_trust_level"`
+
+	// 已使用Key布隆过滤器
+	KeyFilterCapacity int64   `mapstructure:"key_filter_capacity"` // 预计Key数量
+	KeyFilterFPRate   float64 `mapstructure:"key_filter_fp_rate"`  // 期望误判率
+
+	// 领取周期
+	ClaimTimezone     string `mapstructure:"claim_timezone"`
+	ClaimPeriod       string `mapstructure:"claim_period"` // daily, weekly, rolling
+	ClaimRollingHours int    `mapstructure:"claim_rolling_hours"`
+
+	// 剩余额度超过 MinQuotaThreshold 时的处理方式
+	ClaimThresholdMode string `mapstructure:"claim_threshold_mode"` // block, scale
+
+	// 绑定挑战：用户需要把一次性验证码加入公益站显示名称以证明拥有该账号
+	BindChallenge    bool `mapstructure:"bind_challenge"`
+	BindChallengeTTL int  `mapstructure:"bind_challenge_ttl"` // minutes
+
+	// 绑定后多久才能申请解绑（小时），0 表示不限制
+	UnbindCooldownHours int `mapstructure:"unbind_cooldown_hours"`
+
+	// 用户组档位：超过多少天没有投喂降回默认用户组（0 表示不降级），后台评估间隔（分钟）
+	GroupDemoteInactiveDays  int `mapstructure:"group_demote_inactive_days"`
+	GroupTierIntervalMinutes int `mapstructure:"group_tier_interval_minutes"`
+
+	// 公益站 Session 健康检查：校验间隔（分钟，0 表示不校验），
+	// 配置管理员账号密码时 Session 失效后自动重新登录，失败时告警发送到 Webhook
+	SessionCheckIntervalMinutes int    `mapstructure:"session_check_interval_minutes"`
+	LoginUsername               string `mapstructure:"login_username"`
+	LoginPassword               string `mapstructure:"login_password"`
+	AlertWebhookURL             string `mapstructure:"alert_webhook_url"`
 }
 
 // AdminConfig 管理员配置
 type AdminConfig struct {
+	Username      string `mapstructure:"username"` // 管理员表为空时创建的首个 owner 账号
 	Password      string `mapstructure:"password"`
 	JWTSecret     string `mapstructure:"jwt_secret"`
 	SessionExpire int    `mapstructure:"session_expire"` // hours
+
+	// 软删除用户保留天数，到期后彻底清除，0 表示不自动清除
+	DeletedUserRetentionDays int `mapstructure:"deleted_user_retention_days"`
+}
+
+// EncryptionConfig 敏感数据加密配置
+type EncryptionConfig struct {
+	MasterKeys string `mapstructure:"master_keys"` // 1:<base64 32字节>,2:<base64 32字节>
+	KeyVersion int    `mapstructure:"key_version"` // 加密使用的主密钥版本，0 表示最大版本
+	Disabled   bool   `mapstructure:"disabled"`    // 显式关闭加密，release 模式下未配置主密钥时必须设置
 }
 
 // LogConfig 日志配置
@@ -166,24 +241,74 @@ func Load() (*Config, error) {
 		AuthURL:      viper.GetString("LINUX_DO_AUTH_URL"),
 		TokenURL:     viper.GetString("LINUX_DO_TOKEN_URL"),
 		UserInfoURL:  viper.GetString("LINUX_DO_USER_INFO_URL"),
+
+		VerifyIntervalHours: viper.GetInt("LINUX_DO_VERIFY_INTERVAL_HOURS"),
+		VerifyActiveDays:    viper.GetInt("LINUX_DO_VERIFY_ACTIVE_DAYS"),
+	}
+
+	// 解析 OIDC 配置
+	config.OIDC = OIDCConfig{
+		Name:         viper.GetString("OIDC_NAME"),
+		DisplayName:  viper.GetString("OIDC_DISPLAY_NAME"),
+		Issuer:       viper.GetString("OIDC_ISSUER"),
+		ClientID:     viper.GetString("OIDC_CLIENT_ID"),
+		ClientSecret: viper.GetString("OIDC_CLIENT_SECRET"),
+		RedirectURI:  viper.GetString("OIDC_REDIRECT_URI"),
+		Scopes:       viper.GetString("OIDC_SCOPES"),
 	}
 
 	// 解析公益站配置
 	config.Kyx = KyxConfig{
-		APIBase:             viper.GetString("KYX_API_BASE"),
-		ModelScopeAPIBase:   viper.GetString("MODELSCOPE_API_BASE"),
-		DefaultClaimQuota:   viper.GetInt64("DEFAULT_CLAIM_QUOTA"),
-		DonateQuotaPerKey:   viper.GetInt64("DONATE_QUOTA_PER_KEY"),
-		MinQuotaThreshold:   viper.GetInt64("MIN_QUOTA_THRESHOLD"),
-		MaxDonateKeysPerDay: viper.GetInt("MAX_DONATE_KEYS_PER_DAY"),
-		FirstBindBonusQuota: viper.GetInt64("FIRST_BIND_BONUS_QUOTA"),
+		APIBase:                    viper.GetString("KYX_API_BASE"),
+		ModelScopeAPIBase:          viper.GetString("MODELSCOPE_API_BASE"),
+		DefaultClaimQuota:          viper.GetInt64("DEFAULT_CLAIM_QUOTA"),
+		DonateQuotaPerKey:          viper.GetInt64("DONATE_QUOTA_PER_KEY"),
+		MinQuotaThreshold:          viper.GetInt64("MIN_QUOTA_THRESHOLD"),
+		MaxDonateKeysPerDay:        viper.GetInt("MAX_DONATE_KEYS_PER_DAY"),
+		MaxDonateSubmissionsPerDay: viper.GetInt("MAX_DONATE_SUBMISSIONS_PER_DAY"),
+		MaxKeysPerSubmission:       viper.GetInt("MAX_KEYS_PER_SUBMISSION"),
+		FirstBindBonusQuota:        viper.GetInt64("FIRST_BIND_BONUS_QUOTA"),
+		KeyVerifyDisabled:          viper.GetBool("KEY_VERIFY_DISABLED"),
+		KeyVerifyModel:             viper.GetString("KEY_VERIFY_MODEL"),
+		KeyVerifyConcurrency:       viper.GetInt("KEY_VERIFY_CONCURRENCY"),
+		KeyVerifyTimeout:           viper.GetDuration("KEY_VERIFY_TIMEOUT") * time.Second,
+		DonateWorkers:              viper.GetInt("DONATE_WORKERS"),
+		MinBindTrustLevel:          viper.GetInt("MIN_BIND_TRUST_LEVEL"),
+		MinClaimTrustLevel:         viper.GetInt("MIN_CLAIM_TRUST_LEVEL"),
+		MinDonateTrustLevel:        viper.GetInt("MIN_DONATE_TRUST_LEVEL"),
+		KeyFilterCapacity:          viper.GetInt64("KEY_FILTER_CAPACITY"),
+		KeyFilterFPRate:            viper.GetFloat64("KEY_FILTER_FP_RATE"),
+		ClaimTimezone:              viper.GetString("CLAIM_TIMEZONE"),
+		ClaimPeriod:                viper.GetString("CLAIM_PERIOD"),
+		ClaimRollingHours:          viper.GetInt("CLAIM_ROLLING_HOURS"),
+		ClaimThresholdMode:         viper.GetString("CLAIM_THRESHOLD_MODE"),
+		BindChallenge:              viper.GetBool("BIND_CHALLENGE"),
+		BindChallengeTTL:           viper.GetInt("BIND_CHALLENGE_TTL_MINUTES"),
+		UnbindCooldownHours:        viper.GetInt("UNBIND_COOLDOWN_HOURS"),
+		GroupDemoteInactiveDays:    viper.GetInt("GROUP_DEMOTE_INACTIVE_DAYS"),
+		GroupTierIntervalMinutes:   viper.GetInt("GROUP_TIER_INTERVAL_MINUTES"),
+
+		SessionCheckIntervalMinutes: viper.GetInt("KYX_SESSION_CHECK_INTERVAL_MINUTES"),
+		LoginUsername:               viper.GetString("KYX_LOGIN_USERNAME"),
+		LoginPassword:               viper.GetString("KYX_LOGIN_PASSWORD"),
+		AlertWebhookURL:             viper.GetString("ALERT_WEBHOOK_URL"),
 	}
 
 	// 解析管理员配置
 	config.Admin = AdminConfig{
+		Username:      viper.GetString("ADMIN_USERNAME"),
 		Password:      viper.GetString("ADMIN_PASSWORD"),
 		JWTSecret:     viper.GetString("JWT_SECRET"),
 		SessionExpire: viper.GetInt("SESSION_EXPIRE_HOURS"),
+
+		DeletedUserRetentionDays: viper.GetInt("DELETED_USER_RETENTION_DAYS"),
+	}
+
+	// 解析加密配置
+	config.Encryption = EncryptionConfig{
+		MasterKeys: viper.GetString("ENCRYPTION_MASTER_KEYS"),
+		KeyVersion: viper.GetInt("ENCRYPTION_KEY_VERSION"),
+		Disabled:   viper.GetBool("ENCRYPTION_DISABLED"),
 	}
 
 	// 解析日志配置
@@ -234,20 +359,55 @@ func setDefaults() {
 	viper.SetDefault("LINUX_DO_AUTH_URL", "https://connect.linux.do/oauth2/authorize")
 	viper.SetDefault("LINUX_DO_TOKEN_URL", "https://connect.linux.do/oauth2/token")
 	viper.SetDefault("LINUX_DO_USER_INFO_URL", "https://connect.linux.do/api/user")
+	viper.SetDefault("LINUX_DO_VERIFY_INTERVAL_HOURS", 24)
+	viper.SetDefault("LINUX_DO_VERIFY_ACTIVE_DAYS", 30)
+
+	// OIDC默认值
+	viper.SetDefault("OIDC_NAME", "oidc")
+	viper.SetDefault("OIDC_DISPLAY_NAME", "OIDC")
+	viper.SetDefault("OIDC_SCOPES", "openid profile email")
 
 	// 公益站默认值
 	viper.SetDefault("KYX_API_BASE", "https://api.kkyyxx.xyz")
 	viper.SetDefault("MODELSCOPE_API_BASE", "https://api-inference.modelscope.cn/v1")
 	viper.SetDefault("DEFAULT_CLAIM_QUOTA", 20000000)
-	viper.SetDefault("DONATE_QUOTA_PER_KEY", 25000000)
-	viper.SetDefault("MIN_QUOTA_THRESHOLD", 10000000)
-	viper.SetDefault("MAX_DONATE_KEYS_PER_DAY", 5)
-	viper.SetDefault("FIRST_BIND_BONUS_QUOTA", 50000000)
+	viper.SetDefault("DONATE_QUOTA_PER_KEY", 500000) // 每个Key $1，与升级前固定的单价一致
+	viper.SetDefault("MIN_QUOTA_THRESHOLD", 0)       // 默认不限制，避免领取额度大于阈值时所有用户都无法领取
+	viper.SetDefault("MAX_DONATE_KEYS_PER_DAY", 0)   // 默认不限制每日Key数量
+	viper.SetDefault("MAX_DONATE_SUBMISSIONS_PER_DAY", 10)
+	viper.SetDefault("MAX_KEYS_PER_SUBMISSION", 0)
+	viper.SetDefault("FIRST_BIND_BONUS_QUOTA", 20000000) // 与默认领取额度一致（升级前首次绑定奖励等于领取额度）
+	viper.SetDefault("KEY_VERIFY_DISABLED", false)
+	viper.SetDefault("KEY_VERIFY_MODEL", "Qwen/Qwen2.5-7B-Instruct")
+	viper.SetDefault("KEY_VERIFY_CONCURRENCY", 5)
+	viper.SetDefault("KEY_VERIFY_TIMEOUT", 10) // seconds
+	viper.SetDefault("DONATE_WORKERS", 2)
+	viper.SetDefault("MIN_BIND_TRUST_LEVEL", 0)
+	viper.SetDefault("MIN_CLAIM_TRUST_LEVEL", 0)
+	viper.SetDefault("MIN_DONATE_TRUST_LEVEL", 0)
+	viper.SetDefault("KEY_FILTER_CAPACITY", 1000000)
+	viper.SetDefault("KEY_FILTER_FP_RATE", 0.001)
+	viper.SetDefault("CLAIM_TIMEZONE", "Asia/Shanghai")
+	viper.SetDefault("CLAIM_PERIOD", "daily")
+	viper.SetDefault("CLAIM_ROLLING_HOURS", 24)
+	viper.SetDefault("CLAIM_THRESHOLD_MODE", "block")
+	viper.SetDefault("BIND_CHALLENGE", false)
+	viper.SetDefault("BIND_CHALLENGE_TTL_MINUTES", 30)
+	viper.SetDefault("UNBIND_COOLDOWN_HOURS", 168) // 7 days
+	viper.SetDefault("GROUP_DEMOTE_INACTIVE_DAYS", 0)
+	viper.SetDefault("GROUP_TIER_INTERVAL_MINUTES", 60)
+	viper.SetDefault("KYX_SESSION_CHECK_INTERVAL_MINUTES", 5)
 
 	// 管理员默认值
+	viper.SetDefault("ADMIN_USERNAME", "admin")
 	viper.SetDefault("ADMIN_PASSWORD", "admin123")
 	viper.SetDefault("JWT_SECRET", "your-secret-key-please-change-in-production")
 	viper.SetDefault("SESSION_EXPIRE_HOURS", 168) // 7 days
+	viper.SetDefault("DELETED_USER_RETENTION_DAYS", 30)
+
+	// 加密默认值
+	viper.SetDefault("ENCRYPTION_KEY_VERSION", 0)
+	viper.SetDefault("ENCRYPTION_DISABLED", false)
 
 	// 日志默认值
 	viper.SetDefault("LOG_LEVEL", "info")
@@ -292,6 +452,17 @@ func bindEnvVariables() {
 	viper.BindEnv("LINUX_DO_AUTH_URL")
 	viper.BindEnv("LINUX_DO_TOKEN_URL")
 	viper.BindEnv("LINUX_DO_USER_INFO_URL")
+	viper.BindEnv("LINUX_DO_VERIFY_INTERVAL_HOURS")
+	viper.BindEnv("LINUX_DO_VERIFY_ACTIVE_DAYS")
+
+	// OIDC
+	viper.BindEnv("OIDC_NAME")
+	viper.BindEnv("OIDC_DISPLAY_NAME")
+	viper.BindEnv("OIDC_ISSUER")
+	viper.BindEnv("OIDC_CLIENT_ID")
+	viper.BindEnv("OIDC_CLIENT_SECRET")
+	viper.BindEnv("OIDC_REDIRECT_URI")
+	viper.BindEnv("OIDC_SCOPES")
 
 	// 公益站
 	viper.BindEnv("KYX_API_BASE")
@@ -300,12 +471,44 @@ func bindEnvVariables() {
 	viper.BindEnv("DONATE_QUOTA_PER_KEY")
 	viper.BindEnv("MIN_QUOTA_THRESHOLD")
 	viper.BindEnv("MAX_DONATE_KEYS_PER_DAY")
+	viper.BindEnv("MAX_DONATE_SUBMISSIONS_PER_DAY")
+	viper.BindEnv("MAX_KEYS_PER_SUBMISSION")
 	viper.BindEnv("FIRST_BIND_BONUS_QUOTA")
+	viper.BindEnv("KEY_VERIFY_DISABLED")
+	viper.BindEnv("KEY_VERIFY_MODEL")
+	viper.BindEnv("KEY_VERIFY_CONCURRENCY")
+	viper.BindEnv("KEY_VERIFY_TIMEOUT")
+	viper.BindEnv("DONATE_WORKERS")
+	viper.BindEnv("MIN_BIND_TRUST_LEVEL")
+	viper.BindEnv("MIN_CLAIM_TRUST_LEVEL")
+	viper.BindEnv("MIN_DONATE_TRUST_LEVEL")
+	viper.BindEnv("KEY_FILTER_CAPACITY")
+	viper.BindEnv("KEY_FILTER_FP_RATE")
+	viper.BindEnv("CLAIM_TIMEZONE")
+	viper.BindEnv("CLAIM_PERIOD")
+	viper.BindEnv("CLAIM_ROLLING_HOURS")
+	viper.BindEnv("CLAIM_THRESHOLD_MODE")
+	viper.BindEnv("BIND_CHALLENGE")
+	viper.BindEnv("BIND_CHALLENGE_TTL_MINUTES")
+	viper.BindEnv("UNBIND_COOLDOWN_HOURS")
+	viper.BindEnv("GROUP_DEMOTE_INACTIVE_DAYS")
+	viper.BindEnv("GROUP_TIER_INTERVAL_MINUTES")
+	viper.BindEnv("KYX_SESSION_CHECK_INTERVAL_MINUTES")
+	viper.BindEnv("KYX_LOGIN_USERNAME")
+	viper.BindEnv("KYX_LOGIN_PASSWORD")
+	viper.BindEnv("ALERT_WEBHOOK_URL")
 
 	// 管理员
+	viper.BindEnv("ADMIN_USERNAME")
 	viper.BindEnv("ADMIN_PASSWORD")
 	viper.BindEnv("JWT_SECRET")
 	viper.BindEnv("SESSION_EXPIRE_HOURS")
+	viper.BindEnv("DELETED_USER_RETENTION_DAYS")
+
+	// 加密
+	viper.BindEnv("ENCRYPTION_MASTER_KEYS")
+	viper.BindEnv("ENCRYPTION_KEY_VERSION")
+	viper.BindEnv("ENCRYPTION_DISABLED")
 
 	// 日志
 	viper.BindEnv("LOG_LEVEL")
@@ -314,6 +517,29 @@ func bindEnvVariables() {
 	viper.BindEnv("LOG_PATH")
 }
 
+// Warnings 返回不影响启动但需要注意的配置问题，由调用方通过日志输出
+func (c *Config) Warnings() []string {
+	var warnings []string
+
+	if c.Database.Password == "" {
+		warnings = append(warnings, "database password is empty")
+	}
+
+	if c.Admin.Password == "admin123" {
+		warnings = append(warnings, "using default admin password, please change it in production")
+	}
+
+	if c.Admin.JWTSecret == "your-secret-key-please-change-in-production" {
+		warnings = append(warnings, "using default JWT secret, please change it in production")
+	}
+
+	if c.Encryption.MasterKeys == "" {
+		warnings = append(warnings, "ENCRYPTION_MASTER_KEYS not set, donated keys and secrets are stored unencrypted")
+	}
+
+	return warnings
+}
+
 // Validate 验证配置
 func (c *Config) Validate() error {
 	// 验证必填项
@@ -325,10 +551,6 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("database configuration is incomplete")
 	}
 
-	if c.Database.Password == "" {
-		fmt.Println("Warning: database password is empty")
-	}
-
 	if c.Redis.Host == "" {
 		return fmt.Errorf("redis host is required")
 	}
@@ -341,12 +563,21 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("Linux Do redirect URI is required")
 	}
 
-	if c.Admin.Password == "admin123" {
-		fmt.Println("Warning: using default admin password, please change it in production")
+	if c.OIDC.Enabled() {
+		if c.OIDC.ClientSecret == "" || c.OIDC.RedirectURI == "" {
+			return fmt.Errorf("OIDC client secret and redirect URI are required")
+		}
+		if c.OIDC.Name == "linuxdo" {
+			return fmt.Errorf("OIDC provider name must not be linuxdo")
+		}
 	}
 
-	if c.Admin.JWTSecret == "your-secret-key-please-change-in-production" {
-		fmt.Println("Warning: using default JWT secret, please change it in production")
+	// 验证敏感数据加密：release 模式下必须配置主密钥，除非显式关闭加密
+	if c.Encryption.Disabled && c.Encryption.MasterKeys != "" {
+		return fmt.Errorf("ENCRYPTION_MASTER_KEYS must be empty when ENCRYPTION_DISABLED=true")
+	}
+	if c.Encryption.MasterKeys == "" && !c.Encryption.Disabled && c.Server.Mode == "release" {
+		return fmt.Errorf("ENCRYPTION_MASTER_KEYS is required in release mode unless ENCRYPTION_DISABLED=true")
 	}
 
 	// 验证服务器模式
@@ -365,6 +596,86 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid log level: %s", c.Log.Level)
 	}
 
+	// 验证领取周期
+	if _, err := time.LoadLocation(c.Kyx.ClaimTimezone); err != nil {
+		return fmt.Errorf("invalid claim timezone: %s", c.Kyx.ClaimTimezone)
+	}
+	switch c.Kyx.ClaimPeriod {
+	case "daily", "weekly":
+	case "rolling":
+		if c.Kyx.ClaimRollingHours <= 0 {
+			return fmt.Errorf("claim rolling hours must be positive")
+		}
+	default:
+		return fmt.Errorf("invalid claim period: %s (must be 'daily', 'weekly' or 'rolling')", c.Kyx.ClaimPeriod)
+	}
+
+	// 验证投喂Key上游校验：未显式关闭时必须配置校验接口，避免为不可用的Key发放额度
+	if !c.Kyx.KeyVerifyDisabled && (c.Kyx.ModelScopeAPIBase == "" || c.Kyx.KeyVerifyModel == "") {
+		return fmt.Errorf("MODELSCOPE_API_BASE and KEY_VERIFY_MODEL are required unless KEY_VERIFY_DISABLED=true")
+	}
+
+	// 验证额度阈值策略
+	if c.Kyx.MinQuotaThreshold < 0 {
+		return fmt.Errorf("min quota threshold cannot be negative")
+	}
+	if c.Kyx.ClaimThresholdMode != "block" && c.Kyx.ClaimThresholdMode != "scale" {
+		return fmt.Errorf("invalid claim threshold mode: %s (must be 'block' or 'scale')", c.Kyx.ClaimThresholdMode)
+	}
+
+	if c.Kyx.FirstBindBonusQuota < 0 {
+		return fmt.Errorf("first bind bonus quota cannot be negative")
+	}
+
+	// 验证绑定挑战有效期
+	if c.Kyx.BindChallenge && c.Kyx.BindChallengeTTL <= 0 {
+		return fmt.Errorf("bind challenge ttl must be positive")
+	}
+
+	if c.Kyx.UnbindCooldownHours < 0 {
+		return fmt.Errorf("unbind cooldown hours cannot be negative")
+	}
+
+	// 验证用户组档位配置
+	if c.Kyx.GroupDemoteInactiveDays < 0 {
+		return fmt.Errorf("group demote inactive days cannot be negative")
+	}
+	if c.Kyx.GroupTierIntervalMinutes <= 0 {
+		return fmt.Errorf("group tier interval minutes must be positive")
+	}
+
+	// 验证公益站 Session 健康检查配置
+	if c.Kyx.SessionCheckIntervalMinutes < 0 {
+		return fmt.Errorf("kyx session check interval minutes cannot be negative")
+	}
+	if (c.Kyx.LoginUsername == "") != (c.Kyx.LoginPassword == "") {
+		return fmt.Errorf("KYX_LOGIN_USERNAME and KYX_LOGIN_PASSWORD must be set together")
+	}
+	if c.Kyx.AlertWebhookURL != "" {
+		if u, err := url.Parse(c.Kyx.AlertWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid alert webhook url: %s", c.Kyx.AlertWebhookURL)
+		}
+	}
+
+	// 验证信任等级限制
+	for name, level := range map[string]int{
+		"bind":   c.Kyx.MinBindTrustLevel,
+		"claim":  c.Kyx.MinClaimTrustLevel,
+		"donate": c.Kyx.MinDonateTrustLevel,
+	} {
+		if level < 0 || level > 4 {
+			return fmt.Errorf("invalid min %s trust level: %d (must be between 0 and 4)", name, level)
+		}
+	}
+
+	// 验证布隆过滤器参数
+	if c.Kyx.KeyFilterCapacity <= 0 {
+		return fmt.Errorf("key filter capacity must be positive")
+	}
+	if c.Kyx.KeyFilterFPRate <= 0 || c.Kyx.KeyFilterFPRate >= 1 {
+		return fmt.Errorf("key filter false positive rate must be between 0 and 1")
+	}
+
 	return nil
 }
 
@@ -389,6 +700,11 @@ func (c *RedisConfig) GetRedisAddr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
 }
 
+// Enabled 是否启用 OIDC 登录
+func (c *OIDCConfig) Enabled() bool {
+	return c.Issuer != "" && c.ClientID != ""
+}
+
 // IsDevelopment 是否开发模式
 func (c *ServerConfig) IsDevelopment() bool {
 	return c.Mode == "debug"
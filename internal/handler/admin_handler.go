@@ -4,20 +4,27 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/middleware"
 	"github.com/yourusername/kyx-quota-bridge/internal/model"
 	"github.com/yourusername/kyx-quota-bridge/internal/service"
 )
 
 // AdminHandler 管理员处理器
 type AdminHandler struct {
-	adminService  *service.AdminService
-	userService   *service.UserService
-	quotaService  *service.QuotaService
-	donateService *service.DonateService
-	logger        *logrus.Logger
+	adminService   *service.AdminService
+	userService    *service.UserService
+	quotaService   *service.QuotaService
+	donateService  *service.DonateService
+	grantService   *service.QuotaGrantService
+	auditService   *service.AuditService
+	banService     *service.BanService
+	bindingService *service.BindingService
+	tierService    *service.GroupTierService
+	logger         *logrus.Logger
 }
 
 // NewAdminHandler 创建管理员处理器
@@ -26,14 +33,24 @@ func NewAdminHandler(
 	userService *service.UserService,
 	quotaService *service.QuotaService,
 	donateService *service.DonateService,
+	grantService *service.QuotaGrantService,
+	auditService *service.AuditService,
+	banService *service.BanService,
+	bindingService *service.BindingService,
+	tierService *service.GroupTierService,
 	logger *logrus.Logger,
 ) *AdminHandler {
 	return &AdminHandler{
-		adminService:  adminService,
-		userService:   userService,
-		quotaService:  quotaService,
-		donateService: donateService,
-		logger:        logger,
+		adminService:   adminService,
+		userService:    userService,
+		quotaService:   quotaService,
+		donateService:  donateService,
+		grantService:   grantService,
+		auditService:   auditService,
+		banService:     banService,
+		bindingService: bindingService,
+		tierService:    tierService,
+		logger:         logger,
 	}
 }
 
@@ -98,7 +115,8 @@ func (h *AdminHandler) UpdateConfig(c *gin.Context) {
 		"group_id":           req.GroupID,
 	}).Info("Received config update request")
 
-	if err := h.adminService.UpdateConfig(c.Request.Context(), &req); err != nil {
+	change, err := h.adminService.UpdateConfig(c.Request.Context(), &req)
+	if err != nil {
 		h.logger.WithError(err).WithFields(logrus.Fields{
 			"error_type": fmt.Sprintf("%T", err),
 			"error_msg":  err.Error(),
@@ -107,6 +125,8 @@ func (h *AdminHandler) UpdateConfig(c *gin.Context) {
 		return
 	}
 
+	middleware.SetAuditChange(c, change)
+
 	h.logger.Info("Admin config updated successfully")
 	c.JSON(http.StatusOK, model.NewResponse(nil, "Config updated successfully"))
 }
@@ -204,13 +224,41 @@ func (h *AdminHandler) GetAllStatistics(c *gin.Context) {
 	c.JSON(http.StatusOK, model.NewResponse(stats, "Statistics retrieved"))
 }
 
+// ListDeletedUsers 获取已删除的用户列表
+// @Summary 获取已删除的用户列表
+// @Description 获取保留期内已软删除、可以恢复的用户分页列表
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} model.PaginationResult
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/admin/users/deleted [get]
+// @Security BearerAuth
+func (h *AdminHandler) ListDeletedUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	result, err := h.adminService.ListDeletedUsers(c.Request.Context(), page, pageSize)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list deleted users")
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to list deleted users", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // DeleteUser 删除用户
 // @Summary 删除用户
-// @Description 删除指定用户及其所有数据
+// @Description 软删除指定用户（保留期内可以恢复），purge=true 时在一个事务中彻底清除用户及其所有数据
 // @Tags Admin
 // @Accept json
 // @Produce json
 // @Param linux_do_id path string true "Linux Do ID"
+// @Param purge query bool false "立即彻底清除"
 // @Success 200 {object} model.Response
 // @Failure 400 {object} model.ErrorResponse
 // @Failure 401 {object} model.ErrorResponse
@@ -224,16 +272,459 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.adminService.DeleteUser(c.Request.Context(), linuxDoID); err != nil {
+	current, exists := middleware.GetAdmin(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	purge := c.Query("purge") == "true"
+	if err := h.adminService.DeleteUser(c.Request.Context(), current, linuxDoID, purge); err != nil {
 		h.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to delete user")
 		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to delete user", err))
 		return
 	}
 
-	h.logger.WithField("linux_do_id", linuxDoID).Info("User deleted by admin")
+	h.logger.WithFields(logrus.Fields{
+		"linux_do_id": linuxDoID,
+		"purge":       purge,
+	}).Info("User deleted by admin")
 	c.JSON(http.StatusOK, model.NewResponse(nil, "User deleted successfully"))
 }
 
+// RestoreUser 恢复已删除的用户
+// @Summary 恢复已删除的用户
+// @Description 恢复保留期内软删除的用户，用户需要重新登录
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param linux_do_id path string true "Linux Do ID"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Router /api/admin/users/{linux_do_id}/restore [post]
+// @Security BearerAuth
+func (h *AdminHandler) RestoreUser(c *gin.Context) {
+	linuxDoID := c.Param("linux_do_id")
+	if linuxDoID == "" {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("linux_do_id is required", nil))
+		return
+	}
+
+	user, err := h.adminService.RestoreUser(c.Request.Context(), linuxDoID)
+	if err != nil {
+		h.logger.WithError(err).WithField("linux_do_id", linuxDoID).Warn("Failed to restore user")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to restore user", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(user, "User restored"))
+}
+
+// ForceLogoutUser 强制用户退出登录
+// @Summary 强制退出登录
+// @Description 删除指定用户在所有设备上的会话
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param linux_do_id path string true "Linux Do ID"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/admin/users/{linux_do_id}/sessions [delete]
+// @Security BearerAuth
+func (h *AdminHandler) ForceLogoutUser(c *gin.Context) {
+	linuxDoID := c.Param("linux_do_id")
+	if linuxDoID == "" {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("linux_do_id is required", nil))
+		return
+	}
+
+	count, err := h.adminService.ForceLogoutUser(c.Request.Context(), linuxDoID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to force logout user", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(gin.H{"revoked": count}, "User logged out from all devices"))
+}
+
+// ResetUserClaim 重置用户领取状态
+// @Summary 重置领取状态
+// @Description 清除用户当前周期的已领取缓存标记
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param linux_do_id path string true "Linux Do ID"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/admin/users/{linux_do_id}/claim/reset [post]
+// @Security BearerAuth
+func (h *AdminHandler) ResetUserClaim(c *gin.Context) {
+	linuxDoID := c.Param("linux_do_id")
+	if linuxDoID == "" {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("linux_do_id is required", nil))
+		return
+	}
+
+	if err := h.quotaService.ResetDailyClaim(c.Request.Context(), linuxDoID); err != nil {
+		h.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to reset claim status")
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to reset claim status", err))
+		return
+	}
+
+	h.logger.WithField("linux_do_id", linuxDoID).Info("Claim status reset by admin")
+	c.JSON(http.StatusOK, model.NewResponse(nil, "Claim status reset"))
+}
+
+// GetUserStatus 获取用户处置状态
+// @Summary 获取用户处置状态
+// @Description 获取用户当前状态（正常、暂停、封禁）、是否在观察名单中，以及全部处置历史；用户尚未登录时按 Linux.do ID 查询
+// @Tags Admin
+// @Produce json
+// @Param linux_do_id path string true "Linux Do ID"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/admin/users/{linux_do_id}/status [get]
+// @Security BearerAuth
+func (h *AdminHandler) GetUserStatus(c *gin.Context) {
+	linuxDoID := c.Param("linux_do_id")
+	if linuxDoID == "" {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("linux_do_id is required", nil))
+		return
+	}
+
+	status, history, err := h.banService.GetUserStatus(c.Request.Context(), linuxDoID)
+	if err != nil {
+		h.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get user status")
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to get user status", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(gin.H{
+		"status":  status,
+		"history": history,
+	}, "User status retrieved"))
+}
+
+// ListBans 获取处置记录
+// @Summary 获取处置记录
+// @Description 分页获取封禁、暂停和观察名单记录（含已解除的历史），可按 Linux.do ID、公益站用户ID、类型过滤
+// @Tags Admin
+// @Produce json
+// @Param linux_do_id query string false "Linux Do ID"
+// @Param kyx_user_id query int false "Kyx user ID"
+// @Param kind query string false "ban / suspend / watch"
+// @Param active query bool false "Only active records"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} model.PaginationResult
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/admin/bans [get]
+// @Security BearerAuth
+func (h *AdminHandler) ListBans(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	filter := model.UserBanFilter{
+		LinuxDoID:  c.Query("linux_do_id"),
+		Kind:       c.Query("kind"),
+		ActiveOnly: c.Query("active") == "true",
+	}
+
+	switch filter.Kind {
+	case "", model.UserBanKindBan, model.UserBanKindSuspend, model.UserBanKindWatch:
+	default:
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid ban kind", nil))
+		return
+	}
+
+	if value := c.Query("kyx_user_id"); value != "" {
+		kyxUserID, err := strconv.Atoi(value)
+		if err != nil || kyxUserID <= 0 {
+			c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid kyx_user_id", err))
+			return
+		}
+		filter.KyxUserID = kyxUserID
+	}
+
+	bans, total, err := h.banService.ListBans(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list user bans")
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to list bans", err))
+		return
+	}
+
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+
+	result := &model.PaginationResult{
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+		TotalPages: totalPages,
+		HasMore:    page < totalPages,
+		Data:       bans,
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// CreateBan 封禁、暂停用户或加入观察名单
+// @Summary 封禁或暂停用户
+// @Description 按 Linux.do ID 或公益站用户ID 封禁、暂停用户或加入观察名单，可在用户首次登录前设置；封禁和暂停立即清除用户的登录会话
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body model.CreateUserBanRequest true "Ban request"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /api/admin/bans [post]
+// @Security BearerAuth
+func (h *AdminHandler) CreateBan(c *gin.Context) {
+	var req model.CreateUserBanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid request body", err))
+		return
+	}
+
+	current, exists := middleware.GetAdmin(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	ban, err := h.banService.CreateBan(c.Request.Context(), current, &req)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to create user ban")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to create ban", err))
+		return
+	}
+
+	target := ban.LinuxDoID
+	if target == "" {
+		target = "kyx:" + strconv.Itoa(ban.KyxUserID)
+	}
+	middleware.SetAuditTarget(c, target)
+
+	c.JSON(http.StatusOK, model.NewResponse(ban, "Ban applied"))
+}
+
+// LiftBan 解除处置
+// @Summary 解除处置
+// @Description 解除封禁、暂停或移出观察名单，记录保留在处置历史中
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Ban ID"
+// @Param request body model.LiftUserBanRequest false "Lift request"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /api/admin/bans/{id}/lift [post]
+// @Security BearerAuth
+func (h *AdminHandler) LiftBan(c *gin.Context) {
+	banID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || banID <= 0 {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid ban id", err))
+		return
+	}
+
+	var req model.LiftUserBanRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid request body", err))
+			return
+		}
+	}
+
+	current, exists := middleware.GetAdmin(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	ban, err := h.banService.LiftBan(c.Request.Context(), current, banID, req.Reason)
+	if err != nil {
+		h.logger.WithError(err).WithField("ban_id", banID).Warn("Failed to lift user ban")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to lift ban", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(ban, "Ban lifted"))
+}
+
+// ListUnbindRequests 获取解绑申请列表
+// @Summary 获取解绑申请列表
+// @Description 获取用户提交的解绑申请，status 为 pending 时只返回待处理的申请
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param status query string false "pending / approved / rejected / cancelled"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} model.PaginationResult
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Router /api/admin/unbind-requests [get]
+// @Security BearerAuth
+func (h *AdminHandler) ListUnbindRequests(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	result, err := h.bindingService.ListUnbindRequests(c.Request.Context(), c.Query("status"), page, pageSize)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to list unbind requests")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to list unbind requests", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ApproveUnbind 批准解绑申请
+// @Summary 批准解绑申请
+// @Description 批准待处理的解绑申请，解除用户的公益站账号绑定并记录到绑定历史
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Unbind request ID"
+// @Param request body model.ReviewUnbindRequest false "Review request"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /api/admin/unbind-requests/{id}/approve [post]
+// @Security BearerAuth
+func (h *AdminHandler) ApproveUnbind(c *gin.Context) {
+	h.reviewUnbind(c, true)
+}
+
+// RejectUnbind 拒绝解绑申请
+// @Summary 拒绝解绑申请
+// @Description 拒绝待处理的解绑申请，用户保持当前绑定
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Unbind request ID"
+// @Param request body model.ReviewUnbindRequest false "Review request"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /api/admin/unbind-requests/{id}/reject [post]
+// @Security BearerAuth
+func (h *AdminHandler) RejectUnbind(c *gin.Context) {
+	h.reviewUnbind(c, false)
+}
+
+// reviewUnbind 批准或拒绝解绑申请
+func (h *AdminHandler) reviewUnbind(c *gin.Context, approve bool) {
+	requestID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || requestID <= 0 {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid unbind request id", err))
+		return
+	}
+
+	var req model.ReviewUnbindRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid request body", err))
+			return
+		}
+	}
+
+	current, exists := middleware.GetAdmin(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	var unbind *model.UnbindRequest
+	if approve {
+		unbind, err = h.bindingService.ApproveUnbind(c.Request.Context(), current, requestID, req.Note)
+	} else {
+		unbind, err = h.bindingService.RejectUnbind(c.Request.Context(), current, requestID, req.Note)
+	}
+	if err != nil {
+		h.logger.WithError(err).WithField("request_id", requestID).Warn("Failed to review unbind request")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to review unbind request", err))
+		return
+	}
+
+	middleware.SetAuditTarget(c, unbind.LinuxDoID)
+
+	message := "Unbind request rejected"
+	if approve {
+		message = "Unbind request approved"
+	}
+	c.JSON(http.StatusOK, model.NewResponse(unbind, message))
+}
+
+// GetBindingHistory 获取用户的绑定历史
+// @Summary 获取绑定历史
+// @Description 获取 Linux.do 用户的全部公益站账号绑定记录（包括已解绑和已清除用户的记录）
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param linux_do_id path string true "Linux Do ID"
+// @Success 200 {object} model.Response
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/admin/users/{linux_do_id}/bindings [get]
+// @Security BearerAuth
+func (h *AdminHandler) GetBindingHistory(c *gin.Context) {
+	linuxDoID := c.Param("linux_do_id")
+
+	history, err := h.bindingService.ListBindingHistory(c.Request.Context(), linuxDoID)
+	if err != nil {
+		h.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to list binding history")
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to list binding history", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(history, "Binding history retrieved"))
+}
+
+// ListGroupChanges 获取用户组调整记录
+// @Summary 获取用户组调整记录
+// @Description 获取按投喂档位自动调整公益站用户组的记录（包括调用失败的调整），可按 Linux.do ID 筛选
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param linux_do_id query string false "Linux Do ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} model.PaginationResult
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/admin/group-changes [get]
+// @Security BearerAuth
+func (h *AdminHandler) ListGroupChanges(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	result, err := h.tierService.ListChanges(c.Request.Context(), c.Query("linux_do_id"), page, pageSize)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list group changes")
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to list group changes", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // ListAllClaims 获取所有领取记录
 // @Summary 获取所有领取记录
 // @Description 获取所有用户的领取记录
@@ -310,6 +801,117 @@ func (h *AdminHandler) ListAllDonates(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// ListQuotaGrants 获取额度发放记录
+// @Summary 获取额度发放记录
+// @Description 获取额度发放发件箱中的记录，可按状态过滤（pending/delivering/delivered/review/dead）
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param status query string false "Grant status"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} model.PaginationResult
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/admin/grants [get]
+// @Security BearerAuth
+func (h *AdminHandler) ListQuotaGrants(c *gin.Context) {
+	status := c.Query("status")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	switch status {
+	case "", model.GrantStatusPending, model.GrantStatusDelivering, model.GrantStatusDelivered, model.GrantStatusReview, model.GrantStatusDead:
+	default:
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid grant status", nil))
+		return
+	}
+
+	grants, total, err := h.grantService.ListGrants(c.Request.Context(), status, page, pageSize)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list quota grants")
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to list quota grants", err))
+		return
+	}
+
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+
+	result := &model.PaginationResult{
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+		TotalPages: totalPages,
+		HasMore:    page < totalPages,
+		Data:       grants,
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ReplayQuotaGrant 重放额度发放
+// @Summary 重放额度发放
+// @Description 将死信、待核对或待投递的额度发放重置并立即尝试投递到公益站（待核对的发放需先确认公益站未到账）
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Grant ID"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Router /api/admin/grants/{id}/replay [post]
+// @Security BearerAuth
+func (h *AdminHandler) ReplayQuotaGrant(c *gin.Context) {
+	grantID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || grantID <= 0 {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid grant id", err))
+		return
+	}
+
+	grant, err := h.grantService.ReplayGrant(c.Request.Context(), grantID)
+	if err != nil {
+		h.logger.WithError(err).WithField("grant_id", grantID).Warn("Failed to replay quota grant")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to replay quota grant", err))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"grant_id": grantID,
+		"status":   grant.Status,
+	}).Info("Quota grant replayed by admin")
+
+	c.JSON(http.StatusOK, model.NewResponse(grant, "Quota grant replayed"))
+}
+
+// ConfirmQuotaGrant 确认额度发放已到账
+// @Summary 确认额度发放已到账
+// @Description 管理员在公益站核对待核对的额度发放已到账后，标记为已投递（不再投递）
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Grant ID"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Router /api/admin/grants/{id}/confirm [post]
+// @Security BearerAuth
+func (h *AdminHandler) ConfirmQuotaGrant(c *gin.Context) {
+	grantID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || grantID <= 0 {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid grant id", err))
+		return
+	}
+
+	grant, err := h.grantService.ConfirmGrant(c.Request.Context(), grantID)
+	if err != nil {
+		h.logger.WithError(err).WithField("grant_id", grantID).Warn("Failed to confirm quota grant")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to confirm quota grant", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(grant, "Quota grant confirmed"))
+}
+
 // GetRecentActivity 获取最近活动
 // @Summary 获取最近活动
 // @Description 获取系统最近的活动记录
@@ -397,6 +999,35 @@ func (h *AdminHandler) CleanOldKeys(c *gin.Context) {
 	))
 }
 
+// RebuildKeyFilter 重建已使用Key布隆过滤器
+// @Summary 重建Key布隆过滤器
+// @Description 从已使用Key表重建布隆过滤器，Redis 数据丢失或调整容量、误判率后使用
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.Response
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/admin/maintenance/keys/filter [post]
+// @Security BearerAuth
+func (h *AdminHandler) RebuildKeyFilter(c *gin.Context) {
+	count, err := h.donateService.RebuildKeyFilter(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to rebuild key filter")
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to rebuild key filter", err))
+		return
+	}
+
+	h.logger.WithField("count", count).Info("Key filter rebuilt by admin")
+
+	c.JSON(http.StatusOK, model.NewResponse(
+		gin.H{
+			"key_count": count,
+		},
+		"Key filter rebuilt",
+	))
+}
+
 // ClearCache 清除缓存
 // @Summary 清除缓存
 // @Description 清除指定类型的缓存
@@ -533,6 +1164,9 @@ func (h *AdminHandler) ExportData(c *gin.Context) {
 		return
 	}
 
+	// 导出包含用户数据，读取操作也记录审计日志
+	middleware.SetAuditTarget(c, "type="+dataType)
+
 	data, err := h.adminService.ExportData(c.Request.Context(), dataType)
 	if err != nil {
 		h.logger.WithError(err).WithField("type", dataType).Error("Failed to export data")
@@ -543,3 +1177,283 @@ func (h *AdminHandler) ExportData(c *gin.Context) {
 	h.logger.WithField("type", dataType).Info("Data exported by admin")
 	c.JSON(http.StatusOK, model.NewResponse(data, "Data exported successfully"))
 }
+
+// ListAdmins 获取管理员列表
+// @Summary 获取管理员列表
+// @Description 获取全部管理员账号（仅 owner）
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.Response
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/admin/admins [get]
+// @Security BearerAuth
+func (h *AdminHandler) ListAdmins(c *gin.Context) {
+	admins, err := h.adminService.ListAdmins(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list admins")
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to list admins", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(admins, "Admins retrieved"))
+}
+
+// CreateAdmin 创建管理员
+// @Summary 创建管理员
+// @Description 创建管理员账号并指定角色（仅 owner）
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body model.CreateAdminRequest true "Admin account"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /api/admin/admins [post]
+// @Security BearerAuth
+func (h *AdminHandler) CreateAdmin(c *gin.Context) {
+	var req model.CreateAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid request body", err))
+		return
+	}
+
+	admin, err := h.adminService.CreateAdmin(c.Request.Context(), &req)
+	if err != nil {
+		h.logger.WithError(err).WithField("username", req.Username).Warn("Failed to create admin")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to create admin", err))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"admin_id": admin.ID,
+		"username": admin.Username,
+		"role":     admin.Role,
+	}).Info("Admin created by admin")
+
+	c.JSON(http.StatusOK, model.NewResponse(admin, "Admin created"))
+}
+
+// UpdateAdmin 更新管理员
+// @Summary 更新管理员
+// @Description 修改管理员密码、角色或停用状态（仅 owner）
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Admin ID"
+// @Param request body model.UpdateAdminRequest true "Fields to update"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /api/admin/admins/{id} [put]
+// @Security BearerAuth
+func (h *AdminHandler) UpdateAdmin(c *gin.Context) {
+	adminID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || adminID <= 0 {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid admin id", err))
+		return
+	}
+
+	var req model.UpdateAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid request body", err))
+		return
+	}
+
+	admin, err := h.adminService.UpdateAdmin(c.Request.Context(), adminID, &req)
+	if err != nil {
+		h.logger.WithError(err).WithField("admin_id", adminID).Warn("Failed to update admin")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to update admin", err))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"admin_id": admin.ID,
+		"role":     admin.Role,
+		"disabled": admin.Disabled,
+	}).Info("Admin updated by admin")
+
+	c.JSON(http.StatusOK, model.NewResponse(admin, "Admin updated"))
+}
+
+// DeleteAdmin 删除管理员
+// @Summary 删除管理员
+// @Description 删除管理员账号，不能删除自己和最后一个 owner（仅 owner）
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Admin ID"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /api/admin/admins/{id} [delete]
+// @Security BearerAuth
+func (h *AdminHandler) DeleteAdmin(c *gin.Context) {
+	adminID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || adminID <= 0 {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid admin id", err))
+		return
+	}
+
+	current, exists := middleware.GetAdmin(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	if err := h.adminService.DeleteAdmin(c.Request.Context(), current.ID, adminID); err != nil {
+		h.logger.WithError(err).WithField("admin_id", adminID).Warn("Failed to delete admin")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to delete admin", err))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"admin_id":   adminID,
+		"deleted_by": current.ID,
+	}).Info("Admin deleted by admin")
+
+	c.JSON(http.StatusOK, model.NewResponse(nil, "Admin deleted"))
+}
+
+// ResetAdminTOTP 重置管理员两步验证
+// @Summary 重置管理员两步验证
+// @Description 关闭其他管理员的两步验证并清除密钥和恢复码，用于丢失验证器的情况（仅 owner）
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Admin ID"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /api/admin/admins/{id}/2fa [delete]
+// @Security BearerAuth
+func (h *AdminHandler) ResetAdminTOTP(c *gin.Context) {
+	adminID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || adminID <= 0 {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid admin id", err))
+		return
+	}
+
+	current, exists := middleware.GetAdmin(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	if err := h.adminService.ResetAdminTOTP(c.Request.Context(), current.ID, adminID); err != nil {
+		h.logger.WithError(err).WithField("admin_id", adminID).Warn("Failed to reset admin two-factor authentication")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to reset two-factor authentication", err))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"admin_id": adminID,
+		"reset_by": current.ID,
+	}).Warn("Admin two-factor authentication reset by owner")
+
+	c.JSON(http.StatusOK, model.NewResponse(nil, "Two-factor authentication reset"))
+}
+
+// ListAuditLogs 获取审计日志
+// @Summary 获取审计日志
+// @Description 分页获取管理员审计日志，可按操作人、动作和时间范围过滤（仅 owner）
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param admin_id query int false "Admin ID"
+// @Param actor query string false "Admin username"
+// @Param action query string false "Action, e.g. update_config"
+// @Param start query string false "Start time (RFC3339)"
+// @Param end query string false "End time (RFC3339, exclusive)"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} model.PaginationResult
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/admin/audit [get]
+// @Security BearerAuth
+func (h *AdminHandler) ListAuditLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	filter := model.AuditLogFilter{
+		Username: c.Query("actor"),
+		Action:   c.Query("action"),
+	}
+
+	if value := c.Query("admin_id"); value != "" {
+		adminID, err := strconv.Atoi(value)
+		if err != nil || adminID <= 0 {
+			c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid admin_id", err))
+			return
+		}
+		filter.AdminID = adminID
+	}
+
+	for param, dest := range map[string]*time.Time{"start": &filter.Start, "end": &filter.End} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid "+param+" time, expected RFC3339", err))
+			return
+		}
+		*dest = t
+	}
+
+	logs, total, err := h.auditService.ListLogs(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list audit logs")
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to list audit logs", err))
+		return
+	}
+
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+
+	result := &model.PaginationResult{
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+		TotalPages: totalPages,
+		HasMore:    page < totalPages,
+		Data:       logs,
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// VerifyAuditLog 校验审计日志哈希链
+// @Summary 校验审计日志
+// @Description 重新计算全部审计日志的哈希链，返回第一条被修改或缺失的记录（仅 owner）
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.Response
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/admin/audit/verify [get]
+// @Security BearerAuth
+func (h *AdminHandler) VerifyAuditLog(c *gin.Context) {
+	status, err := h.auditService.VerifyChain(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to verify audit log")
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to verify audit log", err))
+		return
+	}
+
+	message := "Audit log chain is intact"
+	if !status.Valid {
+		message = "Audit log chain is broken"
+	}
+	c.JSON(http.StatusOK, model.NewResponse(status, message))
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -12,29 +13,43 @@ import (
 
 // AuthHandler 认证处理器
 type AuthHandler struct {
-	authService *service.AuthService
-	logger      *logrus.Logger
+	authService  *service.AuthService
+	tokenService *service.AccessTokenService
+	logger       *logrus.Logger
 }
 
 // NewAuthHandler 创建认证处理器
-func NewAuthHandler(authService *service.AuthService, logger *logrus.Logger) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, tokenService *service.AccessTokenService, logger *logrus.Logger) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		logger:      logger,
+		authService:  authService,
+		tokenService: tokenService,
+		logger:       logger,
 	}
 }
 
+// GetProviders 获取可用的身份提供方
+// @Summary 获取身份提供方
+// @Description 列出已启用的登录方式
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} model.Response
+// @Router /api/auth/providers [get]
+func (h *AuthHandler) GetProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, model.NewResponse(h.authService.Providers(), "Identity providers retrieved successfully"))
+}
+
 // GetAuthURL 获取OAuth授权URL
 // @Summary 获取OAuth授权URL
-// @Description 生成Linux.do OAuth授权链接
+// @Description 生成OAuth授权链接，默认使用Linux.do；其他提供方只能登录已关联的账号
 // @Tags Auth
 // @Accept json
 // @Produce json
+// @Param provider query string false "Identity provider (default linuxdo)"
 // @Success 200 {object} model.Response
 // @Failure 500 {object} model.ErrorResponse
 // @Router /api/auth/url [get]
 func (h *AuthHandler) GetAuthURL(c *gin.Context) {
-	authURL, state, err := h.authService.GetAuthorizationURL(c.Request.Context())
+	authURL, state, err := h.authService.GetAuthorizationURL(c.Request.Context(), c.Query("provider"), 0)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to generate authorization URL")
 		c.JSON(http.StatusInternalServerError, model.NewErrorResponse(
@@ -57,7 +72,7 @@ func (h *AuthHandler) GetAuthURL(c *gin.Context) {
 
 // HandleCallback 处理OAuth回调
 // @Summary OAuth回调处理
-// @Description 处理Linux.do OAuth授权回调，创建用户会话
+// @Description 处理身份提供方的OAuth授权回调，登录时创建用户会话，关联身份时沿用当前会话
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -90,12 +105,20 @@ func (h *AuthHandler) HandleCallback(c *gin.Context) {
 	}
 
 	// 处理回调
-	user, sessionID, err := h.authService.HandleCallback(c.Request.Context(), code, state)
+	user, sessionID, err := h.authService.HandleCallback(c.Request.Context(), code, state, sessionMeta(c))
 	if err != nil {
 		h.logger.WithError(err).WithFields(logrus.Fields{
 			"code":  code,
 			"state": state,
 		}).Error("Failed to handle OAuth callback")
+		if isAccountBanError(err) {
+			c.JSON(http.StatusForbidden, model.NewErrorResponse("账号已被封禁或暂停，暂不能登录", err))
+			return
+		}
+		if isAccountDeletedError(err) {
+			c.JSON(http.StatusForbidden, model.NewErrorResponse("账号已被删除，请联系管理员", err))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, model.NewErrorResponse(
 			"failed to complete authentication",
 			err,
@@ -103,17 +126,13 @@ func (h *AuthHandler) HandleCallback(c *gin.Context) {
 		return
 	}
 
-	// 设置Cookie (使用 http.Cookie 以支持 SameSite 属性)
-	http.SetCookie(c.Writer, &http.Cookie{
-		Name:     "session_id",
-		Value:    sessionID,
-		Path:     "/",
-		Domain:   "",
-		MaxAge:   86400 * 7, // 7天
-		Secure:   true,      // HTTPS 环境必须为 true
-		HttpOnly: true,      // 防止 XSS 攻击
-		SameSite: http.SameSiteLaxMode, // 防止 CSRF 攻击
-	})
+	// 关联身份的回调沿用当前会话
+	if sessionID == "" {
+		c.Redirect(http.StatusFound, "/user/dashboard")
+		return
+	}
+
+	setSessionCookie(c, sessionID)
 
 	h.logger.WithFields(logrus.Fields{
 		"user_id":     user.ID,
@@ -157,17 +176,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		return
 	}
 
-	// 清除Cookie
-	http.SetCookie(c.Writer, &http.Cookie{
-		Name:     "session_id",
-		Value:    "",
-		Path:     "/",
-		Domain:   "",
-		MaxAge:   -1,
-		Secure:   true,
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-	})
+	clearSessionCookie(c)
 
 	h.logger.WithField("session_id", sessionID).Info("User logged out")
 
@@ -205,7 +214,7 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 
 // AdminLogin 管理员登录
 // @Summary 管理员登录
-// @Description 使用密码进行管理员登录，返回JWT token
+// @Description 使用管理员用户名和密码登录，返回包含角色的JWT token；启用两步验证时返回 challenge_token
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -226,30 +235,196 @@ func (h *AuthHandler) AdminLogin(c *gin.Context) {
 		return
 	}
 
-	// 验证密码并生成token
-	token, err := h.authService.AdminLogin(c.Request.Context(), req.Password)
+	// 验证用户名和密码并生成token（或两步验证挑战）
+	result, err := h.authService.AdminLogin(c.Request.Context(), req.Username, req.Password)
 	if err != nil {
-		h.logger.WithError(err).Warn("Admin login failed")
+		h.logger.WithError(err).WithField("username", req.Username).Warn("Admin login failed")
 		c.JSON(http.StatusUnauthorized, model.NewErrorResponse(
-			"invalid password",
+			"invalid username or password",
 			err,
 		))
 		return
 	}
 
-	h.logger.Info("Admin logged in successfully")
+	middleware.SetAuditActor(c, result.Admin)
+
+	message := "Admin login successful"
+	if result.TwoFactorRequired {
+		message = "Two-factor authentication required"
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(result, message))
+}
+
+// AdminLoginOTP 管理员两步验证登录
+// @Summary 管理员两步验证登录
+// @Description 提交登录挑战和验证器生成的验证码（或恢复码），返回JWT token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body model.AdminOTPLoginRequest true "Challenge and code"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Router /api/auth/admin/login/otp [post]
+func (h *AuthHandler) AdminLoginOTP(c *gin.Context) {
+	var req model.AdminOTPLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid request body", err))
+		return
+	}
+
+	result, err := h.authService.AdminLoginOTP(c.Request.Context(), req.ChallengeToken, req.Code)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("two-factor authentication failed", err))
+		return
+	}
+
+	middleware.SetAuditActor(c, result.Admin)
+
+	c.JSON(http.StatusOK, model.NewResponse(result, "Admin login successful"))
+}
+
+// SetupAdminTOTP 开始绑定两步验证
+// @Summary 开始绑定两步验证
+// @Description 为当前管理员生成 TOTP 密钥和二维码 URI，需调用启用接口提交验证码后生效
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Router /api/admin/me/2fa/setup [post]
+// @Security BearerAuth
+func (h *AuthHandler) SetupAdminTOTP(c *gin.Context) {
+	admin, exists := middleware.GetAdmin(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	setup, err := h.authService.SetupAdminTOTP(c.Request.Context(), admin)
+	if err != nil {
+		h.logger.WithError(err).WithField("admin_id", admin.ID).Warn("Failed to set up two-factor authentication")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to set up two-factor authentication", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(setup, "Scan the QR code and confirm with a verification code"))
+}
+
+// EnableAdminTOTP 启用两步验证
+// @Summary 启用两步验证
+// @Description 提交验证器生成的验证码确认绑定，返回一次性恢复码（只显示一次）
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body model.AdminOTPRequest true "Verification code"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Router /api/admin/me/2fa/enable [post]
+// @Security BearerAuth
+func (h *AuthHandler) EnableAdminTOTP(c *gin.Context) {
+	admin, exists := middleware.GetAdmin(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	var req model.AdminOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid request body", err))
+		return
+	}
+
+	codes, err := h.authService.EnableAdminTOTP(c.Request.Context(), admin.ID, req.Code)
+	if err != nil {
+		h.logger.WithError(err).WithField("admin_id", admin.ID).Warn("Failed to enable two-factor authentication")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to enable two-factor authentication", err))
+		return
+	}
 
 	c.JSON(http.StatusOK, model.NewResponse(
-		gin.H{
-			"token": token,
-		},
-		"Admin login successful",
+		gin.H{"recovery_codes": codes},
+		"Two-factor authentication enabled",
 	))
 }
 
+// RegenerateRecoveryCodes 重新生成恢复码
+// @Summary 重新生成恢复码
+// @Description 验证后重新生成恢复码，之前的恢复码全部失效
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body model.AdminOTPRequest true "Verification code"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Router /api/admin/me/2fa/recovery-codes [post]
+// @Security BearerAuth
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	admin, exists := middleware.GetAdmin(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	var req model.AdminOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid request body", err))
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(c.Request.Context(), admin.ID, req.Code)
+	if err != nil {
+		h.logger.WithError(err).WithField("admin_id", admin.ID).Warn("Failed to regenerate recovery codes")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to regenerate recovery codes", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(
+		gin.H{"recovery_codes": codes},
+		"Recovery codes regenerated",
+	))
+}
+
+// DisableAdminTOTP 关闭两步验证
+// @Summary 关闭两步验证
+// @Description 提交验证码或恢复码后关闭当前管理员的两步验证
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body model.AdminOTPRequest true "Verification code"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Router /api/admin/me/2fa [delete]
+// @Security BearerAuth
+func (h *AuthHandler) DisableAdminTOTP(c *gin.Context) {
+	admin, exists := middleware.GetAdmin(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	var req model.AdminOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid request body", err))
+		return
+	}
+
+	if err := h.authService.DisableAdminTOTP(c.Request.Context(), admin.ID, req.Code); err != nil {
+		h.logger.WithError(err).WithField("admin_id", admin.ID).Warn("Failed to disable two-factor authentication")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to disable two-factor authentication", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(nil, "Two-factor authentication disabled"))
+}
+
 // RefreshSession 刷新会话
 // @Summary 刷新会话
-// @Description 延长当前会话的有效期
+// @Description 使用新的会话ID重新签发当前会话并延长有效期，旧会话ID随即失效
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -268,9 +443,10 @@ func (h *AuthHandler) RefreshSession(c *gin.Context) {
 		return
 	}
 
-	// 刷新会话
-	if err := h.authService.RefreshSession(c.Request.Context(), sessionID); err != nil {
-		h.logger.WithError(err).WithField("session_id", sessionID).Error("Failed to refresh session")
+	// 刷新会话（轮换会话ID）
+	newSessionID, err := h.authService.RefreshSession(c.Request.Context(), sessionID, sessionMeta(c))
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to refresh session")
 		c.JSON(http.StatusInternalServerError, model.NewErrorResponse(
 			"failed to refresh session",
 			err,
@@ -278,7 +454,7 @@ func (h *AuthHandler) RefreshSession(c *gin.Context) {
 		return
 	}
 
-	h.logger.WithField("session_id", sessionID).Debug("Session refreshed")
+	setSessionCookie(c, newSessionID)
 
 	c.JSON(http.StatusOK, model.NewResponse(
 		nil,
@@ -327,3 +503,325 @@ func (h *AuthHandler) CheckAuth(c *gin.Context) {
 		"Authenticated",
 	))
 }
+
+// ListSessions 获取当前用户的登录设备
+// @Summary 获取登录设备
+// @Description 列出当前用户的全部有效会话（IP、User-Agent、最近活跃时间），current 标记当前会话
+// @Tags User
+// @Produce json
+// @Success 200 {object} model.Response
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/user/sessions [get]
+// @Security SessionAuth
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	user, exists := middleware.GetUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+	sessionID, _ := middleware.GetSessionID(c)
+
+	sessions, err := h.authService.ListUserSessions(c.Request.Context(), user.ID, sessionID)
+	if err != nil {
+		h.logger.WithError(err).WithField("user_id", user.ID).Error("Failed to list user sessions")
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to list sessions", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(sessions, "Sessions retrieved successfully"))
+}
+
+// RevokeSession 退出指定设备
+// @Summary 退出指定设备
+// @Description 删除当前用户的指定会话；删除的是当前会话时同时清除Cookie
+// @Tags User
+// @Produce json
+// @Param id path string true "Session ID (from session list)"
+// @Success 200 {object} model.Response
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /api/user/sessions/{id} [delete]
+// @Security SessionAuth
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	user, exists := middleware.GetUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+	currentID, _ := middleware.GetSessionID(c)
+
+	revokedID, err := h.authService.RevokeUserSession(c.Request.Context(), user.ID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.NewErrorResponse("failed to revoke session", err))
+		return
+	}
+
+	if revokedID == currentID {
+		clearSessionCookie(c)
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(nil, "Session revoked successfully"))
+}
+
+// RevokeAllSessions 退出全部设备
+// @Summary 退出全部设备
+// @Description 删除当前用户的全部会话；keep_current=true 时保留当前会话，仅退出其他设备
+// @Tags User
+// @Produce json
+// @Param keep_current query bool false "Keep the current session"
+// @Success 200 {object} model.Response
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/user/sessions [delete]
+// @Security SessionAuth
+func (h *AuthHandler) RevokeAllSessions(c *gin.Context) {
+	user, exists := middleware.GetUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	keepCurrent := c.Query("keep_current") == "true"
+	exceptID := ""
+	if keepCurrent {
+		exceptID, _ = middleware.GetSessionID(c)
+	}
+
+	revoked, err := h.authService.RevokeAllUserSessions(c.Request.Context(), user, exceptID)
+	if err != nil {
+		h.logger.WithError(err).WithField("user_id", user.ID).Error("Failed to revoke user sessions")
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to revoke sessions", err))
+		return
+	}
+
+	if !keepCurrent {
+		clearSessionCookie(c)
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":      user.ID,
+		"revoked":      revoked,
+		"keep_current": keepCurrent,
+	}).Info("User sessions revoked")
+
+	c.JSON(http.StatusOK, model.NewResponse(gin.H{"revoked": revoked}, "Sessions revoked successfully"))
+}
+
+// ListAccessTokens 获取个人访问令牌
+// @Summary 获取个人访问令牌
+// @Description 列出当前用户未撤销的个人访问令牌（不含令牌明文）
+// @Tags User
+// @Produce json
+// @Success 200 {object} model.Response
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/user/tokens [get]
+// @Security SessionAuth
+func (h *AuthHandler) ListAccessTokens(c *gin.Context) {
+	user, exists := middleware.GetUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	tokens, err := h.tokenService.ListTokens(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to list access tokens", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(tokens, "Access tokens retrieved successfully"))
+}
+
+// CreateAccessToken 创建个人访问令牌
+// @Summary 创建个人访问令牌
+// @Description 签发用于脚本调用的访问令牌（scopes: quota:read, claim, donate），令牌明文只在本次响应中返回
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param request body model.CreateAccessTokenRequest true "Token request"
+// @Success 201 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Router /api/user/tokens [post]
+// @Security SessionAuth
+func (h *AuthHandler) CreateAccessToken(c *gin.Context) {
+	user, exists := middleware.GetUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	var req model.CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid request body", err))
+		return
+	}
+
+	resp, err := h.tokenService.CreateToken(c.Request.Context(), user, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to create access token", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, model.NewResponse(resp, "Access token created, store it now as it will not be shown again"))
+}
+
+// RevokeAccessToken 撤销个人访问令牌
+// @Summary 撤销个人访问令牌
+// @Description 撤销当前用户的指定访问令牌，撤销后立即失效
+// @Tags User
+// @Produce json
+// @Param id path int true "Token ID"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /api/user/tokens/{id} [delete]
+// @Security SessionAuth
+func (h *AuthHandler) RevokeAccessToken(c *gin.Context) {
+	user, exists := middleware.GetUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid token id", err))
+		return
+	}
+
+	if err := h.tokenService.RevokeToken(c.Request.Context(), user.ID, id); err != nil {
+		c.JSON(http.StatusNotFound, model.NewErrorResponse("failed to revoke access token", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(nil, "Access token revoked successfully"))
+}
+
+// ListIdentities 获取关联的身份
+// @Summary 获取关联的身份
+// @Description 列出当前用户关联的身份提供方账号
+// @Tags User
+// @Produce json
+// @Success 200 {object} model.Response
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/user/identities [get]
+// @Security SessionAuth
+func (h *AuthHandler) ListIdentities(c *gin.Context) {
+	user, exists := middleware.GetUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	identities, err := h.authService.ListIdentities(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to list identities", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(identities, "Identities retrieved successfully"))
+}
+
+// LinkIdentity 关联其他提供方的身份
+// @Summary 关联身份
+// @Description 生成授权链接，授权完成后为当前用户关联该提供方的账号
+// @Tags User
+// @Produce json
+// @Param provider path string true "Identity provider"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Router /api/user/identities/{provider} [post]
+// @Security SessionAuth
+func (h *AuthHandler) LinkIdentity(c *gin.Context) {
+	user, exists := middleware.GetUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	provider := c.Param("provider")
+	if provider == model.IdentityProviderLinuxDo {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("linux.do identity is already linked", nil))
+		return
+	}
+
+	authURL, state, err := h.authService.GetAuthorizationURL(c.Request.Context(), provider, user.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to generate authorization URL", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(
+		gin.H{
+			"auth_url": authURL,
+			"state":    state,
+		},
+		"Authorization URL generated successfully",
+	))
+}
+
+// UnlinkIdentity 取消关联身份
+// @Summary 取消关联身份
+// @Description 取消当前用户与指定提供方账号的关联（Linux.do 身份不能取消）
+// @Tags User
+// @Produce json
+// @Param provider path string true "Identity provider"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Router /api/user/identities/{provider} [delete]
+// @Security SessionAuth
+func (h *AuthHandler) UnlinkIdentity(c *gin.Context) {
+	user, exists := middleware.GetUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	if err := h.authService.UnlinkIdentity(c.Request.Context(), user.ID, c.Param("provider")); err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to unlink identity", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(nil, "Identity unlinked successfully"))
+}
+
+// sessionMeta 当前请求的客户端信息
+func sessionMeta(c *gin.Context) model.SessionMeta {
+	return model.SessionMeta{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// setSessionCookie 设置会话Cookie (使用 http.Cookie 以支持 SameSite 属性)
+func setSessionCookie(c *gin.Context, sessionID string) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     "session_id",
+		Value:    sessionID,
+		Path:     "/",
+		MaxAge:   86400 * 7,            // 7天
+		Secure:   true,                 // HTTPS 环境必须为 true
+		HttpOnly: true,                 // 防止 XSS 攻击
+		SameSite: http.SameSiteLaxMode, // 防止 CSRF 攻击
+	})
+}
+
+// clearSessionCookie 清除会话Cookie
+func clearSessionCookie(c *gin.Context) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     "session_id",
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -14,10 +14,12 @@ import (
 
 // UserHandler 用户处理器
 type UserHandler struct {
-	userService   *service.UserService
-	quotaService  *service.QuotaService
-	donateService *service.DonateService
-	logger        *logrus.Logger
+	userService    *service.UserService
+	quotaService   *service.QuotaService
+	donateService  *service.DonateService
+	bindingService *service.BindingService
+	tierService    *service.GroupTierService
+	logger         *logrus.Logger
 }
 
 // NewUserHandler 创建用户处理器
@@ -25,13 +27,17 @@ func NewUserHandler(
 	userService *service.UserService,
 	quotaService *service.QuotaService,
 	donateService *service.DonateService,
+	bindingService *service.BindingService,
+	tierService *service.GroupTierService,
 	logger *logrus.Logger,
 ) *UserHandler {
 	return &UserHandler{
-		userService:   userService,
-		quotaService:  quotaService,
-		donateService: donateService,
-		logger:        logger,
+		userService:    userService,
+		quotaService:   quotaService,
+		donateService:  donateService,
+		bindingService: bindingService,
+		tierService:    tierService,
+		logger:         logger,
 	}
 }
 
@@ -49,7 +55,7 @@ func NewUserHandler(
 // @Router /api/user/bind [post]
 // @Security SessionAuth
 func (h *UserHandler) BindAccount(c *gin.Context) {
-	linuxDoID, exists := middleware.GetLinuxDoID(c)
+	userID, exists := middleware.GetUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
 		return
@@ -63,11 +69,11 @@ func (h *UserHandler) BindAccount(c *gin.Context) {
 	}
 
 	// 绑定账号
-	response, err := h.userService.BindAccount(c.Request.Context(), linuxDoID, req.Username)
+	response, err := h.userService.BindAccount(c.Request.Context(), userID, req.Username)
 	if err != nil {
 		h.logger.WithError(err).WithFields(logrus.Fields{
-			"linux_do_id": linuxDoID,
-			"username":    req.Username,
+			"user_id":  userID,
+			"username": req.Username,
 		}).Error("Failed to bind account")
 		
 		// 判断是否是 Session 未配置错误
@@ -76,13 +82,29 @@ func (h *UserHandler) BindAccount(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, model.NewErrorResponse("请联系管理员配置 Session 密钥后再进行绑定", err))
 			return
 		}
+		if isAccountBanError(err) {
+			c.JSON(http.StatusForbidden, model.NewErrorResponse("账号已被封禁或暂停，暂不能绑定", err))
+			return
+		}
+		if isTrustPolicyError(err) {
+			c.JSON(http.StatusForbidden, model.NewErrorResponse("Linux.do 账号信任等级不足或账号受限，暂不能绑定", err))
+			return
+		}
+		if isBindConflictError(err) {
+			c.JSON(http.StatusConflict, model.NewErrorResponse("该公益站账号已绑定其他 Linux.do 账号", err))
+			return
+		}
+		if isBindChallengeError(err) {
+			c.JSON(http.StatusBadRequest, model.NewErrorResponse("请先获取验证码并将其加入公益站显示名称", err))
+			return
+		}
 		
 		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to bind account", err))
 		return
 	}
 
 	h.logger.WithFields(logrus.Fields{
-		"linux_do_id":   linuxDoID,
+		"user_id":       userID,
 		"username":      req.Username,
 		"is_first_bind": response.IsFirstBind,
 	}).Info("Account bound successfully")
@@ -90,6 +112,44 @@ func (h *UserHandler) BindAccount(c *gin.Context) {
 	c.JSON(http.StatusOK, model.NewResponse(response, "Account bound successfully"))
 }
 
+// CreateBindChallenge 获取绑定验证码
+// @Summary 获取绑定验证码
+// @Description 挑战模式下生成一次性验证码，用户将其加入公益站显示名称后再提交绑定
+// @Tags User
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /api/user/bind/challenge [post]
+// @Security SessionAuth
+func (h *UserHandler) CreateBindChallenge(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	challenge, err := h.userService.CreateBindChallenge(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.WithError(err).WithField("user_id", userID).Warn("Failed to create bind challenge")
+		if isAccountBanError(err) || isTrustPolicyError(err) {
+			c.JSON(http.StatusForbidden, model.NewErrorResponse("账号受限，暂不能绑定", err))
+			return
+		}
+		if isBindConflictError(err) {
+			c.JSON(http.StatusConflict, model.NewErrorResponse("该公益站账号已绑定其他 Linux.do 账号", err))
+			return
+		}
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to create bind challenge", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(challenge, "Bind challenge created"))
+}
+
 // GetQuota 获取用户额度信息
 // @Summary 获取额度信息
 // @Description 获取用户在公益站的额度信息
@@ -102,16 +162,16 @@ func (h *UserHandler) BindAccount(c *gin.Context) {
 // @Router /api/user/quota [get]
 // @Security SessionAuth
 func (h *UserHandler) GetQuota(c *gin.Context) {
-	linuxDoID, exists := middleware.GetLinuxDoID(c)
+	userID, exists := middleware.GetUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
 		return
 	}
 
 	// 获取额度信息
-	quotaInfo, err := h.userService.GetQuotaInfo(c.Request.Context(), linuxDoID)
+	quotaInfo, err := h.userService.GetQuotaInfo(c.Request.Context(), userID)
 	if err != nil {
-		h.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get quota info")
+		h.logger.WithError(err).WithField("user_id", userID).Error("Failed to get quota info")
 		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to get quota info", err))
 		return
 	}
@@ -129,25 +189,34 @@ func (h *UserHandler) GetQuota(c *gin.Context) {
 // @Failure 400 {object} model.ErrorResponse
 // @Failure 401 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
+// @Failure 503 {object} model.ErrorResponse
 // @Router /api/user/claim [post]
 // @Security SessionAuth
 func (h *UserHandler) ClaimQuota(c *gin.Context) {
-	linuxDoID, exists := middleware.GetLinuxDoID(c)
+	userID, exists := middleware.GetUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
 		return
 	}
 
 	// 领取额度
-	record, err := h.quotaService.ClaimQuota(c.Request.Context(), linuxDoID)
+	record, err := h.quotaService.ClaimQuota(c.Request.Context(), userID)
 	if err != nil {
-		h.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to claim quota")
+		h.logger.WithError(err).WithField("user_id", userID).Error("Failed to claim quota")
+		if isAccountBanError(err) {
+			c.JSON(http.StatusForbidden, model.NewErrorResponse("账号已被封禁或暂停，暂不能领取", err))
+			return
+		}
+		if isUpstreamUnavailableError(err) {
+			c.JSON(http.StatusServiceUnavailable, model.NewErrorResponse("公益站暂时不可用，请稍后再试", err))
+			return
+		}
 		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to claim quota", err))
 		return
 	}
 
 	h.logger.WithFields(logrus.Fields{
-		"linux_do_id": linuxDoID,
+		"user_id":     userID,
 		"quota_added": record.QuotaAdded,
 	}).Info("Quota claimed successfully")
 
@@ -175,7 +244,7 @@ func (h *UserHandler) ClaimQuota(c *gin.Context) {
 // @Router /api/user/claims [get]
 // @Security SessionAuth
 func (h *UserHandler) GetClaimHistory(c *gin.Context) {
-	linuxDoID, exists := middleware.GetLinuxDoID(c)
+	userID, exists := middleware.GetUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
 		return
@@ -184,9 +253,9 @@ func (h *UserHandler) GetClaimHistory(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 
-	records, total, err := h.quotaService.GetClaimHistory(c.Request.Context(), linuxDoID, page, pageSize)
+	records, total, err := h.quotaService.GetClaimHistory(c.Request.Context(), userID, page, pageSize)
 	if err != nil {
-		h.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get claim history")
+		h.logger.WithError(err).WithField("user_id", userID).Error("Failed to get claim history")
 		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to get claim history", err))
 		return
 	}
@@ -207,19 +276,20 @@ func (h *UserHandler) GetClaimHistory(c *gin.Context) {
 
 // DonateKeys 投喂Keys
 // @Summary 投喂Keys
-// @Description 投喂OpenAI Keys并获得额度奖励
+// @Description 提交投喂任务，Key的校验、推送和额度发放在后台异步完成，通过任务ID查询进度
 // @Tags User
 // @Accept json
 // @Produce json
 // @Param request body model.DonateRequest true "Donate request"
-// @Success 200 {object} model.Response
+// @Success 202 {object} model.Response
 // @Failure 400 {object} model.ErrorResponse
 // @Failure 401 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
+// @Failure 503 {object} model.ErrorResponse
 // @Router /api/user/donate [post]
 // @Security SessionAuth
 func (h *UserHandler) DonateKeys(c *gin.Context) {
-	linuxDoID, exists := middleware.GetLinuxDoID(c)
+	userID, exists := middleware.GetUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
 		return
@@ -237,24 +307,72 @@ func (h *UserHandler) DonateKeys(c *gin.Context) {
 		return
 	}
 
-	// 投喂Keys
-	response, err := h.donateService.DonateKeys(c.Request.Context(), linuxDoID, req.Keys)
+	// 提交投喂任务
+	job, err := h.donateService.SubmitDonation(c.Request.Context(), userID, req.Keys)
 	if err != nil {
 		h.logger.WithError(err).WithFields(logrus.Fields{
-			"linux_do_id": linuxDoID,
-			"keys_count":  len(req.Keys),
-		}).Error("Failed to donate keys")
+			"user_id":    userID,
+			"keys_count": len(req.Keys),
+		}).Error("Failed to submit donate job")
+		if isAccountBanError(err) {
+			c.JSON(http.StatusForbidden, model.NewErrorResponse("账号已被封禁或暂停，暂不能投喂", err))
+			return
+		}
+		if isTrustPolicyError(err) {
+			c.JSON(http.StatusForbidden, model.NewErrorResponse("Linux.do 账号信任等级不足或账号受限，暂不能投喂", err))
+			return
+		}
+		if isUpstreamUnavailableError(err) {
+			c.JSON(http.StatusServiceUnavailable, model.NewErrorResponse("公益站暂时不可用，请稍后再试", err))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to donate keys", err))
 		return
 	}
 
-	h.logger.WithFields(logrus.Fields{
-		"linux_do_id": linuxDoID,
-		"valid_keys":  response.ValidKeys,
-		"quota_added": response.QuotaAdded,
-	}).Info("Keys donated successfully")
+	c.JSON(http.StatusAccepted, model.NewResponse(job, "Donation accepted"))
+}
 
-	c.JSON(http.StatusOK, model.NewResponse(response, "Keys donated successfully"))
+// GetDonateJob 获取投喂任务状态
+// @Summary 获取投喂任务状态
+// @Description 获取投喂任务的状态和每个Key的处理进度
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/user/donate/jobs/{id} [get]
+// @Security SessionAuth
+func (h *UserHandler) GetDonateJob(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	jobID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid job id", err))
+		return
+	}
+
+	job, err := h.donateService.GetDonateJob(c.Request.Context(), userID, jobID)
+	if err != nil {
+		h.logger.WithError(err).WithField("job_id", jobID).Error("Failed to get donate job")
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to get donate job", err))
+		return
+	}
+
+	if job == nil {
+		c.JSON(http.StatusNotFound, model.NewErrorResponse("donate job not found", nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(job, ""))
 }
 
 // GetDonateHistory 获取投喂历史
@@ -271,7 +389,7 @@ func (h *UserHandler) DonateKeys(c *gin.Context) {
 // @Router /api/user/donates [get]
 // @Security SessionAuth
 func (h *UserHandler) GetDonateHistory(c *gin.Context) {
-	linuxDoID, exists := middleware.GetLinuxDoID(c)
+	userID, exists := middleware.GetUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
 		return
@@ -280,9 +398,9 @@ func (h *UserHandler) GetDonateHistory(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 
-	records, total, err := h.donateService.GetDonateHistory(c.Request.Context(), linuxDoID, page, pageSize)
+	records, total, err := h.donateService.GetDonateHistory(c.Request.Context(), userID, page, pageSize)
 	if err != nil {
-		h.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get donate history")
+		h.logger.WithError(err).WithField("user_id", userID).Error("Failed to get donate history")
 		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to get donate history", err))
 		return
 	}
@@ -313,16 +431,16 @@ func (h *UserHandler) GetDonateHistory(c *gin.Context) {
 // @Router /api/user/statistics [get]
 // @Security SessionAuth
 func (h *UserHandler) GetStatistics(c *gin.Context) {
-	linuxDoID, exists := middleware.GetLinuxDoID(c)
+	userID, exists := middleware.GetUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
 		return
 	}
 
 	// 获取用户统计
-	stats, err := h.userService.GetStatistics(c.Request.Context(), linuxDoID)
+	stats, err := h.userService.GetStatistics(c.Request.Context(), userID)
 	if err != nil {
-		h.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get user statistics")
+		h.logger.WithError(err).WithField("user_id", userID).Error("Failed to get user statistics")
 		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to get statistics", err))
 		return
 	}
@@ -342,16 +460,16 @@ func (h *UserHandler) GetStatistics(c *gin.Context) {
 // @Router /api/user/profile [get]
 // @Security SessionAuth
 func (h *UserHandler) GetProfile(c *gin.Context) {
-	linuxDoID, exists := middleware.GetLinuxDoID(c)
+	userID, exists := middleware.GetUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
 		return
 	}
 
 	// 获取用户信息
-	user, err := h.userService.GetUser(c.Request.Context(), linuxDoID)
+	user, err := h.userService.GetUser(c.Request.Context(), userID)
 	if err != nil {
-		h.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get user profile")
+		h.logger.WithError(err).WithField("user_id", userID).Error("Failed to get user profile")
 		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to get profile", err))
 		return
 	}
@@ -362,12 +480,14 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 	}
 
 	// 获取统计信息
-	stats, _ := h.userService.GetStatistics(c.Request.Context(), linuxDoID)
+	stats, _ := h.userService.GetStatistics(c.Request.Context(), user.ID)
 
-	// 获取额度信息（如果已绑定）
+	// 获取额度信息和用户组档位进度（如果已绑定）
 	var quotaInfo *model.QuotaInfo
+	var tierProgress *model.GroupTierProgress
 	if user.KyxUserID > 0 {
-		quotaInfo, _ = h.userService.GetQuotaInfo(c.Request.Context(), linuxDoID)
+		quotaInfo, _ = h.userService.GetQuotaInfo(c.Request.Context(), userID)
+		tierProgress, _ = h.tierService.GetProgress(c.Request.Context(), user.ID)
 	}
 
 	c.JSON(http.StatusOK, model.NewResponse(
@@ -375,6 +495,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 			"user":       user,
 			"statistics": stats,
 			"quota":      quotaInfo,
+			"group_tier": tierProgress,
 		},
 		"Profile retrieved",
 	))
@@ -391,15 +512,15 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 // @Router /api/user/bind/status [get]
 // @Security SessionAuth
 func (h *UserHandler) CheckBindStatus(c *gin.Context) {
-	linuxDoID, exists := middleware.GetLinuxDoID(c)
+	userID, exists := middleware.GetUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
 		return
 	}
 
-	isBound, err := h.userService.IsAccountBound(c.Request.Context(), linuxDoID)
+	isBound, err := h.userService.IsAccountBound(c.Request.Context(), userID)
 	if err != nil {
-		h.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to check bind status")
+		h.logger.WithError(err).WithField("user_id", userID).Error("Failed to check bind status")
 		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to check bind status", err))
 		return
 	}
@@ -411,3 +532,128 @@ func (h *UserHandler) CheckBindStatus(c *gin.Context) {
 		"Bind status retrieved",
 	))
 }
+
+// RequestUnbind 提交解绑申请
+// @Summary 申请解绑
+// @Description 申请解除公益站账号绑定，绑定后冷却期内不能申请，管理员批准后才会解除绑定
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param request body model.CreateUnbindRequest false "Unbind request"
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Router /api/user/unbind [post]
+// @Security SessionAuth
+func (h *UserHandler) RequestUnbind(c *gin.Context) {
+	linuxDoID, exists := middleware.GetLinuxDoID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	var req model.CreateUnbindRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, model.NewErrorResponse("invalid request body", err))
+			return
+		}
+	}
+
+	unbind, err := h.bindingService.RequestUnbind(c.Request.Context(), linuxDoID, req.Reason)
+	if err != nil {
+		h.logger.WithError(err).WithField("linux_do_id", linuxDoID).Warn("Failed to request unbind")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to request unbind", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(unbind, "Unbind request submitted"))
+}
+
+// GetUnbindRequest 获取解绑申请
+// @Summary 获取解绑申请
+// @Description 获取最近一次解绑申请及其处理结果
+// @Tags User
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.Response
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /api/user/unbind [get]
+// @Security SessionAuth
+func (h *UserHandler) GetUnbindRequest(c *gin.Context) {
+	linuxDoID, exists := middleware.GetLinuxDoID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	unbind, err := h.bindingService.GetUnbindRequest(c.Request.Context(), linuxDoID)
+	if err != nil {
+		h.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get unbind request")
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to get unbind request", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(unbind, "Unbind request retrieved"))
+}
+
+// CancelUnbind 撤回解绑申请
+// @Summary 撤回解绑申请
+// @Description 撤回待处理的解绑申请
+// @Tags User
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.Response
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Router /api/user/unbind [delete]
+// @Security SessionAuth
+func (h *UserHandler) CancelUnbind(c *gin.Context) {
+	linuxDoID, exists := middleware.GetLinuxDoID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("not authenticated", nil))
+		return
+	}
+
+	if err := h.bindingService.CancelUnbind(c.Request.Context(), linuxDoID); err != nil {
+		h.logger.WithError(err).WithField("linux_do_id", linuxDoID).Warn("Failed to cancel unbind request")
+		c.JSON(http.StatusBadRequest, model.NewErrorResponse("failed to cancel unbind request", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewResponse(nil, "Unbind request cancelled"))
+}
+
+// isAccountBanError 是否为账号被管理员封禁或暂停（含定期校验产生的暂停）的错误
+func isAccountBanError(err error) bool {
+	msg := err.Error()
+	return strings.HasPrefix(msg, model.AccountBlockedBanned) || strings.HasPrefix(msg, model.AccountBlockedSuspended)
+}
+
+// isBindConflictError 是否为公益站账号已绑定其他 Linux.do 账号的错误
+func isBindConflictError(err error) bool {
+	return strings.HasPrefix(err.Error(), model.BindBlockedAccountTaken)
+}
+
+// isBindChallengeError 是否为未通过绑定挑战的错误
+func isBindChallengeError(err error) bool {
+	return strings.HasPrefix(err.Error(), model.BindBlockedChallenge)
+}
+
+// isAccountDeletedError 是否为账号已被管理员删除的错误
+func isAccountDeletedError(err error) bool {
+	return strings.HasPrefix(err.Error(), model.AccountBlockedDeleted)
+}
+
+// isUpstreamUnavailableError 是否为公益站暂时不可用（Session 失效且无法自动续期）的错误
+func isUpstreamUnavailableError(err error) bool {
+	return strings.HasPrefix(err.Error(), model.UpstreamUnavailable)
+}
+
+// isTrustPolicyError 是否为 Linux.do 信任等级或账号状态不满足要求的错误
+func isTrustPolicyError(err error) bool {
+	msg := err.Error()
+	return strings.HasPrefix(msg, model.AccountBlockedTrustLevel) || strings.HasPrefix(msg, model.AccountBlockedRestricted) ||
+		strings.HasPrefix(msg, model.AccountBlockedSuspended)
+}
@@ -0,0 +1,242 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/internal/service"
+)
+
+const (
+	// auditMaxBodySize 记录的请求体上限，超出时不记录请求内容
+	auditMaxBodySize = 64 << 10
+	// auditMaxErrorBody 用于提取失败原因的响应体上限
+	auditMaxErrorBody = 4 << 10
+	// auditWriteTimeout 写入审计日志的超时时间（不受客户端断开影响）
+	auditWriteTimeout = 5 * time.Second
+
+	auditTargetKey = "audit_target"
+	auditChangeKey = "audit_change"
+	auditActorKey  = "audit_actor"
+)
+
+// AuditMiddleware 管理员审计日志中间件
+type AuditMiddleware struct {
+	auditService *service.AuditService
+	logger       *logrus.Logger
+}
+
+// NewAuditMiddleware 创建管理员审计日志中间件
+func NewAuditMiddleware(auditService *service.AuditService, logger *logrus.Logger) *AuditMiddleware {
+	return &AuditMiddleware{
+		auditService: auditService,
+		logger:       logger,
+	}
+}
+
+// auditResponseWriter 保留失败响应的开头部分，用于记录失败原因
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write 写入响应
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString 写入字符串响应
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// capture 保存失败响应
+func (w *auditResponseWriter) capture(data []byte) {
+	if w.Status() < http.StatusBadRequest {
+		return
+	}
+	if remaining := auditMaxErrorBody - w.body.Len(); remaining > 0 {
+		if len(data) > remaining {
+			data = data[:remaining]
+		}
+		w.body.Write(data)
+	}
+}
+
+// errorMessage 从错误响应中提取失败原因
+func (w *auditResponseWriter) errorMessage() string {
+	var resp model.ErrorResponse
+	if err := json.Unmarshal(w.body.Bytes(), &resp); err == nil {
+		if resp.Error != "" {
+			return resp.Message + ": " + resp.Error
+		}
+		if resp.Message != "" {
+			return resp.Message
+		}
+	}
+	return http.StatusText(w.Status())
+}
+
+// Handler 返回审计处理函数
+// 记录全部非 GET 请求，以及处理器通过 SetAuditTarget / SetAuditChange 标记的 GET 请求（如数据导出）
+func (m *AuditMiddleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body []byte
+		if c.Request.Method != http.MethodGet && c.Request.Body != nil {
+			body, _ = io.ReadAll(io.LimitReader(c.Request.Body, auditMaxBodySize+1))
+			c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+		}
+
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		target, hasTarget := c.Get(auditTargetKey)
+		change, hasChange := c.Get(auditChangeKey)
+		if c.Request.Method == http.MethodGet && !hasTarget && !hasChange {
+			return
+		}
+
+		statusCode := writer.Status()
+		entry := &model.AdminAuditLog{
+			Action:     auditAction(c.HandlerName()),
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			IP:         c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+			StatusCode: statusCode,
+			Success:    statusCode < http.StatusBadRequest,
+		}
+		if !entry.Success {
+			entry.Error = writer.errorMessage()
+		}
+
+		if hasTarget {
+			entry.Target, _ = target.(string)
+		} else {
+			entry.Target = auditTargetFromParams(c.Params)
+		}
+
+		// 请求内容
+		request := model.JSONMap{}
+		if len(body) > auditMaxBodySize {
+			request["body_truncated"] = true
+		} else if len(body) > 0 {
+			var payload map[string]interface{}
+			if err := json.Unmarshal(body, &payload); err == nil {
+				for key, value := range payload {
+					request[key] = value
+				}
+			}
+		}
+		if query := c.Request.URL.Query(); len(query) > 0 {
+			params := make(map[string]interface{}, len(query))
+			for key := range query {
+				params[key] = query.Get(key)
+			}
+			request["query"] = params
+		}
+
+		if hasChange {
+			if ch, ok := change.(*model.AuditChange); ok {
+				entry.Before = ch.Before
+				entry.After = ch.After
+			}
+		} else if len(request) > 0 {
+			entry.After = request
+		}
+
+		// 操作人：已认证的管理员，其次是登录成功的管理员，最后是登录请求提交的用户名
+		admin, ok := GetAdmin(c)
+		if !ok {
+			if actor, exists := c.Get(auditActorKey); exists {
+				admin, ok = actor.(*model.Admin)
+			}
+		}
+		if ok && admin != nil {
+			entry.AdminID = sql.NullInt64{Int64: int64(admin.ID), Valid: true}
+			entry.AdminUsername = admin.Username
+		} else if username, isString := request["username"].(string); isString {
+			entry.AdminUsername = username
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), auditWriteTimeout)
+		defer cancel()
+
+		if err := m.auditService.Record(ctx, entry); err != nil {
+			m.logger.WithError(err).WithFields(logrus.Fields{
+				"action": entry.Action,
+				"path":   entry.Path,
+				"admin":  entry.AdminUsername,
+			}).Error("Failed to record admin audit log")
+		}
+	}
+}
+
+// SetAuditTarget 设置审计日志的操作目标（默认使用路径参数；GET 请求设置后也会被记录）
+func SetAuditTarget(c *gin.Context, target string) {
+	c.Set(auditTargetKey, target)
+}
+
+// SetAuditChange 设置审计日志的变更前后内容（替代默认记录的请求内容）
+func SetAuditChange(c *gin.Context, change *model.AuditChange) {
+	if change != nil {
+		c.Set(auditChangeKey, change)
+	}
+}
+
+// SetAuditActor 设置审计日志的操作人（用于登录等尚未携带管理员令牌的请求）
+func SetAuditActor(c *gin.Context, admin *model.Admin) {
+	if admin != nil {
+		c.Set(auditActorKey, admin)
+	}
+}
+
+// auditAction 由处理函数名生成动作名，如 (*AdminHandler).UpdateConfig-fm -> update_config
+func auditAction(handlerName string) string {
+	name := handlerName[strings.LastIndex(handlerName, ".")+1:]
+	name = strings.TrimSuffix(name, "-fm")
+
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && unicode.IsLower(runes[i-1])
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || nextLower {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// auditTargetFromParams 由路径参数生成操作目标，如 linux_do_id=123
+func auditTargetFromParams(params gin.Params) string {
+	if len(params) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(params))
+	for _, param := range params {
+		parts = append(parts, param.Key+"="+param.Value)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
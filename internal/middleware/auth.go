@@ -12,21 +12,37 @@ import (
 
 // AuthMiddleware 认证中间件
 type AuthMiddleware struct {
-	authService *service.AuthService
-	logger      *logrus.Logger
+	authService  *service.AuthService
+	tokenService *service.AccessTokenService
+	banService   *service.BanService
+	logger       *logrus.Logger
 }
 
 // NewAuthMiddleware 创建认证中间件
-func NewAuthMiddleware(authService *service.AuthService, logger *logrus.Logger) *AuthMiddleware {
+func NewAuthMiddleware(
+	authService *service.AuthService,
+	tokenService *service.AccessTokenService,
+	banService *service.BanService,
+	logger *logrus.Logger,
+) *AuthMiddleware {
 	return &AuthMiddleware{
-		authService: authService,
-		logger:      logger,
+		authService:  authService,
+		tokenService: tokenService,
+		banService:   banService,
+		logger:       logger,
 	}
 }
 
 // RequireAuth 要求用户认证
-func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
+// 未指定 scopes 时只接受会话Cookie；指定后也接受包含全部 scopes 的个人访问令牌（Authorization: Bearer）
+// 被封禁或暂停的用户返回 403
+func (m *AuthMiddleware) RequireAuth(scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+			m.authenticateToken(c, authHeader, scopes)
+			return
+		}
+
 		// 从Cookie获取session_id
 		sessionID, err := c.Cookie("session_id")
 		if err != nil || sessionID == "" {
@@ -36,8 +52,9 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
-		// 验证会话
-		user, err := m.authService.ValidateSession(c.Request.Context(), sessionID)
+		// 验证会话并记录活跃时间
+		meta := model.SessionMeta{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+		user, err := m.authService.AuthenticateSession(c.Request.Context(), sessionID, meta)
 		if err != nil {
 			m.logger.WithError(err).WithField("session_id", sessionID).Warn("Invalid session")
 			c.JSON(http.StatusUnauthorized, model.NewErrorResponse("session expired or invalid", err))
@@ -45,9 +62,14 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
+		if m.rejectBanned(c, user) {
+			return
+		}
+
 		// 将用户信息存入上下文
 		c.Set("user", user)
 		c.Set("session_id", sessionID)
+		c.Set("user_id", user.ID)
 		c.Set("linux_do_id", user.LinuxDoID)
 		c.Set("username", user.Username)
 
@@ -61,8 +83,93 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	}
 }
 
-// RequireAdmin 要求管理员认证
-func (m *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
+// authenticateToken 使用个人访问令牌认证
+func (m *AuthMiddleware) authenticateToken(c *gin.Context, authHeader string, scopes []string) {
+	if len(scopes) == 0 {
+		c.JSON(http.StatusForbidden, model.NewErrorResponse("access tokens are not accepted for this endpoint", nil))
+		c.Abort()
+		return
+	}
+
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("invalid authorization header", nil))
+		c.Abort()
+		return
+	}
+
+	user, token, err := m.tokenService.Authenticate(c.Request.Context(), parts[1], c.ClientIP())
+	if err != nil {
+		m.logger.WithError(err).WithField("path", c.Request.URL.Path).Warn("Invalid access token")
+		c.JSON(http.StatusUnauthorized, model.NewErrorResponse("invalid or expired access token", err))
+		c.Abort()
+		return
+	}
+
+	if m.rejectBanned(c, user) {
+		return
+	}
+
+	for _, scope := range scopes {
+		if !token.HasScope(scope) {
+			m.logger.WithFields(logrus.Fields{
+				"linux_do_id": user.LinuxDoID,
+				"token_id":    token.ID,
+				"scope":       scope,
+			}).Warn("Access token missing scope")
+			c.JSON(http.StatusForbidden, model.NewErrorResponse("access token missing scope: "+scope, nil))
+			c.Abort()
+			return
+		}
+	}
+
+	// 将用户信息存入上下文
+	c.Set("user", user)
+	c.Set("access_token", token)
+	c.Set("user_id", user.ID)
+	c.Set("linux_do_id", user.LinuxDoID)
+	c.Set("username", user.Username)
+
+	m.logger.WithFields(logrus.Fields{
+		"linux_do_id": user.LinuxDoID,
+		"token_id":    token.ID,
+		"path":        c.Request.URL.Path,
+	}).Debug("User authenticated via access token")
+
+	c.Next()
+}
+
+// rejectBanned 用户被封禁或暂停时返回 403 并中止请求
+func (m *AuthMiddleware) rejectBanned(c *gin.Context, user *model.User) bool {
+	ban, err := m.banService.Restriction(c.Request.Context(), user.LinuxDoID, user.KyxUserID)
+	if err != nil {
+		m.logger.WithError(err).WithField("linux_do_id", user.LinuxDoID).Error("Failed to check user ban")
+		c.JSON(http.StatusInternalServerError, model.NewErrorResponse("failed to check account status", nil))
+		c.Abort()
+		return true
+	}
+	if ban == nil {
+		return false
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"linux_do_id": user.LinuxDoID,
+		"ban_id":      ban.ID,
+		"kind":        ban.Kind,
+		"path":        c.Request.URL.Path,
+	}).Debug("Banned user rejected")
+
+	c.JSON(http.StatusForbidden, &model.ErrorResponse{
+		Success: false,
+		Message: ban.BlockedReason(),
+		Error:   service.BanMessage(ban),
+	})
+	c.Abort()
+	return true
+}
+
+// RequireRole 要求管理员认证，且管理员角色属于 roles 之一
+func (m *AuthMiddleware) RequireRole(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从Authorization header获取token
 		authHeader := c.GetHeader("Authorization")
@@ -76,7 +183,7 @@ func (m *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
 		// 提取Bearer token
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			m.logger.WithField("auth_header", authHeader).Warn("Invalid authorization header format")
+			m.logger.WithField("path", c.Request.URL.Path).Warn("Invalid authorization header format")
 			c.JSON(http.StatusUnauthorized, model.NewErrorResponse("invalid authorization header", nil))
 			c.Abort()
 			return
@@ -85,7 +192,7 @@ func (m *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
 		token := parts[1]
 
 		// 验证admin token
-		err := m.authService.ValidateAdminToken(token)
+		admin, err := m.authService.ValidateAdminToken(c.Request.Context(), token)
 		if err != nil {
 			m.logger.WithError(err).Warn("Invalid admin token")
 			c.JSON(http.StatusUnauthorized, model.NewErrorResponse("invalid or expired token", err))
@@ -93,10 +200,34 @@ func (m *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
 			return
 		}
 
-		// 设置管理员标识
+		// 验证角色
+		allowed := false
+		for _, role := range roles {
+			if admin.Role == role {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			m.logger.WithFields(logrus.Fields{
+				"admin_id": admin.ID,
+				"role":     admin.Role,
+				"path":     c.Request.URL.Path,
+			}).Warn("Admin role not permitted")
+			c.JSON(http.StatusForbidden, model.NewErrorResponse("insufficient permissions", nil))
+			c.Abort()
+			return
+		}
+
+		// 设置管理员信息
 		c.Set("is_admin", true)
+		c.Set("admin", admin)
 
-		m.logger.WithField("path", c.Request.URL.Path).Debug("Admin authenticated")
+		m.logger.WithFields(logrus.Fields{
+			"admin_id": admin.ID,
+			"role":     admin.Role,
+			"path":     c.Request.URL.Path,
+		}).Debug("Admin authenticated")
 
 		c.Next()
 	}
@@ -125,6 +256,7 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 		// 将用户信息存入上下文
 		c.Set("user", user)
 		c.Set("session_id", sessionID)
+		c.Set("user_id", user.ID)
 		c.Set("linux_do_id", user.LinuxDoID)
 		c.Set("username", user.Username)
 
@@ -142,6 +274,16 @@ func GetUser(c *gin.Context) (*model.User, bool) {
 	return nil, false
 }
 
+// GetUserID 从上下文获取内部用户ID
+func GetUserID(c *gin.Context) (int, bool) {
+	if userID, exists := c.Get("user_id"); exists {
+		if id, ok := userID.(int); ok {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
 // GetLinuxDoID 从上下文获取LinuxDoID
 func GetLinuxDoID(c *gin.Context) (string, bool) {
 	if linuxDoID, exists := c.Get("linux_do_id"); exists {
@@ -172,6 +314,16 @@ func GetSessionID(c *gin.Context) (string, bool) {
 	return "", false
 }
 
+// GetAccessToken 从上下文获取当前请求使用的个人访问令牌（会话认证时不存在）
+func GetAccessToken(c *gin.Context) (*model.AccessToken, bool) {
+	if token, exists := c.Get("access_token"); exists {
+		if t, ok := token.(*model.AccessToken); ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
 // IsAdmin 检查是否为管理员
 func IsAdmin(c *gin.Context) bool {
 	if isAdmin, exists := c.Get("is_admin"); exists {
@@ -181,3 +333,13 @@ func IsAdmin(c *gin.Context) bool {
 	}
 	return false
 }
+
+// GetAdmin 从上下文获取当前管理员
+func GetAdmin(c *gin.Context) (*model.Admin, bool) {
+	if admin, exists := c.Get("admin"); exists {
+		if a, ok := admin.(*model.Admin); ok {
+			return a, true
+		}
+	}
+	return nil, false
+}
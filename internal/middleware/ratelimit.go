@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -71,17 +72,18 @@ func (m *RateLimitMiddleware) RateLimitByIP(limit int64, window time.Duration) g
 }
 
 // RateLimitByUser 基于用户的限流
+// 使用个人访问令牌的请求还需满足令牌自身的每小时请求上限
 func (m *RateLimitMiddleware) RateLimitByUser(limit int64, window time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取用户标识
-		linuxDoID, exists := c.Get("linux_do_id")
+		userID, exists := GetUserID(c)
 		if !exists {
 			// 如果未认证，使用IP限流
 			m.RateLimitByIP(limit, window)(c)
 			return
 		}
 
-		identifier := linuxDoID.(string)
+		identifier := strconv.Itoa(userID)
 		key := model.RateLimitAPI + "user:" + identifier
 
 		allowed, remaining, err := m.checkRateLimit(c.Request.Context(), key, limit, window)
@@ -110,10 +112,52 @@ func (m *RateLimitMiddleware) RateLimitByUser(limit int64, window time.Duration)
 			return
 		}
 
+		if token, ok := GetAccessToken(c); ok && !m.checkTokenRateLimit(c, token) {
+			return
+		}
+
 		c.Next()
 	}
 }
 
+// checkTokenRateLimit 检查个人访问令牌的每小时请求上限，超出时中止请求并返回 false
+func (m *RateLimitMiddleware) checkTokenRateLimit(c *gin.Context, token *model.AccessToken) bool {
+	limit := int64(token.RateLimit)
+	key := fmt.Sprintf("%s%d", model.RateLimitToken, token.ID)
+
+	allowed, remaining, err := m.checkRateLimit(c.Request.Context(), key, limit, time.Hour)
+	if err != nil {
+		m.logger.WithError(err).WithField("token_id", token.ID).Error("Failed to check access token rate limit")
+		return true
+	}
+
+	// 令牌限额更严格时以令牌为准
+	current, parseErr := strconv.ParseInt(c.Writer.Header().Get("X-RateLimit-Remaining"), 10, 64)
+	if parseErr != nil || remaining < current || !allowed {
+		c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
+		c.Header("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
+		c.Header("X-RateLimit-Reset", fmt.Sprintf("%d", time.Now().Add(time.Hour).Unix()))
+	}
+
+	if !allowed {
+		m.logger.WithFields(logrus.Fields{
+			"user":     token.UserID,
+			"token_id": token.ID,
+			"path":     c.Request.URL.Path,
+			"limit":    limit,
+		}).Warn("Access token rate limit exceeded")
+
+		c.JSON(http.StatusTooManyRequests, model.NewErrorResponse(
+			fmt.Sprintf("access token rate limit exceeded (max %d requests per hour)", limit),
+			nil,
+		))
+		c.Abort()
+		return false
+	}
+
+	return true
+}
+
 // LoginRateLimit 登录限流（更严格）
 func (m *RateLimitMiddleware) LoginRateLimit() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -151,9 +195,10 @@ func (m *RateLimitMiddleware) LoginRateLimit() gin.HandlerFunc {
 }
 
 // DonateRateLimit 投喂限流
-func (m *RateLimitMiddleware) DonateRateLimit() gin.HandlerFunc {
+// limitFunc 返回每日最多投喂次数，0 表示不限制
+func (m *RateLimitMiddleware) DonateRateLimit(limitFunc func(context.Context) int64) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		linuxDoID, exists := c.Get("linux_do_id")
+		userID, exists := GetUserID(c)
 		if !exists {
 			// 未认证用户不应该访问投喂接口
 			c.JSON(http.StatusUnauthorized, model.NewErrorResponse("unauthorized", nil))
@@ -161,22 +206,28 @@ func (m *RateLimitMiddleware) DonateRateLimit() gin.HandlerFunc {
 			return
 		}
 
-		identifier := linuxDoID.(string)
+		limit := limitFunc(c.Request.Context())
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		identifier := strconv.Itoa(userID)
 		key := model.RateLimitDonate + identifier
 
-		// 投喂限流：每天最多10次
+		// 投喂限流：每天最多 limit 次
 		now := time.Now()
 		tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
 		window := tomorrow.Sub(now)
 
-		allowed, remaining, err := m.checkRateLimit(c.Request.Context(), key, 10, window)
+		allowed, remaining, err := m.checkRateLimit(c.Request.Context(), key, limit, window)
 		if err != nil {
 			m.logger.WithError(err).WithField("user", identifier).Error("Failed to check donate rate limit")
 			c.Next()
 			return
 		}
 
-		c.Header("X-RateLimit-Limit", "10")
+		c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
 		c.Header("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
 		c.Header("X-RateLimit-Reset", fmt.Sprintf("%d", tomorrow.Unix()))
 
@@ -187,7 +238,7 @@ func (m *RateLimitMiddleware) DonateRateLimit() gin.HandlerFunc {
 			}).Warn("Donate rate limit exceeded")
 
 			c.JSON(http.StatusTooManyRequests, model.NewErrorResponse(
-				"daily donate limit exceeded (max 10 times per day)",
+				fmt.Sprintf("daily donate limit exceeded (max %d times per day)", limit),
 				nil,
 			))
 			c.Abort()
@@ -262,8 +313,8 @@ func (m *RateLimitMiddleware) GlobalRateLimit(limit int64, window time.Duration)
 	return func(c *gin.Context) {
 		// 优先使用用户标识，其次使用IP
 		var identifier string
-		if linuxDoID, exists := c.Get("linux_do_id"); exists {
-			identifier = "user:" + linuxDoID.(string)
+		if userID, exists := GetUserID(c); exists {
+			identifier = "user:" + strconv.Itoa(userID)
 		} else {
 			identifier = "ip:" + c.ClientIP()
 		}
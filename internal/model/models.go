@@ -1,8 +1,10 @@
 package model
 
 import (
+	"crypto/sha256"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -16,21 +18,55 @@ type User struct {
 	KyxUserID int       `json:"kyx_user_id" db:"kyx_user_id"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// Linux.do 账号状态（登录和定期校验时刷新）
+	TrustLevel int  `json:"trust_level" db:"trust_level"`
+	Active     bool `json:"active" db:"active"`
+	Silenced   bool `json:"silenced" db:"silenced"`
+
+	// 定期校验发现 Linux.do 账号异常时暂停，重新登录且账号正常后解除
+	SuspendedAt   sql.NullTime `json:"suspended_at" db:"suspended_at"`
+	SuspendReason string       `json:"suspend_reason,omitempty" db:"suspend_reason"`
+
+	// 管理员软删除，保留期内可以恢复，超期后由后台任务彻底清除
+	DeletedAt sql.NullTime `json:"deleted_at" db:"deleted_at"`
+	DeletedBy string       `json:"deleted_by,omitempty" db:"deleted_by"`
+}
+
+// LinuxDoToken 保存的 Linux.do 刷新令牌（在仓库层加解密），用于定期重新校验账号状态
+type LinuxDoToken struct {
+	LinuxDoID    string       `db:"linux_do_id"`
+	RefreshToken string       `db:"refresh_token"`
+	KeyVersion   int          `db:"key_version"`
+	LastLoginAt  time.Time    `db:"last_login_at"`
+	VerifiedAt   sql.NullTime `db:"verified_at"`
+	NextVerifyAt time.Time    `db:"next_verify_at"`
+	Failures     int          `db:"failures"`
+	LastError    string       `db:"last_error"`
+	CreatedAt    time.Time    `db:"created_at"`
+	UpdatedAt    time.Time    `db:"updated_at"`
 }
 
 // ClaimRecord 领取记录模型
 type ClaimRecord struct {
 	ID         int       `json:"id" db:"id"`
+	UserID     int       `json:"user_id" db:"user_id"`
 	LinuxDoID  string    `json:"linux_do_id" db:"linux_do_id"`
 	Username   string    `json:"username" db:"username"`
 	QuotaAdded int64     `json:"quota_added" db:"quota_added"`
-	ClaimDate  string    `json:"claim_date" db:"claim_date"` // YYYY-MM-DD
+	BaseQuota  int64     `json:"base_quota" db:"base_quota"`
+	BonusQuota int64     `json:"bonus_quota" db:"bonus_quota"` // 连续领取奖励，QuotaAdded = BaseQuota + BonusQuota
+	Streak     int       `json:"streak" db:"streak"`           // 领取时的连续领取周期数
+	ClaimDate  string    `json:"claim_date" db:"claim_date"`   // YYYY-MM-DD
+	PeriodKey  string    `json:"period_key" db:"period_key"`
+	Status     string    `json:"status" db:"status"` // reserved, granted, released
 	CreatedAt  time.Time `json:"created_at" db:"created_at"`
 }
 
 // DonateRecord 投喂记录模型
 type DonateRecord struct {
 	ID              int       `json:"id" db:"id"`
+	UserID          int       `json:"user_id" db:"user_id"`
 	LinuxDoID       string    `json:"linux_do_id" db:"linux_do_id"`
 	Username        string    `json:"username" db:"username"`
 	KeysCount       int       `json:"keys_count" db:"keys_count"`
@@ -43,11 +79,103 @@ type DonateRecord struct {
 
 // UsedKey 已使用的Key模型
 type UsedKey struct {
-	KeyHash   string    `json:"key_hash" db:"key_hash"`
-	FullKey   string    `json:"full_key" db:"full_key"`
-	LinuxDoID string    `json:"linux_do_id" db:"linux_do_id"`
-	Username  string    `json:"username" db:"username"`
-	UsedAt    time.Time `json:"used_at" db:"used_at"`
+	KeyHash    string    `json:"key_hash" db:"key_hash"`
+	FullKey    string    `json:"full_key" db:"full_key"`
+	KeyVersion int       `json:"key_version" db:"key_version"` // 加密主密钥版本，0 表示明文
+	LinuxDoID  string    `json:"linux_do_id" db:"linux_do_id"`
+	Username   string    `json:"username" db:"username"`
+	UsedAt     time.Time `json:"used_at" db:"used_at"`
+}
+
+// QuotaGrant 额度发放记录（事务发件箱）
+type QuotaGrant struct {
+	ID             int            `json:"id" db:"id"`
+	IdempotencyKey string         `json:"idempotency_key" db:"idempotency_key"`
+	UserID         int            `json:"user_id" db:"user_id"`
+	LinuxDoID      string         `json:"linux_do_id" db:"linux_do_id"`
+	KyxUserID      int            `json:"kyx_user_id" db:"kyx_user_id"`
+	Quota          int64          `json:"quota" db:"quota"`
+	Source         string         `json:"source" db:"source"` // claim, donate, bind_bonus
+	SourceID       int            `json:"source_id" db:"source_id"`
+	Status         string         `json:"status" db:"status"` // pending, delivering, delivered, review, dead
+	Attempts       int            `json:"attempts" db:"attempts"`
+	MaxAttempts    int            `json:"max_attempts" db:"max_attempts"`
+	NextAttemptAt  time.Time      `json:"next_attempt_at" db:"next_attempt_at"`
+	KyxTotalBefore sql.NullInt64  `json:"kyx_total_before" db:"kyx_total_before"` // 最近一次投递前公益站累计额度，用于重试前对账
+	KyxTotalAt     sql.NullTime   `json:"kyx_total_at" db:"kyx_total_at"`         // 记录 KyxTotalBefore 的时间
+	LastError      sql.NullString `json:"last_error" db:"last_error"`
+	DeliveredAt    sql.NullTime   `json:"delivered_at" db:"delivered_at"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// DonateJob 异步投喂任务
+type DonateJob struct {
+	ID             int            `json:"id" db:"id"`
+	UserID         int            `json:"user_id" db:"user_id"`
+	LinuxDoID      string         `json:"linux_do_id" db:"linux_do_id"`
+	Username       string         `json:"username" db:"username"`
+	Status         string         `json:"status" db:"status"` // queued, processing, completed, failed
+	Keys           JSONArray      `json:"-" db:"keys"`
+	Items          DonateJobItems `json:"items" db:"items"`
+	TotalKeys      int            `json:"total_keys" db:"total_keys"`
+	ProcessedKeys  int            `json:"processed_keys" db:"processed_keys"`
+	ValidKeys      int            `json:"valid_keys" db:"valid_keys"`
+	QuotaAdded     int64          `json:"quota_added" db:"quota_added"`
+	DonateRecordID sql.NullInt64  `json:"donate_record_id" db:"donate_record_id"`
+	Attempts       int            `json:"attempts" db:"attempts"`
+	MaxAttempts    int            `json:"max_attempts" db:"max_attempts"`
+	NextAttemptAt  time.Time      `json:"-" db:"next_attempt_at"`
+	LastError      sql.NullString `json:"last_error" db:"last_error"`
+	StartedAt      sql.NullTime   `json:"started_at" db:"started_at"`
+	FinishedAt     sql.NullTime   `json:"finished_at" db:"finished_at"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// ItemIndexes 获取处于指定状态的Key下标
+func (j *DonateJob) ItemIndexes(statuses ...string) []int {
+	indexes := make([]int, 0, len(j.Items))
+	for i, item := range j.Items {
+		for _, status := range statuses {
+			if item.Status == status {
+				indexes = append(indexes, i)
+				break
+			}
+		}
+	}
+	return indexes
+}
+
+// DonateJobItem 投喂任务中单个Key的处理进度
+type DonateJobItem struct {
+	Key    string `json:"key"`    // 脱敏后的Key
+	Status string `json:"status"` // pending, rejected, valid, pushed, push_failed, duplicate
+	Reason string `json:"reason,omitempty"`
+}
+
+// DonateJobItems 投喂任务进度列表（JSONB）
+type DonateJobItems []DonateJobItem
+
+// Value 实现 driver.Valuer 接口
+func (items DonateJobItems) Value() (driver.Value, error) {
+	if items == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(items)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (items *DonateJobItems) Scan(value interface{}) error {
+	if value == nil {
+		*items = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return json.Unmarshal([]byte(value.(string)), items)
+	}
+	return json.Unmarshal(bytes, items)
 }
 
 // AdminConfig 管理员配置模型
@@ -60,6 +188,380 @@ type AdminConfig struct {
 	KeysAuthorization sql.NullString `json:"keys_authorization" db:"keys_authorization"`
 	GroupID           int            `json:"group_id" db:"group_id"`
 	UpdatedAt         time.Time      `json:"updated_at" db:"updated_at"`
+
+	// 投喂策略（NULL 时使用环境变量默认值）
+	DonateQuotaPerKey          sql.NullInt64 `json:"donate_quota_per_key" db:"donate_quota_per_key"`
+	MaxDonateKeysPerDay        sql.NullInt64 `json:"max_donate_keys_per_day" db:"max_donate_keys_per_day"`
+	MaxDonateSubmissionsPerDay sql.NullInt64 `json:"max_donate_submissions_per_day" db:"max_donate_submissions_per_day"`
+	MaxKeysPerSubmission       sql.NullInt64 `json:"max_keys_per_submission" db:"max_keys_per_submission"`
+
+	// 连续领取奖励表（NULL 时使用 DefaultStreakBonuses）
+	StreakBonuses StreakBonusTable `json:"streak_bonuses" db:"streak_bonuses"`
+
+	// 剩余额度阈值策略（NULL 时使用环境变量默认值）
+	ClaimQuotaThreshold sql.NullInt64  `json:"claim_quota_threshold" db:"claim_quota_threshold"`
+	ClaimThresholdMode  sql.NullString `json:"claim_threshold_mode" db:"claim_threshold_mode"`
+
+	// session 和 keys_authorization 加密使用的主密钥版本，0 表示明文
+	SecretsKeyVersion int `json:"secrets_key_version" db:"secrets_key_version"`
+
+	// Linux.do 信任等级策略（最低等级为 NULL 时使用环境变量默认值）
+	MinBindTrustLevel     sql.NullInt64        `json:"min_bind_trust_level" db:"min_bind_trust_level"`
+	MinClaimTrustLevel    sql.NullInt64        `json:"min_claim_trust_level" db:"min_claim_trust_level"`
+	MinDonateTrustLevel   sql.NullInt64        `json:"min_donate_trust_level" db:"min_donate_trust_level"`
+	TrustLevelClaimQuotas TrustLevelQuotaTable `json:"trust_level_claim_quotas" db:"trust_level_claim_quotas"`
+
+	// 首次绑定奖励策略（启用和额度为 NULL 时使用环境变量默认值）
+	BindBonusEnabled       sql.NullBool  `json:"bind_bonus_enabled" db:"bind_bonus_enabled"`
+	BindBonusQuota         sql.NullInt64 `json:"bind_bonus_quota" db:"bind_bonus_quota"`
+	BindBonusAccountsAfter sql.NullTime  `json:"bind_bonus_accounts_after" db:"bind_bonus_accounts_after"`
+
+	// 用户组档位策略（档位为 NULL 时不自动调整，降级天数为 NULL 时使用环境变量默认值）
+	GroupTiers              GroupTierTable `json:"group_tiers" db:"group_tiers"`
+	GroupDemoteInactiveDays sql.NullInt64  `json:"group_demote_inactive_days" db:"group_demote_inactive_days"`
+}
+
+// DonatePolicy 投喂策略（限制类字段为 0 表示不限制）
+type DonatePolicy struct {
+	QuotaPerKey          int64 `json:"donate_quota_per_key"`
+	MaxKeysPerDay        int   `json:"max_donate_keys_per_day"`
+	MaxSubmissionsPerDay int   `json:"max_donate_submissions_per_day"`
+	MaxKeysPerSubmission int   `json:"max_keys_per_submission"`
+}
+
+// DonatePolicy 获取生效的投喂策略，未配置的字段使用默认值
+func (c *AdminConfig) DonatePolicy(defaults DonatePolicy) DonatePolicy {
+	policy := defaults
+	if c == nil {
+		return policy
+	}
+	if c.DonateQuotaPerKey.Valid {
+		policy.QuotaPerKey = c.DonateQuotaPerKey.Int64
+	}
+	if c.MaxDonateKeysPerDay.Valid {
+		policy.MaxKeysPerDay = int(c.MaxDonateKeysPerDay.Int64)
+	}
+	if c.MaxDonateSubmissionsPerDay.Valid {
+		policy.MaxSubmissionsPerDay = int(c.MaxDonateSubmissionsPerDay.Int64)
+	}
+	if c.MaxKeysPerSubmission.Valid {
+		policy.MaxKeysPerSubmission = int(c.MaxKeysPerSubmission.Int64)
+	}
+	return policy
+}
+
+// ClaimGatePolicy 按剩余额度限制领取的策略（QuotaThreshold 为 0 表示不限制）
+type ClaimGatePolicy struct {
+	QuotaThreshold int64  `json:"claim_quota_threshold"`
+	Mode           string `json:"claim_threshold_mode"` // block, scale
+}
+
+// BlockedReason 用户当前剩余额度在 block 模式下被拒绝领取时返回原因，否则返回空字符串
+func (p ClaimGatePolicy) BlockedReason(balance int64) string {
+	if p.QuotaThreshold <= 0 || balance <= p.QuotaThreshold || p.Mode == ClaimThresholdScale {
+		return ""
+	}
+	return ClaimBlockedQuotaThreshold
+}
+
+// Apply 根据用户当前剩余额度计算本次可领取的额度，返回 0 时同时返回不能领取的原因
+// scale 模式按 阈值/剩余额度 的比例缩减，剩余额度越多领取越少
+func (p ClaimGatePolicy) Apply(balance, quota int64) (int64, string) {
+	if p.QuotaThreshold <= 0 || balance <= p.QuotaThreshold {
+		return quota, ""
+	}
+	if reason := p.BlockedReason(balance); reason != "" {
+		return 0, reason
+	}
+
+	scaled := int64(float64(quota) * float64(p.QuotaThreshold) / float64(balance))
+	if scaled <= 0 {
+		return 0, ClaimBlockedQuotaThreshold
+	}
+	return scaled, ""
+}
+
+// ClaimGatePolicy 获取生效的剩余额度阈值策略，未配置的字段使用默认值
+func (c *AdminConfig) ClaimGatePolicy(defaults ClaimGatePolicy) ClaimGatePolicy {
+	policy := defaults
+	if c == nil {
+		return policy
+	}
+	if c.ClaimQuotaThreshold.Valid {
+		policy.QuotaThreshold = c.ClaimQuotaThreshold.Int64
+	}
+	if c.ClaimThresholdMode.Valid && c.ClaimThresholdMode.String != "" {
+		policy.Mode = c.ClaimThresholdMode.String
+	}
+	return policy
+}
+
+// TrustPolicy Linux.do 信任等级策略（最低等级为 0 表示不限制）
+type TrustPolicy struct {
+	MinBindLevel   int                  `json:"min_bind_trust_level"`
+	MinClaimLevel  int                  `json:"min_claim_trust_level"`
+	MinDonateLevel int                  `json:"min_donate_trust_level"`
+	ClaimQuotas    TrustLevelQuotaTable `json:"trust_level_claim_quotas"`
+}
+
+// BlockedReason 用户不满足最低信任等级或账号状态受限时返回原因，否则返回空字符串
+func (p TrustPolicy) BlockedReason(user *User, minLevel int) string {
+	if user.SuspendedAt.Valid {
+		return AccountBlockedSuspended
+	}
+	if !user.Active || user.Silenced {
+		return AccountBlockedRestricted
+	}
+	if user.TrustLevel < minLevel {
+		return AccountBlockedTrustLevel
+	}
+	return ""
+}
+
+// TrustPolicy 获取生效的信任等级策略，未配置的字段使用默认值
+func (c *AdminConfig) TrustPolicy(defaults TrustPolicy) TrustPolicy {
+	policy := defaults
+	if c == nil {
+		return policy
+	}
+	if c.MinBindTrustLevel.Valid {
+		policy.MinBindLevel = int(c.MinBindTrustLevel.Int64)
+	}
+	if c.MinClaimTrustLevel.Valid {
+		policy.MinClaimLevel = int(c.MinClaimTrustLevel.Int64)
+	}
+	if c.MinDonateTrustLevel.Valid {
+		policy.MinDonateLevel = int(c.MinDonateTrustLevel.Int64)
+	}
+	if c.TrustLevelClaimQuotas != nil {
+		policy.ClaimQuotas = c.TrustLevelClaimQuotas
+	}
+	return policy
+}
+
+// BindBonusPolicy 首次绑定奖励策略（每个 Linux.do 账号和公益站账号只奖励一次）
+type BindBonusPolicy struct {
+	Enabled       bool       `json:"bind_bonus_enabled"`
+	Quota         int64      `json:"bind_bonus_quota"`
+	AccountsAfter *time.Time `json:"bind_bonus_accounts_after"` // 只奖励此时间之后创建的账号，为空表示不限制
+}
+
+// Amount 用户本次绑定可获得的奖励额度，策略未启用或账号创建时间不满足时返回 0
+func (p BindBonusPolicy) Amount(user *User) int64 {
+	if !p.Enabled || p.Quota <= 0 {
+		return 0
+	}
+	if p.AccountsAfter != nil && user.CreatedAt.Before(*p.AccountsAfter) {
+		return 0
+	}
+	return p.Quota
+}
+
+// BindBonusPolicy 获取生效的首次绑定奖励策略，未配置的字段使用默认值
+func (c *AdminConfig) BindBonusPolicy(defaults BindBonusPolicy) BindBonusPolicy {
+	policy := defaults
+	if c == nil {
+		return policy
+	}
+	if c.BindBonusEnabled.Valid {
+		policy.Enabled = c.BindBonusEnabled.Bool
+	}
+	if c.BindBonusQuota.Valid {
+		policy.Quota = c.BindBonusQuota.Int64
+	}
+	if c.BindBonusAccountsAfter.Valid {
+		after := c.BindBonusAccountsAfter.Time
+		policy.AccountsAfter = &after
+	}
+	return policy
+}
+
+// GroupTierPolicy 按投喂量自动调整公益站用户组的策略
+type GroupTierPolicy struct {
+	Tiers              GroupTierTable `json:"group_tiers"`
+	DemoteInactiveDays int            `json:"group_demote_inactive_days"` // 超过该天数没有投喂降回默认用户组，0 表示不降级
+	DefaultGroupID     int            `json:"group_id"`                   // 未达到任何档位时的用户组
+}
+
+// GroupTierPolicy 获取生效的用户组档位策略，未配置的字段使用默认值
+func (c *AdminConfig) GroupTierPolicy(defaults GroupTierPolicy) GroupTierPolicy {
+	policy := defaults
+	if c == nil {
+		return policy
+	}
+	if c.GroupTiers != nil {
+		policy.Tiers = c.GroupTiers
+	}
+	if c.GroupDemoteInactiveDays.Valid {
+		policy.DemoteInactiveDays = int(c.GroupDemoteInactiveDays.Int64)
+	}
+	if c.GroupID > 0 {
+		policy.DefaultGroupID = c.GroupID
+	}
+	return policy
+}
+
+// GroupTier 用户组档位：累计投喂Key数或投喂额度任一达到门槛即进入该档位（门槛为 0 表示不使用该条件）
+type GroupTier struct {
+	Name           string `json:"name"`
+	GroupID        int    `json:"group_id"`
+	MinKeys        int64  `json:"min_keys"`
+	MinDonateQuota int64  `json:"min_donate_quota"`
+}
+
+// Reached 是否达到该档位
+func (t GroupTier) Reached(keys, quota int64) bool {
+	return (t.MinKeys > 0 && keys >= t.MinKeys) || (t.MinDonateQuota > 0 && quota >= t.MinDonateQuota)
+}
+
+// GroupTierTable 用户组档位表（按从低到高排列）
+type GroupTierTable []GroupTier
+
+// Match 获取已达到的最高档位的下标，没有达到任何档位时返回 -1
+func (t GroupTierTable) Match(keys, quota int64) int {
+	matched := -1
+	for i, tier := range t {
+		if tier.Reached(keys, quota) {
+			matched = i
+		}
+	}
+	return matched
+}
+
+// Find 按名称查找档位，不存在时返回 nil
+func (t GroupTierTable) Find(name string) *GroupTier {
+	for i := range t {
+		if t[i].Name == name {
+			return &t[i]
+		}
+	}
+	return nil
+}
+
+// Value 实现 driver.Valuer 接口
+func (t GroupTierTable) Value() (driver.Value, error) {
+	if t == nil {
+		return nil, nil
+	}
+	return json.Marshal(t)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (t *GroupTierTable) Scan(value interface{}) error {
+	if value == nil {
+		*t = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return json.Unmarshal([]byte(value.(string)), t)
+	}
+	return json.Unmarshal(bytes, t)
+}
+
+// TrustLevelQuota 信任等级达到 Level 时的领取额度
+type TrustLevelQuota struct {
+	Level int   `json:"level"`
+	Quota int64 `json:"quota"`
+}
+
+// TrustLevelQuotaTable 按信任等级的领取额度表（按 Level 升序）
+type TrustLevelQuotaTable []TrustLevelQuota
+
+// Quota 获取指定信任等级的领取额度（取已达到的最高档位），没有匹配的档位时返回 fallback
+func (t TrustLevelQuotaTable) Quota(level int, fallback int64) int64 {
+	quota := fallback
+	matched := -1
+	for _, entry := range t {
+		if level >= entry.Level && entry.Level > matched {
+			quota = entry.Quota
+			matched = entry.Level
+		}
+	}
+	return quota
+}
+
+// Value 实现 driver.Valuer 接口
+func (t TrustLevelQuotaTable) Value() (driver.Value, error) {
+	if t == nil {
+		return nil, nil
+	}
+	return json.Marshal(t)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (t *TrustLevelQuotaTable) Scan(value interface{}) error {
+	if value == nil {
+		*t = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return json.Unmarshal([]byte(value.(string)), t)
+	}
+	return json.Unmarshal(bytes, t)
+}
+
+// StreakBonus 连续领取奖励档位：连续领取达到 Days 个周期后额外奖励基础额度的 Percent%
+type StreakBonus struct {
+	Days    int `json:"days"`
+	Percent int `json:"percent"`
+}
+
+// StreakBonusTable 连续领取奖励表（按 Days 升序）
+type StreakBonusTable []StreakBonus
+
+// DefaultStreakBonuses 默认连续领取奖励表
+var DefaultStreakBonuses = StreakBonusTable{
+	{Days: 7, Percent: 10},
+	{Days: 30, Percent: 25},
+}
+
+// Percent 获取指定连续领取数对应的奖励百分比（取已达到的最高档位）
+func (t StreakBonusTable) Percent(streak int) int {
+	percent := 0
+	for _, bonus := range t {
+		if streak >= bonus.Days && bonus.Percent > percent {
+			percent = bonus.Percent
+		}
+	}
+	return percent
+}
+
+// Value 实现 driver.Valuer 接口
+func (t StreakBonusTable) Value() (driver.Value, error) {
+	if t == nil {
+		return nil, nil
+	}
+	return json.Marshal(t)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (t *StreakBonusTable) Scan(value interface{}) error {
+	if value == nil {
+		*t = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return json.Unmarshal([]byte(value.(string)), t)
+	}
+	return json.Unmarshal(bytes, t)
+}
+
+// StreakBonusTable 获取生效的连续领取奖励表，未配置时使用默认值
+func (c *AdminConfig) StreakBonusTable() StreakBonusTable {
+	if c == nil || c.StreakBonuses == nil {
+		return DefaultStreakBonuses
+	}
+	return c.StreakBonuses
+}
+
+// ClaimStreak 用户的连续领取信息
+type ClaimStreak struct {
+	CurrentStreak int    `json:"current_streak"` // 当前连续领取周期数（已中断时为 0）
+	LongestStreak int    `json:"longest_streak"`
+	LastClaimDate string `json:"last_claim_date,omitempty"`
 }
 
 // Session 会话模型
@@ -70,8 +572,313 @@ type Session struct {
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
+// SessionMeta 会话的客户端信息
+type SessionMeta struct {
+	IP        string
+	UserAgent string
+}
+
+// UserSessionInfo 用户登录设备信息
+type UserSessionInfo struct {
+	ID         string `json:"id"` // 会话标识（会话ID的哈希，不暴露会话ID本身）
+	IP         string `json:"ip"`
+	UserAgent  string `json:"user_agent"`
+	CreatedAt  int64  `json:"created_at"`
+	LastSeenAt int64  `json:"last_seen_at"`
+	ExpiresAt  int64  `json:"expires_at"`
+	Current    bool   `json:"current"`
+}
+
+// AccessToken 用户个人访问令牌（只保存令牌哈希）
+type AccessToken struct {
+	ID          int64        `json:"id" db:"id"`
+	UserID      int          `json:"-" db:"user_id"`
+	LinuxDoID   string       `json:"-" db:"linux_do_id"`
+	Name        string       `json:"name" db:"name"`
+	TokenPrefix string       `json:"token_prefix" db:"token_prefix"`
+	TokenHash   string       `json:"-" db:"token_hash"`
+	Scopes      JSONArray    `json:"scopes" db:"scopes"`
+	RateLimit   int          `json:"rate_limit" db:"rate_limit"` // 每小时请求上限
+	ExpiresAt   sql.NullTime `json:"expires_at" db:"expires_at"`
+	LastUsedAt  sql.NullTime `json:"last_used_at" db:"last_used_at"`
+	LastUsedIP  string       `json:"last_used_ip" db:"last_used_ip"`
+	RevokedAt   sql.NullTime `json:"-" db:"revoked_at"`
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+}
+
+// HasScope 令牌是否包含指定授权范围
+func (t *AccessToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidTokenScope 是否为有效的访问令牌授权范围
+func IsValidTokenScope(scope string) bool {
+	return scope == TokenScopeQuotaRead || scope == TokenScopeClaim || scope == TokenScopeDonate
+}
+
+// Admin 管理员账号模型
+type Admin struct {
+	ID           int          `json:"id" db:"id"`
+	Username     string       `json:"username" db:"username"`
+	PasswordHash string       `json:"-" db:"password_hash"`
+	Role         string       `json:"role" db:"role"` // viewer, operator, owner
+	Disabled     bool         `json:"disabled" db:"disabled"`
+	LastLoginAt  sql.NullTime `json:"last_login_at" db:"last_login_at"`
+	CreatedAt    time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at" db:"updated_at"`
+
+	// 两步验证（TOTP 密钥在仓库层加解密）
+	TOTPSecret        sql.NullString `json:"-" db:"totp_secret"`
+	TOTPKeyVersion    int            `json:"-" db:"totp_key_version"`
+	TOTPEnabled       bool           `json:"totp_enabled" db:"totp_enabled"`
+	TOTPLastCounter   int64          `json:"-" db:"totp_last_counter"`
+	TOTPRecoveryCodes JSONArray      `json:"-" db:"totp_recovery_codes"` // 恢复码 SHA-256 哈希
+}
+
+// IsValidAdminRole 是否为有效的管理员角色
+func IsValidAdminRole(role string) bool {
+	return role == AdminRoleViewer || role == AdminRoleOperator || role == AdminRoleOwner
+}
+
+// AdminAuditLog 管理员审计日志（只追加，按 id 顺序组成哈希链）
+type AdminAuditLog struct {
+	ID            int64         `json:"id" db:"id"`
+	AdminID       sql.NullInt64 `json:"admin_id" db:"admin_id"`
+	AdminUsername string        `json:"admin_username" db:"admin_username"`
+	Action        string        `json:"action" db:"action"`
+	Method        string        `json:"method" db:"method"`
+	Path          string        `json:"path" db:"path"`
+	Target        string        `json:"target" db:"target"`
+	Before        JSONMap       `json:"before" db:"before"`
+	After         JSONMap       `json:"after" db:"after"`
+	IP            string        `json:"ip" db:"ip"`
+	UserAgent     string        `json:"user_agent" db:"user_agent"`
+	StatusCode    int           `json:"status_code" db:"status_code"`
+	Success       bool          `json:"success" db:"success"`
+	Error         string        `json:"error" db:"error"`
+	PrevHash      string        `json:"prev_hash" db:"prev_hash"`
+	Hash          string        `json:"hash" db:"hash"`
+	CreatedAt     time.Time     `json:"created_at" db:"created_at"`
+}
+
+// ComputeHash 计算记录的哈希：SHA-256(prev_hash + 记录内容的规范化 JSON)
+// before / after 先经过 JSON 往返规范化（键排序、数字统一为浮点），保证写入时与从 JSONB 读回后计算结果一致
+func (l *AdminAuditLog) ComputeHash() (string, error) {
+	before, err := canonicalJSON(l.Before)
+	if err != nil {
+		return "", err
+	}
+	after, err := canonicalJSON(l.After)
+	if err != nil {
+		return "", err
+	}
+
+	var adminID *int64
+	if l.AdminID.Valid {
+		adminID = &l.AdminID.Int64
+	}
+
+	payload, err := json.Marshal([]interface{}{
+		adminID,
+		l.AdminUsername,
+		l.Action,
+		l.Method,
+		l.Path,
+		l.Target,
+		before,
+		after,
+		l.IP,
+		l.UserAgent,
+		l.StatusCode,
+		l.Success,
+		l.Error,
+		l.CreatedAt.UnixMicro(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit log: %w", err)
+	}
+
+	sum := sha256.Sum256(append([]byte(l.PrevHash), payload...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalJSON 规范化 JSON 对象
+func canonicalJSON(m JSONMap) (string, error) {
+	if m == nil {
+		return "null", nil
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit data: %w", err)
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return "", fmt.Errorf("failed to normalize audit data: %w", err)
+	}
+	data, err = json.Marshal(normalized)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit data: %w", err)
+	}
+	return string(data), nil
+}
+
+// BindingHistory 公益站账号绑定历史（每次绑定一条，解绑时记录解绑时间）
+type BindingHistory struct {
+	ID              int64         `json:"id" db:"id"`
+	UserID          sql.NullInt64 `json:"user_id" db:"user_id"` // 用户被彻底清除后为空
+	LinuxDoID       string        `json:"linux_do_id" db:"linux_do_id"`
+	KyxUserID       int           `json:"kyx_user_id" db:"kyx_user_id"`
+	KyxUsername     string        `json:"kyx_username" db:"kyx_username"`
+	BonusGranted    bool          `json:"bonus_granted" db:"bonus_granted"`
+	BoundAt         time.Time     `json:"bound_at" db:"bound_at"`
+	UnboundAt       sql.NullTime  `json:"unbound_at" db:"unbound_at"`
+	UnbindRequestID sql.NullInt64 `json:"unbind_request_id" db:"unbind_request_id"`
+}
+
+// DonorProgress 已绑定用户的累计投喂和当前档位（用于评估用户组档位）
+type DonorProgress struct {
+	UserID       int          `db:"user_id"`
+	LinuxDoID    string       `db:"linux_do_id"`
+	KyxUserID    int          `db:"kyx_user_id"`
+	KeysDonated  int64        `db:"keys_donated"`
+	DonateQuota  int64        `db:"donate_quota"`
+	LastDonateAt sql.NullTime `db:"last_donate_at"`
+	CurrentTier  string       `db:"current_tier"` // 最近一次成功调整的目标档位，空字符串表示默认用户组
+}
+
+// GroupChange 公益站用户组调整记录
+type GroupChange struct {
+	ID          int64         `json:"id" db:"id"`
+	UserID      sql.NullInt64 `json:"user_id" db:"user_id"` // 用户被彻底清除后为空
+	LinuxDoID   string        `json:"linux_do_id" db:"linux_do_id"`
+	KyxUserID   int           `json:"kyx_user_id" db:"kyx_user_id"`
+	FromTier    string        `json:"from_tier" db:"from_tier"`
+	ToTier      string        `json:"to_tier" db:"to_tier"`
+	GroupID     int           `json:"group_id" db:"group_id"`
+	Source      string        `json:"source" db:"source"` // donate, scheduled
+	Status      string        `json:"status" db:"status"` // success, failed
+	Error       string        `json:"error,omitempty" db:"error"`
+	KeysDonated int64         `json:"keys_donated" db:"keys_donated"`
+	DonateQuota int64         `json:"donate_quota" db:"donate_quota"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+}
+
+// GroupTierProgress 用户当前档位和升级到下一档位的进度
+type GroupTierProgress struct {
+	CurrentTier    string     `json:"current_tier"` // 空字符串表示默认用户组
+	GroupID        int        `json:"group_id"`
+	KeysDonated    int64      `json:"keys_donated"`
+	DonateQuota    int64      `json:"donate_quota"`
+	DonateQuotaUSD float64    `json:"donate_quota_usd"`
+	LastDonateAt   *time.Time `json:"last_donate_at,omitempty"`
+	DemoteAt       *time.Time `json:"demote_at,omitempty"` // 之后不再投喂时降回默认用户组的时间
+	NextTier       *GroupTier `json:"next_tier,omitempty"`
+	KeysToNext     int64      `json:"keys_to_next,omitempty"`
+	QuotaToNext    int64      `json:"quota_to_next,omitempty"`
+}
+
+// SystemAlert 系统告警，同一类型同一时间只有一条未解决的告警
+type SystemAlert struct {
+	ID         int64        `json:"id" db:"id"`
+	AlertType  string       `json:"alert_type" db:"alert_type"`
+	Message    string       `json:"message" db:"message"` // 最近一次失败的错误信息
+	Failures   int          `json:"failures" db:"failures"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at" db:"updated_at"`
+	ResolvedAt sql.NullTime `json:"resolved_at" db:"resolved_at"`
+}
+
+// KyxSessionStatus 公益站 Session 最近一次校验结果
+type KyxSessionStatus struct {
+	Checked     bool       `json:"checked"` // 启动后尚未校验时为 false
+	Valid       bool       `json:"valid"`
+	Error       string     `json:"error,omitempty"`
+	CheckedAt   *time.Time `json:"checked_at,omitempty"`
+	RenewedAt   *time.Time `json:"renewed_at,omitempty"` // 最近一次自动重新登录成功的时间
+	AutoRenewal bool       `json:"auto_renewal"`         // 是否配置了自动重新登录
+}
+
+// UnbindRequest 用户自助解绑申请（管理员批准后解除绑定）
+type UnbindRequest struct {
+	ID           int64         `json:"id" db:"id"`
+	UserID       int           `json:"user_id" db:"user_id"`
+	LinuxDoID    string        `json:"linux_do_id" db:"linux_do_id"`
+	KyxUserID    int           `json:"kyx_user_id" db:"kyx_user_id"`
+	KyxUsername  string        `json:"kyx_username" db:"kyx_username"`
+	Reason       string        `json:"reason" db:"reason"`
+	Status       string        `json:"status" db:"status"`
+	CreatedAt    time.Time     `json:"created_at" db:"created_at"`
+	ReviewedAt   sql.NullTime  `json:"reviewed_at" db:"reviewed_at"`
+	ReviewedByID sql.NullInt64 `json:"reviewed_by_id" db:"reviewed_by_id"`
+	ReviewedBy   string        `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewNote   string        `json:"review_note,omitempty" db:"review_note"`
+}
+
+// CreateUnbindRequest 提交解绑申请请求
+type CreateUnbindRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// ReviewUnbindRequest 处理解绑申请请求
+type ReviewUnbindRequest struct {
+	Note string `json:"note" binding:"max=500"`
+}
+
+// UserBan 用户封禁、暂停或观察名单记录
+// 按 Linux.do ID 或公益站用户ID 匹配（两者都填写时任一匹配即生效），可在用户首次登录前设置
+type UserBan struct {
+	ID          int64         `json:"id" db:"id"`
+	Kind        string        `json:"kind" db:"kind"`
+	LinuxDoID   string        `json:"linux_do_id,omitempty" db:"linux_do_id"`
+	KyxUserID   int           `json:"kyx_user_id,omitempty" db:"kyx_user_id"`
+	Reason      string        `json:"reason" db:"reason"`
+	ExpiresAt   sql.NullTime  `json:"expires_at" db:"expires_at"` // 为空表示解除前一直有效
+	CreatedByID sql.NullInt64 `json:"created_by_id" db:"created_by_id"`
+	CreatedBy   string        `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	LiftedAt    sql.NullTime  `json:"lifted_at" db:"lifted_at"`
+	LiftedByID  sql.NullInt64 `json:"lifted_by_id" db:"lifted_by_id"`
+	LiftedBy    string        `json:"lifted_by,omitempty" db:"lifted_by"`
+	LiftReason  string        `json:"lift_reason,omitempty" db:"lift_reason"`
+}
+
+// IsActive 记录是否仍然生效（未解除且未到期）
+func (b *UserBan) IsActive(now time.Time) bool {
+	if b.LiftedAt.Valid {
+		return false
+	}
+	return !b.ExpiresAt.Valid || b.ExpiresAt.Time.After(now)
+}
+
+// BlockedReason 记录限制用户操作时返回原因（观察名单不限制，返回空字符串）
+func (b *UserBan) BlockedReason() string {
+	switch b.Kind {
+	case UserBanKindBan:
+		return AccountBlockedBanned
+	case UserBanKindSuspend:
+		return AccountBlockedSuspended
+	}
+	return ""
+}
+
+// UserStatus 用户处置状态
+type UserStatus struct {
+	Status  string     `json:"status"` // active / suspended / banned
+	Reason  string     `json:"reason,omitempty"`
+	Until   *time.Time `json:"until,omitempty"`  // 暂停到期时间，定期校验产生的暂停没有到期时间
+	BanID   int64      `json:"ban_id,omitempty"` // 生效的处置记录
+	Watched bool       `json:"watched"`          // 是否在观察名单中
+}
+
 // UserStatistics 用户统计模型（从视图读取）
 type UserStatistics struct {
+	UserID           int       `json:"user_id" db:"user_id"`
 	LinuxDoID        string    `json:"linux_do_id" db:"linux_do_id"`
 	Username         string    `json:"username" db:"username"`
 	RegisterTime     time.Time `json:"register_time" db:"register_time"`
@@ -80,7 +887,13 @@ type UserStatistics struct {
 	TotalDonates     int       `json:"total_donates" db:"total_donates"`
 	TotalKeysDonated int       `json:"total_keys_donated" db:"total_keys_donated"`
 	TotalDonateQuota int64     `json:"total_donate_quota" db:"total_donate_quota"`
+	TotalBindBonus   int64     `json:"total_bind_bonus_quota" db:"total_bind_bonus_quota"`
 	TotalQuota       int64     `json:"total_quota" db:"total_quota"`
+
+	// 连续领取信息（由服务层按领取周期计算）
+	CurrentStreak int    `json:"current_streak" db:"-"`
+	LongestStreak int    `json:"longest_streak" db:"-"`
+	LastClaimDate string `json:"last_claim_date,omitempty" db:"-"`
 }
 
 // JSONArray 自定义类型用于处理 PostgreSQL JSONB 数组
@@ -138,6 +951,20 @@ type BindAccountRequest struct {
 	Username string `json:"username" binding:"required"`
 }
 
+// BindChallenge 绑定挑战（缓存中保存，绑定成功后作废）
+type BindChallenge struct {
+	Code      string    `json:"code"`
+	KyxUserID int       `json:"kyx_user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// BindChallengeResponse 绑定挑战响应：用户需要把验证码加入公益站显示名称后再提交绑定
+type BindChallengeResponse struct {
+	Code        string    `json:"code"`
+	KyxUsername string    `json:"kyx_username"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
 // BindAccountResponse 绑定账号响应
 type BindAccountResponse struct {
 	User        *User   `json:"user"`
@@ -157,7 +984,15 @@ type QuotaInfo struct {
 	UsedQuota    int64  `json:"used_quota"`
 	Total        int64  `json:"total"`
 	CanClaim     bool   `json:"can_claim"`
-	ClaimedToday bool   `json:"claimed_today"`
+	ClaimedToday bool   `json:"claimed_today"` // 当前领取周期内是否已领取
+	NextClaimAt  *int64 `json:"next_claim_at,omitempty"`
+
+	// 不能领取的原因: already_claimed, quota_above_threshold, trust_level_too_low, account_restricted, account_suspended
+	ClaimBlockedReason string `json:"claim_blocked_reason,omitempty"`
+
+	CurrentStreak int    `json:"current_streak"`
+	LongestStreak int    `json:"longest_streak"`
+	LastClaimDate string `json:"last_claim_date,omitempty"`
 }
 
 // DonateRequest 投喂请求
@@ -165,35 +1000,103 @@ type DonateRequest struct {
 	Keys []string `json:"keys" binding:"required,min=1"`
 }
 
-// DonateResponse 投喂响应
-type DonateResponse struct {
-	ValidKeys        int                   `json:"valid_keys"`
-	AlreadyExists    int                   `json:"already_exists"`
-	DuplicateRemoved int                   `json:"duplicate_removed"`
-	QuotaAdded       int64                 `json:"quota_added"`
-	Results          []KeyValidationResult `json:"results,omitempty"`
-}
-
 // KeyValidationResult Key验证结果
 type KeyValidationResult struct {
 	Key    string `json:"key"`
 	Valid  bool   `json:"valid"`
+	Status string `json:"status,omitempty"` // 上游校验结果: live, invalid, quota_exhausted, rate_limited, unknown
 	Reason string `json:"reason,omitempty"`
 }
 
 // AdminLoginRequest 管理员登录请求
+// 用户名为空时使用 ADMIN_USERNAME 配置的初始 owner 账号（兼容只提交密码的旧版前端）
 type AdminLoginRequest struct {
+	Username string `json:"username"`
 	Password string `json:"password" binding:"required"`
 }
 
+// AdminLoginResponse 管理员登录结果
+// 启用两步验证的管理员密码校验通过后只返回 challenge_token，需提交验证码换取 token
+type AdminLoginResponse struct {
+	Token             string `json:"token,omitempty"`
+	Admin             *Admin `json:"admin,omitempty"`
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+}
+
+// AdminOTPLoginRequest 管理员两步验证登录请求（code 可以是验证码或恢复码）
+type AdminOTPLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// AdminOTPRequest 需要提供验证码的两步验证操作请求
+type AdminOTPRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TOTPSetupResponse 两步验证绑定信息
+type TOTPSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI，用于生成二维码
+}
+
+// CreateAccessTokenRequest 创建个人访问令牌请求
+type CreateAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=64"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days"` // 0 表示永不过期
+	RateLimit     int      `json:"rate_limit"`      // 每小时请求上限，0 使用默认值
+}
+
+// CreateAccessTokenResponse 创建个人访问令牌响应（令牌明文只返回这一次）
+type CreateAccessTokenResponse struct {
+	Token       string       `json:"token"`
+	AccessToken *AccessToken `json:"access_token"`
+}
+
+// CreateUserBanRequest 封禁、暂停用户或加入观察名单请求（linux_do_id 和 kyx_user_id 至少填写一个）
+type CreateUserBanRequest struct {
+	Kind          string `json:"kind" binding:"required,oneof=ban suspend watch"`
+	LinuxDoID     string `json:"linux_do_id" binding:"max=100"`
+	KyxUserID     int    `json:"kyx_user_id" binding:"min=0"`
+	Reason        string `json:"reason" binding:"required,max=500"`
+	DurationHours int    `json:"duration_hours" binding:"min=0"` // 暂停必填；封禁和观察名单为 0 表示解除前一直有效
+}
+
+// LiftUserBanRequest 解除处置请求
+type LiftUserBanRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// CreateAdminRequest 创建管理员请求
+type CreateAdminRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=64"`
+	Password string `json:"password" binding:"required,min=8"`
+	Role     string `json:"role" binding:"required,oneof=viewer operator owner"`
+}
+
+// UpdateAdminRequest 更新管理员请求
+type UpdateAdminRequest struct {
+	Password *string `json:"password,omitempty"`
+	Role     *string `json:"role,omitempty"`
+	Disabled *bool   `json:"disabled,omitempty"`
+}
+
 // AdminConfigResponse 管理员配置响应
 type AdminConfigResponse struct {
-	ClaimQuota                  int64  `json:"claim_quota"`
-	SessionConfigured           bool   `json:"session_configured"`
-	KeysAPIURL                  string `json:"keys_api_url"`
-	KeysAuthorizationConfigured bool   `json:"keys_authorization_configured"`
-	GroupID                     int    `json:"group_id"`
-	UpdatedAt                   int64  `json:"updated_at"`
+	ClaimQuota                  int64            `json:"claim_quota"`
+	SessionConfigured           bool             `json:"session_configured"`
+	KeysAPIURL                  string           `json:"keys_api_url"`
+	KeysAuthorizationConfigured bool             `json:"keys_authorization_configured"`
+	GroupID                     int              `json:"group_id"`
+	DonatePolicy                DonatePolicy     `json:"donate_policy"`
+	StreakBonuses               StreakBonusTable `json:"streak_bonuses"`
+	ClaimGatePolicy             ClaimGatePolicy  `json:"claim_gate_policy"`
+	TrustPolicy                 TrustPolicy      `json:"trust_policy"`
+	BindBonusPolicy             BindBonusPolicy  `json:"bind_bonus_policy"`
+	GroupTierPolicy             GroupTierPolicy  `json:"group_tier_policy"`
+	UpdatedAt                   int64            `json:"updated_at"`
 }
 
 // UpdateConfigRequest 更新配置请求
@@ -204,6 +1107,65 @@ type UpdateConfigRequest struct {
 	KeysAPIURL        *string `json:"keys_api_url,omitempty"`
 	KeysAuthorization *string `json:"keys_authorization,omitempty"`
 	GroupID           *int    `json:"group_id,omitempty"`
+
+	// 投喂策略
+	DonateQuotaPerKey          *int64 `json:"donate_quota_per_key,omitempty"`
+	MaxDonateKeysPerDay        *int   `json:"max_donate_keys_per_day,omitempty"`
+	MaxDonateSubmissionsPerDay *int   `json:"max_donate_submissions_per_day,omitempty"`
+	MaxKeysPerSubmission       *int   `json:"max_keys_per_submission,omitempty"`
+
+	// 连续领取奖励表（空数组表示关闭连续奖励）
+	StreakBonuses *StreakBonusTable `json:"streak_bonuses,omitempty"`
+
+	// 剩余额度阈值策略
+	ClaimQuotaThreshold *int64  `json:"claim_quota_threshold,omitempty"`
+	ClaimThresholdMode  *string `json:"claim_threshold_mode,omitempty"`
+
+	// 信任等级策略（trust_level_claim_quotas 为空数组时所有等级使用 claim_quota）
+	MinBindTrustLevel     *int                  `json:"min_bind_trust_level,omitempty"`
+	MinClaimTrustLevel    *int                  `json:"min_claim_trust_level,omitempty"`
+	MinDonateTrustLevel   *int                  `json:"min_donate_trust_level,omitempty"`
+	TrustLevelClaimQuotas *TrustLevelQuotaTable `json:"trust_level_claim_quotas,omitempty"`
+
+	// 首次绑定奖励策略（bind_bonus_accounts_after 为 RFC3339 时间，空字符串表示不限制）
+	BindBonusEnabled       *bool   `json:"bind_bonus_enabled,omitempty"`
+	BindBonusQuota         *int64  `json:"bind_bonus_quota,omitempty"`
+	BindBonusAccountsAfter *string `json:"bind_bonus_accounts_after,omitempty"`
+
+	// 用户组档位策略（group_tiers 为空数组时关闭自动调整）
+	GroupTiers              *GroupTierTable `json:"group_tiers,omitempty"`
+	GroupDemoteInactiveDays *int            `json:"group_demote_inactive_days,omitempty"`
+}
+
+// AuditLogFilter 审计日志查询条件（零值表示不过滤）
+type AuditLogFilter struct {
+	AdminID  int
+	Username string
+	Action   string
+	Start    time.Time
+	End      time.Time
+}
+
+// UserBanFilter 处置记录查询条件（零值表示不过滤）
+type UserBanFilter struct {
+	LinuxDoID  string
+	KyxUserID  int
+	Kind       string
+	ActiveOnly bool
+}
+
+// AuditChange 操作前后的内容，由处理器提供给审计日志（写入前统一脱敏）
+type AuditChange struct {
+	Before JSONMap
+	After  JSONMap
+}
+
+// AuditChainStatus 审计日志哈希链校验结果
+type AuditChainStatus struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`
+	BrokenID int64  `json:"broken_id,omitempty"`
+	Reason   string `json:"reason,omitempty"`
 }
 
 // ========== 外部API结构 ==========
@@ -232,6 +1194,11 @@ type LinuxDoUserInfo struct {
 	Username  string `json:"username"`
 	Name      string `json:"name"`
 	AvatarURL string `json:"avatar_url"`
+
+	// 账号状态
+	TrustLevel int  `json:"trust_level"`
+	Active     bool `json:"active"`
+	Silenced   bool `json:"silenced"`
 }
 
 // LinuxDoTokenResponse Linux Do Token响应
@@ -243,6 +1210,50 @@ type LinuxDoTokenResponse struct {
 	Scope        string `json:"scope,omitempty"`
 }
 
+// ========== 身份提供方 ==========
+
+// IdentityProviderLinuxDo Linux.do 身份提供方名称（新用户只能通过 Linux.do 注册）
+const IdentityProviderLinuxDo = "linuxdo"
+
+// OAuthToken 身份提供方令牌响应
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// ExternalIdentity 身份提供方返回的用户身份
+type ExternalIdentity struct {
+	Provider string
+	Subject  string // 提供方内的用户唯一标识
+	Username string
+	Email    string
+
+	// LinuxDo 仅 Linux.do 提供方返回，包含信任等级和账号状态
+	LinuxDo *LinuxDoUserInfo
+}
+
+// UserIdentity 用户关联的身份提供方账号
+type UserIdentity struct {
+	ID          int64        `json:"id" db:"id"`
+	UserID      int          `json:"user_id" db:"user_id"`
+	Provider    string       `json:"provider" db:"provider"`
+	Subject     string       `json:"subject" db:"subject"`
+	Username    string       `json:"username" db:"username"`
+	Email       string       `json:"email,omitempty" db:"email"`
+	LastLoginAt sql.NullTime `json:"last_login_at" db:"last_login_at"`
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+}
+
+// IdentityProviderInfo 可用的身份提供方
+type IdentityProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
 // ========== 通用响应结构 ==========
 
 // Response 通用响应结构
@@ -283,16 +1294,141 @@ const (
 	// 缓存键前缀
 	CacheKeyUser        = "user:"
 	CacheKeyUserQuota   = "user:quota:"
-	CacheKeyClaimToday  = "claim:today:"
+	CacheKeyClaimNext   = "claim:next:"
 	CacheKeyDonateCount = "donate:count:"
 	CacheKeySession     = "session:"
+	CacheKeyUserSession = "session:user:" // 用户会话索引（集合）
 	CacheKeyAdminConfig = "admin:config"
 	CacheKeyKeysBloom   = "keys:bloom"
+	CacheKeyKeyVerify   = "keys:verify:"
+	CacheKeyLock        = "lock:"
 
 	// 限流键前缀
 	RateLimitLogin  = "ratelimit:login:"
 	RateLimitDonate = "ratelimit:donate:"
 	RateLimitAPI    = "ratelimit:api:"
+	RateLimitToken  = "ratelimit:token:"
+
+	// 领取周期
+	ClaimPeriodDaily   = "daily"
+	ClaimPeriodWeekly  = "weekly"
+	ClaimPeriodRolling = "rolling"
+
+	// 领取记录状态
+	ClaimStatusReserved = "reserved"
+	ClaimStatusGranted  = "granted"
+	ClaimStatusReleased = "released"
+
+	// 剩余额度超过阈值时的处理方式
+	ClaimThresholdBlock = "block"
+	ClaimThresholdScale = "scale"
+
+	// 不能领取的原因（QuotaInfo.ClaimBlockedReason）
+	ClaimBlockedAlreadyClaimed = "already_claimed"
+	ClaimBlockedQuotaThreshold = "quota_above_threshold"
+
+	// Linux.do 账号不满足信任等级策略的原因（绑定、领取、投喂）
+	AccountBlockedTrustLevel = "trust_level_too_low"
+	AccountBlockedRestricted = "account_restricted"
+	AccountBlockedSuspended  = "account_suspended"
+	AccountBlockedBanned     = "account_banned"
+	AccountBlockedDeleted    = "account_deleted"
+
+	// 绑定公益站账号失败的原因
+	BindBlockedAccountTaken = "kyx_account_taken"     // 公益站账号已绑定其他 Linux.do 账号
+	BindBlockedChallenge    = "bind_challenge_failed" // 挑战模式下未通过显示名称验证
+
+	// 用户处置类型（UserBan.Kind）
+	UserBanKindBan     = "ban"
+	UserBanKindSuspend = "suspend"
+	UserBanKindWatch   = "watch"
+
+	// 解绑申请状态（UnbindRequest.Status）
+	UnbindStatusPending   = "pending"
+	UnbindStatusApproved  = "approved"
+	UnbindStatusRejected  = "rejected"
+	UnbindStatusCancelled = "cancelled"
+
+	// 用户组调整触发来源和结果
+	GroupChangeSourceDonate    = "donate"
+	GroupChangeSourceScheduled = "scheduled"
+	GroupChangeStatusSuccess   = "success"
+	GroupChangeStatusFailed    = "failed"
+
+	// 公益站不可用（Session 失效且无法自动续期）时领取、投喂返回的原因
+	UpstreamUnavailable = "upstream_unavailable"
+
+	// 系统告警类型（SystemAlert.AlertType）
+	AlertTypeKyxSession = "kyx_session"
+
+	// 用户处置状态（UserStatus.Status）
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusBanned    = "banned"
+
+	// 定期校验暂停用户的原因（User.SuspendReason）
+	SuspendReasonLinuxDoGone       = "linuxdo_account_gone"          // Linux.do 账号已删除或无法访问
+	SuspendReasonLinuxDoRestricted = "linuxdo_account_restricted"    // 账号被停用或禁言
+	SuspendReasonLinuxDoDemoted    = "linuxdo_trust_level_demoted"   // 信任等级下降
+	SuspendReasonLinuxDoRevoked    = "linuxdo_authorization_revoked" // 刷新令牌失效（用户撤销了授权）
+
+	// Linux.do 信任等级上限
+	MaxTrustLevel = 4
+
+	// 管理员两步验证登录挑战缓存键前缀
+	CacheKeyAdminOTPChallenge = "admin:otp:"
+
+	// 绑定挑战缓存键前缀
+	CacheKeyBindChallenge = "bind:challenge:"
+
+	// 个人访问令牌
+	AccessTokenPrefix           = "kqb_" // 令牌明文前缀，便于识别和密钥扫描
+	DefaultAccessTokenRateLimit = 60     // 默认每小时请求上限
+	MaxAccessTokenRateLimit     = 3600
+	MaxAccessTokensPerUser      = 10
+
+	// 个人访问令牌授权范围
+	TokenScopeQuotaRead = "quota:read"
+	TokenScopeClaim     = "claim"
+	TokenScopeDonate    = "donate"
+
+	// 管理员角色
+	AdminRoleViewer   = "viewer"
+	AdminRoleOperator = "operator"
+	AdminRoleOwner    = "owner"
+
+	// 额度发放来源
+	GrantSourceClaim     = "claim"
+	GrantSourceDonate    = "donate"
+	GrantSourceBindBonus = "bind_bonus"
+
+	// 额度发放状态
+	GrantStatusPending    = "pending"
+	GrantStatusDelivering = "delivering"
+	GrantStatusDelivered  = "delivered"
+	GrantStatusReview     = "review" // 无法确认上次投递是否到账，等待管理员核对
+	GrantStatusDead       = "dead"
+
+	// 投喂Key上游校验状态
+	KeyStatusLive           = "live"
+	KeyStatusInvalid        = "invalid"
+	KeyStatusQuotaExhausted = "quota_exhausted"
+	KeyStatusRateLimited    = "rate_limited"
+	KeyStatusUnknown        = "unknown"
+
+	// 投喂任务状态
+	DonateJobStatusQueued     = "queued"
+	DonateJobStatusProcessing = "processing"
+	DonateJobStatusCompleted  = "completed"
+	DonateJobStatusFailed     = "failed"
+
+	// 投喂任务中Key的处理状态
+	DonateItemStatusPending    = "pending"
+	DonateItemStatusRejected   = "rejected"
+	DonateItemStatusValid      = "valid"
+	DonateItemStatusPushed     = "pushed"
+	DonateItemStatusPushFailed = "push_failed"
+	DonateItemStatusDuplicate  = "duplicate" // 已被其他任务推送或写入 used_keys，不推送也不发放额度
 )
 
 // ========== 辅助函数 ==========
@@ -358,3 +1494,15 @@ func (AdminConfig) TableName() string {
 func (Session) TableName() string {
 	return "sessions"
 }
+
+func (QuotaGrant) TableName() string {
+	return "quota_grants"
+}
+
+func (DonateJob) TableName() string {
+	return "donate_jobs"
+}
+
+func (AdminAuditLog) TableName() string {
+	return "admin_audit_log"
+}
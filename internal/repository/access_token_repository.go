@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+)
+
+// accessTokenColumns 个人访问令牌查询字段
+const accessTokenColumns = `
+	id, user_id, linux_do_id, name, token_prefix, token_hash, scopes, rate_limit,
+	expires_at, last_used_at, last_used_ip, revoked_at, created_at
+`
+
+// AccessTokenRepository 个人访问令牌仓库
+type AccessTokenRepository struct {
+	db     *database.DB
+	logger *logrus.Logger
+}
+
+// NewAccessTokenRepository 创建个人访问令牌仓库
+func NewAccessTokenRepository(db *database.DB, logger *logrus.Logger) *AccessTokenRepository {
+	return &AccessTokenRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create 创建个人访问令牌
+func (r *AccessTokenRepository) Create(ctx context.Context, token *model.AccessToken) error {
+	query := `
+		INSERT INTO access_tokens (user_id, linux_do_id, name, token_prefix, token_hash, scopes, rate_limit, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		token.UserID,
+		token.LinuxDoID,
+		token.Name,
+		token.TokenPrefix,
+		token.TokenHash,
+		token.Scopes,
+		token.RateLimit,
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+
+	if err != nil {
+		r.logger.WithError(err).WithField("user_id", token.UserID).Error("Failed to create access token")
+		return fmt.Errorf("failed to create access token: %w", err)
+	}
+
+	return nil
+}
+
+// GetByHash 根据令牌哈希获取未撤销的令牌
+func (r *AccessTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*model.AccessToken, error) {
+	var token model.AccessToken
+	query := `SELECT ` + accessTokenColumns + ` FROM access_tokens WHERE token_hash = $1 AND revoked_at IS NULL`
+
+	if err := r.db.GetContext(ctx, &token, query, tokenHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.WithError(err).Error("Failed to get access token")
+		return nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// ListByUser 获取用户未撤销的令牌（最新的在前）
+func (r *AccessTokenRepository) ListByUser(ctx context.Context, userID int) ([]*model.AccessToken, error) {
+	var tokens []*model.AccessToken
+	query := `SELECT ` + accessTokenColumns + ` FROM access_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY id DESC`
+
+	if err := r.db.SelectContext(ctx, &tokens, query, userID); err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to list access tokens")
+		return nil, fmt.Errorf("failed to list access tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// CountActiveByUser 统计用户未撤销且未过期的令牌数量
+func (r *AccessTokenRepository) CountActiveByUser(ctx context.Context, userID int) (int, error) {
+	var count int
+	query := `
+		SELECT COUNT(*) FROM access_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())
+	`
+
+	if err := r.db.GetContext(ctx, &count, query, userID); err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to count access tokens")
+		return 0, fmt.Errorf("failed to count access tokens: %w", err)
+	}
+
+	return count, nil
+}
+
+// Revoke 撤销用户的令牌，返回是否存在该令牌
+func (r *AccessTokenRepository) Revoke(ctx context.Context, userID int, id int64) (bool, error) {
+	query := `
+		UPDATE access_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		r.logger.WithError(err).WithField("token_id", id).Error("Failed to revoke access token")
+		return false, fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// TouchLastUsed 记录令牌最近使用时间和IP（距上次记录不足 interval 时不更新）
+func (r *AccessTokenRepository) TouchLastUsed(ctx context.Context, id int64, ip string, interval time.Duration) error {
+	query := `
+		UPDATE access_tokens SET last_used_at = NOW(), last_used_ip = $2
+		WHERE id = $1
+		  AND (last_used_at IS NULL OR last_used_at < NOW() - make_interval(secs => $3) OR last_used_ip <> $2)
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, id, ip, interval.Seconds()); err != nil {
+		r.logger.WithError(err).WithField("token_id", id).Warn("Failed to record access token usage")
+		return fmt.Errorf("failed to record access token usage: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteByUserID 删除用户的全部令牌
+func (r *AccessTokenRepository) DeleteByUserID(ctx context.Context, userID int) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM access_tokens WHERE user_id = $1`, userID); err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to delete access tokens")
+		return fmt.Errorf("failed to delete access tokens: %w", err)
+	}
+
+	return nil
+}
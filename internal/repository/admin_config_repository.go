@@ -10,30 +10,93 @@ import (
 	"github.com/yourusername/kyx-quota-bridge/internal/model"
 	"github.com/yourusername/kyx-quota-bridge/pkg/cache"
 	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+	"github.com/yourusername/kyx-quota-bridge/pkg/encryption"
 )
 
 // AdminConfigRepository 管理员配置仓库
+// session 和 keys_authorization 加密保存（数据库与缓存中均为密文），Get 返回解密后的配置
 type AdminConfigRepository struct {
-	db     *database.DB
-	cache  *cache.Redis
-	logger *logrus.Logger
+	db      *database.DB
+	cache   *cache.Redis
+	keyring *encryption.Keyring
+	logger  *logrus.Logger
 }
 
-// NewAdminConfigRepository 创建管理员配置仓库
-func NewAdminConfigRepository(db *database.DB, cache *cache.Redis, logger *logrus.Logger) *AdminConfigRepository {
+// NewAdminConfigRepository 创建管理员配置仓库，keyring 为 nil 时敏感字段以明文保存
+func NewAdminConfigRepository(db *database.DB, cache *cache.Redis, keyring *encryption.Keyring, logger *logrus.Logger) *AdminConfigRepository {
 	return &AdminConfigRepository{
-		db:     db,
-		cache:  cache,
-		logger: logger,
+		db:      db,
+		cache:   cache,
+		keyring: keyring,
+		logger:  logger,
 	}
 }
 
+// encryptSecret 加密敏感字段，空值保持 NULL
+func (r *AdminConfigRepository) encryptSecret(value sql.NullString) (sql.NullString, error) {
+	if !value.Valid || value.String == "" {
+		return value, nil
+	}
+	encrypted, _, err := r.keyring.Encrypt(value.String)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to encrypt admin config secret: %w", err)
+	}
+	return sql.NullString{String: encrypted, Valid: true}, nil
+}
+
+// decryptSecrets 解密配置中的敏感字段
+func (r *AdminConfigRepository) decryptSecrets(config *model.AdminConfig) error {
+	for _, field := range []*sql.NullString{&config.Session, &config.KeysAuthorization} {
+		if !field.Valid {
+			continue
+		}
+		plaintext, err := r.keyring.Decrypt(field.String)
+		if err != nil {
+			r.logger.WithError(err).Error("Failed to decrypt admin config secret")
+			return fmt.Errorf("failed to decrypt admin config secret: %w", err)
+		}
+		field.String = plaintext
+	}
+	return nil
+}
+
+// encryptSecrets 返回加密后的 session 和 keys_authorization
+func (r *AdminConfigRepository) encryptSecrets(session, authorization sql.NullString) (sql.NullString, sql.NullString, error) {
+	encryptedSession, err := r.encryptSecret(session)
+	if err != nil {
+		return sql.NullString{}, sql.NullString{}, err
+	}
+	encryptedAuthorization, err := r.encryptSecret(authorization)
+	if err != nil {
+		return sql.NullString{}, sql.NullString{}, err
+	}
+	return encryptedSession, encryptedAuthorization, nil
+}
+
+// secretUpdate 将部分更新中的敏感字段值转换为 sql.NullString
+func secretUpdate(val interface{}) sql.NullString {
+	switch v := val.(type) {
+	case sql.NullString:
+		return v
+	case string:
+		return sql.NullString{String: v, Valid: true}
+	case *string:
+		if v != nil {
+			return sql.NullString{String: *v, Valid: true}
+		}
+	}
+	return sql.NullString{}
+}
+
 // Get 获取管理员配置（单例模式）
 func (r *AdminConfigRepository) Get(ctx context.Context) (*model.AdminConfig, error) {
 	// 先从缓存获取
 	var config model.AdminConfig
 	err := r.cache.GetJSON(ctx, model.CacheKeyAdminConfig, &config)
 	if err == nil && config.ID > 0 {
+		if err := r.decryptSecrets(&config); err != nil {
+			return nil, err
+		}
 		r.logger.Debug("Admin config retrieved from cache")
 		return &config, nil
 	}
@@ -45,7 +108,14 @@ func (r *AdminConfigRepository) Get(ctx context.Context) (*model.AdminConfig, er
 	// 从数据库获取
 	query := `
 		SELECT id, session, new_api_user, claim_quota, keys_api_url,
-		       keys_authorization, group_id, updated_at
+		       keys_authorization, group_id, updated_at,
+		       donate_quota_per_key, max_donate_keys_per_day,
+		       max_donate_submissions_per_day, max_keys_per_submission,
+		       streak_bonuses, claim_quota_threshold, claim_threshold_mode,
+		       secrets_key_version, min_bind_trust_level, min_claim_trust_level,
+		       min_donate_trust_level, trust_level_claim_quotas,
+		       bind_bonus_enabled, bind_bonus_quota, bind_bonus_accounts_after,
+		       group_tiers, group_demote_inactive_days
 		FROM admin_config
 		ORDER BY id DESC
 		LIMIT 1
@@ -61,9 +131,13 @@ func (r *AdminConfigRepository) Get(ctx context.Context) (*model.AdminConfig, er
 		return nil, fmt.Errorf("failed to get admin config: %w", err)
 	}
 
-	// 缓存配置（1小时）
+	// 缓存配置（1小时，敏感字段保持密文）
 	_ = r.cache.SetJSON(ctx, model.CacheKeyAdminConfig, &config, time.Hour)
 
+	if err := r.decryptSecrets(&config); err != nil {
+		return nil, err
+	}
+
 	r.logger.WithField("config_id", config.ID).Debug("Admin config retrieved from database")
 	return &config, nil
 }
@@ -73,23 +147,53 @@ func (r *AdminConfigRepository) Create(ctx context.Context, config *model.AdminC
 	query := `
 		INSERT INTO admin_config (
 			session, new_api_user, claim_quota, keys_api_url,
-			keys_authorization, group_id, updated_at
+			keys_authorization, group_id, updated_at,
+			donate_quota_per_key, max_donate_keys_per_day,
+			max_donate_submissions_per_day, max_keys_per_submission,
+			streak_bonuses, claim_quota_threshold, claim_threshold_mode,
+			secrets_key_version, min_bind_trust_level, min_claim_trust_level,
+			min_donate_trust_level, trust_level_claim_quotas,
+			bind_bonus_enabled, bind_bonus_quota, bind_bonus_accounts_after,
+			group_tiers, group_demote_inactive_days
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)
 		RETURNING id, updated_at
 	`
 
+	session, authorization, err := r.encryptSecrets(config.Session, config.KeysAuthorization)
+	if err != nil {
+		return err
+	}
+	config.SecretsKeyVersion = r.keyring.CurrentVersion()
+
 	now := time.Now()
-	err := r.db.QueryRowContext(
+	err = r.db.QueryRowContext(
 		ctx,
 		query,
-		config.Session,
+		session,
 		config.NewAPIUser,
 		config.ClaimQuota,
 		config.KeysAPIURL,
-		config.KeysAuthorization,
+		authorization,
 		config.GroupID,
 		now,
+		config.DonateQuotaPerKey,
+		config.MaxDonateKeysPerDay,
+		config.MaxDonateSubmissionsPerDay,
+		config.MaxKeysPerSubmission,
+		config.StreakBonuses,
+		config.ClaimQuotaThreshold,
+		config.ClaimThresholdMode,
+		config.SecretsKeyVersion,
+		config.MinBindTrustLevel,
+		config.MinClaimTrustLevel,
+		config.MinDonateTrustLevel,
+		config.TrustLevelClaimQuotas,
+		config.BindBonusEnabled,
+		config.BindBonusQuota,
+		config.BindBonusAccountsAfter,
+		config.GroupTiers,
+		config.GroupDemoteInactiveDays,
 	).Scan(&config.ID, &config.UpdatedAt)
 
 	if err != nil {
@@ -126,22 +230,30 @@ func (r *AdminConfigRepository) Update(ctx context.Context, config *model.AdminC
 		    keys_api_url = $4,
 		    keys_authorization = $5,
 		    group_id = $6,
-		    updated_at = $7
-		WHERE id = $8
+		    updated_at = $7,
+		    secrets_key_version = $8
+		WHERE id = $9
 		RETURNING id, updated_at
 	`
 
+	session, authorization, err := r.encryptSecrets(config.Session, config.KeysAuthorization)
+	if err != nil {
+		return err
+	}
+	config.SecretsKeyVersion = r.keyring.CurrentVersion()
+
 	now := time.Now()
 	err = r.db.QueryRowContext(
 		ctx,
 		query,
-		config.Session,
+		session,
 		config.NewAPIUser,
 		config.ClaimQuota,
 		config.KeysAPIURL,
-		config.KeysAuthorization,
+		authorization,
 		config.GroupID,
 		now,
+		config.SecretsKeyVersion,
 		currentConfig.ID,
 	).Scan(&config.ID, &config.UpdatedAt)
 
@@ -174,10 +286,28 @@ func (r *AdminConfigRepository) UpdatePartial(ctx context.Context, updates map[s
 	args := []interface{}{time.Now()}
 	paramIndex := 2
 
-	if val, ok := updates["session"]; ok {
-		query += fmt.Sprintf(", session = $%d", paramIndex)
-		args = append(args, val)
-		paramIndex++
+	// 敏感字段：任一字段更新时两者一起用当前主密钥重新加密，保证 secrets_key_version 一致
+	sessionVal, sessionUpdated := updates["session"]
+	authorizationVal, authorizationUpdated := updates["keys_authorization"]
+	if sessionUpdated || authorizationUpdated {
+		session := currentConfig.Session
+		if sessionUpdated {
+			session = secretUpdate(sessionVal)
+		}
+		authorization := currentConfig.KeysAuthorization
+		if authorizationUpdated {
+			authorization = secretUpdate(authorizationVal)
+		}
+
+		encryptedSession, encryptedAuthorization, err := r.encryptSecrets(session, authorization)
+		if err != nil {
+			return err
+		}
+
+		query += fmt.Sprintf(", session = $%d, keys_authorization = $%d, secrets_key_version = $%d",
+			paramIndex, paramIndex+1, paramIndex+2)
+		args = append(args, encryptedSession, encryptedAuthorization, r.keyring.CurrentVersion())
+		paramIndex += 3
 	}
 	if val, ok := updates["new_api_user"]; ok {
 		query += fmt.Sprintf(", new_api_user = $%d", paramIndex)
@@ -194,13 +324,88 @@ func (r *AdminConfigRepository) UpdatePartial(ctx context.Context, updates map[s
 		args = append(args, val)
 		paramIndex++
 	}
-	if val, ok := updates["keys_authorization"]; ok {
-		query += fmt.Sprintf(", keys_authorization = $%d", paramIndex)
+	if val, ok := updates["group_id"]; ok {
+		query += fmt.Sprintf(", group_id = $%d", paramIndex)
 		args = append(args, val)
 		paramIndex++
 	}
-	if val, ok := updates["group_id"]; ok {
-		query += fmt.Sprintf(", group_id = $%d", paramIndex)
+	if val, ok := updates["donate_quota_per_key"]; ok {
+		query += fmt.Sprintf(", donate_quota_per_key = $%d", paramIndex)
+		args = append(args, val)
+		paramIndex++
+	}
+	if val, ok := updates["max_donate_keys_per_day"]; ok {
+		query += fmt.Sprintf(", max_donate_keys_per_day = $%d", paramIndex)
+		args = append(args, val)
+		paramIndex++
+	}
+	if val, ok := updates["max_donate_submissions_per_day"]; ok {
+		query += fmt.Sprintf(", max_donate_submissions_per_day = $%d", paramIndex)
+		args = append(args, val)
+		paramIndex++
+	}
+	if val, ok := updates["max_keys_per_submission"]; ok {
+		query += fmt.Sprintf(", max_keys_per_submission = $%d", paramIndex)
+		args = append(args, val)
+		paramIndex++
+	}
+	if val, ok := updates["streak_bonuses"]; ok {
+		query += fmt.Sprintf(", streak_bonuses = $%d", paramIndex)
+		args = append(args, val)
+		paramIndex++
+	}
+	if val, ok := updates["claim_quota_threshold"]; ok {
+		query += fmt.Sprintf(", claim_quota_threshold = $%d", paramIndex)
+		args = append(args, val)
+		paramIndex++
+	}
+	if val, ok := updates["claim_threshold_mode"]; ok {
+		query += fmt.Sprintf(", claim_threshold_mode = $%d", paramIndex)
+		args = append(args, val)
+		paramIndex++
+	}
+	if val, ok := updates["min_bind_trust_level"]; ok {
+		query += fmt.Sprintf(", min_bind_trust_level = $%d", paramIndex)
+		args = append(args, val)
+		paramIndex++
+	}
+	if val, ok := updates["min_claim_trust_level"]; ok {
+		query += fmt.Sprintf(", min_claim_trust_level = $%d", paramIndex)
+		args = append(args, val)
+		paramIndex++
+	}
+	if val, ok := updates["min_donate_trust_level"]; ok {
+		query += fmt.Sprintf(", min_donate_trust_level = $%d", paramIndex)
+		args = append(args, val)
+		paramIndex++
+	}
+	if val, ok := updates["trust_level_claim_quotas"]; ok {
+		query += fmt.Sprintf(", trust_level_claim_quotas = $%d", paramIndex)
+		args = append(args, val)
+		paramIndex++
+	}
+	if val, ok := updates["bind_bonus_enabled"]; ok {
+		query += fmt.Sprintf(", bind_bonus_enabled = $%d", paramIndex)
+		args = append(args, val)
+		paramIndex++
+	}
+	if val, ok := updates["bind_bonus_quota"]; ok {
+		query += fmt.Sprintf(", bind_bonus_quota = $%d", paramIndex)
+		args = append(args, val)
+		paramIndex++
+	}
+	if val, ok := updates["bind_bonus_accounts_after"]; ok {
+		query += fmt.Sprintf(", bind_bonus_accounts_after = $%d", paramIndex)
+		args = append(args, val)
+		paramIndex++
+	}
+	if val, ok := updates["group_tiers"]; ok {
+		query += fmt.Sprintf(", group_tiers = $%d", paramIndex)
+		args = append(args, val)
+		paramIndex++
+	}
+	if val, ok := updates["group_demote_inactive_days"]; ok {
+		query += fmt.Sprintf(", group_demote_inactive_days = $%d", paramIndex)
 		args = append(args, val)
 		paramIndex++
 	}
@@ -222,9 +427,13 @@ func (r *AdminConfigRepository) UpdatePartial(ctx context.Context, updates map[s
 	// 清除缓存
 	_ = r.cache.Del(ctx, model.CacheKeyAdminConfig)
 
+	fields := make([]string, 0, len(updates))
+	for field := range updates {
+		fields = append(fields, field)
+	}
 	r.logger.WithFields(logrus.Fields{
 		"config_id": currentConfig.ID,
-		"updates":   updates,
+		"fields":    fields,
 	}).Info("Admin config partially updated successfully")
 
 	return nil
@@ -336,6 +545,119 @@ func (r *AdminConfigRepository) UpdateKeysAPIConfig(ctx context.Context, url, au
 	})
 }
 
+// GetDonatePolicy 获取生效的投喂策略
+func (r *AdminConfigRepository) GetDonatePolicy(ctx context.Context, defaults model.DonatePolicy) (model.DonatePolicy, error) {
+	config, err := r.Get(ctx)
+	if err != nil {
+		return defaults, err
+	}
+	return config.DonatePolicy(defaults), nil
+}
+
+// GetClaimGatePolicy 获取生效的剩余额度阈值策略
+func (r *AdminConfigRepository) GetClaimGatePolicy(ctx context.Context, defaults model.ClaimGatePolicy) (model.ClaimGatePolicy, error) {
+	config, err := r.Get(ctx)
+	if err != nil {
+		return defaults, err
+	}
+	return config.ClaimGatePolicy(defaults), nil
+}
+
+// GetTrustPolicy 获取生效的信任等级策略
+func (r *AdminConfigRepository) GetTrustPolicy(ctx context.Context, defaults model.TrustPolicy) (model.TrustPolicy, error) {
+	config, err := r.Get(ctx)
+	if err != nil {
+		return defaults, err
+	}
+	return config.TrustPolicy(defaults), nil
+}
+
+// GetBindBonusPolicy 获取生效的首次绑定奖励策略
+func (r *AdminConfigRepository) GetBindBonusPolicy(ctx context.Context, defaults model.BindBonusPolicy) (model.BindBonusPolicy, error) {
+	config, err := r.Get(ctx)
+	if err != nil {
+		return defaults, err
+	}
+	return config.BindBonusPolicy(defaults), nil
+}
+
+// GetGroupTierPolicy 获取生效的用户组档位策略
+func (r *AdminConfigRepository) GetGroupTierPolicy(ctx context.Context, defaults model.GroupTierPolicy) (model.GroupTierPolicy, error) {
+	config, err := r.Get(ctx)
+	if err != nil {
+		return defaults, err
+	}
+	return config.GroupTierPolicy(defaults), nil
+}
+
+// GetStreakBonuses 获取生效的连续领取奖励表
+func (r *AdminConfigRepository) GetStreakBonuses(ctx context.Context) (model.StreakBonusTable, error) {
+	config, err := r.Get(ctx)
+	if err != nil {
+		return model.DefaultStreakBonuses, err
+	}
+	return config.StreakBonusTable(), nil
+}
+
+// ReencryptSecrets 使用当前主密钥重新加密 session 和 keys_authorization（轮换主密钥或首次启用加密时使用）
+// 返回是否进行了重新加密
+func (r *AdminConfigRepository) ReencryptSecrets(ctx context.Context) (bool, error) {
+	current := r.keyring.CurrentVersion()
+	if current == 0 {
+		return false, fmt.Errorf("encryption is not configured")
+	}
+
+	var config model.AdminConfig
+	query := `
+		SELECT id, session, keys_authorization, secrets_key_version
+		FROM admin_config
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &config, query)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get admin config for re-encryption")
+		return false, fmt.Errorf("failed to get admin config: %w", err)
+	}
+
+	if config.SecretsKeyVersion == current {
+		return false, nil
+	}
+
+	if err := r.decryptSecrets(&config); err != nil {
+		return false, err
+	}
+
+	session, authorization, err := r.encryptSecrets(config.Session, config.KeysAuthorization)
+	if err != nil {
+		return false, err
+	}
+
+	updateQuery := `
+		UPDATE admin_config
+		SET session = $1, keys_authorization = $2, secrets_key_version = $3
+		WHERE id = $4 AND secrets_key_version = $5
+	`
+	if _, err := r.db.ExecContext(ctx, updateQuery, session, authorization, current, config.ID, config.SecretsKeyVersion); err != nil {
+		r.logger.WithError(err).WithField("config_id", config.ID).Error("Failed to re-encrypt admin config secrets")
+		return false, fmt.Errorf("failed to re-encrypt admin config secrets: %w", err)
+	}
+
+	// 清除缓存
+	_ = r.cache.Del(ctx, model.CacheKeyAdminConfig)
+
+	r.logger.WithFields(logrus.Fields{
+		"config_id":   config.ID,
+		"key_version": current,
+	}).Info("Admin config secrets re-encrypted")
+
+	return true, nil
+}
+
 // ClearCache 清除配置缓存
 func (r *AdminConfigRepository) ClearCache(ctx context.Context) error {
 	err := r.cache.Del(ctx, model.CacheKeyAdminConfig)
@@ -0,0 +1,395 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+	"github.com/yourusername/kyx-quota-bridge/pkg/encryption"
+)
+
+// adminColumns 管理员查询字段
+const adminColumns = `id, username, password_hash, role, disabled, last_login_at, created_at, updated_at,
+	totp_secret, totp_key_version, totp_enabled, totp_last_counter, totp_recovery_codes`
+
+// AdminRepository 管理员账号仓库
+type AdminRepository struct {
+	db      *database.DB
+	keyring *encryption.Keyring
+	logger  *logrus.Logger
+}
+
+// NewAdminRepository 创建管理员账号仓库
+func NewAdminRepository(db *database.DB, keyring *encryption.Keyring, logger *logrus.Logger) *AdminRepository {
+	return &AdminRepository{
+		db:      db,
+		keyring: keyring,
+		logger:  logger,
+	}
+}
+
+// decryptTOTPSecret 解密管理员的 TOTP 密钥
+func (r *AdminRepository) decryptTOTPSecret(admin *model.Admin) error {
+	if !admin.TOTPSecret.Valid || admin.TOTPSecret.String == "" {
+		return nil
+	}
+
+	secret, err := r.keyring.Decrypt(admin.TOTPSecret.String)
+	if err != nil {
+		r.logger.WithError(err).WithField("admin_id", admin.ID).Error("Failed to decrypt admin totp secret")
+		return fmt.Errorf("failed to decrypt totp secret: %w", err)
+	}
+	admin.TOTPSecret.String = secret
+	return nil
+}
+
+// Create 创建管理员
+func (r *AdminRepository) Create(ctx context.Context, admin *model.Admin) error {
+	query := `
+		INSERT INTO admins (username, password_hash, role, disabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+
+	now := time.Now()
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		admin.Username,
+		admin.PasswordHash,
+		admin.Role,
+		admin.Disabled,
+		now,
+		now,
+	).Scan(&admin.ID, &admin.CreatedAt, &admin.UpdatedAt)
+
+	if err != nil {
+		r.logger.WithError(err).WithField("username", admin.Username).Error("Failed to create admin")
+		return fmt.Errorf("failed to create admin: %w", err)
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"admin_id": admin.ID,
+		"username": admin.Username,
+		"role":     admin.Role,
+	}).Info("Admin created successfully")
+
+	return nil
+}
+
+// GetByID 根据ID获取管理员
+func (r *AdminRepository) GetByID(ctx context.Context, id int) (*model.Admin, error) {
+	var admin model.Admin
+	query := `SELECT ` + adminColumns + ` FROM admins WHERE id = $1`
+
+	err := r.db.GetContext(ctx, &admin, query, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.WithError(err).WithField("admin_id", id).Error("Failed to get admin by ID")
+		return nil, fmt.Errorf("failed to get admin by id: %w", err)
+	}
+
+	if err := r.decryptTOTPSecret(&admin); err != nil {
+		return nil, err
+	}
+
+	return &admin, nil
+}
+
+// GetByUsername 根据用户名获取管理员
+func (r *AdminRepository) GetByUsername(ctx context.Context, username string) (*model.Admin, error) {
+	var admin model.Admin
+	query := `SELECT ` + adminColumns + ` FROM admins WHERE username = $1`
+
+	err := r.db.GetContext(ctx, &admin, query, username)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.WithError(err).WithField("username", username).Error("Failed to get admin by username")
+		return nil, fmt.Errorf("failed to get admin by username: %w", err)
+	}
+
+	if err := r.decryptTOTPSecret(&admin); err != nil {
+		return nil, err
+	}
+
+	return &admin, nil
+}
+
+// List 获取全部管理员
+func (r *AdminRepository) List(ctx context.Context) ([]*model.Admin, error) {
+	query := `SELECT ` + adminColumns + ` FROM admins ORDER BY id`
+
+	var admins []*model.Admin
+	if err := r.db.SelectContext(ctx, &admins, query); err != nil {
+		r.logger.WithError(err).Error("Failed to list admins")
+		return nil, fmt.Errorf("failed to list admins: %w", err)
+	}
+
+	for _, admin := range admins {
+		if err := r.decryptTOTPSecret(admin); err != nil {
+			return nil, err
+		}
+	}
+
+	return admins, nil
+}
+
+// Count 获取管理员数量
+func (r *AdminRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM admins`
+
+	if err := r.db.GetContext(ctx, &count, query); err != nil {
+		r.logger.WithError(err).Error("Failed to count admins")
+		return 0, fmt.Errorf("failed to count admins: %w", err)
+	}
+
+	return count, nil
+}
+
+// CountActiveOwners 获取未停用的 owner 数量
+func (r *AdminRepository) CountActiveOwners(ctx context.Context) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM admins WHERE role = $1 AND NOT disabled`
+
+	if err := r.db.GetContext(ctx, &count, query, model.AdminRoleOwner); err != nil {
+		r.logger.WithError(err).Error("Failed to count active owners")
+		return 0, fmt.Errorf("failed to count active owners: %w", err)
+	}
+
+	return count, nil
+}
+
+// Update 更新管理员的密码哈希、角色和停用状态
+func (r *AdminRepository) Update(ctx context.Context, admin *model.Admin) error {
+	query := `
+		UPDATE admins
+		SET password_hash = $1, role = $2, disabled = $3, updated_at = $4
+		WHERE id = $5
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		admin.PasswordHash,
+		admin.Role,
+		admin.Disabled,
+		time.Now(),
+		admin.ID,
+	).Scan(&admin.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("admin not found")
+	}
+	if err != nil {
+		r.logger.WithError(err).WithField("admin_id", admin.ID).Error("Failed to update admin")
+		return fmt.Errorf("failed to update admin: %w", err)
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"admin_id": admin.ID,
+		"username": admin.Username,
+		"role":     admin.Role,
+		"disabled": admin.Disabled,
+	}).Info("Admin updated successfully")
+
+	return nil
+}
+
+// UpdateLastLogin 更新最近登录时间
+func (r *AdminRepository) UpdateLastLogin(ctx context.Context, id int) error {
+	query := `UPDATE admins SET last_login_at = $1 WHERE id = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, time.Now(), id); err != nil {
+		r.logger.WithError(err).WithField("admin_id", id).Error("Failed to update admin last login")
+		return fmt.Errorf("failed to update admin last login: %w", err)
+	}
+
+	return nil
+}
+
+// SetPendingTOTP 保存待验证的 TOTP 密钥（未启用状态），覆盖之前未完成的绑定
+func (r *AdminRepository) SetPendingTOTP(ctx context.Context, id int, secret string) error {
+	encrypted, version, err := r.keyring.Encrypt(secret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt totp secret: %w", err)
+	}
+
+	query := `
+		UPDATE admins
+		SET totp_secret = $1, totp_key_version = $2, totp_enabled = FALSE,
+			totp_last_counter = 0, totp_recovery_codes = '[]', updated_at = $3
+		WHERE id = $4 AND NOT totp_enabled
+	`
+
+	result, err := r.db.ExecContext(ctx, query, encrypted, version, time.Now(), id)
+	if err != nil {
+		r.logger.WithError(err).WithField("admin_id", id).Error("Failed to set pending totp secret")
+		return fmt.Errorf("failed to set totp secret: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("admin not found or two-factor authentication already enabled")
+	}
+
+	return nil
+}
+
+// EnableTOTP 启用两步验证，记录首次验证使用的时间步并保存恢复码哈希
+func (r *AdminRepository) EnableTOTP(ctx context.Context, id int, counter int64, recoveryCodes model.JSONArray) error {
+	query := `
+		UPDATE admins
+		SET totp_enabled = TRUE, totp_last_counter = $1, totp_recovery_codes = $2, updated_at = $3
+		WHERE id = $4 AND NOT totp_enabled AND totp_secret IS NOT NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, counter, recoveryCodes, time.Now(), id)
+	if err != nil {
+		r.logger.WithError(err).WithField("admin_id", id).Error("Failed to enable totp")
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("two-factor authentication is not pending setup")
+	}
+
+	r.logger.WithField("admin_id", id).Info("Admin two-factor authentication enabled")
+	return nil
+}
+
+// UseTOTPCounter 记录已使用的时间步，时间步不大于上次记录时返回 false（验证码重放）
+func (r *AdminRepository) UseTOTPCounter(ctx context.Context, id int, counter int64) (bool, error) {
+	query := `UPDATE admins SET totp_last_counter = $1 WHERE id = $2 AND totp_last_counter < $1`
+
+	result, err := r.db.ExecContext(ctx, query, counter, id)
+	if err != nil {
+		r.logger.WithError(err).WithField("admin_id", id).Error("Failed to record totp counter")
+		return false, fmt.Errorf("failed to record totp counter: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+// UseRecoveryCode 消费一个恢复码（按哈希匹配），恢复码不存在或已使用时返回 false
+func (r *AdminRepository) UseRecoveryCode(ctx context.Context, id int, codeHash string) (bool, error) {
+	query := `
+		UPDATE admins
+		SET totp_recovery_codes = totp_recovery_codes - $1::text, updated_at = $2
+		WHERE id = $3 AND totp_enabled AND jsonb_exists(totp_recovery_codes, $1::text)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, codeHash, time.Now(), id)
+	if err != nil {
+		r.logger.WithError(err).WithField("admin_id", id).Error("Failed to use recovery code")
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+// SetRecoveryCodes 替换恢复码哈希（重新生成恢复码）
+func (r *AdminRepository) SetRecoveryCodes(ctx context.Context, id int, recoveryCodes model.JSONArray) error {
+	query := `UPDATE admins SET totp_recovery_codes = $1, updated_at = $2 WHERE id = $3 AND totp_enabled`
+
+	result, err := r.db.ExecContext(ctx, query, recoveryCodes, time.Now(), id)
+	if err != nil {
+		r.logger.WithError(err).WithField("admin_id", id).Error("Failed to set recovery codes")
+		return fmt.Errorf("failed to set recovery codes: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("two-factor authentication is not enabled")
+	}
+
+	return nil
+}
+
+// DisableTOTP 关闭两步验证并清除密钥和恢复码
+func (r *AdminRepository) DisableTOTP(ctx context.Context, id int) error {
+	query := `
+		UPDATE admins
+		SET totp_secret = NULL, totp_key_version = 0, totp_enabled = FALSE,
+			totp_last_counter = 0, totp_recovery_codes = '[]', updated_at = $1
+		WHERE id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		r.logger.WithError(err).WithField("admin_id", id).Error("Failed to disable totp")
+		return fmt.Errorf("failed to disable totp: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("admin not found")
+	}
+
+	r.logger.WithField("admin_id", id).Info("Admin two-factor authentication disabled")
+	return nil
+}
+
+// ReencryptTOTPSecrets 使用当前主密钥重新加密旧版本的 TOTP 密钥
+func (r *AdminRepository) ReencryptTOTPSecrets(ctx context.Context) (int, error) {
+	current := r.keyring.CurrentVersion()
+	if current == 0 {
+		return 0, fmt.Errorf("encryption is not configured")
+	}
+
+	var admins []*model.Admin
+	query := `SELECT id, totp_secret FROM admins WHERE totp_secret IS NOT NULL AND totp_key_version <> $1`
+	if err := r.db.SelectContext(ctx, &admins, query, current); err != nil {
+		return 0, fmt.Errorf("failed to list totp secrets: %w", err)
+	}
+
+	updated := 0
+	for _, admin := range admins {
+		if err := r.decryptTOTPSecret(admin); err != nil {
+			return updated, err
+		}
+		encrypted, version, err := r.keyring.Encrypt(admin.TOTPSecret.String)
+		if err != nil {
+			return updated, fmt.Errorf("failed to encrypt totp secret: %w", err)
+		}
+
+		update := `UPDATE admins SET totp_secret = $1, totp_key_version = $2 WHERE id = $3`
+		if _, err := r.db.ExecContext(ctx, update, encrypted, version, admin.ID); err != nil {
+			return updated, fmt.Errorf("failed to update totp secret: %w", err)
+		}
+		updated++
+	}
+
+	return updated, nil
+}
+
+// Delete 删除管理员
+func (r *AdminRepository) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM admins WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		r.logger.WithError(err).WithField("admin_id", id).Error("Failed to delete admin")
+		return fmt.Errorf("failed to delete admin: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("admin not found")
+	}
+
+	r.logger.WithField("admin_id", id).Info("Admin deleted successfully")
+	return nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+)
+
+// systemAlertColumns 系统告警查询字段
+const systemAlertColumns = `id, alert_type, message, failures, created_at, updated_at, resolved_at`
+
+// AlertRepository 系统告警仓库
+type AlertRepository struct {
+	db     *database.DB
+	logger *logrus.Logger
+}
+
+// NewAlertRepository 创建系统告警仓库
+func NewAlertRepository(db *database.DB, logger *logrus.Logger) *AlertRepository {
+	return &AlertRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Raise 产生告警：没有未解决的同类告警时新建，否则更新错误信息并累加失败次数
+// created 表示是否为新建的告警
+func (r *AlertRepository) Raise(ctx context.Context, alertType, message string) (alert *model.SystemAlert, created bool, err error) {
+	query := `
+		INSERT INTO system_alerts (alert_type, message)
+		VALUES ($1, $2)
+		ON CONFLICT (alert_type) WHERE resolved_at IS NULL
+		DO UPDATE SET message = EXCLUDED.message, failures = system_alerts.failures + 1, updated_at = CURRENT_TIMESTAMP
+		RETURNING ` + systemAlertColumns + `, (xmax = 0) AS created
+	`
+
+	var row struct {
+		model.SystemAlert
+		Created bool `db:"created"`
+	}
+	if err := r.db.GetContext(ctx, &row, query, alertType, message); err != nil {
+		r.logger.WithError(err).WithField("alert_type", alertType).Error("Failed to raise system alert")
+		return nil, false, fmt.Errorf("failed to raise system alert: %w", err)
+	}
+
+	return &row.SystemAlert, row.Created, nil
+}
+
+// Resolve 解决未解决的告警，没有未解决的告警时返回 nil
+func (r *AlertRepository) Resolve(ctx context.Context, alertType string) (*model.SystemAlert, error) {
+	query := `
+		UPDATE system_alerts SET resolved_at = CURRENT_TIMESTAMP
+		WHERE alert_type = $1 AND resolved_at IS NULL
+		RETURNING ` + systemAlertColumns
+
+	var alerts []*model.SystemAlert
+	if err := r.db.SelectContext(ctx, &alerts, query, alertType); err != nil {
+		r.logger.WithError(err).WithField("alert_type", alertType).Error("Failed to resolve system alert")
+		return nil, fmt.Errorf("failed to resolve system alert: %w", err)
+	}
+	if len(alerts) == 0 {
+		return nil, nil
+	}
+
+	return alerts[0], nil
+}
+
+// ListOpen 获取全部未解决的告警
+func (r *AlertRepository) ListOpen(ctx context.Context) ([]*model.SystemAlert, error) {
+	query := `
+		SELECT ` + systemAlertColumns + `
+		FROM system_alerts
+		WHERE resolved_at IS NULL
+		ORDER BY created_at
+	`
+
+	var alerts []*model.SystemAlert
+	if err := r.db.SelectContext(ctx, &alerts, query); err != nil {
+		r.logger.WithError(err).Error("Failed to list open system alerts")
+		return nil, fmt.Errorf("failed to list open system alerts: %w", err)
+	}
+
+	return alerts, nil
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+)
+
+// auditLogColumns 审计日志查询字段
+const auditLogColumns = `id, admin_id, admin_username, action, method, path, target, before, after,
+	ip, user_agent, status_code, success, error, prev_hash, hash, created_at`
+
+// auditChainLockID 追加审计日志时使用的事务级 advisory lock，保证哈希链按顺序写入
+const auditChainLockID = 7240014
+
+// auditLogFilterClause 审计日志过滤条件（参数依次为 admin_id、username、action、开始时间、结束时间）
+const auditLogFilterClause = `
+	WHERE ($1 = 0 OR admin_id = $1)
+	  AND ($2 = '' OR admin_username = $2)
+	  AND ($3 = '' OR action = $3)
+	  AND ($4::timestamp IS NULL OR created_at >= $4)
+	  AND ($5::timestamp IS NULL OR created_at < $5)
+`
+
+// AuditLogRepository 管理员审计日志仓库
+type AuditLogRepository struct {
+	db     *database.DB
+	logger *logrus.Logger
+}
+
+// NewAuditLogRepository 创建管理员审计日志仓库
+func NewAuditLogRepository(db *database.DB, logger *logrus.Logger) *AuditLogRepository {
+	return &AuditLogRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Append 追加审计日志：在同一事务中读取链尾哈希、计算本条哈希并写入
+func (r *AuditLogRepository) Append(ctx context.Context, entry *model.AdminAuditLog) error {
+	err := r.db.Transaction(ctx, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLockID); err != nil {
+			return fmt.Errorf("failed to lock audit chain: %w", err)
+		}
+
+		var prevHash string
+		err := tx.GetContext(ctx, &prevHash, `SELECT hash FROM admin_audit_log ORDER BY id DESC LIMIT 1`)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to get audit chain tail: %w", err)
+		}
+
+		// 时间精度与数据库一致，保证读回后哈希不变
+		entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		entry.PrevHash = prevHash
+		hash, err := entry.ComputeHash()
+		if err != nil {
+			return err
+		}
+		entry.Hash = hash
+
+		query := `
+			INSERT INTO admin_audit_log (
+				admin_id, admin_username, action, method, path, target, before, after,
+				ip, user_agent, status_code, success, error, prev_hash, hash, created_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+			RETURNING id
+		`
+		return tx.QueryRowContext(
+			ctx,
+			query,
+			entry.AdminID,
+			entry.AdminUsername,
+			entry.Action,
+			entry.Method,
+			entry.Path,
+			entry.Target,
+			entry.Before,
+			entry.After,
+			entry.IP,
+			entry.UserAgent,
+			entry.StatusCode,
+			entry.Success,
+			entry.Error,
+			entry.PrevHash,
+			entry.Hash,
+			entry.CreatedAt,
+		).Scan(&entry.ID)
+	})
+
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"action":   entry.Action,
+			"admin_id": entry.AdminID.Int64,
+		}).Error("Failed to append admin audit log")
+		return fmt.Errorf("failed to append audit log: %w", err)
+	}
+
+	return nil
+}
+
+// filterArgs 过滤条件参数
+func (r *AuditLogRepository) filterArgs(filter model.AuditLogFilter) []interface{} {
+	var start, end interface{}
+	if !filter.Start.IsZero() {
+		start = filter.Start.UTC()
+	}
+	if !filter.End.IsZero() {
+		end = filter.End.UTC()
+	}
+	return []interface{}{filter.AdminID, filter.Username, filter.Action, start, end}
+}
+
+// List 按条件分页获取审计日志（最新的在前）
+func (r *AuditLogRepository) List(ctx context.Context, filter model.AuditLogFilter, limit, offset int) ([]*model.AdminAuditLog, error) {
+	query := `SELECT ` + auditLogColumns + ` FROM admin_audit_log` + auditLogFilterClause +
+		`ORDER BY id DESC LIMIT $6 OFFSET $7`
+
+	args := append(r.filterArgs(filter), limit, offset)
+
+	var logs []*model.AdminAuditLog
+	if err := r.db.SelectContext(ctx, &logs, query, args...); err != nil {
+		r.logger.WithError(err).Error("Failed to list admin audit logs")
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+
+	return logs, nil
+}
+
+// Count 按条件统计审计日志数量
+func (r *AuditLogRepository) Count(ctx context.Context, filter model.AuditLogFilter) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM admin_audit_log` + auditLogFilterClause
+
+	if err := r.db.GetContext(ctx, &count, query, r.filterArgs(filter)...); err != nil {
+		r.logger.WithError(err).Error("Failed to count admin audit logs")
+		return 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+
+	return count, nil
+}
+
+// ForEach 按 id 顺序分批遍历全部审计日志
+func (r *AuditLogRepository) ForEach(ctx context.Context, batchSize int, fn func(entry *model.AdminAuditLog) error) error {
+	query := `SELECT ` + auditLogColumns + ` FROM admin_audit_log WHERE id > $1 ORDER BY id LIMIT $2`
+
+	var lastID int64
+	for {
+		var logs []*model.AdminAuditLog
+		if err := r.db.SelectContext(ctx, &logs, query, lastID, batchSize); err != nil {
+			r.logger.WithError(err).Error("Failed to iterate admin audit logs")
+			return fmt.Errorf("failed to iterate audit logs: %w", err)
+		}
+
+		for _, entry := range logs {
+			if err := fn(entry); err != nil {
+				return err
+			}
+			lastID = entry.ID
+		}
+
+		if len(logs) < batchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+)
+
+// userBanColumns 处置记录查询字段
+const userBanColumns = `
+	id, kind, linux_do_id, kyx_user_id, reason, expires_at, created_by_id, created_by, created_at,
+	lifted_at, lifted_by_id, lifted_by, lift_reason
+`
+
+// userBanActiveClause 处置记录生效条件（未解除且未到期）
+const userBanActiveClause = `lifted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`
+
+// userBanFilterClause 处置记录过滤条件（参数依次为 linux_do_id、kyx_user_id、kind、是否只查询生效记录）
+// 同时指定 linux_do_id 和 kyx_user_id 时匹配任意一个
+const userBanFilterClause = `
+	WHERE (($1 = '' AND $2 = 0) OR ($1 <> '' AND linux_do_id = $1) OR ($2 > 0 AND kyx_user_id = $2))
+	  AND ($3 = '' OR kind = $3)
+	  AND (NOT $4 OR (` + userBanActiveClause + `))
+`
+
+// BanRepository 用户处置记录仓库
+type BanRepository struct {
+	db     *database.DB
+	logger *logrus.Logger
+}
+
+// NewBanRepository 创建用户处置记录仓库
+func NewBanRepository(db *database.DB, logger *logrus.Logger) *BanRepository {
+	return &BanRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create 创建处置记录
+func (r *BanRepository) Create(ctx context.Context, ban *model.UserBan) error {
+	query := `
+		INSERT INTO user_bans (kind, linux_do_id, kyx_user_id, reason, expires_at, created_by_id, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		ban.Kind,
+		ban.LinuxDoID,
+		ban.KyxUserID,
+		ban.Reason,
+		ban.ExpiresAt,
+		ban.CreatedByID,
+		ban.CreatedBy,
+	).Scan(&ban.ID, &ban.CreatedAt)
+
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"kind":        ban.Kind,
+			"linux_do_id": ban.LinuxDoID,
+			"kyx_user_id": ban.KyxUserID,
+		}).Error("Failed to create user ban")
+		return fmt.Errorf("failed to create user ban: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID 根据ID获取处置记录
+func (r *BanRepository) GetByID(ctx context.Context, id int64) (*model.UserBan, error) {
+	var ban model.UserBan
+	query := `SELECT ` + userBanColumns + ` FROM user_bans WHERE id = $1`
+
+	if err := r.db.GetContext(ctx, &ban, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.WithError(err).WithField("ban_id", id).Error("Failed to get user ban")
+		return nil, fmt.Errorf("failed to get user ban: %w", err)
+	}
+
+	return &ban, nil
+}
+
+// ListActive 获取匹配 Linux.do ID 或公益站用户ID 的生效记录（封禁在前，其次是暂停和观察名单）
+func (r *BanRepository) ListActive(ctx context.Context, linuxDoID string, kyxUserID int) ([]*model.UserBan, error) {
+	query := `
+		SELECT ` + userBanColumns + `
+		FROM user_bans
+		WHERE ` + userBanActiveClause + `
+		  AND ((linux_do_id <> '' AND linux_do_id = $1) OR (kyx_user_id > 0 AND kyx_user_id = $2))
+		ORDER BY CASE kind WHEN 'ban' THEN 0 WHEN 'suspend' THEN 1 ELSE 2 END, expires_at DESC NULLS FIRST, id DESC
+	`
+
+	var bans []*model.UserBan
+	if err := r.db.SelectContext(ctx, &bans, query, linuxDoID, kyxUserID); err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"linux_do_id": linuxDoID,
+			"kyx_user_id": kyxUserID,
+		}).Error("Failed to list active user bans")
+		return nil, fmt.Errorf("failed to list active user bans: %w", err)
+	}
+
+	return bans, nil
+}
+
+// Lift 解除处置，返回记录是否此前仍未解除
+func (r *BanRepository) Lift(ctx context.Context, ban *model.UserBan) (bool, error) {
+	query := `
+		UPDATE user_bans
+		SET lifted_at = NOW(), lifted_by_id = $1, lifted_by = $2, lift_reason = $3
+		WHERE id = $4 AND lifted_at IS NULL
+		RETURNING lifted_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query, ban.LiftedByID, ban.LiftedBy, ban.LiftReason, ban.ID).Scan(&ban.LiftedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		r.logger.WithError(err).WithField("ban_id", ban.ID).Error("Failed to lift user ban")
+		return false, fmt.Errorf("failed to lift user ban: %w", err)
+	}
+
+	return true, nil
+}
+
+// filterArgs 过滤条件参数
+func (r *BanRepository) filterArgs(filter model.UserBanFilter) []interface{} {
+	return []interface{}{filter.LinuxDoID, filter.KyxUserID, filter.Kind, filter.ActiveOnly}
+}
+
+// List 按条件分页获取处置记录（最新的在前）
+func (r *BanRepository) List(ctx context.Context, filter model.UserBanFilter, limit, offset int) ([]*model.UserBan, error) {
+	query := `SELECT ` + userBanColumns + ` FROM user_bans` + userBanFilterClause +
+		`ORDER BY id DESC LIMIT $5 OFFSET $6`
+
+	args := append(r.filterArgs(filter), limit, offset)
+
+	var bans []*model.UserBan
+	if err := r.db.SelectContext(ctx, &bans, query, args...); err != nil {
+		r.logger.WithError(err).Error("Failed to list user bans")
+		return nil, fmt.Errorf("failed to list user bans: %w", err)
+	}
+
+	return bans, nil
+}
+
+// Count 按条件统计处置记录数量
+func (r *BanRepository) Count(ctx context.Context, filter model.UserBanFilter) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM user_bans` + userBanFilterClause
+
+	if err := r.db.GetContext(ctx, &count, query, r.filterArgs(filter)...); err != nil {
+		r.logger.WithError(err).Error("Failed to count user bans")
+		return 0, fmt.Errorf("failed to count user bans: %w", err)
+	}
+
+	return count, nil
+}
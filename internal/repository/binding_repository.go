@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+)
+
+// bindingHistoryColumns 绑定历史查询字段
+const bindingHistoryColumns = `
+	id, user_id, linux_do_id, kyx_user_id, kyx_username, bonus_granted, bound_at, unbound_at, unbind_request_id
+`
+
+// unbindRequestColumns 解绑申请查询字段
+const unbindRequestColumns = `
+	id, user_id, linux_do_id, kyx_user_id, kyx_username, reason, status, created_at,
+	reviewed_at, reviewed_by_id, reviewed_by, review_note
+`
+
+// BindingRepository 绑定历史和解绑申请仓库
+type BindingRepository struct {
+	db     *database.DB
+	logger *logrus.Logger
+}
+
+// NewBindingRepository 创建绑定历史和解绑申请仓库
+func NewBindingRepository(db *database.DB, logger *logrus.Logger) *BindingRepository {
+	return &BindingRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateHistoryTx 在事务中记录一次绑定
+func (r *BindingRepository) CreateHistoryTx(ctx context.Context, tx *sqlx.Tx, history *model.BindingHistory) error {
+	query := `
+		INSERT INTO binding_history (user_id, linux_do_id, kyx_user_id, kyx_username, bonus_granted)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, bound_at
+	`
+
+	err := tx.QueryRowxContext(
+		ctx,
+		query,
+		history.UserID,
+		history.LinuxDoID,
+		history.KyxUserID,
+		history.KyxUsername,
+		history.BonusGranted,
+	).Scan(&history.ID, &history.BoundAt)
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"linux_do_id": history.LinuxDoID,
+			"kyx_user_id": history.KyxUserID,
+		}).Error("Failed to create binding history")
+		return fmt.Errorf("failed to create binding history: %w", err)
+	}
+
+	return nil
+}
+
+// GetOpenBinding 获取用户当前的绑定记录，没有时返回 nil
+func (r *BindingRepository) GetOpenBinding(ctx context.Context, userID int) (*model.BindingHistory, error) {
+	var history model.BindingHistory
+	query := `SELECT ` + bindingHistoryColumns + ` FROM binding_history WHERE user_id = $1 AND unbound_at IS NULL`
+
+	if err := r.db.GetContext(ctx, &history, query, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to get open binding")
+		return nil, fmt.Errorf("failed to get open binding: %w", err)
+	}
+
+	return &history, nil
+}
+
+// CloseBindingTx 在事务中结束用户当前的绑定记录（requestID 为 0 表示不是通过解绑申请解除）
+func (r *BindingRepository) CloseBindingTx(ctx context.Context, tx *sqlx.Tx, userID int, requestID int64) error {
+	query := `
+		UPDATE binding_history
+		SET unbound_at = NOW(), unbind_request_id = NULLIF($1::BIGINT, 0)
+		WHERE user_id = $2 AND unbound_at IS NULL
+	`
+
+	if _, err := tx.ExecContext(ctx, query, requestID, userID); err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to close binding")
+		return fmt.Errorf("failed to close binding: %w", err)
+	}
+
+	return nil
+}
+
+// HasBindBonus 检查 Linux.do ID 或公益站账号是否已经领取过首次绑定奖励
+func (r *BindingRepository) HasBindBonus(ctx context.Context, linuxDoID string, kyxUserID int) (bool, error) {
+	var exists bool
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM binding_history
+			WHERE bonus_granted AND (linux_do_id = $1 OR kyx_user_id = $2)
+		)
+	`
+
+	if err := r.db.GetContext(ctx, &exists, query, linuxDoID, kyxUserID); err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"linux_do_id": linuxDoID,
+			"kyx_user_id": kyxUserID,
+		}).Error("Failed to check bind bonus")
+		return false, fmt.Errorf("failed to check bind bonus: %w", err)
+	}
+
+	return exists, nil
+}
+
+// ListHistory 获取 Linux.do ID 的全部绑定历史（最新的在前）
+func (r *BindingRepository) ListHistory(ctx context.Context, linuxDoID string) ([]*model.BindingHistory, error) {
+	query := `SELECT ` + bindingHistoryColumns + ` FROM binding_history WHERE linux_do_id = $1 ORDER BY id DESC`
+
+	var history []*model.BindingHistory
+	if err := r.db.SelectContext(ctx, &history, query, linuxDoID); err != nil {
+		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to list binding history")
+		return nil, fmt.Errorf("failed to list binding history: %w", err)
+	}
+
+	return history, nil
+}
+
+// CreateUnbindRequest 创建解绑申请
+// 用户已有待处理的申请时违反唯一约束，返回的错误可用 database.IsUniqueViolation 判断
+func (r *BindingRepository) CreateUnbindRequest(ctx context.Context, req *model.UnbindRequest) error {
+	query := `
+		INSERT INTO unbind_requests (user_id, linux_do_id, kyx_user_id, kyx_username, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		req.UserID,
+		req.LinuxDoID,
+		req.KyxUserID,
+		req.KyxUsername,
+		req.Reason,
+	).Scan(&req.ID, &req.Status, &req.CreatedAt)
+	if err != nil {
+		if !database.IsUniqueViolation(err) {
+			r.logger.WithError(err).WithField("linux_do_id", req.LinuxDoID).Error("Failed to create unbind request")
+		}
+		return fmt.Errorf("failed to create unbind request: %w", err)
+	}
+
+	return nil
+}
+
+// GetUnbindRequest 根据ID获取解绑申请
+func (r *BindingRepository) GetUnbindRequest(ctx context.Context, id int64) (*model.UnbindRequest, error) {
+	var req model.UnbindRequest
+	query := `SELECT ` + unbindRequestColumns + ` FROM unbind_requests WHERE id = $1`
+
+	if err := r.db.GetContext(ctx, &req, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.WithError(err).WithField("id", id).Error("Failed to get unbind request")
+		return nil, fmt.Errorf("failed to get unbind request: %w", err)
+	}
+
+	return &req, nil
+}
+
+// GetLatestUnbindRequest 获取用户最近一次解绑申请，没有时返回 nil
+func (r *BindingRepository) GetLatestUnbindRequest(ctx context.Context, userID int) (*model.UnbindRequest, error) {
+	var req model.UnbindRequest
+	query := `SELECT ` + unbindRequestColumns + ` FROM unbind_requests WHERE user_id = $1 ORDER BY id DESC LIMIT 1`
+
+	if err := r.db.GetContext(ctx, &req, query, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to get latest unbind request")
+		return nil, fmt.Errorf("failed to get latest unbind request: %w", err)
+	}
+
+	return &req, nil
+}
+
+// CancelUnbindRequest 撤回用户待处理的解绑申请，返回是否存在待处理的申请
+func (r *BindingRepository) CancelUnbindRequest(ctx context.Context, userID int) (bool, error) {
+	query := `UPDATE unbind_requests SET status = $1 WHERE user_id = $2 AND status = $3`
+
+	result, err := r.db.ExecContext(ctx, query, model.UnbindStatusCancelled, userID, model.UnbindStatusPending)
+	if err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to cancel unbind request")
+		return false, fmt.Errorf("failed to cancel unbind request: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+// ReviewUnbindRequestTx 在事务中处理待处理的解绑申请，返回申请此前是否仍待处理
+func (r *BindingRepository) ReviewUnbindRequestTx(ctx context.Context, tx *sqlx.Tx, req *model.UnbindRequest) (bool, error) {
+	query := `
+		UPDATE unbind_requests
+		SET status = $1, reviewed_at = NOW(), reviewed_by_id = $2, reviewed_by = $3, review_note = $4
+		WHERE id = $5 AND status = $6
+		RETURNING reviewed_at
+	`
+
+	err := tx.QueryRowxContext(
+		ctx,
+		query,
+		req.Status,
+		req.ReviewedByID,
+		req.ReviewedBy,
+		req.ReviewNote,
+		req.ID,
+		model.UnbindStatusPending,
+	).Scan(&req.ReviewedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		r.logger.WithError(err).WithField("id", req.ID).Error("Failed to review unbind request")
+		return false, fmt.Errorf("failed to review unbind request: %w", err)
+	}
+
+	return true, nil
+}
+
+// ListUnbindRequests 分页获取解绑申请（status 为空时返回全部，最新的在前）
+func (r *BindingRepository) ListUnbindRequests(ctx context.Context, status string, limit, offset int) ([]*model.UnbindRequest, error) {
+	query := `
+		SELECT ` + unbindRequestColumns + `
+		FROM unbind_requests
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	var reqs []*model.UnbindRequest
+	if err := r.db.SelectContext(ctx, &reqs, query, status, limit, offset); err != nil {
+		r.logger.WithError(err).WithField("status", status).Error("Failed to list unbind requests")
+		return nil, fmt.Errorf("failed to list unbind requests: %w", err)
+	}
+
+	return reqs, nil
+}
+
+// CountUnbindRequests 统计解绑申请数量（status 为空时统计全部）
+func (r *BindingRepository) CountUnbindRequests(ctx context.Context, status string) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM unbind_requests WHERE ($1 = '' OR status = $1)`
+
+	if err := r.db.GetContext(ctx, &count, query, status); err != nil {
+		r.logger.WithError(err).WithField("status", status).Error("Failed to count unbind requests")
+		return 0, fmt.Errorf("failed to count unbind requests: %w", err)
+	}
+
+	return count, nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
 	"github.com/yourusername/kyx-quota-bridge/internal/model"
 	"github.com/yourusername/kyx-quota-bridge/pkg/database"
@@ -27,27 +28,62 @@ func NewClaimRepository(db *database.DB, logger *logrus.Logger) *ClaimRepository
 
 // Create 创建领取记录
 func (r *ClaimRepository) Create(ctx context.Context, record *model.ClaimRecord) error {
+	return r.create(ctx, r.db, record)
+}
+
+// CreateTx 在事务中创建领取记录
+func (r *ClaimRepository) CreateTx(ctx context.Context, tx *sqlx.Tx, record *model.ClaimRecord) error {
+	return r.create(ctx, tx, record)
+}
+
+// create 使用指定的执行器创建领取记录
+// ClaimDate 和 PeriodKey 由调用方按配置的领取周期计算
+func (r *ClaimRepository) create(ctx context.Context, q sqlx.QueryerContext, record *model.ClaimRecord) error {
 	query := `
-		INSERT INTO claim_records (linux_do_id, username, quota_added, claim_date, created_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO claim_records (
+			user_id, linux_do_id, username, quota_added, base_quota, bonus_quota, streak,
+			claim_date, period_key, status, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id, created_at
 	`
 
 	now := time.Now()
-	claimDate := now.Format("2006-01-02")
+	if record.ClaimDate == "" {
+		record.ClaimDate = now.Format("2006-01-02")
+	}
+	if record.PeriodKey == "" {
+		record.PeriodKey = record.ClaimDate
+	}
+	if record.Status == "" {
+		record.Status = model.ClaimStatusReserved
+	}
+	if record.BaseQuota == 0 {
+		record.BaseQuota = record.QuotaAdded - record.BonusQuota
+	}
+	if record.Streak == 0 {
+		record.Streak = 1
+	}
 
-	err := r.db.QueryRowContext(
+	err := q.QueryRowxContext(
 		ctx,
 		query,
+		record.UserID,
 		record.LinuxDoID,
 		record.Username,
 		record.QuotaAdded,
-		claimDate,
+		record.BaseQuota,
+		record.BonusQuota,
+		record.Streak,
+		record.ClaimDate,
+		record.PeriodKey,
+		record.Status,
 		now,
 	).Scan(&record.ID, &record.CreatedAt)
 
 	if err != nil {
 		r.logger.WithError(err).WithFields(logrus.Fields{
+			"user_id":     record.UserID,
 			"linux_do_id": record.LinuxDoID,
 			"username":    record.Username,
 			"quota_added": record.QuotaAdded,
@@ -55,57 +91,114 @@ func (r *ClaimRepository) Create(ctx context.Context, record *model.ClaimRecord)
 		return fmt.Errorf("failed to create claim record: %w", err)
 	}
 
-	record.ClaimDate = claimDate
-
 	r.logger.WithFields(logrus.Fields{
 		"record_id":   record.ID,
 		"linux_do_id": record.LinuxDoID,
 		"username":    record.Username,
 		"quota_added": record.QuotaAdded,
-		"claim_date":  claimDate,
+		"bonus_quota": record.BonusQuota,
+		"streak":      record.Streak,
+		"claim_date":  record.ClaimDate,
+		"period_key":  record.PeriodKey,
 	}).Info("Claim record created successfully")
 
 	return nil
 }
 
-// HasClaimedToday 检查用户今天是否已领取
-func (r *ClaimRepository) HasClaimedToday(ctx context.Context, linuxDoID string) (bool, error) {
-	today := time.Now().Format("2006-01-02")
-	var exists bool
+// GetLastActive 获取用户最近一条有效（未释放）的领取记录
+func (r *ClaimRepository) GetLastActive(ctx context.Context, userID int) (*model.ClaimRecord, error) {
+	var record model.ClaimRecord
+	query := `
+		SELECT id, COALESCE(user_id, 0) AS user_id, linux_do_id, username, quota_added, base_quota, bonus_quota, streak,
+		       claim_date, period_key, status, created_at
+		FROM claim_records
+		WHERE user_id = $1 AND status <> 'released'
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
 
+	err := r.db.GetContext(ctx, &record, query, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to get last claim record")
+		return nil, fmt.Errorf("failed to get last claim record: %w", err)
+	}
+
+	return &record, nil
+}
+
+// GetLongestStreak 获取用户历史最长连续领取数
+func (r *ClaimRepository) GetLongestStreak(ctx context.Context, userID int) (int, error) {
+	var longest int
 	query := `
-		SELECT EXISTS(
-			SELECT 1 FROM claim_records
-			WHERE linux_do_id = $1 AND claim_date = $2
-		)
+		SELECT COALESCE(MAX(streak), 0)
+		FROM claim_records
+		WHERE user_id = $1 AND status <> 'released'
 	`
 
-	err := r.db.GetContext(ctx, &exists, query, linuxDoID, today)
+	err := r.db.GetContext(ctx, &longest, query, userID)
 	if err != nil {
-		r.logger.WithError(err).WithFields(logrus.Fields{
-			"linux_do_id": linuxDoID,
-			"date":        today,
-		}).Error("Failed to check if claimed today")
-		return false, fmt.Errorf("failed to check if claimed today: %w", err)
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to get longest claim streak")
+		return 0, fmt.Errorf("failed to get longest claim streak: %w", err)
+	}
+
+	return longest, nil
+}
+
+// MarkGranted 将预留的领取记录标记为已发放
+func (r *ClaimRepository) MarkGranted(ctx context.Context, id int) error {
+	query := `UPDATE claim_records SET status = $1 WHERE id = $2 AND status = $3`
+
+	if _, err := r.db.ExecContext(ctx, query, model.ClaimStatusGranted, id, model.ClaimStatusReserved); err != nil {
+		r.logger.WithError(err).WithField("id", id).Error("Failed to mark claim record granted")
+		return fmt.Errorf("failed to mark claim record granted: %w", err)
+	}
+
+	return nil
+}
+
+// Release 释放预留的领取记录（额度投递最终失败），用户可重新领取
+func (r *ClaimRepository) Release(ctx context.Context, id int) error {
+	query := `UPDATE claim_records SET status = $1 WHERE id = $2 AND status = $3`
+
+	if _, err := r.db.ExecContext(ctx, query, model.ClaimStatusReleased, id, model.ClaimStatusReserved); err != nil {
+		r.logger.WithError(err).WithField("id", id).Error("Failed to release claim record")
+		return fmt.Errorf("failed to release claim record: %w", err)
 	}
 
-	return exists, nil
+	return nil
 }
 
-// GetByLinuxDoID 获取用户的领取记录
-func (r *ClaimRepository) GetByLinuxDoID(ctx context.Context, linuxDoID string, limit, offset int) ([]*model.ClaimRecord, error) {
+// Reserve 重新预留已释放的领取记录（重放死信发放时使用）
+// 用户当天已重新领取时违反唯一约束，返回的错误可用 database.IsUniqueViolation 判断
+func (r *ClaimRepository) Reserve(ctx context.Context, id int) error {
+	query := `UPDATE claim_records SET status = $1 WHERE id = $2 AND status = $3`
+
+	if _, err := r.db.ExecContext(ctx, query, model.ClaimStatusReserved, id, model.ClaimStatusReleased); err != nil {
+		r.logger.WithError(err).WithField("id", id).Warn("Failed to reserve claim record")
+		return fmt.Errorf("failed to reserve claim record: %w", err)
+	}
+
+	return nil
+}
+
+// GetByUserID 获取用户的领取记录
+func (r *ClaimRepository) GetByUserID(ctx context.Context, userID int, limit, offset int) ([]*model.ClaimRecord, error) {
 	query := `
-		SELECT id, linux_do_id, username, quota_added, claim_date, created_at
+		SELECT id, COALESCE(user_id, 0) AS user_id, linux_do_id, username, quota_added, base_quota, bonus_quota, streak,
+		       claim_date, period_key, status, created_at
 		FROM claim_records
-		WHERE linux_do_id = $1
+		WHERE user_id = $1
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
 	`
 
 	var records []*model.ClaimRecord
-	err := r.db.SelectContext(ctx, &records, query, linuxDoID, limit, offset)
+	err := r.db.SelectContext(ctx, &records, query, userID, limit, offset)
 	if err != nil {
-		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get claim records by LinuxDoID")
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to get claim records by user")
 		return nil, fmt.Errorf("failed to get claim records: %w", err)
 	}
 
@@ -115,7 +208,8 @@ func (r *ClaimRepository) GetByLinuxDoID(ctx context.Context, linuxDoID string,
 // GetByDate 获取指定日期的领取记录
 func (r *ClaimRepository) GetByDate(ctx context.Context, date string, limit, offset int) ([]*model.ClaimRecord, error) {
 	query := `
-		SELECT id, linux_do_id, username, quota_added, claim_date, created_at
+		SELECT id, COALESCE(user_id, 0) AS user_id, linux_do_id, username, quota_added, base_quota, bonus_quota, streak,
+		       claim_date, period_key, status, created_at
 		FROM claim_records
 		WHERE claim_date = $1
 		ORDER BY created_at DESC
@@ -135,7 +229,8 @@ func (r *ClaimRepository) GetByDate(ctx context.Context, date string, limit, off
 // List 获取领取记录列表（分页）
 func (r *ClaimRepository) List(ctx context.Context, limit, offset int) ([]*model.ClaimRecord, error) {
 	query := `
-		SELECT id, linux_do_id, username, quota_added, claim_date, created_at
+		SELECT id, COALESCE(user_id, 0) AS user_id, linux_do_id, username, quota_added, base_quota, bonus_quota, streak,
+		       claim_date, period_key, status, created_at
 		FROM claim_records
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
@@ -165,14 +260,28 @@ func (r *ClaimRepository) Count(ctx context.Context) (int64, error) {
 	return count, nil
 }
 
-// CountByLinuxDoID 获取用户的领取记录总数
-func (r *ClaimRepository) CountByLinuxDoID(ctx context.Context, linuxDoID string) (int64, error) {
+// CountByUserID 获取用户的有效领取次数（不含已释放的预留）
+func (r *ClaimRepository) CountByUserID(ctx context.Context, userID int) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM claim_records WHERE user_id = $1 AND status <> 'released'`
+
+	err := r.db.GetContext(ctx, &count, query, userID)
+	if err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to count user claim records")
+		return 0, fmt.Errorf("failed to count user claim records: %w", err)
+	}
+
+	return count, nil
+}
+
+// CountAllByUserID 获取用户的领取记录总数（包括已释放的记录，与 GetByUserID 的分页一致）
+func (r *ClaimRepository) CountAllByUserID(ctx context.Context, userID int) (int64, error) {
 	var count int64
-	query := `SELECT COUNT(*) FROM claim_records WHERE linux_do_id = $1`
+	query := `SELECT COUNT(*) FROM claim_records WHERE user_id = $1`
 
-	err := r.db.GetContext(ctx, &count, query, linuxDoID)
+	err := r.db.GetContext(ctx, &count, query, userID)
 	if err != nil {
-		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to count user claim records")
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to count user claim records")
 		return 0, fmt.Errorf("failed to count user claim records: %w", err)
 	}
 
@@ -180,32 +289,31 @@ func (r *ClaimRepository) CountByLinuxDoID(ctx context.Context, linuxDoID string
 }
 
 // GetTotalQuota 获取用户总领取额度
-func (r *ClaimRepository) GetTotalQuota(ctx context.Context, linuxDoID string) (int64, error) {
+func (r *ClaimRepository) GetTotalQuota(ctx context.Context, userID int) (int64, error) {
 	var total sql.NullInt64
 	query := `
 		SELECT COALESCE(SUM(quota_added), 0)
 		FROM claim_records
-		WHERE linux_do_id = $1
+		WHERE user_id = $1 AND status <> 'released'
 	`
 
-	err := r.db.GetContext(ctx, &total, query, linuxDoID)
+	err := r.db.GetContext(ctx, &total, query, userID)
 	if err != nil {
-		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get total claimed quota")
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to get total claimed quota")
 		return 0, fmt.Errorf("failed to get total claimed quota: %w", err)
 	}
 
 	return total.Int64, nil
 }
 
-// GetTodayStats 获取今日领取统计
-func (r *ClaimRepository) GetTodayStats(ctx context.Context) (count int64, totalQuota int64, err error) {
-	today := time.Now().Format("2006-01-02")
+// GetTodayStats 获取今日领取统计（today 按领取时区计算）
+func (r *ClaimRepository) GetTodayStats(ctx context.Context, today string) (count int64, totalQuota int64, err error) {
 	query := `
 		SELECT
 			COUNT(*) as count,
 			COALESCE(SUM(quota_added), 0) as total_quota
 		FROM claim_records
-		WHERE claim_date = $1
+		WHERE claim_date = $1 AND status <> 'released'
 	`
 
 	var result struct {
@@ -222,6 +330,31 @@ func (r *ClaimRepository) GetTodayStats(ctx context.Context) (count int64, total
 	return result.Count, result.TotalQuota, nil
 }
 
+// GetStatsSince 获取指定时间之后的领取统计（用于当前领取周期）
+func (r *ClaimRepository) GetStatsSince(ctx context.Context, since time.Time) (count int64, totalQuota int64, err error) {
+	query := `
+		SELECT
+			COUNT(*) as count,
+			COALESCE(SUM(quota_added), 0) as total_quota
+		FROM claim_records
+		WHERE created_at >= $1 AND status <> 'released'
+	`
+
+	var result struct {
+		Count      int64 `db:"count"`
+		TotalQuota int64 `db:"total_quota"`
+	}
+
+	// created_at 为不带时区的服务器本地时间
+	err = r.db.GetContext(ctx, &result, query, since.In(time.Local))
+	if err != nil {
+		r.logger.WithError(err).WithField("since", since).Error("Failed to get claim stats since")
+		return 0, 0, fmt.Errorf("failed to get claim stats: %w", err)
+	}
+
+	return result.Count, result.TotalQuota, nil
+}
+
 // GetDateRangeStats 获取日期范围内的领取统计
 func (r *ClaimRepository) GetDateRangeStats(ctx context.Context, startDate, endDate string) (count int64, totalQuota int64, err error) {
 	query := `
@@ -229,7 +362,7 @@ func (r *ClaimRepository) GetDateRangeStats(ctx context.Context, startDate, endD
 			COUNT(*) as count,
 			COALESCE(SUM(quota_added), 0) as total_quota
 		FROM claim_records
-		WHERE claim_date BETWEEN $1 AND $2
+		WHERE claim_date BETWEEN $1 AND $2 AND status <> 'released'
 	`
 
 	var result struct {
@@ -249,6 +382,33 @@ func (r *ClaimRepository) GetDateRangeStats(ctx context.Context, startDate, endD
 	return result.Count, result.TotalQuota, nil
 }
 
+// GetBonusStats 获取日期范围内基础额度与连续奖励额度的拆分统计
+func (r *ClaimRepository) GetBonusStats(ctx context.Context, startDate, endDate string) (baseQuota int64, bonusQuota int64, err error) {
+	query := `
+		SELECT
+			COALESCE(SUM(base_quota), 0) as base_quota,
+			COALESCE(SUM(bonus_quota), 0) as bonus_quota
+		FROM claim_records
+		WHERE claim_date BETWEEN $1 AND $2 AND status <> 'released'
+	`
+
+	var result struct {
+		BaseQuota  int64 `db:"base_quota"`
+		BonusQuota int64 `db:"bonus_quota"`
+	}
+
+	err = r.db.GetContext(ctx, &result, query, startDate, endDate)
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"start_date": startDate,
+			"end_date":   endDate,
+		}).Error("Failed to get claim bonus stats")
+		return 0, 0, fmt.Errorf("failed to get claim bonus stats: %w", err)
+	}
+
+	return result.BaseQuota, result.BonusQuota, nil
+}
+
 // Delete 删除领取记录
 func (r *ClaimRepository) Delete(ctx context.Context, id int) error {
 	query := `DELETE FROM claim_records WHERE id = $1`
@@ -268,21 +428,16 @@ func (r *ClaimRepository) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
-// DeleteByLinuxDoID 删除用户的所有领取记录
-func (r *ClaimRepository) DeleteByLinuxDoID(ctx context.Context, linuxDoID string) error {
-	query := `DELETE FROM claim_records WHERE linux_do_id = $1`
+// DeleteByUserIDTx 在事务中删除用户的所有领取记录
+func (r *ClaimRepository) DeleteByUserIDTx(ctx context.Context, tx *sqlx.Tx, userID int) (int64, error) {
+	query := `DELETE FROM claim_records WHERE user_id = $1`
 
-	result, err := r.db.ExecContext(ctx, query, linuxDoID)
+	result, err := tx.ExecContext(ctx, query, userID)
 	if err != nil {
-		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to delete user claim records")
-		return fmt.Errorf("failed to delete user claim records: %w", err)
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to delete user claim records")
+		return 0, fmt.Errorf("failed to delete user claim records: %w", err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
-	r.logger.WithFields(logrus.Fields{
-		"linux_do_id":   linuxDoID,
-		"rows_affected": rowsAffected,
-	}).Info("User claim records deleted successfully")
-
-	return nil
+	return rowsAffected, nil
 }
@@ -0,0 +1,353 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+	"github.com/yourusername/kyx-quota-bridge/pkg/encryption"
+)
+
+// donateJobColumns 投喂任务查询字段
+const donateJobColumns = `
+	id, COALESCE(user_id, 0) AS user_id, linux_do_id, username, status, keys, items, total_keys, processed_keys,
+	valid_keys, quota_added, donate_record_id, attempts, max_attempts,
+	next_attempt_at, last_error, started_at, finished_at, created_at, updated_at
+`
+
+// DonateJobRepository 投喂任务仓库
+// 任务执行期间保存的完整Key逐个加密，任务结束后清空
+type DonateJobRepository struct {
+	db      *database.DB
+	keyring *encryption.Keyring
+	logger  *logrus.Logger
+}
+
+// NewDonateJobRepository 创建投喂任务仓库
+func NewDonateJobRepository(db *database.DB, keyring *encryption.Keyring, logger *logrus.Logger) *DonateJobRepository {
+	return &DonateJobRepository{
+		db:      db,
+		keyring: keyring,
+		logger:  logger,
+	}
+}
+
+// encryptKeys 加密任务中的完整Key
+func (r *DonateJobRepository) encryptKeys(keys model.JSONArray) (model.JSONArray, error) {
+	if keys == nil {
+		return nil, nil
+	}
+	encrypted := make(model.JSONArray, len(keys))
+	for i, key := range keys {
+		value, _, err := r.keyring.Encrypt(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt donate job key: %w", err)
+		}
+		encrypted[i] = value
+	}
+	return encrypted, nil
+}
+
+// decryptKeys 解密任务中的完整Key
+func (r *DonateJobRepository) decryptKeys(job *model.DonateJob) error {
+	for i, key := range job.Keys {
+		value, err := r.keyring.Decrypt(key)
+		if err != nil {
+			r.logger.WithError(err).WithField("id", job.ID).Error("Failed to decrypt donate job key")
+			return fmt.Errorf("failed to decrypt donate job key: %w", err)
+		}
+		job.Keys[i] = value
+	}
+	return nil
+}
+
+// Create 创建投喂任务
+func (r *DonateJobRepository) Create(ctx context.Context, job *model.DonateJob) error {
+	query := `
+		INSERT INTO donate_jobs (user_id, linux_do_id, username, status, keys, items, total_keys, max_attempts)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, attempts, next_attempt_at, created_at, updated_at
+	`
+
+	if job.Status == "" {
+		job.Status = model.DonateJobStatusQueued
+	}
+
+	keys, err := r.encryptKeys(job.Keys)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRowxContext(
+		ctx,
+		query,
+		job.UserID,
+		job.LinuxDoID,
+		job.Username,
+		job.Status,
+		keys,
+		job.Items,
+		job.TotalKeys,
+		job.MaxAttempts,
+	).Scan(&job.ID, &job.Attempts, &job.NextAttemptAt, &job.CreatedAt, &job.UpdatedAt)
+
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"user_id":    job.UserID,
+			"total_keys": job.TotalKeys,
+		}).Error("Failed to create donate job")
+		return fmt.Errorf("failed to create donate job: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID 根据ID获取投喂任务
+func (r *DonateJobRepository) GetByID(ctx context.Context, id int) (*model.DonateJob, error) {
+	var job model.DonateJob
+	query := `SELECT ` + donateJobColumns + ` FROM donate_jobs WHERE id = $1`
+
+	err := r.db.GetContext(ctx, &job, query, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.WithError(err).WithField("id", id).Error("Failed to get donate job by ID")
+		return nil, fmt.Errorf("failed to get donate job: %w", err)
+	}
+
+	if err := r.decryptKeys(&job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// AcquireNext 领取下一个待执行的任务
+// 被领取的任务进入 processing 状态，next_attempt_at 作为租约到期时间，
+// 进程崩溃后租约到期的任务会被重新领取并从已保存的进度继续执行
+func (r *DonateJobRepository) AcquireNext(ctx context.Context, lease time.Duration) (*model.DonateJob, error) {
+	query := `
+		UPDATE donate_jobs
+		SET status = $1, attempts = attempts + 1, next_attempt_at = $2,
+			started_at = COALESCE(started_at, NOW())
+		WHERE id = (
+			SELECT id FROM donate_jobs
+			WHERE status IN ($3, $1) AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + donateJobColumns
+
+	var job model.DonateJob
+	err := r.db.GetContext(
+		ctx,
+		&job,
+		query,
+		model.DonateJobStatusProcessing,
+		time.Now().Add(lease),
+		model.DonateJobStatusQueued,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to acquire donate job")
+		return nil, fmt.Errorf("failed to acquire donate job: %w", err)
+	}
+
+	if err := r.decryptKeys(&job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// SaveProgress 保存任务进度并续租
+func (r *DonateJobRepository) SaveProgress(ctx context.Context, job *model.DonateJob, lease time.Duration) error {
+	query := `
+		UPDATE donate_jobs
+		SET items = $1, processed_keys = $2, next_attempt_at = $3
+		WHERE id = $4 AND status = $5
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		job.Items,
+		job.ProcessedKeys,
+		time.Now().Add(lease),
+		job.ID,
+		model.DonateJobStatusProcessing,
+	)
+	if err != nil {
+		r.logger.WithError(err).WithField("id", job.ID).Error("Failed to save donate job progress")
+		return fmt.Errorf("failed to save donate job progress: %w", err)
+	}
+
+	return nil
+}
+
+// CompleteTx 在事务中标记任务完成（与投喂记录一同提交）
+func (r *DonateJobRepository) CompleteTx(ctx context.Context, tx *sqlx.Tx, job *model.DonateJob) error {
+	query := `
+		UPDATE donate_jobs
+		SET status = $1, keys = '[]', items = $2, processed_keys = $3, valid_keys = $4,
+			quota_added = $5, donate_record_id = $6, last_error = NULL, finished_at = NOW()
+		WHERE id = $7
+	`
+
+	_, err := tx.ExecContext(
+		ctx,
+		query,
+		model.DonateJobStatusCompleted,
+		job.Items,
+		job.ProcessedKeys,
+		job.ValidKeys,
+		job.QuotaAdded,
+		job.DonateRecordID,
+		job.ID,
+	)
+	if err != nil {
+		r.logger.WithError(err).WithField("id", job.ID).Error("Failed to complete donate job")
+		return fmt.Errorf("failed to complete donate job: %w", err)
+	}
+
+	return nil
+}
+
+// CountUnfinishedByUserIDTx 在事务中统计用户排队或执行中的任务数量
+func (r *DonateJobRepository) CountUnfinishedByUserIDTx(ctx context.Context, tx *sqlx.Tx, userID int) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM donate_jobs WHERE user_id = $1 AND status IN ($2, $3)`
+
+	err := tx.GetContext(ctx, &count, query, userID, model.DonateJobStatusQueued, model.DonateJobStatusProcessing)
+	if err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to count unfinished donate jobs")
+		return 0, fmt.Errorf("failed to count unfinished donate jobs: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteByUserIDTx 在事务中删除用户的所有投喂任务
+func (r *DonateJobRepository) DeleteByUserIDTx(ctx context.Context, tx *sqlx.Tx, userID int) (int64, error) {
+	query := `DELETE FROM donate_jobs WHERE user_id = $1`
+
+	result, err := tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to delete user donate jobs")
+		return 0, fmt.Errorf("failed to delete user donate jobs: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected, nil
+}
+
+// MarkRetry 标记任务执行失败并安排重试
+func (r *DonateJobRepository) MarkRetry(ctx context.Context, job *model.DonateJob, nextAttemptAt time.Time, lastError string) error {
+	query := `
+		UPDATE donate_jobs
+		SET status = $1, items = $2, processed_keys = $3, next_attempt_at = $4, last_error = $5
+		WHERE id = $6
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		model.DonateJobStatusQueued,
+		job.Items,
+		job.ProcessedKeys,
+		nextAttemptAt,
+		lastError,
+		job.ID,
+	)
+	if err != nil {
+		r.logger.WithError(err).WithField("id", job.ID).Error("Failed to schedule donate job retry")
+		return fmt.Errorf("failed to schedule donate job retry: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailed 标记任务失败（不再重试，清空完整Key）
+func (r *DonateJobRepository) MarkFailed(ctx context.Context, job *model.DonateJob, lastError string) error {
+	query := `
+		UPDATE donate_jobs
+		SET status = $1, keys = '[]', items = $2, processed_keys = $3,
+			last_error = $4, finished_at = NOW()
+		WHERE id = $5
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		model.DonateJobStatusFailed,
+		job.Items,
+		job.ProcessedKeys,
+		lastError,
+		job.ID,
+	)
+	if err != nil {
+		r.logger.WithError(err).WithField("id", job.ID).Error("Failed to mark donate job failed")
+		return fmt.Errorf("failed to mark donate job failed: %w", err)
+	}
+
+	return nil
+}
+
+// ReencryptKeys 使用当前主密钥重新加密未结束任务中的完整Key，返回处理的任务数
+func (r *DonateJobRepository) ReencryptKeys(ctx context.Context) (int, error) {
+	current := r.keyring.CurrentVersion()
+	if current == 0 {
+		return 0, fmt.Errorf("encryption is not configured")
+	}
+
+	var jobs []*model.DonateJob
+	query := `SELECT id, keys FROM donate_jobs WHERE keys <> '[]'::jsonb`
+	if err := r.db.SelectContext(ctx, &jobs, query); err != nil {
+		r.logger.WithError(err).Error("Failed to list donate jobs for re-encryption")
+		return 0, fmt.Errorf("failed to list donate jobs: %w", err)
+	}
+
+	updated := 0
+	for _, job := range jobs {
+		stale := false
+		for _, key := range job.Keys {
+			if encryption.VersionOf(key) != current {
+				stale = true
+				break
+			}
+		}
+		if !stale {
+			continue
+		}
+
+		original := make(model.JSONArray, len(job.Keys))
+		copy(original, job.Keys)
+
+		if err := r.decryptKeys(job); err != nil {
+			return updated, err
+		}
+		keys, err := r.encryptKeys(job.Keys)
+		if err != nil {
+			return updated, err
+		}
+
+		// 任务在此期间结束（完整Key被清空）时不再写回
+		updateQuery := `UPDATE donate_jobs SET keys = $1 WHERE id = $2 AND keys = $3`
+		if _, err := r.db.ExecContext(ctx, updateQuery, keys, job.ID, original); err != nil {
+			r.logger.WithError(err).WithField("id", job.ID).Error("Failed to re-encrypt donate job keys")
+			return updated, fmt.Errorf("failed to re-encrypt donate job keys: %w", err)
+		}
+		updated++
+	}
+
+	return updated, nil
+}
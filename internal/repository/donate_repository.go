@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
 	"github.com/yourusername/kyx-quota-bridge/internal/model"
 	"github.com/yourusername/kyx-quota-bridge/pkg/database"
@@ -27,20 +28,31 @@ func NewDonateRepository(db *database.DB, logger *logrus.Logger) *DonateReposito
 
 // Create 创建投喂记录
 func (r *DonateRepository) Create(ctx context.Context, record *model.DonateRecord) error {
+	return r.create(ctx, r.db, record)
+}
+
+// CreateTx 在事务中创建投喂记录
+func (r *DonateRepository) CreateTx(ctx context.Context, tx *sqlx.Tx, record *model.DonateRecord) error {
+	return r.create(ctx, tx, record)
+}
+
+// create 使用指定的执行器创建投喂记录
+func (r *DonateRepository) create(ctx context.Context, q sqlx.QueryerContext, record *model.DonateRecord) error {
 	query := `
 		INSERT INTO donate_records (
-			linux_do_id, username, keys_count, total_quota_added,
+			user_id, linux_do_id, username, keys_count, total_quota_added,
 			push_status, push_message, failed_keys, created_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id, created_at
 	`
 
 	now := time.Now()
 
-	err := r.db.QueryRowContext(
+	err := q.QueryRowxContext(
 		ctx,
 		query,
+		record.UserID,
 		record.LinuxDoID,
 		record.Username,
 		record.KeysCount,
@@ -53,6 +65,7 @@ func (r *DonateRepository) Create(ctx context.Context, record *model.DonateRecor
 
 	if err != nil {
 		r.logger.WithError(err).WithFields(logrus.Fields{
+			"user_id":           record.UserID,
 			"linux_do_id":       record.LinuxDoID,
 			"username":          record.Username,
 			"keys_count":        record.KeysCount,
@@ -77,7 +90,7 @@ func (r *DonateRepository) Create(ctx context.Context, record *model.DonateRecor
 func (r *DonateRepository) GetByID(ctx context.Context, id int) (*model.DonateRecord, error) {
 	var record model.DonateRecord
 	query := `
-		SELECT id, linux_do_id, username, keys_count, total_quota_added,
+		SELECT id, COALESCE(user_id, 0) AS user_id, linux_do_id, username, keys_count, total_quota_added,
 			   push_status, push_message, failed_keys, created_at
 		FROM donate_records
 		WHERE id = $1
@@ -95,21 +108,21 @@ func (r *DonateRepository) GetByID(ctx context.Context, id int) (*model.DonateRe
 	return &record, nil
 }
 
-// GetByLinuxDoID 获取用户的投喂记录
-func (r *DonateRepository) GetByLinuxDoID(ctx context.Context, linuxDoID string, limit, offset int) ([]*model.DonateRecord, error) {
+// GetByUserID 获取用户的投喂记录
+func (r *DonateRepository) GetByUserID(ctx context.Context, userID int, limit, offset int) ([]*model.DonateRecord, error) {
 	query := `
-		SELECT id, linux_do_id, username, keys_count, total_quota_added,
+		SELECT id, COALESCE(user_id, 0) AS user_id, linux_do_id, username, keys_count, total_quota_added,
 			   push_status, push_message, failed_keys, created_at
 		FROM donate_records
-		WHERE linux_do_id = $1
+		WHERE user_id = $1
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
 	`
 
 	var records []*model.DonateRecord
-	err := r.db.SelectContext(ctx, &records, query, linuxDoID, limit, offset)
+	err := r.db.SelectContext(ctx, &records, query, userID, limit, offset)
 	if err != nil {
-		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get donate records by LinuxDoID")
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to get donate records by user")
 		return nil, fmt.Errorf("failed to get donate records: %w", err)
 	}
 
@@ -119,7 +132,7 @@ func (r *DonateRepository) GetByLinuxDoID(ctx context.Context, linuxDoID string,
 // List 获取投喂记录列表（分页）
 func (r *DonateRepository) List(ctx context.Context, limit, offset int) ([]*model.DonateRecord, error) {
 	query := `
-		SELECT id, linux_do_id, username, keys_count, total_quota_added,
+		SELECT id, COALESCE(user_id, 0) AS user_id, linux_do_id, username, keys_count, total_quota_added,
 			   push_status, push_message, failed_keys, created_at
 		FROM donate_records
 		ORDER BY created_at DESC
@@ -150,14 +163,14 @@ func (r *DonateRepository) Count(ctx context.Context) (int64, error) {
 	return count, nil
 }
 
-// CountByLinuxDoID 获取用户的投喂记录总数
-func (r *DonateRepository) CountByLinuxDoID(ctx context.Context, linuxDoID string) (int64, error) {
+// CountByUserID 获取用户的投喂记录总数
+func (r *DonateRepository) CountByUserID(ctx context.Context, userID int) (int64, error) {
 	var count int64
-	query := `SELECT COUNT(*) FROM donate_records WHERE linux_do_id = $1`
+	query := `SELECT COUNT(*) FROM donate_records WHERE user_id = $1`
 
-	err := r.db.GetContext(ctx, &count, query, linuxDoID)
+	err := r.db.GetContext(ctx, &count, query, userID)
 	if err != nil {
-		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to count user donate records")
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to count user donate records")
 		return 0, fmt.Errorf("failed to count user donate records: %w", err)
 	}
 
@@ -165,17 +178,17 @@ func (r *DonateRepository) CountByLinuxDoID(ctx context.Context, linuxDoID strin
 }
 
 // GetTotalKeys 获取用户投喂的总Key数量
-func (r *DonateRepository) GetTotalKeys(ctx context.Context, linuxDoID string) (int64, error) {
+func (r *DonateRepository) GetTotalKeys(ctx context.Context, userID int) (int64, error) {
 	var total sql.NullInt64
 	query := `
 		SELECT COALESCE(SUM(keys_count), 0)
 		FROM donate_records
-		WHERE linux_do_id = $1
+		WHERE user_id = $1
 	`
 
-	err := r.db.GetContext(ctx, &total, query, linuxDoID)
+	err := r.db.GetContext(ctx, &total, query, userID)
 	if err != nil {
-		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get total donated keys")
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to get total donated keys")
 		return 0, fmt.Errorf("failed to get total donated keys: %w", err)
 	}
 
@@ -183,17 +196,17 @@ func (r *DonateRepository) GetTotalKeys(ctx context.Context, linuxDoID string) (
 }
 
 // GetTotalQuota 获取用户投喂的总额度
-func (r *DonateRepository) GetTotalQuota(ctx context.Context, linuxDoID string) (int64, error) {
+func (r *DonateRepository) GetTotalQuota(ctx context.Context, userID int) (int64, error) {
 	var total sql.NullInt64
 	query := `
 		SELECT COALESCE(SUM(total_quota_added), 0)
 		FROM donate_records
-		WHERE linux_do_id = $1
+		WHERE user_id = $1
 	`
 
-	err := r.db.GetContext(ctx, &total, query, linuxDoID)
+	err := r.db.GetContext(ctx, &total, query, userID)
 	if err != nil {
-		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get total donated quota")
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to get total donated quota")
 		return 0, fmt.Errorf("failed to get total donated quota: %w", err)
 	}
 
@@ -229,6 +242,28 @@ func (r *DonateRepository) GetTodayStats(ctx context.Context) (count int64, tota
 	return result.Count, result.TotalKeys, result.TotalQuota, nil
 }
 
+// GetUserTodayKeys 获取用户今天已成功投喂的Key数量
+func (r *DonateRepository) GetUserTodayKeys(ctx context.Context, userID int) (int64, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	tomorrow := today.AddDate(0, 0, 1)
+
+	var total int64
+	query := `
+		SELECT COALESCE(SUM(keys_count), 0)
+		FROM donate_records
+		WHERE user_id = $1 AND created_at >= $2 AND created_at < $3
+	`
+
+	err := r.db.GetContext(ctx, &total, query, userID, today, tomorrow)
+	if err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to get user's today donated keys")
+		return 0, fmt.Errorf("failed to get today's donated keys: %w", err)
+	}
+
+	return total, nil
+}
+
 // GetDateRangeStats 获取日期范围内的投喂统计
 func (r *DonateRepository) GetDateRangeStats(ctx context.Context, startDate, endDate time.Time) (count int64, totalKeys int64, totalQuota int64, err error) {
 	query := `
@@ -259,13 +294,13 @@ func (r *DonateRepository) GetDateRangeStats(ctx context.Context, startDate, end
 }
 
 // GetSuccessRate 获取投喂成功率
-func (r *DonateRepository) GetSuccessRate(ctx context.Context, linuxDoID string) (successRate float64, err error) {
+func (r *DonateRepository) GetSuccessRate(ctx context.Context, userID int) (successRate float64, err error) {
 	query := `
 		SELECT
 			COUNT(*) as total,
 			COUNT(CASE WHEN push_status = 'success' THEN 1 END) as success
 		FROM donate_records
-		WHERE linux_do_id = $1
+		WHERE user_id = $1
 	`
 
 	var result struct {
@@ -273,9 +308,9 @@ func (r *DonateRepository) GetSuccessRate(ctx context.Context, linuxDoID string)
 		Success int64 `db:"success"`
 	}
 
-	err = r.db.GetContext(ctx, &result, query, linuxDoID)
+	err = r.db.GetContext(ctx, &result, query, userID)
 	if err != nil {
-		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get donate success rate")
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to get donate success rate")
 		return 0, fmt.Errorf("failed to get donate success rate: %w", err)
 	}
 
@@ -290,7 +325,7 @@ func (r *DonateRepository) GetSuccessRate(ctx context.Context, linuxDoID string)
 // GetFailedRecords 获取失败的投喂记录
 func (r *DonateRepository) GetFailedRecords(ctx context.Context, limit, offset int) ([]*model.DonateRecord, error) {
 	query := `
-		SELECT id, linux_do_id, username, keys_count, total_quota_added,
+		SELECT id, COALESCE(user_id, 0) AS user_id, linux_do_id, username, keys_count, total_quota_added,
 			   push_status, push_message, failed_keys, created_at
 		FROM donate_records
 		WHERE push_status = 'failed'
@@ -341,21 +376,16 @@ func (r *DonateRepository) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
-// DeleteByLinuxDoID 删除用户的所有投喂记录
-func (r *DonateRepository) DeleteByLinuxDoID(ctx context.Context, linuxDoID string) error {
-	query := `DELETE FROM donate_records WHERE linux_do_id = $1`
+// DeleteByUserIDTx 在事务中删除用户的所有投喂记录
+func (r *DonateRepository) DeleteByUserIDTx(ctx context.Context, tx *sqlx.Tx, userID int) (int64, error) {
+	query := `DELETE FROM donate_records WHERE user_id = $1`
 
-	result, err := r.db.ExecContext(ctx, query, linuxDoID)
+	result, err := tx.ExecContext(ctx, query, userID)
 	if err != nil {
-		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to delete user donate records")
-		return fmt.Errorf("failed to delete user donate records: %w", err)
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to delete user donate records")
+		return 0, fmt.Errorf("failed to delete user donate records: %w", err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
-	r.logger.WithFields(logrus.Fields{
-		"linux_do_id":   linuxDoID,
-		"rows_affected": rowsAffected,
-	}).Info("User donate records deleted successfully")
-
-	return nil
+	return rowsAffected, nil
 }
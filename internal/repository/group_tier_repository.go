@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+)
+
+// groupChangeColumns 用户组调整记录查询字段
+const groupChangeColumns = `
+	id, user_id, linux_do_id, kyx_user_id, from_tier, to_tier, group_id, source, status, error,
+	keys_donated, donate_quota, created_at
+`
+
+// donorProgressQuery 已绑定用户的累计投喂和当前档位
+// 当前档位只认对当前绑定的公益站账号成功执行的调整，更换绑定后从默认用户组重新评估
+const donorProgressQuery = `
+	SELECT
+		u.id AS user_id,
+		u.linux_do_id,
+		u.kyx_user_id,
+		COALESCE(d.keys_donated, 0) AS keys_donated,
+		COALESCE(d.donate_quota, 0) AS donate_quota,
+		d.last_donate_at,
+		COALESCE((
+			SELECT gc.to_tier FROM group_changes gc
+			WHERE gc.user_id = u.id AND gc.kyx_user_id = u.kyx_user_id AND gc.status = 'success'
+			ORDER BY gc.id DESC
+			LIMIT 1
+		), '') AS current_tier
+	FROM users u
+	LEFT JOIN LATERAL (
+		SELECT
+			SUM(dr.keys_count) AS keys_donated,
+			SUM(dr.total_quota_added) AS donate_quota,
+			MAX(dr.created_at) FILTER (WHERE dr.keys_count > 0) AS last_donate_at
+		FROM donate_records dr
+		WHERE dr.user_id = u.id
+	) d ON TRUE
+	WHERE u.deleted_at IS NULL AND u.kyx_user_id > 0
+`
+
+// GroupTierRepository 用户组档位评估和调整记录仓库
+type GroupTierRepository struct {
+	db     *database.DB
+	logger *logrus.Logger
+}
+
+// NewGroupTierRepository 创建用户组档位仓库
+func NewGroupTierRepository(db *database.DB, logger *logrus.Logger) *GroupTierRepository {
+	return &GroupTierRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetDonor 按用户ID获取已绑定用户的累计投喂和当前档位，用户不存在或未绑定时返回 nil
+// 投喂按 user_id 汇总，Linux.do 身份重新关联或改名后历史投喂仍然计入
+func (r *GroupTierRepository) GetDonor(ctx context.Context, userID int) (*model.DonorProgress, error) {
+	var donor model.DonorProgress
+	query := donorProgressQuery + ` AND u.id = $1`
+
+	if err := r.db.GetContext(ctx, &donor, query, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to get donor progress")
+		return nil, fmt.Errorf("failed to get donor progress: %w", err)
+	}
+
+	return &donor, nil
+}
+
+// ListDonors 按用户ID分批获取已绑定用户的累计投喂和当前档位（返回 afterID 之后的用户）
+func (r *GroupTierRepository) ListDonors(ctx context.Context, afterID, limit int) ([]*model.DonorProgress, error) {
+	query := donorProgressQuery + ` AND u.id > $1 ORDER BY u.id LIMIT $2`
+
+	var donors []*model.DonorProgress
+	if err := r.db.SelectContext(ctx, &donors, query, afterID, limit); err != nil {
+		r.logger.WithError(err).Error("Failed to list donor progress")
+		return nil, fmt.Errorf("failed to list donor progress: %w", err)
+	}
+
+	return donors, nil
+}
+
+// CreateChange 记录一次用户组调整
+func (r *GroupTierRepository) CreateChange(ctx context.Context, change *model.GroupChange) error {
+	query := `
+		INSERT INTO group_changes (
+			user_id, linux_do_id, kyx_user_id, from_tier, to_tier, group_id, source, status, error,
+			keys_donated, donate_quota
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		change.UserID,
+		change.LinuxDoID,
+		change.KyxUserID,
+		change.FromTier,
+		change.ToTier,
+		change.GroupID,
+		change.Source,
+		change.Status,
+		change.Error,
+		change.KeysDonated,
+		change.DonateQuota,
+	).Scan(&change.ID, &change.CreatedAt)
+	if err != nil {
+		r.logger.WithError(err).WithField("linux_do_id", change.LinuxDoID).Error("Failed to create group change")
+		return fmt.Errorf("failed to create group change: %w", err)
+	}
+
+	return nil
+}
+
+// ListChanges 分页获取用户组调整记录（linux_do_id 为空时返回全部，最新的在前）
+func (r *GroupTierRepository) ListChanges(ctx context.Context, linuxDoID string, limit, offset int) ([]*model.GroupChange, error) {
+	query := `
+		SELECT ` + groupChangeColumns + `
+		FROM group_changes
+		WHERE ($1 = '' OR linux_do_id = $1)
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	var changes []*model.GroupChange
+	if err := r.db.SelectContext(ctx, &changes, query, linuxDoID, limit, offset); err != nil {
+		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to list group changes")
+		return nil, fmt.Errorf("failed to list group changes: %w", err)
+	}
+
+	return changes, nil
+}
+
+// CountChanges 统计用户组调整记录数量（linux_do_id 为空时统计全部）
+func (r *GroupTierRepository) CountChanges(ctx context.Context, linuxDoID string) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM group_changes WHERE ($1 = '' OR linux_do_id = $1)`
+
+	if err := r.db.GetContext(ctx, &count, query, linuxDoID); err != nil {
+		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to count group changes")
+		return 0, fmt.Errorf("failed to count group changes: %w", err)
+	}
+
+	return count, nil
+}
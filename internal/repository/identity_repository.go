@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+)
+
+// userIdentityColumns 用户身份查询字段
+const userIdentityColumns = `
+	id, user_id, provider, subject, username, email, last_login_at, created_at
+`
+
+// IdentityRepository 用户身份仓库
+type IdentityRepository struct {
+	db     *database.DB
+	logger *logrus.Logger
+}
+
+// NewIdentityRepository 创建用户身份仓库
+func NewIdentityRepository(db *database.DB, logger *logrus.Logger) *IdentityRepository {
+	return &IdentityRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create 为用户关联身份（同一身份已关联其他用户、或用户已关联该提供方时返回唯一约束错误）
+func (r *IdentityRepository) Create(ctx context.Context, identity *model.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, username, email, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id, last_login_at, created_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		identity.UserID,
+		identity.Provider,
+		identity.Subject,
+		identity.Username,
+		identity.Email,
+	).Scan(&identity.ID, &identity.LastLoginAt, &identity.CreatedAt)
+
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"user_id":  identity.UserID,
+			"provider": identity.Provider,
+		}).Error("Failed to create user identity")
+		return fmt.Errorf("failed to create user identity: %w", err)
+	}
+
+	return nil
+}
+
+// GetByProviderSubject 根据提供方和提供方用户标识获取身份
+func (r *IdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	query := `SELECT ` + userIdentityColumns + ` FROM user_identities WHERE provider = $1 AND subject = $2`
+
+	if err := r.db.GetContext(ctx, &identity, query, provider, subject); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.WithError(err).WithField("provider", provider).Error("Failed to get user identity")
+		return nil, fmt.Errorf("failed to get user identity: %w", err)
+	}
+
+	return &identity, nil
+}
+
+// ListByUser 获取用户关联的全部身份
+func (r *IdentityRepository) ListByUser(ctx context.Context, userID int) ([]*model.UserIdentity, error) {
+	var identities []*model.UserIdentity
+	query := `SELECT ` + userIdentityColumns + ` FROM user_identities WHERE user_id = $1 ORDER BY id`
+
+	if err := r.db.SelectContext(ctx, &identities, query, userID); err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to list user identities")
+		return nil, fmt.Errorf("failed to list user identities: %w", err)
+	}
+
+	return identities, nil
+}
+
+// TouchLogin 记录登录时间，并更新提供方返回的用户名和邮箱
+func (r *IdentityRepository) TouchLogin(ctx context.Context, id int64, username, email string) error {
+	query := `
+		UPDATE user_identities
+		SET username = $1, email = $2, last_login_at = NOW()
+		WHERE id = $3
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, username, email, id); err != nil {
+		r.logger.WithError(err).WithField("identity_id", id).Warn("Failed to record identity login")
+		return fmt.Errorf("failed to record identity login: %w", err)
+	}
+
+	return nil
+}
+
+// Delete 取消用户与提供方的关联，返回是否存在该关联
+func (r *IdentityRepository) Delete(ctx context.Context, userID int, provider string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM user_identities WHERE user_id = $1 AND provider = $2`, userID, provider)
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"user_id":  userID,
+			"provider": provider,
+		}).Error("Failed to delete user identity")
+		return false, fmt.Errorf("failed to delete user identity: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rows > 0, nil
+}
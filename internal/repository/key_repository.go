@@ -8,22 +8,29 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
 	"github.com/yourusername/kyx-quota-bridge/internal/model"
 	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+	"github.com/yourusername/kyx-quota-bridge/pkg/encryption"
 )
 
+// usedKeyColumns 已使用Key查询字段
+const usedKeyColumns = `key_hash, full_key, key_version, linux_do_id, username, used_at`
+
 // KeyRepository 已使用的Key仓库
 type KeyRepository struct {
-	db     *database.DB
-	logger *logrus.Logger
+	db      *database.DB
+	keyring *encryption.Keyring
+	logger  *logrus.Logger
 }
 
-// NewKeyRepository 创建Key仓库
-func NewKeyRepository(db *database.DB, logger *logrus.Logger) *KeyRepository {
+// NewKeyRepository 创建Key仓库，keyring 为 nil 时完整Key以明文保存
+func NewKeyRepository(db *database.DB, keyring *encryption.Keyring, logger *logrus.Logger) *KeyRepository {
 	return &KeyRepository{
-		db:     db,
-		logger: logger,
+		db:      db,
+		keyring: keyring,
+		logger:  logger,
 	}
 }
 
@@ -33,11 +40,34 @@ func HashKey(key string) string {
 	return hex.EncodeToString(hash[:])
 }
 
+// encryptKey 加密完整Key并记录使用的主密钥版本
+func (r *KeyRepository) encryptKey(key *model.UsedKey) (string, error) {
+	fullKey, version, err := r.keyring.Encrypt(key.FullKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt key: %w", err)
+	}
+	key.KeyVersion = version
+	return fullKey, nil
+}
+
+// decryptKeys 解密查询结果中的完整Key
+func (r *KeyRepository) decryptKeys(keys ...*model.UsedKey) error {
+	for _, key := range keys {
+		fullKey, err := r.keyring.Decrypt(key.FullKey)
+		if err != nil {
+			r.logger.WithError(err).WithField("key_hash", key.KeyHash).Error("Failed to decrypt used key")
+			return fmt.Errorf("failed to decrypt used key: %w", err)
+		}
+		key.FullKey = fullKey
+	}
+	return nil
+}
+
 // Add 添加已使用的Key
 func (r *KeyRepository) Add(ctx context.Context, key *model.UsedKey) error {
 	query := `
-		INSERT INTO used_keys (key_hash, full_key, linux_do_id, username, used_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO used_keys (key_hash, full_key, key_version, linux_do_id, username, used_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		ON CONFLICT (key_hash) DO NOTHING
 	`
 
@@ -51,11 +81,17 @@ func (r *KeyRepository) Add(ctx context.Context, key *model.UsedKey) error {
 		key.KeyHash = HashKey(key.FullKey)
 	}
 
+	fullKey, err := r.encryptKey(key)
+	if err != nil {
+		return err
+	}
+
 	result, err := r.db.ExecContext(
 		ctx,
 		query,
 		key.KeyHash,
-		key.FullKey,
+		fullKey,
+		key.KeyVersion,
 		key.LinuxDoID,
 		key.Username,
 		key.UsedAt,
@@ -91,22 +127,38 @@ func (r *KeyRepository) AddBatch(ctx context.Context, keys []*model.UsedKey) (in
 		return 0, nil
 	}
 
-	query := `
-		INSERT INTO used_keys (key_hash, full_key, linux_do_id, username, used_at)
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (key_hash) DO NOTHING
-	`
-
-	now := time.Now()
 	addedCount := 0
 
 	// 使用事务批量插入
-	tx, err := r.db.BeginTxx(ctx, nil)
+	err := r.db.Transaction(ctx, func(tx *sqlx.Tx) error {
+		added, err := r.AddBatchTx(ctx, tx, keys)
+		addedCount = len(added)
+		return err
+	})
 	if err != nil {
-		r.logger.WithError(err).Error("Failed to begin transaction for batch add keys")
-		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+		r.logger.WithError(err).Error("Failed to batch add keys")
+		return 0, err
 	}
-	defer tx.Rollback()
+
+	r.logger.WithFields(logrus.Fields{
+		"total":       len(keys),
+		"added_count": addedCount,
+	}).Info("Batch add keys completed")
+
+	return addedCount, nil
+}
+
+// AddBatchTx 在事务中批量添加已使用的Key，返回实际写入的 key_hash（已存在的Key不写入）
+func (r *KeyRepository) AddBatchTx(ctx context.Context, tx *sqlx.Tx, keys []*model.UsedKey) (map[string]bool, error) {
+	query := `
+		INSERT INTO used_keys (key_hash, full_key, key_version, linux_do_id, username, used_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (key_hash) DO NOTHING
+		RETURNING key_hash
+	`
+
+	now := time.Now()
+	added := make(map[string]bool, len(keys))
 
 	for _, key := range keys {
 		if key.UsedAt.IsZero() {
@@ -116,38 +168,89 @@ func (r *KeyRepository) AddBatch(ctx context.Context, keys []*model.UsedKey) (in
 			key.KeyHash = HashKey(key.FullKey)
 		}
 
-		result, err := tx.ExecContext(
+		fullKey, err := r.encryptKey(key)
+		if err != nil {
+			return added, err
+		}
+
+		var keyHash string
+		err = tx.QueryRowContext(
 			ctx,
 			query,
 			key.KeyHash,
-			key.FullKey,
+			fullKey,
+			key.KeyVersion,
 			key.LinuxDoID,
 			key.Username,
 			key.UsedAt,
-		)
-
-		if err != nil {
-			r.logger.WithError(err).WithField("key_hash", key.KeyHash).Warn("Failed to add key in batch")
+		).Scan(&keyHash)
+		if err == sql.ErrNoRows {
 			continue
 		}
+		if err != nil {
+			r.logger.WithError(err).WithField("key_hash", key.KeyHash).Error("Failed to add key in batch")
+			return added, fmt.Errorf("failed to add used key: %w", err)
+		}
+
+		added[keyHash] = true
+	}
+
+	return added, nil
+}
+
+// ReserveKeys 推送前为投喂任务预留Key，返回预留成功的 key_hash
+// 已被其他任务预留或已写入 used_keys 的Key预留失败；任务重试时已持有的预留视为成功
+func (r *KeyRepository) ReserveKeys(ctx context.Context, jobID int, keyHashes []string) (map[string]bool, error) {
+	query := `
+		INSERT INTO donate_key_reservations (key_hash, job_id)
+		SELECT $1::VARCHAR(64), $2::INTEGER
+		WHERE NOT EXISTS (SELECT 1 FROM used_keys WHERE key_hash = $1)
+		ON CONFLICT (key_hash) DO UPDATE SET job_id = EXCLUDED.job_id
+		WHERE donate_key_reservations.job_id = EXCLUDED.job_id
+		RETURNING key_hash
+	`
 
-		rowsAffected, _ := result.RowsAffected()
-		if rowsAffected > 0 {
-			addedCount++
+	reserved := make(map[string]bool, len(keyHashes))
+	for _, keyHash := range keyHashes {
+		var hash string
+		err := r.db.QueryRowContext(ctx, query, keyHash, jobID).Scan(&hash)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			r.logger.WithError(err).WithField("job_id", jobID).Error("Failed to reserve donate key")
+			return nil, fmt.Errorf("failed to reserve donate key: %w", err)
 		}
+		reserved[hash] = true
 	}
 
-	if err := tx.Commit(); err != nil {
-		r.logger.WithError(err).Error("Failed to commit batch add keys transaction")
-		return addedCount, fmt.Errorf("failed to commit transaction: %w", err)
+	return reserved, nil
+}
+
+// ReleaseKeys 释放投喂任务持有的Key预留（推送失败或任务失败时未推送的Key）
+func (r *KeyRepository) ReleaseKeys(ctx context.Context, jobID int, keyHashes []string) error {
+	query := `DELETE FROM donate_key_reservations WHERE job_id = $1 AND key_hash = $2`
+
+	for _, keyHash := range keyHashes {
+		if _, err := r.db.ExecContext(ctx, query, jobID, keyHash); err != nil {
+			r.logger.WithError(err).WithField("job_id", jobID).Error("Failed to release donate key")
+			return fmt.Errorf("failed to release donate key: %w", err)
+		}
 	}
 
-	r.logger.WithFields(logrus.Fields{
-		"total":       len(keys),
-		"added_count": addedCount,
-	}).Info("Batch add keys completed")
+	return nil
+}
 
-	return addedCount, nil
+// DeleteReservationsTx 在事务中删除投喂任务的全部Key预留（Key已写入 used_keys）
+func (r *KeyRepository) DeleteReservationsTx(ctx context.Context, tx *sqlx.Tx, jobID int) error {
+	query := `DELETE FROM donate_key_reservations WHERE job_id = $1`
+
+	if _, err := tx.ExecContext(ctx, query, jobID); err != nil {
+		r.logger.WithError(err).WithField("job_id", jobID).Error("Failed to delete donate key reservations")
+		return fmt.Errorf("failed to delete donate key reservations: %w", err)
+	}
+
+	return nil
 }
 
 // Exists 检查Key是否已被使用
@@ -196,11 +299,59 @@ func (r *KeyRepository) ExistsBatch(ctx context.Context, keyHashes []string) (ma
 	return result, nil
 }
 
+// ForEachHash 按 key_hash 顺序分批遍历全部已使用Key的哈希值，用于重建布隆过滤器
+func (r *KeyRepository) ForEachHash(ctx context.Context, batchSize int, fn func(keyHashes []string) error) error {
+	if batchSize <= 0 {
+		batchSize = 5000
+	}
+
+	query := `
+		SELECT key_hash FROM used_keys
+		WHERE key_hash > $1
+		ORDER BY key_hash
+		LIMIT $2
+	`
+
+	after := ""
+	for {
+		var keyHashes []string
+		if err := r.db.SelectContext(ctx, &keyHashes, query, after, batchSize); err != nil {
+			r.logger.WithError(err).Error("Failed to iterate used key hashes")
+			return fmt.Errorf("failed to iterate used key hashes: %w", err)
+		}
+		if len(keyHashes) == 0 {
+			return nil
+		}
+
+		if err := fn(keyHashes); err != nil {
+			return err
+		}
+
+		if len(keyHashes) < batchSize {
+			return nil
+		}
+		after = keyHashes[len(keyHashes)-1]
+	}
+}
+
+// ListHashesSince 获取指定时间之后使用的Key哈希值
+func (r *KeyRepository) ListHashesSince(ctx context.Context, since time.Time) ([]string, error) {
+	query := `SELECT key_hash FROM used_keys WHERE used_at >= $1`
+
+	var keyHashes []string
+	if err := r.db.SelectContext(ctx, &keyHashes, query, since); err != nil {
+		r.logger.WithError(err).WithField("since", since).Error("Failed to list recent used key hashes")
+		return nil, fmt.Errorf("failed to list recent used key hashes: %w", err)
+	}
+
+	return keyHashes, nil
+}
+
 // GetByHash 根据哈希值获取Key信息
 func (r *KeyRepository) GetByHash(ctx context.Context, keyHash string) (*model.UsedKey, error) {
 	var key model.UsedKey
 	query := `
-		SELECT key_hash, full_key, linux_do_id, username, used_at
+		SELECT ` + usedKeyColumns + `
 		FROM used_keys
 		WHERE key_hash = $1
 	`
@@ -214,13 +365,17 @@ func (r *KeyRepository) GetByHash(ctx context.Context, keyHash string) (*model.U
 		return nil, fmt.Errorf("failed to get key by hash: %w", err)
 	}
 
+	if err := r.decryptKeys(&key); err != nil {
+		return nil, err
+	}
+
 	return &key, nil
 }
 
 // GetByLinuxDoID 获取用户使用的Key列表
 func (r *KeyRepository) GetByLinuxDoID(ctx context.Context, linuxDoID string, limit, offset int) ([]*model.UsedKey, error) {
 	query := `
-		SELECT key_hash, full_key, linux_do_id, username, used_at
+		SELECT ` + usedKeyColumns + `
 		FROM used_keys
 		WHERE linux_do_id = $1
 		ORDER BY used_at DESC
@@ -234,13 +389,17 @@ func (r *KeyRepository) GetByLinuxDoID(ctx context.Context, linuxDoID string, li
 		return nil, fmt.Errorf("failed to get keys by linux_do_id: %w", err)
 	}
 
+	if err := r.decryptKeys(keys...); err != nil {
+		return nil, err
+	}
+
 	return keys, nil
 }
 
 // List 获取已使用的Key列表（分页）
 func (r *KeyRepository) List(ctx context.Context, limit, offset int) ([]*model.UsedKey, error) {
 	query := `
-		SELECT key_hash, full_key, linux_do_id, username, used_at
+		SELECT ` + usedKeyColumns + `
 		FROM used_keys
 		ORDER BY used_at DESC
 		LIMIT $1 OFFSET $2
@@ -253,6 +412,10 @@ func (r *KeyRepository) List(ctx context.Context, limit, offset int) ([]*model.U
 		return nil, fmt.Errorf("failed to list used keys: %w", err)
 	}
 
+	if err := r.decryptKeys(keys...); err != nil {
+		return nil, err
+	}
+
 	return keys, nil
 }
 
@@ -345,23 +508,19 @@ func (r *KeyRepository) Delete(ctx context.Context, keyHash string) error {
 	return nil
 }
 
-// DeleteByLinuxDoID 删除用户的所有Key记录
-func (r *KeyRepository) DeleteByLinuxDoID(ctx context.Context, linuxDoID string) error {
-	query := `DELETE FROM used_keys WHERE linux_do_id = $1`
+// ReleaseByLinuxDoIDTx 在事务中清除用户已使用 Key 的明文和归属信息
+// 保留 key_hash，删除用户后同一个 Key 仍不能被重复投喂
+func (r *KeyRepository) ReleaseByLinuxDoIDTx(ctx context.Context, tx *sqlx.Tx, linuxDoID string) (int64, error) {
+	query := `UPDATE used_keys SET full_key = '', key_version = 0, linux_do_id = '', username = '' WHERE linux_do_id = $1`
 
-	result, err := r.db.ExecContext(ctx, query, linuxDoID)
+	result, err := tx.ExecContext(ctx, query, linuxDoID)
 	if err != nil {
-		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to delete user used keys")
-		return fmt.Errorf("failed to delete user used keys: %w", err)
+		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to release user used keys")
+		return 0, fmt.Errorf("failed to release user used keys: %w", err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
-	r.logger.WithFields(logrus.Fields{
-		"linux_do_id":   linuxDoID,
-		"rows_affected": rowsAffected,
-	}).Info("User used keys deleted successfully")
-
-	return nil
+	return rowsAffected, nil
 }
 
 // DeleteOlderThan 删除早于指定时间的Key记录
@@ -389,7 +548,7 @@ func (r *KeyRepository) DeleteOlderThan(ctx context.Context, olderThan time.Time
 func (r *KeyRepository) GetRecentKeys(ctx context.Context, duration time.Duration, limit int) ([]*model.UsedKey, error) {
 	since := time.Now().Add(-duration)
 	query := `
-		SELECT key_hash, full_key, linux_do_id, username, used_at
+		SELECT ` + usedKeyColumns + `
 		FROM used_keys
 		WHERE used_at >= $1
 		ORDER BY used_at DESC
@@ -406,13 +565,17 @@ func (r *KeyRepository) GetRecentKeys(ctx context.Context, duration time.Duratio
 		return nil, fmt.Errorf("failed to get recent used keys: %w", err)
 	}
 
+	if err := r.decryptKeys(keys...); err != nil {
+		return nil, err
+	}
+
 	return keys, nil
 }
 
 // GetKeysByUsername 根据用户名获取Key列表
 func (r *KeyRepository) GetKeysByUsername(ctx context.Context, username string, limit, offset int) ([]*model.UsedKey, error) {
 	query := `
-		SELECT key_hash, full_key, linux_do_id, username, used_at
+		SELECT ` + usedKeyColumns + `
 		FROM used_keys
 		WHERE username = $1
 		ORDER BY used_at DESC
@@ -426,6 +589,10 @@ func (r *KeyRepository) GetKeysByUsername(ctx context.Context, username string,
 		return nil, fmt.Errorf("failed to get keys by username: %w", err)
 	}
 
+	if err := r.decryptKeys(keys...); err != nil {
+		return nil, err
+	}
+
 	return keys, nil
 }
 
@@ -442,3 +609,46 @@ func (r *KeyRepository) GetUniqueUsers(ctx context.Context) (int64, error) {
 
 	return count, nil
 }
+
+// ReencryptBatch 使用当前主密钥重新加密一批非当前版本（含历史明文）的Key，返回处理的数量
+// 删除用户后只保留哈希的 Key 不需要加密；轮换主密钥或首次启用加密时循环调用直到返回 0
+func (r *KeyRepository) ReencryptBatch(ctx context.Context, limit int) (int, error) {
+	current := r.keyring.CurrentVersion()
+	if current == 0 {
+		return 0, fmt.Errorf("encryption is not configured")
+	}
+
+	query := `
+		SELECT ` + usedKeyColumns + `
+		FROM used_keys
+		WHERE key_version <> $1 AND full_key <> ''
+		LIMIT $2
+	`
+
+	var keys []*model.UsedKey
+	if err := r.db.SelectContext(ctx, &keys, query, current, limit); err != nil {
+		r.logger.WithError(err).Error("Failed to list keys for re-encryption")
+		return 0, fmt.Errorf("failed to list keys for re-encryption: %w", err)
+	}
+
+	updateQuery := `UPDATE used_keys SET full_key = $1, key_version = $2 WHERE key_hash = $3 AND key_version = $4`
+
+	for _, key := range keys {
+		oldVersion := key.KeyVersion
+		if err := r.decryptKeys(key); err != nil {
+			return 0, err
+		}
+
+		fullKey, err := r.encryptKey(key)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := r.db.ExecContext(ctx, updateQuery, fullKey, key.KeyVersion, key.KeyHash, oldVersion); err != nil {
+			r.logger.WithError(err).WithField("key_hash", key.KeyHash).Error("Failed to re-encrypt used key")
+			return 0, fmt.Errorf("failed to re-encrypt used key: %w", err)
+		}
+	}
+
+	return len(keys), nil
+}
@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+	"github.com/yourusername/kyx-quota-bridge/pkg/encryption"
+)
+
+// linuxDoTokenColumns Linux.do 刷新令牌查询字段
+const linuxDoTokenColumns = `
+	linux_do_id, refresh_token, key_version, last_login_at, verified_at,
+	next_verify_at, failures, last_error, created_at, updated_at
+`
+
+// LinuxDoTokenRepository Linux.do 刷新令牌仓库（刷新令牌加密保存）
+type LinuxDoTokenRepository struct {
+	db      *database.DB
+	keyring *encryption.Keyring
+	logger  *logrus.Logger
+}
+
+// NewLinuxDoTokenRepository 创建 Linux.do 刷新令牌仓库
+func NewLinuxDoTokenRepository(db *database.DB, keyring *encryption.Keyring, logger *logrus.Logger) *LinuxDoTokenRepository {
+	return &LinuxDoTokenRepository{
+		db:      db,
+		keyring: keyring,
+		logger:  logger,
+	}
+}
+
+// decrypt 解密刷新令牌
+func (r *LinuxDoTokenRepository) decrypt(token *model.LinuxDoToken) error {
+	plaintext, err := r.keyring.Decrypt(token.RefreshToken)
+	if err != nil {
+		r.logger.WithError(err).WithField("linux_do_id", token.LinuxDoID).Error("Failed to decrypt linux.do refresh token")
+		return fmt.Errorf("failed to decrypt refresh token: %w", err)
+	}
+	token.RefreshToken = plaintext
+	return nil
+}
+
+// Save 登录时保存刷新令牌，并安排在 nextVerifyAt 进行下一次校验
+func (r *LinuxDoTokenRepository) Save(ctx context.Context, linuxDoID, refreshToken string, nextVerifyAt time.Time) error {
+	encrypted, version, err := r.keyring.Encrypt(refreshToken)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt refresh token: %w", err)
+	}
+
+	query := `
+		INSERT INTO linuxdo_tokens (linux_do_id, refresh_token, key_version, last_login_at, next_verify_at)
+		VALUES ($1, $2, $3, NOW(), $4)
+		ON CONFLICT (linux_do_id) DO UPDATE
+		SET refresh_token = EXCLUDED.refresh_token,
+		    key_version = EXCLUDED.key_version,
+		    last_login_at = NOW(),
+		    verified_at = NOW(),
+		    next_verify_at = EXCLUDED.next_verify_at,
+		    failures = 0,
+		    last_error = ''
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, linuxDoID, encrypted, version, nextVerifyAt); err != nil {
+		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to save linux.do refresh token")
+		return fmt.Errorf("failed to save refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// Get 获取用户的刷新令牌
+func (r *LinuxDoTokenRepository) Get(ctx context.Context, linuxDoID string) (*model.LinuxDoToken, error) {
+	var token model.LinuxDoToken
+	query := `SELECT ` + linuxDoTokenColumns + ` FROM linuxdo_tokens WHERE linux_do_id = $1`
+
+	if err := r.db.GetContext(ctx, &token, query, linuxDoID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get linux.do refresh token")
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if err := r.decrypt(&token); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// AcquireDue 领取到期需要校验的令牌（仅未暂停且 activeSince 之后登录或领取过的用户）
+// next_verify_at 被设置为租约到期时间，进程崩溃后租约到期的令牌会被重新领取
+func (r *LinuxDoTokenRepository) AcquireDue(ctx context.Context, limit int, lease time.Duration, activeSince time.Time) ([]*model.LinuxDoToken, error) {
+	query := `
+		UPDATE linuxdo_tokens
+		SET next_verify_at = $1
+		WHERE linux_do_id IN (
+			SELECT t.linux_do_id FROM linuxdo_tokens t
+			JOIN users u ON u.linux_do_id = t.linux_do_id
+			WHERE t.next_verify_at <= NOW()
+			  AND u.suspended_at IS NULL
+			  AND (
+			      t.last_login_at >= $2
+			      OR EXISTS (
+			          SELECT 1 FROM claim_records c
+			          WHERE c.linux_do_id = t.linux_do_id AND c.created_at >= $2
+			      )
+			  )
+			ORDER BY t.next_verify_at
+			LIMIT $3
+			FOR UPDATE OF t SKIP LOCKED
+		)
+		RETURNING ` + linuxDoTokenColumns
+
+	var tokens []*model.LinuxDoToken
+	if err := r.db.SelectContext(ctx, &tokens, query, time.Now().Add(lease), activeSince, limit); err != nil {
+		r.logger.WithError(err).Error("Failed to acquire due linux.do tokens")
+		return nil, fmt.Errorf("failed to acquire linux.do tokens: %w", err)
+	}
+
+	for _, token := range tokens {
+		if err := r.decrypt(token); err != nil {
+			return nil, err
+		}
+	}
+
+	return tokens, nil
+}
+
+// UpdateRefreshToken 保存刷新后得到的新刷新令牌
+func (r *LinuxDoTokenRepository) UpdateRefreshToken(ctx context.Context, linuxDoID, refreshToken string) error {
+	encrypted, version, err := r.keyring.Encrypt(refreshToken)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt refresh token: %w", err)
+	}
+
+	query := `UPDATE linuxdo_tokens SET refresh_token = $1, key_version = $2 WHERE linux_do_id = $3`
+	if _, err := r.db.ExecContext(ctx, query, encrypted, version, linuxDoID); err != nil {
+		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to update linux.do refresh token")
+		return fmt.Errorf("failed to update refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// MarkVerified 校验成功：安排下一次校验
+func (r *LinuxDoTokenRepository) MarkVerified(ctx context.Context, linuxDoID string, nextVerifyAt time.Time) error {
+	query := `
+		UPDATE linuxdo_tokens
+		SET verified_at = NOW(), next_verify_at = $1, failures = 0, last_error = ''
+		WHERE linux_do_id = $2
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, nextVerifyAt, linuxDoID); err != nil {
+		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to mark linux.do token verified")
+		return fmt.Errorf("failed to mark token verified: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailed 校验因临时错误失败：记录原因并安排重试
+func (r *LinuxDoTokenRepository) MarkFailed(ctx context.Context, linuxDoID string, nextVerifyAt time.Time, lastError string) error {
+	query := `
+		UPDATE linuxdo_tokens
+		SET failures = failures + 1, last_error = $1, next_verify_at = $2
+		WHERE linux_do_id = $3
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, lastError, nextVerifyAt, linuxDoID); err != nil {
+		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to mark linux.do token failed")
+		return fmt.Errorf("failed to mark token failed: %w", err)
+	}
+
+	return nil
+}
+
+// Delete 删除用户的刷新令牌
+func (r *LinuxDoTokenRepository) Delete(ctx context.Context, linuxDoID string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM linuxdo_tokens WHERE linux_do_id = $1`, linuxDoID); err != nil {
+		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to delete linux.do refresh token")
+		return fmt.Errorf("failed to delete refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// ReencryptTokens 使用当前主密钥重新加密旧版本的刷新令牌
+func (r *LinuxDoTokenRepository) ReencryptTokens(ctx context.Context) (int, error) {
+	current := r.keyring.CurrentVersion()
+	if current == 0 {
+		return 0, fmt.Errorf("encryption is not configured")
+	}
+
+	var tokens []*model.LinuxDoToken
+	query := `SELECT linux_do_id, refresh_token FROM linuxdo_tokens WHERE key_version <> $1`
+	if err := r.db.SelectContext(ctx, &tokens, query, current); err != nil {
+		return 0, fmt.Errorf("failed to list refresh tokens: %w", err)
+	}
+
+	updated := 0
+	for _, token := range tokens {
+		if err := r.decrypt(token); err != nil {
+			return updated, err
+		}
+		encrypted, version, err := r.keyring.Encrypt(token.RefreshToken)
+		if err != nil {
+			return updated, fmt.Errorf("failed to encrypt refresh token: %w", err)
+		}
+
+		update := `UPDATE linuxdo_tokens SET refresh_token = $1, key_version = $2 WHERE linux_do_id = $3`
+		if _, err := r.db.ExecContext(ctx, update, encrypted, version, token.LinuxDoID); err != nil {
+			return updated, fmt.Errorf("failed to update refresh token: %w", err)
+		}
+		updated++
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,407 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+)
+
+// quotaGrantColumns 额度发放记录查询字段
+const quotaGrantColumns = `
+	id, idempotency_key, COALESCE(user_id, 0) AS user_id, linux_do_id, kyx_user_id, quota, source, source_id,
+	status, attempts, max_attempts, next_attempt_at, kyx_total_before, kyx_total_at, last_error,
+	delivered_at, created_at, updated_at
+`
+
+// QuotaGrantRepository 额度发放记录仓库
+type QuotaGrantRepository struct {
+	db     *database.DB
+	logger *logrus.Logger
+}
+
+// NewQuotaGrantRepository 创建额度发放记录仓库
+func NewQuotaGrantRepository(db *database.DB, logger *logrus.Logger) *QuotaGrantRepository {
+	return &QuotaGrantRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateTx 在事务中创建额度发放记录
+func (r *QuotaGrantRepository) CreateTx(ctx context.Context, tx *sqlx.Tx, grant *model.QuotaGrant) error {
+	query := `
+		INSERT INTO quota_grants (
+			idempotency_key, user_id, linux_do_id, kyx_user_id, quota, source, source_id,
+			status, max_attempts, next_attempt_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, attempts, created_at, updated_at
+	`
+
+	if grant.Status == "" {
+		grant.Status = model.GrantStatusPending
+	}
+	if grant.NextAttemptAt.IsZero() {
+		grant.NextAttemptAt = time.Now()
+	}
+
+	err := tx.QueryRowxContext(
+		ctx,
+		query,
+		grant.IdempotencyKey,
+		grant.UserID,
+		grant.LinuxDoID,
+		grant.KyxUserID,
+		grant.Quota,
+		grant.Source,
+		grant.SourceID,
+		grant.Status,
+		grant.MaxAttempts,
+		grant.NextAttemptAt,
+	).Scan(&grant.ID, &grant.Attempts, &grant.CreatedAt, &grant.UpdatedAt)
+
+	if err != nil {
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"idempotency_key": grant.IdempotencyKey,
+			"user_id":         grant.UserID,
+			"quota":           grant.Quota,
+		}).Error("Failed to create quota grant")
+		return fmt.Errorf("failed to create quota grant: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID 根据ID获取额度发放记录
+func (r *QuotaGrantRepository) GetByID(ctx context.Context, id int) (*model.QuotaGrant, error) {
+	var grant model.QuotaGrant
+	query := `SELECT ` + quotaGrantColumns + ` FROM quota_grants WHERE id = $1`
+
+	err := r.db.GetContext(ctx, &grant, query, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.WithError(err).WithField("id", id).Error("Failed to get quota grant by ID")
+		return nil, fmt.Errorf("failed to get quota grant: %w", err)
+	}
+
+	return &grant, nil
+}
+
+// AcquireDue 领取到期的待投递记录
+// 被领取的记录进入 delivering 状态，next_attempt_at 作为租约到期时间，
+// 进程崩溃后租约到期的记录会被重新领取
+func (r *QuotaGrantRepository) AcquireDue(ctx context.Context, limit int, lease time.Duration) ([]*model.QuotaGrant, error) {
+	query := `
+		UPDATE quota_grants
+		SET status = $1, attempts = attempts + 1, next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM quota_grants
+			WHERE status IN ($3, $1) AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + quotaGrantColumns
+
+	var grants []*model.QuotaGrant
+	err := r.db.SelectContext(
+		ctx,
+		&grants,
+		query,
+		model.GrantStatusDelivering,
+		time.Now().Add(lease),
+		model.GrantStatusPending,
+		limit,
+	)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to acquire due quota grants")
+		return nil, fmt.Errorf("failed to acquire quota grants: %w", err)
+	}
+
+	return grants, nil
+}
+
+// AcquireByID 领取指定的待投递记录（用于写入后立即投递）
+func (r *QuotaGrantRepository) AcquireByID(ctx context.Context, id int, lease time.Duration) (*model.QuotaGrant, error) {
+	query := `
+		UPDATE quota_grants
+		SET status = $1, attempts = attempts + 1, next_attempt_at = $2
+		WHERE id = $3 AND status = $4
+		RETURNING ` + quotaGrantColumns
+
+	var grant model.QuotaGrant
+	err := r.db.GetContext(
+		ctx,
+		&grant,
+		query,
+		model.GrantStatusDelivering,
+		time.Now().Add(lease),
+		id,
+		model.GrantStatusPending,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.WithError(err).WithField("id", id).Error("Failed to acquire quota grant")
+		return nil, fmt.Errorf("failed to acquire quota grant: %w", err)
+	}
+
+	return &grant, nil
+}
+
+// MarkDelivered 标记为投递成功
+func (r *QuotaGrantRepository) MarkDelivered(ctx context.Context, id int) error {
+	query := `
+		UPDATE quota_grants
+		SET status = $1, delivered_at = NOW(), last_error = NULL
+		WHERE id = $2
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, model.GrantStatusDelivered, id); err != nil {
+		r.logger.WithError(err).WithField("id", id).Error("Failed to mark quota grant delivered")
+		return fmt.Errorf("failed to mark quota grant delivered: %w", err)
+	}
+
+	return nil
+}
+
+// SaveKyxTotal 投递前记录公益站用户的累计额度和记录时间，用于下次投递前对账
+func (r *QuotaGrantRepository) SaveKyxTotal(ctx context.Context, id int, total int64) error {
+	query := `UPDATE quota_grants SET kyx_total_before = $1, kyx_total_at = NOW() WHERE id = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, total, id); err != nil {
+		r.logger.WithError(err).WithField("id", id).Error("Failed to save quota grant kyx total")
+		return fmt.Errorf("failed to save quota grant kyx total: %w", err)
+	}
+
+	return nil
+}
+
+// SumDeliveredSince 汇总同一公益站用户在指定时间之后记录累计额度并已投递的其他发放额度
+// 同一公益站用户的投递串行执行，这些发放都在该时间之后才加额度
+func (r *QuotaGrantRepository) SumDeliveredSince(ctx context.Context, kyxUserID, excludeID int, since time.Time) (int64, error) {
+	query := `
+		SELECT COALESCE(SUM(quota), 0)
+		FROM quota_grants
+		WHERE kyx_user_id = $1 AND id <> $2 AND status = $3 AND kyx_total_at > $4
+	`
+
+	var total int64
+	if err := r.db.GetContext(ctx, &total, query, kyxUserID, excludeID, model.GrantStatusDelivered, since); err != nil {
+		r.logger.WithError(err).WithField("kyx_user_id", kyxUserID).Error("Failed to sum delivered quota grants")
+		return 0, fmt.Errorf("failed to sum delivered quota grants: %w", err)
+	}
+
+	return total, nil
+}
+
+// MarkReview 标记为待人工核对（无法确认上次投递是否到账，不再自动重试）
+func (r *QuotaGrantRepository) MarkReview(ctx context.Context, id int, reason string) error {
+	query := `
+		UPDATE quota_grants
+		SET status = $1, last_error = $2
+		WHERE id = $3
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, model.GrantStatusReview, reason, id); err != nil {
+		r.logger.WithError(err).WithField("id", id).Error("Failed to mark quota grant for review")
+		return fmt.Errorf("failed to mark quota grant for review: %w", err)
+	}
+
+	return nil
+}
+
+// ConfirmDelivered 管理员核对后将待核对的记录标记为已投递
+func (r *QuotaGrantRepository) ConfirmDelivered(ctx context.Context, id int) (bool, error) {
+	query := `
+		UPDATE quota_grants
+		SET status = $1, delivered_at = NOW()
+		WHERE id = $2 AND status = $3
+	`
+
+	result, err := r.db.ExecContext(ctx, query, model.GrantStatusDelivered, id, model.GrantStatusReview)
+	if err != nil {
+		r.logger.WithError(err).WithField("id", id).Error("Failed to confirm quota grant delivered")
+		return false, fmt.Errorf("failed to confirm quota grant delivered: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+// MarkRetry 标记为投递失败并安排下次重试
+func (r *QuotaGrantRepository) MarkRetry(ctx context.Context, id int, nextAttemptAt time.Time, lastError string) error {
+	query := `
+		UPDATE quota_grants
+		SET status = $1, next_attempt_at = $2, last_error = $3
+		WHERE id = $4
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, model.GrantStatusPending, nextAttemptAt, lastError, id); err != nil {
+		r.logger.WithError(err).WithField("id", id).Error("Failed to schedule quota grant retry")
+		return fmt.Errorf("failed to schedule quota grant retry: %w", err)
+	}
+
+	return nil
+}
+
+// MarkDead 标记为死信（不再自动重试）
+func (r *QuotaGrantRepository) MarkDead(ctx context.Context, id int, lastError string) error {
+	query := `
+		UPDATE quota_grants
+		SET status = $1, last_error = $2
+		WHERE id = $3
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, model.GrantStatusDead, lastError, id); err != nil {
+		r.logger.WithError(err).WithField("id", id).Error("Failed to mark quota grant dead")
+		return fmt.Errorf("failed to mark quota grant dead: %w", err)
+	}
+
+	return nil
+}
+
+// Replay 重放死信、待核对或待投递的记录（重置尝试次数并立即投递）
+// 待核对的记录由管理员确认未到账后重放，清除对账基准避免再次进入待核对
+func (r *QuotaGrantRepository) Replay(ctx context.Context, id int) (bool, error) {
+	query := `
+		UPDATE quota_grants
+		SET status = $1, attempts = 0, next_attempt_at = NOW(),
+			kyx_total_before = CASE WHEN status = $4 THEN NULL ELSE kyx_total_before END
+		WHERE id = $2 AND status IN ($1, $3, $4)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, model.GrantStatusPending, id, model.GrantStatusDead, model.GrantStatusReview)
+	if err != nil {
+		r.logger.WithError(err).WithField("id", id).Error("Failed to replay quota grant")
+		return false, fmt.Errorf("failed to replay quota grant: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+// CountUndeliveredByUserIDTx 在事务中统计用户待投递、投递中或待核对的记录数量
+func (r *QuotaGrantRepository) CountUndeliveredByUserIDTx(ctx context.Context, tx *sqlx.Tx, userID int) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM quota_grants WHERE user_id = $1 AND status IN ($2, $3, $4)`
+
+	err := tx.GetContext(ctx, &count, query, userID, model.GrantStatusPending, model.GrantStatusDelivering, model.GrantStatusReview)
+	if err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to count undelivered quota grants")
+		return 0, fmt.Errorf("failed to count undelivered quota grants: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteByUserIDTx 在事务中删除用户的所有额度发放记录
+func (r *QuotaGrantRepository) DeleteByUserIDTx(ctx context.Context, tx *sqlx.Tx, userID int) (int64, error) {
+	query := `DELETE FROM quota_grants WHERE user_id = $1`
+
+	result, err := tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to delete user quota grants")
+		return 0, fmt.Errorf("failed to delete user quota grants: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected, nil
+}
+
+// List 获取额度发放记录列表（分页，status 为空时返回全部）
+func (r *QuotaGrantRepository) List(ctx context.Context, status string, limit, offset int) ([]*model.QuotaGrant, error) {
+	query := `
+		SELECT ` + quotaGrantColumns + `
+		FROM quota_grants
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	var grants []*model.QuotaGrant
+	err := r.db.SelectContext(ctx, &grants, query, status, limit, offset)
+	if err != nil {
+		r.logger.WithError(err).WithField("status", status).Error("Failed to list quota grants")
+		return nil, fmt.Errorf("failed to list quota grants: %w", err)
+	}
+
+	return grants, nil
+}
+
+// Count 获取额度发放记录总数（status 为空时统计全部）
+func (r *QuotaGrantRepository) Count(ctx context.Context, status string) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM quota_grants WHERE ($1 = '' OR status = $1)`
+
+	err := r.db.GetContext(ctx, &count, query, status)
+	if err != nil {
+		r.logger.WithError(err).WithField("status", status).Error("Failed to count quota grants")
+		return 0, fmt.Errorf("failed to count quota grants: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetSourceStats 统计指定来源在 since 之后的发放笔数和额度（since 为零值时统计全部，不含死信）
+func (r *QuotaGrantRepository) GetSourceStats(ctx context.Context, source string, since time.Time) (count int64, totalQuota int64, err error) {
+	query := `
+		SELECT
+			COUNT(*) as count,
+			COALESCE(SUM(quota), 0) as total_quota
+		FROM quota_grants
+		WHERE source = $1 AND status <> $2 AND created_at >= $3
+	`
+
+	var result struct {
+		Count      int64 `db:"count"`
+		TotalQuota int64 `db:"total_quota"`
+	}
+
+	err = r.db.GetContext(ctx, &result, query, source, model.GrantStatusDead, since)
+	if err != nil {
+		r.logger.WithError(err).WithField("source", source).Error("Failed to get quota grant source stats")
+		return 0, 0, fmt.Errorf("failed to get quota grant source stats: %w", err)
+	}
+
+	return result.Count, result.TotalQuota, nil
+}
+
+// CountByStatus 按状态统计额度发放记录
+func (r *QuotaGrantRepository) CountByStatus(ctx context.Context) (map[string]int64, error) {
+	query := `SELECT status, COUNT(*) FROM quota_grants GROUP BY status`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to count quota grants by status")
+		return nil, fmt.Errorf("failed to count quota grants by status: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int64{
+		model.GrantStatusPending:    0,
+		model.GrantStatusDelivering: 0,
+		model.GrantStatusDelivered:  0,
+		model.GrantStatusReview:     0,
+		model.GrantStatusDead:       0,
+	}
+	for rows.Next() {
+		var status string
+		var count int64
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan quota grant count: %w", err)
+		}
+		counts[status] = count
+	}
+
+	return counts, rows.Err()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -28,6 +29,25 @@ func NewSessionRepository(db *database.DB, cache *cache.Redis, logger *logrus.Lo
 	}
 }
 
+// sessionOwner 会话所属用户的内部用户ID（从 JSON 恢复的数字为 float64），不存在时返回 0
+func sessionOwner(data model.JSONMap) int {
+	switch v := data["user_id"].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+// userSessionIndexKey 用户会话索引缓存键
+func userSessionIndexKey(userID int) string {
+	return model.CacheKeyUserSession + strconv.Itoa(userID)
+}
+
 // Create 创建会话
 func (r *SessionRepository) Create(ctx context.Context, session *model.Session, ttl time.Duration) error {
 	// 保存到 Redis
@@ -37,6 +57,17 @@ func (r *SessionRepository) Create(ctx context.Context, session *model.Session,
 		return fmt.Errorf("failed to create session: %w", err)
 	}
 
+	// 加入用户会话索引，索引有效期随最新会话延长
+	userID := sessionOwner(session.Data)
+	if userID > 0 {
+		indexKey := userSessionIndexKey(userID)
+		if err := r.cache.SAdd(ctx, indexKey, session.SessionID); err != nil {
+			r.logger.WithError(err).WithField("user_id", userID).Warn("Failed to index session")
+		} else {
+			_ = r.cache.Expire(ctx, indexKey, ttl)
+		}
+	}
+
 	// 异步保存到数据库（可选，作为备份）
 	go func() {
 		bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -44,14 +75,14 @@ func (r *SessionRepository) Create(ctx context.Context, session *model.Session,
 
 		dataJSON, _ := json.Marshal(session.Data)
 		query := `
-			INSERT INTO sessions (session_id, data, expires_at, created_at)
-			VALUES ($1, $2, $3, $4)
+			INSERT INTO sessions (session_id, user_id, data, expires_at, created_at)
+			VALUES ($1, $2, $3, $4, $5)
 			ON CONFLICT (session_id) DO UPDATE SET
 				data = EXCLUDED.data,
 				expires_at = EXCLUDED.expires_at
 		`
 
-		if _, err := r.db.ExecContext(bgCtx, query, session.SessionID, dataJSON, session.ExpiresAt, time.Now()); err != nil {
+		if _, err := r.db.ExecContext(bgCtx, query, session.SessionID, userID, dataJSON, session.ExpiresAt, time.Now()); err != nil {
 			r.logger.WithError(err).WithField("session_id", session.SessionID).Warn("Failed to backup session to database")
 		}
 	}()
@@ -138,13 +169,20 @@ func (r *SessionRepository) Update(ctx context.Context, sessionID string, data m
 
 // Delete 删除会话
 func (r *SessionRepository) Delete(ctx context.Context, sessionID string) error {
-	// 从 Redis 删除
+	// 从 Redis 删除（同时移出用户会话索引）
 	key := model.CacheKeySession + sessionID
+	var data model.JSONMap
+	_ = r.cache.GetJSON(ctx, key, &data)
+
 	if err := r.cache.Del(ctx, key); err != nil {
 		r.logger.WithError(err).WithField("session_id", sessionID).Error("Failed to delete session from Redis")
 		return fmt.Errorf("failed to delete session: %w", err)
 	}
 
+	if userID := sessionOwner(data); userID > 0 {
+		_ = r.cache.SRem(ctx, userSessionIndexKey(userID), sessionID)
+	}
+
 	// 异步从数据库删除
 	go func() {
 		bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -160,6 +198,95 @@ func (r *SessionRepository) Delete(ctx context.Context, sessionID string) error
 	return nil
 }
 
+// listUserSessionIDs 获取用户的全部会话ID（Redis 索引与数据库备份的并集）
+func (r *SessionRepository) listUserSessionIDs(ctx context.Context, userID int) ([]string, error) {
+	ids, err := r.cache.SMembers(ctx, userSessionIndexKey(userID))
+	if err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Warn("Failed to read session index, falling back to database")
+	}
+
+	var stored []string
+	query := `SELECT session_id FROM sessions WHERE user_id = $1 AND expires_at > $2`
+	if err := r.db.SelectContext(ctx, &stored, query, userID, time.Now()); err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to list user sessions")
+		return nil, fmt.Errorf("failed to list user sessions: %w", err)
+	}
+
+	seen := make(map[string]bool, len(ids)+len(stored))
+	result := make([]string, 0, len(ids)+len(stored))
+	for _, id := range append(ids, stored...) {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result, nil
+}
+
+// ListByUser 获取用户的全部有效会话，并清理索引中已过期的会话
+func (r *SessionRepository) ListByUser(ctx context.Context, userID int) ([]*model.Session, error) {
+	ids, err := r.listUserSessionIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*model.Session, 0, len(ids))
+	for _, id := range ids {
+		session, err := r.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if session == nil || sessionOwner(session.Data) != userID {
+			_ = r.cache.SRem(ctx, userSessionIndexKey(userID), id)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// DeleteByUser 删除用户的全部会话（exceptSessionID 非空时保留该会话），返回删除的会话数
+func (r *SessionRepository) DeleteByUser(ctx context.Context, userID int, exceptSessionID string) (int, error) {
+	ids, err := r.listUserSessionIDs(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	// 先同步删除数据库备份，避免 Redis 删除后又从数据库恢复
+	query := `DELETE FROM sessions WHERE user_id = $1 AND session_id <> $2`
+	if _, err := r.db.ExecContext(ctx, query, userID, exceptSessionID); err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to delete user sessions from database")
+		return 0, fmt.Errorf("failed to delete user sessions: %w", err)
+	}
+
+	var keys []string
+	var removed []interface{}
+	for _, id := range ids {
+		if id == exceptSessionID {
+			continue
+		}
+		keys = append(keys, model.CacheKeySession+id)
+		removed = append(removed, id)
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	if err := r.cache.Del(ctx, keys...); err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to delete user sessions from Redis")
+		return 0, fmt.Errorf("failed to delete user sessions: %w", err)
+	}
+	_ = r.cache.SRem(ctx, userSessionIndexKey(userID), removed...)
+
+	r.logger.WithFields(logrus.Fields{
+		"user_id": userID,
+		"count":   len(keys),
+	}).Info("User sessions deleted")
+
+	return len(keys), nil
+}
+
 // Exists 检查会话是否存在
 func (r *SessionRepository) Exists(ctx context.Context, sessionID string) (bool, error) {
 	key := model.CacheKeySession + sessionID
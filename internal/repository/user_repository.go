@@ -12,6 +12,12 @@ import (
 	"github.com/yourusername/kyx-quota-bridge/pkg/database"
 )
 
+// userColumns 用户查询字段
+const userColumns = `
+	id, linux_do_id, username, kyx_user_id, created_at, updated_at,
+	trust_level, active, silenced, suspended_at, suspend_reason, deleted_at, deleted_by
+`
+
 // UserRepository 用户仓库
 type UserRepository struct {
 	db     *database.DB
@@ -30,9 +36,9 @@ func NewUserRepository(db *database.DB, logger *logrus.Logger) *UserRepository {
 func (r *UserRepository) GetByID(ctx context.Context, id int) (*model.User, error) {
 	var user model.User
 	query := `
-		SELECT id, linux_do_id, username, kyx_user_id, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	err := r.db.GetContext(ctx, &user, query, id)
@@ -51,9 +57,9 @@ func (r *UserRepository) GetByID(ctx context.Context, id int) (*model.User, erro
 func (r *UserRepository) GetByLinuxDoID(ctx context.Context, linuxDoID string) (*model.User, error) {
 	var user model.User
 	query := `
-		SELECT id, linux_do_id, username, kyx_user_id, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE linux_do_id = $1
+		WHERE linux_do_id = $1 AND deleted_at IS NULL
 	`
 
 	err := r.db.GetContext(ctx, &user, query, linuxDoID)
@@ -72,9 +78,9 @@ func (r *UserRepository) GetByLinuxDoID(ctx context.Context, linuxDoID string) (
 func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
 	var user model.User
 	query := `
-		SELECT id, linux_do_id, username, kyx_user_id, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE username = $1
+		WHERE username = $1 AND deleted_at IS NULL
 	`
 
 	err := r.db.GetContext(ctx, &user, query, username)
@@ -89,11 +95,34 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*m
 	return &user, nil
 }
 
+// GetByKyxUserID 根据公益站用户ID获取已绑定的用户
+func (r *UserRepository) GetByKyxUserID(ctx context.Context, kyxUserID int) (*model.User, error) {
+	var user model.User
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE kyx_user_id = $1 AND deleted_at IS NULL
+		ORDER BY id
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &user, query, kyxUserID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.WithError(err).WithField("kyx_user_id", kyxUserID).Error("Failed to get user by Kyx user ID")
+		return nil, fmt.Errorf("failed to get user by kyx_user_id: %w", err)
+	}
+
+	return &user, nil
+}
+
 // Create 创建用户
 func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
 	query := `
-		INSERT INTO users (linux_do_id, username, kyx_user_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO users (linux_do_id, username, kyx_user_id, created_at, updated_at, trust_level, active, silenced)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -106,6 +135,9 @@ func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
 		user.KyxUserID,
 		now,
 		now,
+		user.TrustLevel,
+		user.Active,
+		user.Silenced,
 	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
@@ -127,6 +159,17 @@ func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
 
 // Update 更新用户
 func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
+	return r.update(ctx, r.db, user)
+}
+
+// UpdateTx 在事务中更新用户（绑定公益站账号时与绑定历史一同提交）
+func (r *UserRepository) UpdateTx(ctx context.Context, tx *sqlx.Tx, user *model.User) error {
+	return r.update(ctx, tx, user)
+}
+
+// update 使用指定的执行器更新用户
+// 公益站账号已绑定其他用户时违反唯一约束，返回的错误可用 database.IsUniqueViolation 判断
+func (r *UserRepository) update(ctx context.Context, q sqlx.QueryerContext, user *model.User) error {
 	query := `
 		UPDATE users
 		SET username = $1, kyx_user_id = $2, updated_at = $3
@@ -135,7 +178,7 @@ func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
 	`
 
 	now := time.Now()
-	err := r.db.QueryRowContext(
+	err := q.QueryRowxContext(
 		ctx,
 		query,
 		user.Username,
@@ -161,13 +204,155 @@ func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
 	return nil
 }
 
-// Delete 删除用户
-func (r *UserRepository) Delete(ctx context.Context, linuxDoID string) error {
-	query := `DELETE FROM users WHERE linux_do_id = $1`
+// UnbindTx 在事务中解除用户的公益站账号绑定，返回用户是否仍绑定该账号
+func (r *UserRepository) UnbindTx(ctx context.Context, tx *sqlx.Tx, userID, kyxUserID int) (bool, error) {
+	query := `UPDATE users SET kyx_user_id = 0, updated_at = NOW() WHERE id = $1 AND kyx_user_id = $2`
+
+	result, err := tx.ExecContext(ctx, query, userID, kyxUserID)
+	if err != nil {
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to unbind user")
+		return false, fmt.Errorf("failed to unbind user: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+// UpdateLinuxDoInfo 更新从 Linux.do 同步的用户名和账号状态（登录时调用）
+func (r *UserRepository) UpdateLinuxDoInfo(ctx context.Context, user *model.User) error {
+	query := `
+		UPDATE users
+		SET username = $1, trust_level = $2, active = $3, silenced = $4, updated_at = $5
+		WHERE linux_do_id = $6
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		user.Username,
+		user.TrustLevel,
+		user.Active,
+		user.Silenced,
+		time.Now(),
+		user.LinuxDoID,
+	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("user not found")
+	}
+	if err != nil {
+		r.logger.WithError(err).WithField("linux_do_id", user.LinuxDoID).Error("Failed to update Linux.do user info")
+		return fmt.Errorf("failed to update linux.do user info: %w", err)
+	}
+
+	return nil
+}
+
+// Suspend 暂停用户，同时保存校验时获取的最新账号状态
+func (r *UserRepository) Suspend(ctx context.Context, user *model.User, reason string) error {
+	query := `
+		UPDATE users
+		SET trust_level = $1, active = $2, silenced = $3,
+		    suspended_at = COALESCE(suspended_at, NOW()), suspend_reason = $4, updated_at = NOW()
+		WHERE linux_do_id = $5
+		RETURNING suspended_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		user.TrustLevel,
+		user.Active,
+		user.Silenced,
+		reason,
+		user.LinuxDoID,
+	).Scan(&user.SuspendedAt)
+
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("user not found")
+	}
+	if err != nil {
+		r.logger.WithError(err).WithField("linux_do_id", user.LinuxDoID).Error("Failed to suspend user")
+		return fmt.Errorf("failed to suspend user: %w", err)
+	}
+
+	user.SuspendReason = reason
+	return nil
+}
+
+// Unsuspend 解除用户暂停
+func (r *UserRepository) Unsuspend(ctx context.Context, linuxDoID string) error {
+	query := `UPDATE users SET suspended_at = NULL, suspend_reason = '', updated_at = NOW() WHERE linux_do_id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, linuxDoID); err != nil {
+		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to unsuspend user")
+		return fmt.Errorf("failed to unsuspend user: %w", err)
+	}
+
+	return nil
+}
+
+// GetByLinuxDoIDWithDeleted 根据LinuxDoID获取用户（包含已软删除的用户）
+func (r *UserRepository) GetByLinuxDoIDWithDeleted(ctx context.Context, linuxDoID string) (*model.User, error) {
+	var user model.User
+	query := `SELECT ` + userColumns + ` FROM users WHERE linux_do_id = $1`
+
+	err := r.db.GetContext(ctx, &user, query, linuxDoID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get user by LinuxDoID")
+		return nil, fmt.Errorf("failed to get user by linux_do_id: %w", err)
+	}
+
+	return &user, nil
+}
+
+// SoftDelete 软删除用户，返回用户是否此前未被删除
+func (r *UserRepository) SoftDelete(ctx context.Context, linuxDoID, deletedBy string) (bool, error) {
+	query := `
+		UPDATE users
+		SET deleted_at = NOW(), deleted_by = $1, updated_at = NOW()
+		WHERE linux_do_id = $2 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, deletedBy, linuxDoID)
+	if err != nil {
+		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to soft delete user")
+		return false, fmt.Errorf("failed to soft delete user: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+// Restore 恢复软删除的用户，返回用户是否此前处于删除状态
+func (r *UserRepository) Restore(ctx context.Context, linuxDoID string) (bool, error) {
+	query := `
+		UPDATE users
+		SET deleted_at = NULL, deleted_by = '', updated_at = NOW()
+		WHERE linux_do_id = $1 AND deleted_at IS NOT NULL
+	`
 
 	result, err := r.db.ExecContext(ctx, query, linuxDoID)
 	if err != nil {
-		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to delete user")
+		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to restore user")
+		return false, fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	return rowsAffected > 0, nil
+}
+
+// DeleteTx 在事务中彻底删除用户
+func (r *UserRepository) DeleteTx(ctx context.Context, tx *sqlx.Tx, id int) error {
+	query := `DELETE FROM users WHERE id = $1`
+
+	result, err := tx.ExecContext(ctx, query, id)
+	if err != nil {
+		r.logger.WithError(err).WithField("id", id).Error("Failed to delete user")
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
@@ -176,15 +361,15 @@ func (r *UserRepository) Delete(ctx context.Context, linuxDoID string) error {
 		return fmt.Errorf("user not found")
 	}
 
-	r.logger.WithField("linux_do_id", linuxDoID).Info("User deleted successfully")
 	return nil
 }
 
 // List 获取用户列表
 func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]*model.User, error) {
 	query := `
-		SELECT id, linux_do_id, username, kyx_user_id, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
+		WHERE deleted_at IS NULL
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
 	`
@@ -202,7 +387,7 @@ func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]*model.
 // Count 获取用户总数
 func (r *UserRepository) Count(ctx context.Context) (int64, error) {
 	var count int64
-	query := `SELECT COUNT(*) FROM users`
+	query := `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL`
 
 	err := r.db.GetContext(ctx, &count, query)
 	if err != nil {
@@ -213,6 +398,57 @@ func (r *UserRepository) Count(ctx context.Context) (int64, error) {
 	return count, nil
 }
 
+// ListDeleted 获取已软删除的用户列表（最近删除的在前）
+func (r *UserRepository) ListDeleted(ctx context.Context, limit, offset int) ([]*model.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	var users []*model.User
+	if err := r.db.SelectContext(ctx, &users, query, limit, offset); err != nil {
+		r.logger.WithError(err).Error("Failed to list deleted users")
+		return nil, fmt.Errorf("failed to list deleted users: %w", err)
+	}
+
+	return users, nil
+}
+
+// CountDeleted 获取已软删除的用户数量
+func (r *UserRepository) CountDeleted(ctx context.Context) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM users WHERE deleted_at IS NOT NULL`
+
+	if err := r.db.GetContext(ctx, &count, query); err != nil {
+		r.logger.WithError(err).Error("Failed to count deleted users")
+		return 0, fmt.Errorf("failed to count deleted users: %w", err)
+	}
+
+	return count, nil
+}
+
+// ListDeletedBefore 获取在指定时间之前软删除的用户（保留期已过，等待彻底清除）
+func (r *UserRepository) ListDeletedBefore(ctx context.Context, before time.Time, limit int) ([]*model.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE deleted_at IS NOT NULL AND deleted_at < $1
+		ORDER BY deleted_at
+		LIMIT $2
+	`
+
+	var users []*model.User
+	if err := r.db.SelectContext(ctx, &users, query, before, limit); err != nil {
+		r.logger.WithError(err).Error("Failed to list expired deleted users")
+		return nil, fmt.Errorf("failed to list expired deleted users: %w", err)
+	}
+
+	return users, nil
+}
+
 // Exists 检查用户是否存在
 func (r *UserRepository) Exists(ctx context.Context, linuxDoID string) (bool, error) {
 	var exists bool
@@ -228,10 +464,11 @@ func (r *UserRepository) Exists(ctx context.Context, linuxDoID string) (bool, er
 }
 
 // GetStatistics 获取用户统计信息
-func (r *UserRepository) GetStatistics(ctx context.Context, linuxDoID string) (*model.UserStatistics, error) {
+func (r *UserRepository) GetStatistics(ctx context.Context, userID int) (*model.UserStatistics, error) {
 	var stats model.UserStatistics
 	query := `
 		SELECT
+			user_id,
 			linux_do_id,
 			username,
 			register_time,
@@ -240,17 +477,18 @@ func (r *UserRepository) GetStatistics(ctx context.Context, linuxDoID string) (*
 			total_donates,
 			total_keys_donated,
 			total_donate_quota,
+			total_bind_bonus_quota,
 			total_quota
 		FROM user_statistics
-		WHERE linux_do_id = $1
+		WHERE user_id = $1
 	`
 
-	err := r.db.GetContext(ctx, &stats, query, linuxDoID)
+	err := r.db.GetContext(ctx, &stats, query, userID)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
-		r.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get user statistics")
+		r.logger.WithError(err).WithField("user_id", userID).Error("Failed to get user statistics")
 		return nil, fmt.Errorf("failed to get user statistics: %w", err)
 	}
 
@@ -261,6 +499,7 @@ func (r *UserRepository) GetStatistics(ctx context.Context, linuxDoID string) (*
 func (r *UserRepository) GetAllStatistics(ctx context.Context) ([]*model.UserStatistics, error) {
 	query := `
 		SELECT
+			user_id,
 			linux_do_id,
 			username,
 			register_time,
@@ -269,6 +508,7 @@ func (r *UserRepository) GetAllStatistics(ctx context.Context) ([]*model.UserSta
 			total_donates,
 			total_keys_donated,
 			total_donate_quota,
+			total_bind_bonus_quota,
 			total_quota
 		FROM user_statistics
 		ORDER BY total_quota DESC
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+	"github.com/yourusername/kyx-quota-bridge/pkg/utils"
+)
+
+const (
+	// accessTokenLength 令牌随机部分长度
+	accessTokenLength = 40
+	// accessTokenPrefixLen 列表中展示的令牌开头长度（含 kqb_ 前缀）
+	accessTokenPrefixLen = 12
+	// accessTokenTouchInterval 记录令牌最近使用时间的最小间隔
+	accessTokenTouchInterval = time.Minute
+	// maxAccessTokenDays 令牌最长有效天数
+	maxAccessTokenDays = 365
+)
+
+// AccessTokenService 个人访问令牌服务
+type AccessTokenService struct {
+	tokenRepo *repository.AccessTokenRepository
+	userRepo  *repository.UserRepository
+	logger    *logrus.Logger
+}
+
+// NewAccessTokenService 创建个人访问令牌服务
+func NewAccessTokenService(
+	tokenRepo *repository.AccessTokenRepository,
+	userRepo *repository.UserRepository,
+	logger *logrus.Logger,
+) *AccessTokenService {
+	return &AccessTokenService{
+		tokenRepo: tokenRepo,
+		userRepo:  userRepo,
+		logger:    logger,
+	}
+}
+
+// CreateToken 为用户签发个人访问令牌，返回令牌明文（只返回这一次）
+func (s *AccessTokenService) CreateToken(ctx context.Context, user *model.User, req *model.CreateAccessTokenRequest) (*model.CreateAccessTokenResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("token name is required")
+	}
+
+	scopes := utils.UniqueStrings(req.Scopes)
+	for _, scope := range scopes {
+		if !model.IsValidTokenScope(scope) {
+			return nil, fmt.Errorf("invalid scope: %s", scope)
+		}
+	}
+
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxAccessTokenDays {
+		return nil, fmt.Errorf("expires_in_days must be between 0 and %d", maxAccessTokenDays)
+	}
+
+	rateLimit := req.RateLimit
+	if rateLimit == 0 {
+		rateLimit = model.DefaultAccessTokenRateLimit
+	}
+	if rateLimit < 1 || rateLimit > model.MaxAccessTokenRateLimit {
+		return nil, fmt.Errorf("rate_limit must be between 1 and %d", model.MaxAccessTokenRateLimit)
+	}
+
+	count, err := s.tokenRepo.CountActiveByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= model.MaxAccessTokensPerUser {
+		return nil, fmt.Errorf("too many access tokens (max %d), revoke an unused one first", model.MaxAccessTokensPerUser)
+	}
+
+	random, err := utils.GenerateRandomString(accessTokenLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+	raw := model.AccessTokenPrefix + random
+
+	token := &model.AccessToken{
+		UserID:      user.ID,
+		LinuxDoID:   user.LinuxDoID,
+		Name:        name,
+		TokenPrefix: raw[:accessTokenPrefixLen],
+		TokenHash:   utils.HashSHA256(raw),
+		Scopes:      model.JSONArray(scopes),
+		RateLimit:   rateLimit,
+	}
+	if req.ExpiresInDays > 0 {
+		token.ExpiresAt = sql.NullTime{Time: time.Now().AddDate(0, 0, req.ExpiresInDays), Valid: true}
+	}
+
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":  user.ID,
+		"token_id": token.ID,
+		"scopes":   scopes,
+	}).Info("Access token created")
+
+	return &model.CreateAccessTokenResponse{
+		Token:       raw,
+		AccessToken: token,
+	}, nil
+}
+
+// ListTokens 获取用户的个人访问令牌
+func (s *AccessTokenService) ListTokens(ctx context.Context, userID int) ([]*model.AccessToken, error) {
+	return s.tokenRepo.ListByUser(ctx, userID)
+}
+
+// RevokeToken 撤销用户的个人访问令牌
+func (s *AccessTokenService) RevokeToken(ctx context.Context, userID int, id int64) error {
+	revoked, err := s.tokenRepo.Revoke(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return fmt.Errorf("access token not found")
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":  userID,
+		"token_id": id,
+	}).Info("Access token revoked")
+	return nil
+}
+
+// Authenticate 验证个人访问令牌，返回令牌所属用户和令牌，并记录最近使用时间和IP
+func (s *AccessTokenService) Authenticate(ctx context.Context, raw, ip string) (*model.User, *model.AccessToken, error) {
+	if !strings.HasPrefix(raw, model.AccessTokenPrefix) {
+		return nil, nil, fmt.Errorf("invalid access token")
+	}
+
+	token, err := s.tokenRepo.GetByHash(ctx, utils.HashSHA256(raw))
+	if err != nil {
+		return nil, nil, err
+	}
+	if token == nil {
+		return nil, nil, fmt.Errorf("invalid access token")
+	}
+	if token.ExpiresAt.Valid && time.Now().After(token.ExpiresAt.Time) {
+		return nil, nil, fmt.Errorf("access token expired")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, nil, fmt.Errorf("user not found")
+	}
+
+	// 大多数请求距上次记录不足间隔，直接跳过写库
+	if !token.LastUsedAt.Valid || time.Since(token.LastUsedAt.Time) >= accessTokenTouchInterval || token.LastUsedIP != ip {
+		_ = s.tokenRepo.TouchLastUsed(ctx, token.ID, ip, accessTokenTouchInterval)
+	}
+
+	return user, token, nil
+}
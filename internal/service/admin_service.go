@@ -1,14 +1,20 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/yourusername/kyx-quota-bridge/internal/model"
 	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+	"github.com/yourusername/kyx-quota-bridge/pkg/utils"
 )
 
 // AdminService 管理员服务
@@ -19,8 +25,21 @@ type AdminService struct {
 	donateRepo      *repository.DonateRepository
 	keyRepo         *repository.KeyRepository
 	sessionRepo     *repository.SessionRepository
+	adminRepo       *repository.AdminRepository
+	tokenRepo       *repository.AccessTokenRepository
+	grantRepo       *repository.QuotaGrantRepository
 	kyxClient       *KyxClient
+	sessionMonitor  *KyxSessionMonitor
+	alertService    *AlertService
+	verifier        *LinuxDoVerifier
+	purger          *UserPurger
 	cacheService    *CacheService
+	donateDefaults  model.DonatePolicy
+	gateDefaults    model.ClaimGatePolicy
+	trustDefaults   model.TrustPolicy
+	bonusDefaults   model.BindBonusPolicy
+	tierDefaults    model.GroupTierPolicy
+	claimPeriod     *ClaimPeriod
 	logger          *logrus.Logger
 }
 
@@ -32,8 +51,21 @@ func NewAdminService(
 	donateRepo *repository.DonateRepository,
 	keyRepo *repository.KeyRepository,
 	sessionRepo *repository.SessionRepository,
+	adminRepo *repository.AdminRepository,
+	tokenRepo *repository.AccessTokenRepository,
+	grantRepo *repository.QuotaGrantRepository,
 	kyxClient *KyxClient,
+	sessionMonitor *KyxSessionMonitor,
+	alertService *AlertService,
+	verifier *LinuxDoVerifier,
+	purger *UserPurger,
 	cacheService *CacheService,
+	donateDefaults model.DonatePolicy,
+	gateDefaults model.ClaimGatePolicy,
+	trustDefaults model.TrustPolicy,
+	bonusDefaults model.BindBonusPolicy,
+	tierDefaults model.GroupTierPolicy,
+	claimPeriod *ClaimPeriod,
 	logger *logrus.Logger,
 ) *AdminService {
 	return &AdminService{
@@ -43,8 +75,21 @@ func NewAdminService(
 		donateRepo:      donateRepo,
 		keyRepo:         keyRepo,
 		sessionRepo:     sessionRepo,
+		adminRepo:       adminRepo,
+		tokenRepo:       tokenRepo,
+		grantRepo:       grantRepo,
 		kyxClient:       kyxClient,
+		sessionMonitor:  sessionMonitor,
+		alertService:    alertService,
+		verifier:        verifier,
+		purger:          purger,
 		cacheService:    cacheService,
+		donateDefaults:  donateDefaults,
+		gateDefaults:    gateDefaults,
+		trustDefaults:   trustDefaults,
+		bonusDefaults:   bonusDefaults,
+		tierDefaults:    tierDefaults,
+		claimPeriod:     claimPeriod,
 		logger:          logger,
 	}
 }
@@ -65,6 +110,12 @@ func (s *AdminService) GetConfig(ctx context.Context) (*model.AdminConfigRespons
 			KeysAPIURL:                  "",
 			KeysAuthorizationConfigured: false,
 			GroupID:                     1,
+			DonatePolicy:                s.donateDefaults,
+			StreakBonuses:               model.DefaultStreakBonuses,
+			ClaimGatePolicy:             s.gateDefaults,
+			TrustPolicy:                 s.trustDefaults,
+			BindBonusPolicy:             s.bonusDefaults,
+			GroupTierPolicy:             s.tierDefaults,
 			UpdatedAt:                   0,
 		}, nil
 	}
@@ -75,18 +126,24 @@ func (s *AdminService) GetConfig(ctx context.Context) (*model.AdminConfigRespons
 		KeysAPIURL:                  config.KeysAPIURL.String,
 		KeysAuthorizationConfigured: config.KeysAuthorization.Valid && config.KeysAuthorization.String != "",
 		GroupID:                     config.GroupID,
+		DonatePolicy:                config.DonatePolicy(s.donateDefaults),
+		StreakBonuses:               config.StreakBonusTable(),
+		ClaimGatePolicy:             config.ClaimGatePolicy(s.gateDefaults),
+		TrustPolicy:                 config.TrustPolicy(s.trustDefaults),
+		BindBonusPolicy:             config.BindBonusPolicy(s.bonusDefaults),
+		GroupTierPolicy:             config.GroupTierPolicy(s.tierDefaults),
 		UpdatedAt:                   config.UpdatedAt.Unix(),
 	}
 
 	return response, nil
 }
 
-// UpdateConfig 更新管理员配置
-func (s *AdminService) UpdateConfig(ctx context.Context, req *model.UpdateConfigRequest) error {
+// UpdateConfig 更新管理员配置，返回变更前后的字段值（供审计日志使用，未脱敏）
+func (s *AdminService) UpdateConfig(ctx context.Context, req *model.UpdateConfigRequest) (*model.AuditChange, error) {
 	// 获取当前配置
 	currentConfig, err := s.adminConfigRepo.Get(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get current config: %w", err)
+		return nil, fmt.Errorf("failed to get current config: %w", err)
 	}
 
 	// 构建更新map
@@ -94,7 +151,7 @@ func (s *AdminService) UpdateConfig(ctx context.Context, req *model.UpdateConfig
 
 	if req.ClaimQuota != nil {
 		if *req.ClaimQuota < 0 {
-			return fmt.Errorf("claim quota cannot be negative")
+			return nil, fmt.Errorf("claim quota cannot be negative")
 		}
 		updates["claim_quota"] = *req.ClaimQuota
 		s.logger.WithField("claim_quota", *req.ClaimQuota).Info("Updating claim quota")
@@ -102,8 +159,9 @@ func (s *AdminService) UpdateConfig(ctx context.Context, req *model.UpdateConfig
 
 	if req.Session != nil {
 		updates["session"] = *req.Session
-		// 更新 KyxClient 的 session
+		// 更新 KyxClient 的 session，并立即重新校验（解决 Session 失效告警）
 		s.kyxClient.UpdateSession(*req.Session)
+		s.sessionMonitor.Recheck()
 		s.logger.Info("Updating Kyx session")
 	}
 
@@ -123,15 +181,139 @@ func (s *AdminService) UpdateConfig(ctx context.Context, req *model.UpdateConfig
 
 	if req.GroupID != nil {
 		if *req.GroupID < 0 {
-			return fmt.Errorf("group ID cannot be negative")
+			return nil, fmt.Errorf("group ID cannot be negative")
 		}
 		updates["group_id"] = *req.GroupID
 	}
 
+	if req.DonateQuotaPerKey != nil {
+		if *req.DonateQuotaPerKey <= 0 {
+			return nil, fmt.Errorf("donate quota per key must be positive")
+		}
+		updates["donate_quota_per_key"] = *req.DonateQuotaPerKey
+		s.logger.WithField("donate_quota_per_key", *req.DonateQuotaPerKey).Info("Updating donate quota per key")
+	}
+
+	if req.MaxDonateKeysPerDay != nil {
+		if *req.MaxDonateKeysPerDay < 0 {
+			return nil, fmt.Errorf("max donate keys per day cannot be negative")
+		}
+		updates["max_donate_keys_per_day"] = *req.MaxDonateKeysPerDay
+	}
+
+	if req.MaxDonateSubmissionsPerDay != nil {
+		if *req.MaxDonateSubmissionsPerDay < 0 {
+			return nil, fmt.Errorf("max donate submissions per day cannot be negative")
+		}
+		updates["max_donate_submissions_per_day"] = *req.MaxDonateSubmissionsPerDay
+	}
+
+	if req.MaxKeysPerSubmission != nil {
+		if *req.MaxKeysPerSubmission < 0 {
+			return nil, fmt.Errorf("max keys per submission cannot be negative")
+		}
+		updates["max_keys_per_submission"] = *req.MaxKeysPerSubmission
+	}
+
+	if req.StreakBonuses != nil {
+		bonuses, err := normalizeStreakBonuses(*req.StreakBonuses)
+		if err != nil {
+			return nil, err
+		}
+		updates["streak_bonuses"] = bonuses
+		s.logger.WithField("streak_bonuses", bonuses).Info("Updating streak bonuses")
+	}
+
+	if req.ClaimQuotaThreshold != nil {
+		if *req.ClaimQuotaThreshold < 0 {
+			return nil, fmt.Errorf("claim quota threshold cannot be negative")
+		}
+		updates["claim_quota_threshold"] = *req.ClaimQuotaThreshold
+		s.logger.WithField("claim_quota_threshold", *req.ClaimQuotaThreshold).Info("Updating claim quota threshold")
+	}
+
+	if req.ClaimThresholdMode != nil {
+		if *req.ClaimThresholdMode != model.ClaimThresholdBlock && *req.ClaimThresholdMode != model.ClaimThresholdScale {
+			return nil, fmt.Errorf("claim threshold mode must be 'block' or 'scale'")
+		}
+		updates["claim_threshold_mode"] = *req.ClaimThresholdMode
+	}
+
+	trustLevels := map[string]*int{
+		"min_bind_trust_level":   req.MinBindTrustLevel,
+		"min_claim_trust_level":  req.MinClaimTrustLevel,
+		"min_donate_trust_level": req.MinDonateTrustLevel,
+	}
+	for field, level := range trustLevels {
+		if level == nil {
+			continue
+		}
+		if *level < 0 || *level > model.MaxTrustLevel {
+			return nil, fmt.Errorf("%s must be between 0 and %d", field, model.MaxTrustLevel)
+		}
+		updates[field] = *level
+		s.logger.WithField(field, *level).Info("Updating trust level policy")
+	}
+
+	if req.TrustLevelClaimQuotas != nil {
+		quotas, err := normalizeTrustLevelQuotas(*req.TrustLevelClaimQuotas)
+		if err != nil {
+			return nil, err
+		}
+		updates["trust_level_claim_quotas"] = quotas
+		s.logger.WithField("trust_level_claim_quotas", quotas).Info("Updating trust level claim quotas")
+	}
+
+	if req.BindBonusEnabled != nil {
+		updates["bind_bonus_enabled"] = *req.BindBonusEnabled
+		s.logger.WithField("bind_bonus_enabled", *req.BindBonusEnabled).Info("Updating bind bonus policy")
+	}
+
+	if req.BindBonusQuota != nil {
+		if *req.BindBonusQuota < 0 {
+			return nil, fmt.Errorf("bind bonus quota cannot be negative")
+		}
+		updates["bind_bonus_quota"] = *req.BindBonusQuota
+		s.logger.WithField("bind_bonus_quota", *req.BindBonusQuota).Info("Updating bind bonus quota")
+	}
+
+	if req.BindBonusAccountsAfter != nil {
+		// 空字符串清除限制（写入 NULL）
+		var accountsAfter interface{}
+		if *req.BindBonusAccountsAfter != "" {
+			after, err := time.Parse(time.RFC3339, *req.BindBonusAccountsAfter)
+			if err != nil {
+				return nil, fmt.Errorf("bind bonus accounts after must be an RFC3339 time: %w", err)
+			}
+			accountsAfter = after
+		}
+		updates["bind_bonus_accounts_after"] = accountsAfter
+		s.logger.WithField("bind_bonus_accounts_after", *req.BindBonusAccountsAfter).Info("Updating bind bonus accounts after")
+	}
+
+	if req.GroupTiers != nil {
+		tiers, err := normalizeGroupTiers(*req.GroupTiers)
+		if err != nil {
+			return nil, err
+		}
+		updates["group_tiers"] = tiers
+		s.logger.WithField("group_tiers", tiers).Info("Updating group tiers")
+	}
+
+	if req.GroupDemoteInactiveDays != nil {
+		if *req.GroupDemoteInactiveDays < 0 {
+			return nil, fmt.Errorf("group demote inactive days cannot be negative")
+		}
+		updates["group_demote_inactive_days"] = *req.GroupDemoteInactiveDays
+		s.logger.WithField("group_demote_inactive_days", *req.GroupDemoteInactiveDays).Info("Updating group demote inactive days")
+	}
+
 	if len(updates) == 0 {
-		return fmt.Errorf("no updates provided")
+		return nil, fmt.Errorf("no updates provided")
 	}
 
+	change := configAuditChange(currentConfig, updates)
+
 	// 如果配置不存在，创建新配置
 	if currentConfig == nil {
 		newConfig := &model.AdminConfig{
@@ -170,29 +352,230 @@ func (s *AdminService) UpdateConfig(ctx context.Context, req *model.UpdateConfig
 				"type":  fmt.Sprintf("%T", updates["group_id"]),
 			}).Debug("group_id type mismatch or not provided")
 		}
+		if val, ok := updates["donate_quota_per_key"].(int64); ok {
+			newConfig.DonateQuotaPerKey = sql.NullInt64{Int64: val, Valid: true}
+		}
+		if val, ok := updates["max_donate_keys_per_day"].(int); ok {
+			newConfig.MaxDonateKeysPerDay = sql.NullInt64{Int64: int64(val), Valid: true}
+		}
+		if val, ok := updates["max_donate_submissions_per_day"].(int); ok {
+			newConfig.MaxDonateSubmissionsPerDay = sql.NullInt64{Int64: int64(val), Valid: true}
+		}
+		if val, ok := updates["max_keys_per_submission"].(int); ok {
+			newConfig.MaxKeysPerSubmission = sql.NullInt64{Int64: int64(val), Valid: true}
+		}
+		if val, ok := updates["streak_bonuses"].(model.StreakBonusTable); ok {
+			newConfig.StreakBonuses = val
+		}
+		if val, ok := updates["claim_quota_threshold"].(int64); ok {
+			newConfig.ClaimQuotaThreshold = sql.NullInt64{Int64: val, Valid: true}
+		}
+		if val, ok := updates["claim_threshold_mode"].(string); ok {
+			newConfig.ClaimThresholdMode = sql.NullString{String: val, Valid: true}
+		}
+		if val, ok := updates["min_bind_trust_level"].(int); ok {
+			newConfig.MinBindTrustLevel = sql.NullInt64{Int64: int64(val), Valid: true}
+		}
+		if val, ok := updates["min_claim_trust_level"].(int); ok {
+			newConfig.MinClaimTrustLevel = sql.NullInt64{Int64: int64(val), Valid: true}
+		}
+		if val, ok := updates["min_donate_trust_level"].(int); ok {
+			newConfig.MinDonateTrustLevel = sql.NullInt64{Int64: int64(val), Valid: true}
+		}
+		if val, ok := updates["trust_level_claim_quotas"].(model.TrustLevelQuotaTable); ok {
+			newConfig.TrustLevelClaimQuotas = val
+		}
+		if val, ok := updates["bind_bonus_enabled"].(bool); ok {
+			newConfig.BindBonusEnabled = sql.NullBool{Bool: val, Valid: true}
+		}
+		if val, ok := updates["bind_bonus_quota"].(int64); ok {
+			newConfig.BindBonusQuota = sql.NullInt64{Int64: val, Valid: true}
+		}
+		if val, ok := updates["bind_bonus_accounts_after"].(time.Time); ok {
+			newConfig.BindBonusAccountsAfter = sql.NullTime{Time: val, Valid: true}
+		}
+		if val, ok := updates["group_tiers"].(model.GroupTierTable); ok {
+			newConfig.GroupTiers = val
+		}
+		if val, ok := updates["group_demote_inactive_days"].(int); ok {
+			newConfig.GroupDemoteInactiveDays = sql.NullInt64{Int64: int64(val), Valid: true}
+		}
 
-		s.logger.WithFields(logrus.Fields{
-			"new_config": newConfig,
-			"updates":    updates,
-		}).Info("Creating new admin config")
+		s.logger.WithField("claim_quota", newConfig.ClaimQuota).Info("Creating new admin config")
 
 		if err := s.adminConfigRepo.Create(ctx, newConfig); err != nil {
 			s.logger.WithError(err).Error("Failed to create admin config")
-			return fmt.Errorf("failed to create config: %w", err)
+			return nil, fmt.Errorf("failed to create config: %w", err)
 		}
 
 		s.logger.Info("Admin config created successfully")
-		return nil
+		return change, nil
 	}
 
 	// 更新现有配置
 	if err := s.adminConfigRepo.UpdatePartial(ctx, updates); err != nil {
 		s.logger.WithError(err).Error("Failed to update admin config")
-		return fmt.Errorf("failed to update config: %w", err)
+		return nil, fmt.Errorf("failed to update config: %w", err)
 	}
 
-	s.logger.WithField("updates", updates).Info("Admin config updated successfully")
-	return nil
+	s.logger.WithField("fields", len(updates)).Info("Admin config updated successfully")
+	return change, nil
+}
+
+// configAuditChange 生成配置更新前后的字段值，只保留实际发生变化的字段
+func configAuditChange(current *model.AdminConfig, updates map[string]interface{}) *model.AuditChange {
+	var snapshot map[string]interface{}
+	if current != nil {
+		snapshot = map[string]interface{}{
+			"claim_quota":                    current.ClaimQuota,
+			"session":                        nullStringValue(current.Session),
+			"new_api_user":                   nullStringValue(current.NewAPIUser),
+			"keys_api_url":                   nullStringValue(current.KeysAPIURL),
+			"keys_authorization":             nullStringValue(current.KeysAuthorization),
+			"group_id":                       current.GroupID,
+			"donate_quota_per_key":           nullInt64Value(current.DonateQuotaPerKey),
+			"max_donate_keys_per_day":        nullInt64Value(current.MaxDonateKeysPerDay),
+			"max_donate_submissions_per_day": nullInt64Value(current.MaxDonateSubmissionsPerDay),
+			"max_keys_per_submission":        nullInt64Value(current.MaxKeysPerSubmission),
+			"streak_bonuses":                 current.StreakBonuses,
+			"claim_quota_threshold":          nullInt64Value(current.ClaimQuotaThreshold),
+			"claim_threshold_mode":           nullStringValue(current.ClaimThresholdMode),
+			"min_bind_trust_level":           nullInt64Value(current.MinBindTrustLevel),
+			"min_claim_trust_level":          nullInt64Value(current.MinClaimTrustLevel),
+			"min_donate_trust_level":         nullInt64Value(current.MinDonateTrustLevel),
+			"trust_level_claim_quotas":       current.TrustLevelClaimQuotas,
+			"bind_bonus_enabled":             nullBoolValue(current.BindBonusEnabled),
+			"bind_bonus_quota":               nullInt64Value(current.BindBonusQuota),
+			"bind_bonus_accounts_after":      nullTimeValue(current.BindBonusAccountsAfter),
+			"group_tiers":                    current.GroupTiers,
+			"group_demote_inactive_days":     nullInt64Value(current.GroupDemoteInactiveDays),
+		}
+	}
+
+	change := &model.AuditChange{
+		Before: model.JSONMap{},
+		After:  model.JSONMap{},
+	}
+	for field, value := range updates {
+		before, exists := snapshot[field]
+		if exists {
+			beforeJSON, _ := json.Marshal(before)
+			afterJSON, _ := json.Marshal(value)
+			if bytes.Equal(beforeJSON, afterJSON) {
+				continue
+			}
+		}
+		change.Before[field] = before
+		change.After[field] = value
+	}
+	return change
+}
+
+// nullStringValue 可空字符串的审计值
+func nullStringValue(v sql.NullString) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.String
+}
+
+// nullInt64Value 可空整数的审计值
+func nullInt64Value(v sql.NullInt64) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Int64
+}
+
+// nullBoolValue 可空布尔值的审计值
+func nullBoolValue(v sql.NullBool) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Bool
+}
+
+// nullTimeValue 可空时间的审计值
+func nullTimeValue(v sql.NullTime) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Time
+}
+
+// normalizeStreakBonuses 校验连续领取奖励表并按天数升序排列
+func normalizeStreakBonuses(bonuses model.StreakBonusTable) (model.StreakBonusTable, error) {
+	normalized := make(model.StreakBonusTable, 0, len(bonuses))
+	seen := make(map[int]bool, len(bonuses))
+	for _, bonus := range bonuses {
+		if bonus.Days < 2 {
+			return nil, fmt.Errorf("streak bonus days must be at least 2")
+		}
+		if bonus.Percent <= 0 || bonus.Percent > 1000 {
+			return nil, fmt.Errorf("streak bonus percent must be between 1 and 1000")
+		}
+		if seen[bonus.Days] {
+			return nil, fmt.Errorf("duplicate streak bonus days: %d", bonus.Days)
+		}
+		seen[bonus.Days] = true
+		normalized = append(normalized, bonus)
+	}
+
+	sort.Slice(normalized, func(i, j int) bool {
+		return normalized[i].Days < normalized[j].Days
+	})
+	return normalized, nil
+}
+
+// normalizeTrustLevelQuotas 校验按信任等级的领取额度表并按等级升序排列
+func normalizeTrustLevelQuotas(quotas model.TrustLevelQuotaTable) (model.TrustLevelQuotaTable, error) {
+	normalized := make(model.TrustLevelQuotaTable, 0, len(quotas))
+	seen := make(map[int]bool, len(quotas))
+	for _, entry := range quotas {
+		if entry.Level < 0 || entry.Level > model.MaxTrustLevel {
+			return nil, fmt.Errorf("trust level must be between 0 and %d", model.MaxTrustLevel)
+		}
+		if entry.Quota <= 0 {
+			return nil, fmt.Errorf("trust level claim quota must be positive")
+		}
+		if seen[entry.Level] {
+			return nil, fmt.Errorf("duplicate trust level: %d", entry.Level)
+		}
+		seen[entry.Level] = true
+		normalized = append(normalized, entry)
+	}
+
+	sort.Slice(normalized, func(i, j int) bool {
+		return normalized[i].Level < normalized[j].Level
+	})
+	return normalized, nil
+}
+
+// normalizeGroupTiers 校验用户组档位表（保持管理员配置的从低到高顺序）
+func normalizeGroupTiers(tiers model.GroupTierTable) (model.GroupTierTable, error) {
+	normalized := make(model.GroupTierTable, 0, len(tiers))
+	seen := make(map[string]bool, len(tiers))
+	for _, tier := range tiers {
+		tier.Name = strings.TrimSpace(tier.Name)
+		if tier.Name == "" || len(tier.Name) > 64 {
+			return nil, fmt.Errorf("group tier name must be 1-64 characters")
+		}
+		if seen[tier.Name] {
+			return nil, fmt.Errorf("duplicate group tier: %s", tier.Name)
+		}
+		seen[tier.Name] = true
+		if tier.GroupID <= 0 {
+			return nil, fmt.Errorf("group tier %s: group_id must be positive", tier.Name)
+		}
+		if tier.MinKeys < 0 || tier.MinDonateQuota < 0 {
+			return nil, fmt.Errorf("group tier %s: thresholds cannot be negative", tier.Name)
+		}
+		if tier.MinKeys == 0 && tier.MinDonateQuota == 0 {
+			return nil, fmt.Errorf("group tier %s: min_keys or min_donate_quota must be positive", tier.Name)
+		}
+		normalized = append(normalized, tier)
+	}
+	return normalized, nil
 }
 
 // GetSystemStats 获取系统统计信息
@@ -215,7 +598,7 @@ func (s *AdminService) GetSystemStats(ctx context.Context) (map[string]interface
 		stats["total_claims"] = totalClaims
 	}
 
-	todayClaimCount, todayClaimQuota, err := s.claimRepo.GetTodayStats(ctx)
+	todayClaimCount, todayClaimQuota, err := s.claimRepo.GetTodayStats(ctx, s.claimPeriod.ClaimDate(time.Now()))
 	if err != nil {
 		s.logger.WithError(err).Warn("Failed to get today's claim stats")
 	} else {
@@ -224,6 +607,15 @@ func (s *AdminService) GetSystemStats(ctx context.Context) (map[string]interface
 		stats["today_claim_quota_usd"] = model.QuotaToDollar(todayClaimQuota)
 	}
 
+	today := s.claimPeriod.ClaimDate(time.Now())
+	todayBaseQuota, todayBonusQuota, err := s.claimRepo.GetBonusStats(ctx, today, today)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to get today's claim bonus stats")
+	} else {
+		stats["today_claim_base_quota"] = todayBaseQuota
+		stats["today_claim_bonus_quota"] = todayBonusQuota
+	}
+
 	// 投喂统计
 	totalDonates, err := s.donateRepo.Count(ctx)
 	if err != nil {
@@ -242,6 +634,16 @@ func (s *AdminService) GetSystemStats(ctx context.Context) (map[string]interface
 		stats["today_donate_quota_usd"] = model.QuotaToDollar(todayDonateQuota)
 	}
 
+	// 首次绑定奖励统计（不含死信）
+	totalBindBonuses, totalBindBonusQuota, err := s.grantRepo.GetSourceStats(ctx, model.GrantSourceBindBonus, time.Time{})
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to get bind bonus stats")
+	} else {
+		stats["total_bind_bonuses"] = totalBindBonuses
+		stats["total_bind_bonus_quota"] = totalBindBonusQuota
+		stats["total_bind_bonus_quota_usd"] = model.QuotaToDollar(totalBindBonusQuota)
+	}
+
 	// Key统计
 	totalKeys, err := s.keyRepo.Count(ctx)
 	if err != nil {
@@ -297,10 +699,12 @@ func (s *AdminService) GetDashboardStats(ctx context.Context) (map[string]interf
 	weekStart := now.AddDate(0, 0, -int(now.Weekday()))
 	weekEnd := weekStart.AddDate(0, 0, 7)
 
+	// 领取统计按领取时区的自然周（周一开始）
+	claimWeekStart := s.claimPeriod.StartOfWeek(now)
 	weekClaimCount, weekClaimQuota, err := s.claimRepo.GetDateRangeStats(
 		ctx,
-		weekStart.Format("2006-01-02"),
-		weekEnd.Format("2006-01-02"),
+		claimWeekStart.Format("2006-01-02"),
+		claimWeekStart.AddDate(0, 0, 6).Format("2006-01-02"),
 	)
 	if err == nil {
 		stats["week_claims"] = weekClaimCount
@@ -324,10 +728,12 @@ func (s *AdminService) GetDashboardStats(ctx context.Context) (map[string]interf
 	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
 	monthEnd := monthStart.AddDate(0, 1, 0)
 
+	claimNow := s.claimPeriod.Now()
+	claimMonthStart := time.Date(claimNow.Year(), claimNow.Month(), 1, 0, 0, 0, 0, claimNow.Location())
 	monthClaimCount, monthClaimQuota, err := s.claimRepo.GetDateRangeStats(
 		ctx,
-		monthStart.Format("2006-01-02"),
-		monthEnd.Format("2006-01-02"),
+		claimMonthStart.Format("2006-01-02"),
+		claimMonthStart.AddDate(0, 1, -1).Format("2006-01-02"),
 	)
 	if err == nil {
 		stats["month_claims"] = monthClaimCount
@@ -335,6 +741,17 @@ func (s *AdminService) GetDashboardStats(ctx context.Context) (map[string]interf
 		stats["month_claim_quota_usd"] = model.QuotaToDollar(monthClaimQuota)
 	}
 
+	monthBaseQuota, monthBonusQuota, err := s.claimRepo.GetBonusStats(
+		ctx,
+		claimMonthStart.Format("2006-01-02"),
+		claimMonthStart.AddDate(0, 1, -1).Format("2006-01-02"),
+	)
+	if err == nil {
+		stats["month_claim_base_quota"] = monthBaseQuota
+		stats["month_claim_bonus_quota"] = monthBonusQuota
+		stats["month_claim_bonus_quota_usd"] = model.QuotaToDollar(monthBonusQuota)
+	}
+
 	monthDonateCount, monthDonateKeys, monthDonateQuota, err := s.donateRepo.GetDateRangeStats(
 		ctx,
 		monthStart,
@@ -390,34 +807,129 @@ func (s *AdminService) ListAllStatistics(ctx context.Context) ([]*model.UserStat
 	return s.userRepo.GetAllStatistics(ctx)
 }
 
-// DeleteUser 删除用户及其所有数据
-func (s *AdminService) DeleteUser(ctx context.Context, linuxDoID string) error {
-	// 删除领取记录
-	if err := s.claimRepo.DeleteByLinuxDoID(ctx, linuxDoID); err != nil {
-		s.logger.WithError(err).Warn("Failed to delete claim records")
+// ListDeletedUsers 获取已软删除的用户列表（最近删除的在前）
+func (s *AdminService) ListDeletedUsers(ctx context.Context, page, pageSize int) (*model.PaginationResult, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	users, err := s.userRepo.ListDeleted(ctx, pageSize, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted users: %w", err)
+	}
+
+	total, err := s.userRepo.CountDeleted(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count deleted users: %w", err)
+	}
+
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+
+	return &model.PaginationResult{
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+		TotalPages: totalPages,
+		HasMore:    page < totalPages,
+		Data:       users,
+	}, nil
+}
+
+// DeleteUser 删除用户：默认软删除，保留期内可以恢复；purge 为 true 时立即彻底清除（已软删除的用户也可以）
+// 软删除时清除登录会话、个人访问令牌和保存的 Linux.do 刷新令牌
+func (s *AdminService) DeleteUser(ctx context.Context, admin *model.Admin, linuxDoID string, purge bool) error {
+	user, err := s.userRepo.GetByLinuxDoIDWithDeleted(ctx, linuxDoID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+
+	if !user.DeletedAt.Valid {
+		deleted, err := s.userRepo.SoftDelete(ctx, linuxDoID, admin.Username)
+		if err != nil {
+			return err
+		}
+		if deleted {
+			s.revokeUserAccess(ctx, user)
+			s.logger.WithFields(logrus.Fields{
+				"linux_do_id": linuxDoID,
+				"admin_id":    admin.ID,
+			}).Info("User soft deleted")
+		}
+	} else if !purge {
+		return fmt.Errorf("user already deleted")
+	}
+
+	if !purge {
+		return nil
+	}
+
+	return s.purger.Purge(ctx, user)
+}
+
+// revokeUserAccess 删除用户的登录会话和个人访问令牌，撤销保存的 Linux.do 刷新令牌并清除缓存
+func (s *AdminService) revokeUserAccess(ctx context.Context, user *model.User) {
+	if _, err := s.sessionRepo.DeleteByUser(ctx, user.ID, ""); err != nil {
+		s.logger.WithError(err).Warn("Failed to delete user sessions")
 	}
+	if err := s.tokenRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		s.logger.WithError(err).Warn("Failed to delete access tokens")
+	}
+	s.verifier.RevokeUserToken(ctx, user.LinuxDoID)
+
+	_ = s.cacheService.ClearUserCache(ctx, user.ID)
+}
 
-	// 删除投喂记录
-	if err := s.donateRepo.DeleteByLinuxDoID(ctx, linuxDoID); err != nil {
-		s.logger.WithError(err).Warn("Failed to delete donate records")
+// RestoreUser 恢复保留期内软删除的用户（用户需要重新登录）
+func (s *AdminService) RestoreUser(ctx context.Context, linuxDoID string) (*model.User, error) {
+	restored, err := s.userRepo.Restore(ctx, linuxDoID)
+	if err != nil {
+		return nil, err
+	}
+	if !restored {
+		return nil, fmt.Errorf("deleted user not found")
 	}
 
-	// 删除已使用的Key记录
-	if err := s.keyRepo.DeleteByLinuxDoID(ctx, linuxDoID); err != nil {
-		s.logger.WithError(err).Warn("Failed to delete key records")
+	user, err := s.userRepo.GetByLinuxDoID(ctx, linuxDoID)
+	if err != nil {
+		return nil, err
 	}
+	if user != nil {
+		_ = s.cacheService.ClearUserCache(ctx, user.ID)
+	}
+
+	s.logger.WithField("linux_do_id", linuxDoID).Info("Deleted user restored")
+	return user, nil
+}
 
-	// 删除用户
-	if err := s.userRepo.Delete(ctx, linuxDoID); err != nil {
-		s.logger.WithError(err).Error("Failed to delete user")
-		return fmt.Errorf("failed to delete user: %w", err)
+// ForceLogoutUser 强制用户在所有设备上退出登录，返回删除的会话数
+func (s *AdminService) ForceLogoutUser(ctx context.Context, linuxDoID string) (int, error) {
+	user, err := s.userRepo.GetByLinuxDoID(ctx, linuxDoID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return 0, fmt.Errorf("user not found")
 	}
 
-	// 清除缓存
-	_ = s.cacheService.ClearUserCache(ctx, linuxDoID)
+	count, err := s.sessionRepo.DeleteByUser(ctx, user.ID, "")
+	if err != nil {
+		s.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to force logout user")
+		return 0, fmt.Errorf("failed to force logout user: %w", err)
+	}
 
-	s.logger.WithField("linux_do_id", linuxDoID).Info("User and all related data deleted")
-	return nil
+	s.logger.WithFields(logrus.Fields{
+		"linux_do_id": linuxDoID,
+		"sessions":    count,
+	}).Info("User forcibly logged out")
+	return count, nil
 }
 
 // CleanExpiredSessions 清理过期会话
@@ -581,6 +1093,20 @@ func (s *AdminService) GetHealthStatus(ctx context.Context) (map[string]interfac
 		health["kyx_api"] = "healthy"
 	}
 
+	// 公益站 Session 最近一次校验结果（由后台任务定期校验）
+	health["kyx_session"] = s.sessionMonitor.Status()
+
+	// 未解决的系统告警
+	alerts, err := s.alertService.ListOpen(ctx)
+	if err != nil {
+		health["alerts_error"] = err.Error()
+	} else {
+		if alerts == nil {
+			alerts = []*model.SystemAlert{}
+		}
+		health["alerts"] = alerts
+	}
+
 	return health, nil
 }
 
@@ -588,3 +1114,138 @@ func (s *AdminService) GetHealthStatus(ctx context.Context) (map[string]interfac
 func (s *AdminService) InitializeDefaultConfig(ctx context.Context) error {
 	return s.adminConfigRepo.InitializeDefault(ctx)
 }
+
+// minAdminPasswordLength 管理员密码最小长度
+const minAdminPasswordLength = 8
+
+// ListAdmins 获取全部管理员账号
+func (s *AdminService) ListAdmins(ctx context.Context) ([]*model.Admin, error) {
+	return s.adminRepo.List(ctx)
+}
+
+// CreateAdmin 创建管理员账号
+func (s *AdminService) CreateAdmin(ctx context.Context, req *model.CreateAdminRequest) (*model.Admin, error) {
+	if !model.IsValidAdminRole(req.Role) {
+		return nil, fmt.Errorf("invalid admin role: %s", req.Role)
+	}
+	if len(req.Password) < minAdminPasswordLength {
+		return nil, fmt.Errorf("password must be at least %d characters", minAdminPasswordLength)
+	}
+
+	hash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	admin := &model.Admin{
+		Username:     req.Username,
+		PasswordHash: hash,
+		Role:         req.Role,
+	}
+	if err := s.adminRepo.Create(ctx, admin); err != nil {
+		if database.IsUniqueViolation(err) {
+			return nil, fmt.Errorf("admin username already exists")
+		}
+		return nil, err
+	}
+
+	return admin, nil
+}
+
+// UpdateAdmin 更新管理员的密码、角色或停用状态
+// 不允许降级或停用最后一个可用的 owner，避免无人能够管理配置和账号
+func (s *AdminService) UpdateAdmin(ctx context.Context, id int, req *model.UpdateAdminRequest) (*model.Admin, error) {
+	admin, err := s.adminRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if admin == nil {
+		return nil, fmt.Errorf("admin not found")
+	}
+
+	wasActiveOwner := admin.Role == model.AdminRoleOwner && !admin.Disabled
+
+	if req.Password != nil {
+		if len(*req.Password) < minAdminPasswordLength {
+			return nil, fmt.Errorf("password must be at least %d characters", minAdminPasswordLength)
+		}
+		hash, err := utils.HashPassword(*req.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		admin.PasswordHash = hash
+	}
+	if req.Role != nil {
+		if !model.IsValidAdminRole(*req.Role) {
+			return nil, fmt.Errorf("invalid admin role: %s", *req.Role)
+		}
+		admin.Role = *req.Role
+	}
+	if req.Disabled != nil {
+		admin.Disabled = *req.Disabled
+	}
+
+	if wasActiveOwner && (admin.Role != model.AdminRoleOwner || admin.Disabled) {
+		if err := s.ensureAnotherOwner(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.adminRepo.Update(ctx, admin); err != nil {
+		return nil, err
+	}
+
+	return admin, nil
+}
+
+// DeleteAdmin 删除管理员账号（不能删除自己和最后一个可用的 owner）
+func (s *AdminService) DeleteAdmin(ctx context.Context, currentAdminID, id int) error {
+	if currentAdminID == id {
+		return fmt.Errorf("cannot delete your own admin account")
+	}
+
+	admin, err := s.adminRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if admin == nil {
+		return fmt.Errorf("admin not found")
+	}
+
+	if admin.Role == model.AdminRoleOwner && !admin.Disabled {
+		if err := s.ensureAnotherOwner(ctx); err != nil {
+			return err
+		}
+	}
+
+	return s.adminRepo.Delete(ctx, id)
+}
+
+// ResetAdminTOTP 重置其他管理员的两步验证（丢失验证器且恢复码用尽时由 owner 操作）
+func (s *AdminService) ResetAdminTOTP(ctx context.Context, currentAdminID, id int) error {
+	if currentAdminID == id {
+		return fmt.Errorf("cannot reset your own two-factor authentication, disable it with a verification code instead")
+	}
+
+	admin, err := s.adminRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if admin == nil {
+		return fmt.Errorf("admin not found")
+	}
+
+	return s.adminRepo.DisableTOTP(ctx, id)
+}
+
+// ensureAnotherOwner 确认除当前操作的账号外还有其他可用的 owner
+func (s *AdminService) ensureAnotherOwner(ctx context.Context) error {
+	owners, err := s.adminRepo.CountActiveOwners(ctx)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return fmt.Errorf("cannot remove the last active owner")
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+)
+
+// AlertService 系统告警服务
+// 告警持久化到 system_alerts，产生和解决时发送 Webhook（未配置地址时只记录日志）
+type AlertService struct {
+	alertRepo  *repository.AlertRepository
+	webhookURL string
+	httpClient *http.Client
+	logger     *logrus.Logger
+}
+
+// NewAlertService 创建系统告警服务
+func NewAlertService(alertRepo *repository.AlertRepository, webhookURL string, logger *logrus.Logger) *AlertService {
+	return &AlertService{
+		alertRepo:  alertRepo,
+		webhookURL: webhookURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     logger,
+	}
+}
+
+// Raise 产生告警，已有未解决的同类告警时只累加失败次数，不重复发送 Webhook
+func (s *AlertService) Raise(ctx context.Context, alertType, message string) error {
+	alert, created, err := s.alertRepo.Raise(ctx, alertType, message)
+	if err != nil {
+		return err
+	}
+
+	if !created {
+		s.logger.WithFields(logrus.Fields{
+			"alert_type": alertType,
+			"failures":   alert.Failures,
+		}).Debug("System alert still open")
+		return nil
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"alert_id":   alert.ID,
+		"alert_type": alertType,
+		"message":    message,
+	}).Error("System alert raised")
+
+	s.notify(ctx, "alert_raised", alert)
+	return nil
+}
+
+// Resolve 解决未解决的同类告警（没有时不做任何处理）
+func (s *AlertService) Resolve(ctx context.Context, alertType string) error {
+	alert, err := s.alertRepo.Resolve(ctx, alertType)
+	if err != nil {
+		return err
+	}
+	if alert == nil {
+		return nil
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"alert_id":   alert.ID,
+		"alert_type": alertType,
+		"failures":   alert.Failures,
+	}).Info("System alert resolved")
+
+	s.notify(ctx, "alert_resolved", alert)
+	return nil
+}
+
+// ListOpen 获取全部未解决的告警
+func (s *AlertService) ListOpen(ctx context.Context) ([]*model.SystemAlert, error) {
+	return s.alertRepo.ListOpen(ctx)
+}
+
+// notify 发送告警 Webhook，失败只记录日志
+func (s *AlertService) notify(ctx context.Context, event string, alert *model.SystemAlert) {
+	if s.webhookURL == "" {
+		return
+	}
+
+	if err := s.post(ctx, event, alert); err != nil {
+		s.logger.WithError(err).WithFields(logrus.Fields{
+			"event":    event,
+			"alert_id": alert.ID,
+		}).Warn("Failed to send alert webhook")
+	}
+}
+
+// post 以 JSON 发送告警事件
+func (s *AlertService) post(ctx context.Context, event string, alert *model.SystemAlert) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"event":     event,
+		"alert":     alert,
+		"timestamp": time.Now().Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.webhookURL, bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "KyxQuotaBridge/1.0")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+	"github.com/yourusername/kyx-quota-bridge/pkg/utils"
+)
+
+const (
+	// auditVerifyBatch 校验哈希链时每批读取的记录数
+	auditVerifyBatch = 1000
+	// auditRedacted 脱敏后的占位值
+	auditRedacted = "[REDACTED]"
+)
+
+// auditRedactedFields 审计日志中完全隐藏的字段
+var auditRedactedFields = map[string]bool{
+	"password":        true,
+	"password_hash":   true,
+	"token":           true,
+	"challenge_token": true,
+	"code":            true,
+	"secret":          true,
+	"totp_secret":     true,
+	"recovery_codes":  true,
+	"authorization":   true,
+	"keys":            true,
+	"full_key":        true,
+}
+
+// auditFingerprintFields 只记录指纹的字段：可以看出是否被更换，但无法还原原值
+var auditFingerprintFields = map[string]bool{
+	"session":            true,
+	"keys_authorization": true,
+}
+
+// errAuditChainBroken 哈希链校验失败，用于提前结束遍历
+var errAuditChainBroken = errors.New("audit chain broken")
+
+// AuditService 管理员审计日志服务
+type AuditService struct {
+	auditRepo *repository.AuditLogRepository
+	logger    *logrus.Logger
+}
+
+// NewAuditService 创建管理员审计日志服务
+func NewAuditService(auditRepo *repository.AuditLogRepository, logger *logrus.Logger) *AuditService {
+	return &AuditService{
+		auditRepo: auditRepo,
+		logger:    logger,
+	}
+}
+
+// Record 脱敏后追加一条审计日志
+func (s *AuditService) Record(ctx context.Context, entry *model.AdminAuditLog) error {
+	entry.Before = redactAuditMap(entry.Before)
+	entry.After = redactAuditMap(entry.After)
+	entry.AdminUsername = truncateRunes(entry.AdminUsername, 64)
+	entry.Path = truncateRunes(entry.Path, 255)
+	entry.Target = truncateRunes(entry.Target, 255)
+	entry.UserAgent = truncateRunes(entry.UserAgent, 512)
+
+	return s.auditRepo.Append(ctx, entry)
+}
+
+// ListLogs 按条件分页获取审计日志
+func (s *AuditService) ListLogs(ctx context.Context, filter model.AuditLogFilter, page, pageSize int) ([]*model.AdminAuditLog, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	logs, err := s.auditRepo.List(ctx, filter, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.auditRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
+
+// VerifyChain 按顺序重新计算全部记录的哈希，检查记录内容和前后链接是否被篡改
+func (s *AuditService) VerifyChain(ctx context.Context) (*model.AuditChainStatus, error) {
+	status := &model.AuditChainStatus{Valid: true}
+	prevHash := ""
+
+	err := s.auditRepo.ForEach(ctx, auditVerifyBatch, func(entry *model.AdminAuditLog) error {
+		status.Checked++
+
+		if entry.PrevHash != prevHash {
+			status.Valid = false
+			status.BrokenID = entry.ID
+			status.Reason = "prev_hash does not match the previous entry (entry missing or reordered)"
+			return errAuditChainBroken
+		}
+
+		hash, err := entry.ComputeHash()
+		if err != nil {
+			return err
+		}
+		if hash != entry.Hash {
+			status.Valid = false
+			status.BrokenID = entry.ID
+			status.Reason = "entry content does not match its hash (entry modified)"
+			return errAuditChainBroken
+		}
+
+		prevHash = entry.Hash
+		return nil
+	})
+	if err != nil && !errors.Is(err, errAuditChainBroken) {
+		return nil, fmt.Errorf("failed to verify audit chain: %w", err)
+	}
+
+	if !status.Valid {
+		s.logger.WithFields(logrus.Fields{
+			"broken_id": status.BrokenID,
+			"reason":    status.Reason,
+		}).Error("Admin audit log chain verification failed")
+	}
+
+	return status, nil
+}
+
+// redactAuditMap 复制并脱敏审计内容
+func redactAuditMap(m model.JSONMap) model.JSONMap {
+	if m == nil {
+		return nil
+	}
+	return model.JSONMap(redactAuditValue(map[string]interface{}(m)).(map[string]interface{}))
+}
+
+// redactAuditValue 递归脱敏敏感字段
+func redactAuditValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case model.JSONMap:
+		return redactAuditValue(map[string]interface{}(v))
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			switch {
+			case auditRedactedFields[key]:
+				redacted[key] = auditRedacted
+			case auditFingerprintFields[key]:
+				redacted[key] = auditFingerprint(item)
+			default:
+				redacted[key] = redactAuditValue(item)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactAuditValue(item)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
+// auditFingerprint 敏感值的指纹（SHA-256 前 12 位）
+func auditFingerprint(value interface{}) interface{} {
+	str, ok := value.(string)
+	if !ok {
+		if value == nil {
+			return nil
+		}
+		return auditRedacted
+	}
+	if str == "" {
+		return ""
+	}
+	return "sha256:" + utils.HashSHA256(str)[:12]
+}
+
+// truncateRunes 按字符截断字符串，避免截断多字节字符
+func truncateRunes(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen])
+}
@@ -5,40 +5,54 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/sirupsen/logrus"
 	"github.com/yourusername/kyx-quota-bridge/internal/model"
 	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+	"github.com/yourusername/kyx-quota-bridge/pkg/totp"
+	"github.com/yourusername/kyx-quota-bridge/pkg/utils"
 )
 
 // AuthService 认证服务
 type AuthService struct {
-	linuxDoClient  *LinuxDoClient
+	providers      map[string]IdentityProvider
+	providerOrder  []IdentityProvider
 	sessionRepo    *repository.SessionRepository
 	userRepo       *repository.UserRepository
+	identityRepo   *repository.IdentityRepository
+	adminRepo      *repository.AdminRepository
 	cacheService   *CacheService
+	verifier       *LinuxDoVerifier
+	banService     *BanService
 	jwtSecret      string
-	adminPassword  string
 	sessionTimeout time.Duration
+	defaultAdmin   string
 	logger         *logrus.Logger
 }
 
 // AuthServiceConfig 认证服务配置
 type AuthServiceConfig struct {
-	JWTSecret      string
-	AdminPassword  string
-	SessionTimeout time.Duration
+	JWTSecret            string
+	SessionTimeout       time.Duration
+	DefaultAdminUsername string // 管理员登录未提交用户名时使用的账号（ADMIN_USERNAME）
 }
 
 // NewAuthService 创建认证服务
 func NewAuthService(
-	linuxDoClient *LinuxDoClient,
+	providers []IdentityProvider,
 	sessionRepo *repository.SessionRepository,
 	userRepo *repository.UserRepository,
+	identityRepo *repository.IdentityRepository,
+	adminRepo *repository.AdminRepository,
 	cacheService *CacheService,
+	verifier *LinuxDoVerifier,
+	banService *BanService,
 	config AuthServiceConfig,
 	logger *logrus.Logger,
 ) *AuthService {
@@ -46,14 +60,24 @@ func NewAuthService(
 		config.SessionTimeout = 24 * time.Hour
 	}
 
+	providerMap := make(map[string]IdentityProvider, len(providers))
+	for _, provider := range providers {
+		providerMap[provider.Name()] = provider
+	}
+
 	return &AuthService{
-		linuxDoClient:  linuxDoClient,
+		providers:      providerMap,
+		providerOrder:  providers,
 		sessionRepo:    sessionRepo,
 		userRepo:       userRepo,
+		identityRepo:   identityRepo,
+		adminRepo:      adminRepo,
 		cacheService:   cacheService,
+		verifier:       verifier,
+		banService:     banService,
 		jwtSecret:      config.JWTSecret,
-		adminPassword:  config.AdminPassword,
 		sessionTimeout: config.SessionTimeout,
+		defaultAdmin:   config.DefaultAdminUsername,
 		logger:         logger,
 	}
 }
@@ -69,8 +93,43 @@ func (s *AuthService) GenerateState() (string, error) {
 	return state, nil
 }
 
-// GetAuthorizationURL 获取OAuth授权URL
-func (s *AuthService) GetAuthorizationURL(ctx context.Context) (string, string, error) {
+// oauthState 授权流程中缓存的 state 数据
+type oauthState struct {
+	Provider   string `json:"provider"`
+	LinkUserID int    `json:"link_user_id,omitempty"` // 非零表示为已登录用户关联身份
+}
+
+// Providers 获取已启用的身份提供方
+func (s *AuthService) Providers() []*model.IdentityProviderInfo {
+	infos := make([]*model.IdentityProviderInfo, 0, len(s.providerOrder))
+	for _, provider := range s.providerOrder {
+		infos = append(infos, &model.IdentityProviderInfo{
+			Name:        provider.Name(),
+			DisplayName: provider.DisplayName(),
+		})
+	}
+	return infos
+}
+
+// getProvider 根据名称获取身份提供方（名称为空时使用 Linux.do）
+func (s *AuthService) getProvider(name string) (IdentityProvider, error) {
+	if name == "" {
+		name = model.IdentityProviderLinuxDo
+	}
+	provider, ok := s.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown identity provider: %s", name)
+	}
+	return provider, nil
+}
+
+// GetAuthorizationURL 获取OAuth授权URL，linkUserID 非零时授权完成后为该用户关联身份
+func (s *AuthService) GetAuthorizationURL(ctx context.Context, providerName string, linkUserID int) (string, string, error) {
+	provider, err := s.getProvider(providerName)
+	if err != nil {
+		return "", "", err
+	}
+
 	// 生成state
 	state, err := s.GenerateState()
 	if err != nil {
@@ -79,130 +138,332 @@ func (s *AuthService) GetAuthorizationURL(ctx context.Context) (string, string,
 
 	// 将state存储到缓存（15分钟有效期）
 	stateKey := "oauth:state:" + state
-	if err := s.cacheService.Set(ctx, stateKey, "1", 15*time.Minute); err != nil {
+	stateData := &oauthState{Provider: provider.Name(), LinkUserID: linkUserID}
+	if err := s.cacheService.SetJSON(ctx, stateKey, stateData, 15*time.Minute); err != nil {
 		s.logger.WithError(err).Error("Failed to cache OAuth state")
 		return "", "", fmt.Errorf("failed to cache state: %w", err)
 	}
 
 	// 获取授权URL
-	authURL := s.linuxDoClient.GetAuthorizationURL(state)
+	authURL, err := provider.AuthorizationURL(ctx, state)
+	if err != nil {
+		s.logger.WithError(err).WithField("provider", provider.Name()).Error("Failed to build authorization URL")
+		return "", "", fmt.Errorf("failed to get authorization url: %w", err)
+	}
 
-	s.logger.WithField("state", state).Debug("Generated OAuth authorization URL")
+	s.logger.WithFields(logrus.Fields{
+		"state":    state,
+		"provider": provider.Name(),
+	}).Debug("Generated OAuth authorization URL")
 	return authURL, state, nil
 }
 
-// ValidateState 验证OAuth状态参数
-func (s *AuthService) ValidateState(ctx context.Context, state string) error {
+// consumeState 验证并消费OAuth状态参数
+func (s *AuthService) consumeState(ctx context.Context, state string) (*oauthState, error) {
 	if state == "" {
-		return fmt.Errorf("state is required")
+		return nil, fmt.Errorf("state is required")
 	}
 
 	stateKey := "oauth:state:" + state
-	exists, err := s.cacheService.Exists(ctx, stateKey)
-	if err != nil {
-		s.logger.WithError(err).Error("Failed to check state existence")
-		return fmt.Errorf("failed to validate state: %w", err)
-	}
-
-	if !exists {
+	var stateData oauthState
+	if err := s.cacheService.GetJSON(ctx, stateKey, &stateData); err != nil || stateData.Provider == "" {
 		s.logger.WithField("state", state).Warn("Invalid or expired OAuth state")
-		return fmt.Errorf("invalid or expired state")
+		return nil, fmt.Errorf("invalid or expired state")
 	}
 
 	// 删除已使用的state（防止重放攻击）
 	_ = s.cacheService.Del(ctx, stateKey)
 
-	return nil
+	return &stateData, nil
 }
 
-// HandleCallback 处理OAuth回调
-func (s *AuthService) HandleCallback(ctx context.Context, code string, state string) (*model.User, string, error) {
+// HandleCallback 处理OAuth回调，返回登录的用户和新会话ID
+// 关联身份的回调不创建新会话，返回的会话ID为空
+func (s *AuthService) HandleCallback(ctx context.Context, code string, state string, meta model.SessionMeta) (*model.User, string, error) {
 	// 验证state
-	if err := s.ValidateState(ctx, state); err != nil {
+	stateData, err := s.consumeState(ctx, state)
+	if err != nil {
+		return nil, "", err
+	}
+
+	provider, err := s.getProvider(stateData.Provider)
+	if err != nil {
 		return nil, "", err
 	}
 
 	// 交换授权码获取访问令牌
-	tokenResp, err := s.linuxDoClient.ExchangeCode(ctx, code)
+	token, err := provider.ExchangeCode(ctx, code)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to exchange authorization code")
+		s.logger.WithError(err).WithField("provider", provider.Name()).Error("Failed to exchange authorization code")
 		return nil, "", fmt.Errorf("failed to exchange code: %w", err)
 	}
 
 	// 获取用户信息
-	userInfo, err := s.linuxDoClient.GetUserInfo(ctx, tokenResp.AccessToken)
+	identity, err := provider.UserInfo(ctx, token)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to get user info")
+		s.logger.WithError(err).WithField("provider", provider.Name()).Error("Failed to get user info")
 		return nil, "", fmt.Errorf("failed to get user info: %w", err)
 	}
 
-	// 将 Linux.do 用户ID转换为字符串
-	linuxDoID := strconv.Itoa(userInfo.ID)
+	if stateData.LinkUserID != 0 {
+		user, err := s.linkIdentity(ctx, stateData.LinkUserID, identity)
+		if err != nil {
+			return nil, "", err
+		}
+		return user, "", nil
+	}
+
+	var user *model.User
+	if identity.Provider == model.IdentityProviderLinuxDo {
+		user, err = s.loginLinuxDo(ctx, identity, token)
+	} else {
+		user, err = s.loginLinkedIdentity(ctx, identity)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 被封禁或暂停的用户不能登录
+	if err := s.banService.Check(ctx, user.LinuxDoID, user.KyxUserID, "login"); err != nil {
+		return nil, "", err
+	}
+
+	// 创建会话
+	sessionID, err := s.CreateSession(ctx, user, meta)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to create session")
+		return nil, "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":     user.ID,
+		"linux_do_id": user.LinuxDoID,
+		"username":    user.Username,
+		"provider":    identity.Provider,
+		"session_id":  sessionID,
+	}).Info("User logged in successfully")
+
+	return user, sessionID, nil
+}
+
+// loginLinuxDo 使用 Linux.do 身份登录：不存在时创建用户，同步信任等级和账号状态
+func (s *AuthService) loginLinuxDo(ctx context.Context, identity *model.ExternalIdentity, token *model.OAuthToken) (*model.User, error) {
+	userInfo := identity.LinuxDo
+	linuxDoID := identity.Subject
 
 	// 查找或创建用户
-	user, err := s.userRepo.GetByLinuxDoID(ctx, linuxDoID)
+	user, err := s.userRepo.GetByLinuxDoIDWithDeleted(ctx, linuxDoID)
 	if err != nil {
 		s.logger.WithError(err).Error("Failed to get user from database")
-		return nil, "", fmt.Errorf("failed to get user: %w", err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// 管理员删除的用户在恢复前不能登录
+	if user != nil && user.DeletedAt.Valid {
+		s.logger.WithField("linux_do_id", linuxDoID).Warn("Login attempt by deleted user")
+		return nil, fmt.Errorf("%s: account has been deleted", model.AccountBlockedDeleted)
 	}
 
 	if user == nil {
 		// 用户不存在，创建新用户
 		user = &model.User{
-			LinuxDoID: linuxDoID,
-			Username:  userInfo.Username,
-			KyxUserID: 0, // 未绑定
+			LinuxDoID:  linuxDoID,
+			Username:   userInfo.Username,
+			KyxUserID:  0, // 未绑定
+			TrustLevel: userInfo.TrustLevel,
+			Active:     userInfo.Active,
+			Silenced:   userInfo.Silenced,
 		}
 		if err := s.userRepo.Create(ctx, user); err != nil {
 			s.logger.WithError(err).Error("Failed to create user")
-			return nil, "", fmt.Errorf("failed to create user: %w", err)
+			return nil, fmt.Errorf("failed to create user: %w", err)
 		}
 		s.logger.WithFields(logrus.Fields{
 			"user_id":     user.ID,
 			"linux_do_id": user.LinuxDoID,
 			"username":    user.Username,
 		}).Info("New user created")
-	} else {
-		// 更新用户名（可能已改名）
-		if user.Username != userInfo.Username {
-			user.Username = userInfo.Username
-			if err := s.userRepo.Update(ctx, user); err != nil {
-				s.logger.WithError(err).Warn("Failed to update username")
-			}
+	} else if user.Username != userInfo.Username || user.TrustLevel != userInfo.TrustLevel ||
+		user.Active != userInfo.Active || user.Silenced != userInfo.Silenced {
+		// 更新用户名（可能已改名）和信任等级、账号状态
+		user.Username = userInfo.Username
+		user.TrustLevel = userInfo.TrustLevel
+		user.Active = userInfo.Active
+		user.Silenced = userInfo.Silenced
+		if err := s.userRepo.UpdateLinuxDoInfo(ctx, user); err != nil {
+			s.logger.WithError(err).Warn("Failed to update Linux.do user info")
 		}
+		_ = s.cacheService.Del(ctx, s.cacheService.UserKey(user.ID), s.cacheService.UserQuotaKey(user.ID))
 	}
 
-	// 创建会话
-	sessionID, err := s.CreateSession(ctx, user)
+	// 定期校验产生的暂停在重新登录后解除（账号状态已在上面以最新信息更新，由信任策略继续把关）
+	if IsVerifierSuspension(user) {
+		if err := s.userRepo.Unsuspend(ctx, linuxDoID); err != nil {
+			s.logger.WithError(err).WithField("linux_do_id", linuxDoID).Warn("Failed to lift user suspension")
+		} else {
+			s.logger.WithFields(logrus.Fields{
+				"linux_do_id": linuxDoID,
+				"reason":      user.SuspendReason,
+			}).Info("User suspension lifted after re-login")
+			user.SuspendedAt.Valid = false
+			user.SuspendReason = ""
+			_ = s.cacheService.Del(ctx, s.cacheService.UserKey(user.ID), s.cacheService.UserQuotaKey(user.ID))
+		}
+	}
+
+	// 记录 Linux.do 身份（迁移前创建的用户在首次登录时补齐）
+	s.recordIdentityLogin(ctx, user, identity)
+
+	// 保存刷新令牌，供后台定期校验账号状态
+	s.verifier.SaveLoginToken(ctx, linuxDoID, token.RefreshToken)
+
+	return user, nil
+}
+
+// loginLinkedIdentity 使用其他提供方的身份登录（身份需要先在登录后关联）
+// 不支持通过其他提供方注册：公益站账号绑定按 Linux.do ID 查找，信任等级和账号状态也来自 Linux.do，
+// 因此每个用户都必须先有 Linux.do 身份，未关联的身份直接拒绝登录
+func (s *AuthService) loginLinkedIdentity(ctx context.Context, identity *model.ExternalIdentity) (*model.User, error) {
+	linked, err := s.identityRepo.GetByProviderSubject(ctx, identity.Provider, identity.Subject)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to create session")
-		return nil, "", fmt.Errorf("failed to create session: %w", err)
+		return nil, fmt.Errorf("failed to get user identity: %w", err)
+	}
+	if linked == nil {
+		s.logger.WithField("provider", identity.Provider).Warn("Login with unlinked identity")
+		return nil, fmt.Errorf("%s account is not linked, sign in with Linux.do and link it first", identity.Provider)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, linked.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	_ = s.identityRepo.TouchLogin(ctx, linked.ID, identity.Username, identity.Email)
+	return user, nil
+}
+
+// recordIdentityLogin 记录身份登录，身份不存在时为用户创建
+func (s *AuthService) recordIdentityLogin(ctx context.Context, user *model.User, identity *model.ExternalIdentity) {
+	existing, err := s.identityRepo.GetByProviderSubject(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		return
+	}
+	if existing != nil {
+		_ = s.identityRepo.TouchLogin(ctx, existing.ID, identity.Username, identity.Email)
+		return
+	}
+
+	_ = s.identityRepo.Create(ctx, &model.UserIdentity{
+		UserID:   user.ID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Username: identity.Username,
+		Email:    identity.Email,
+	})
+}
+
+// linkIdentity 为已登录用户关联其他提供方的身份
+func (s *AuthService) linkIdentity(ctx context.Context, userID int, identity *model.ExternalIdentity) (*model.User, error) {
+	if identity.Provider == model.IdentityProviderLinuxDo {
+		return nil, fmt.Errorf("linux.do identity cannot be linked to another account")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	existing, err := s.identityRepo.GetByProviderSubject(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user identity: %w", err)
+	}
+	if existing != nil {
+		if existing.UserID != userID {
+			return nil, fmt.Errorf("this %s account is already linked to another user", identity.Provider)
+		}
+		_ = s.identityRepo.TouchLogin(ctx, existing.ID, identity.Username, identity.Email)
+		return user, nil
+	}
+
+	err = s.identityRepo.Create(ctx, &model.UserIdentity{
+		UserID:   userID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Username: identity.Username,
+		Email:    identity.Email,
+	})
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return nil, fmt.Errorf("a %s account is already linked, unlink it first", identity.Provider)
+		}
+		return nil, err
 	}
 
 	s.logger.WithFields(logrus.Fields{
-		"user_id":     user.ID,
+		"user_id":     userID,
 		"linux_do_id": user.LinuxDoID,
-		"username":    user.Username,
-		"session_id":  sessionID,
-	}).Info("User logged in successfully")
+		"provider":    identity.Provider,
+	}).Info("Identity linked")
 
-	return user, sessionID, nil
+	return user, nil
 }
 
-// CreateSession 创建会话
-func (s *AuthService) CreateSession(ctx context.Context, user *model.User) (string, error) {
-	// 生成会话ID
-	sessionID, err := s.GenerateState()
+// ListIdentities 获取用户关联的身份
+func (s *AuthService) ListIdentities(ctx context.Context, userID int) ([]*model.UserIdentity, error) {
+	return s.identityRepo.ListByUser(ctx, userID)
+}
+
+// UnlinkIdentity 取消关联其他提供方的身份（Linux.do 身份不能取消）
+func (s *AuthService) UnlinkIdentity(ctx context.Context, userID int, provider string) error {
+	if provider == model.IdentityProviderLinuxDo {
+		return fmt.Errorf("linux.do identity cannot be unlinked")
+	}
+
+	deleted, err := s.identityRepo.Delete(ctx, userID, provider)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate session ID: %w", err)
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("identity not found")
 	}
 
+	s.logger.WithFields(logrus.Fields{
+		"user_id":  userID,
+		"provider": provider,
+	}).Info("Identity unlinked")
+	return nil
+}
+
+// CreateSession 创建会话
+func (s *AuthService) CreateSession(ctx context.Context, user *model.User, meta model.SessionMeta) (string, error) {
+	now := time.Now().Unix()
+
 	// 创建会话数据
 	sessionData := model.JSONMap{
-		"user_id":     user.ID,
-		"linux_do_id": user.LinuxDoID,
-		"username":    user.Username,
-		"created_at":  time.Now().Unix(),
+		"user_id":      user.ID,
+		"linux_do_id":  user.LinuxDoID,
+		"username":     user.Username,
+		"created_at":   now,
+		"ip":           meta.IP,
+		"user_agent":   utils.TruncateString(meta.UserAgent, sessionUserAgentMaxLen, ""),
+		"last_seen_at": now,
+	}
+
+	return s.saveSession(ctx, sessionData)
+}
+
+// saveSession 使用新的会话ID保存会话数据
+func (s *AuthService) saveSession(ctx context.Context, sessionData model.JSONMap) (string, error) {
+	// 生成会话ID
+	sessionID, err := s.GenerateState()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
 	}
 
 	// 保存会话
@@ -248,98 +509,556 @@ func (s *AuthService) GetSession(ctx context.Context, sessionID string) (*model.
 
 // ValidateSession 验证会话
 func (s *AuthService) ValidateSession(ctx context.Context, sessionID string) (*model.User, error) {
-	session, err := s.GetSession(ctx, sessionID)
+	user, _, err := s.validateSession(ctx, sessionID)
+	return user, err
+}
+
+// AuthenticateSession 验证会话并记录客户端最近活跃时间和IP（按 sessionTouchInterval 节流写入）
+func (s *AuthService) AuthenticateSession(ctx context.Context, sessionID string, meta model.SessionMeta) (*model.User, error) {
+	user, session, err := s.validateSession(ctx, sessionID)
 	if err != nil {
 		return nil, err
 	}
 
-	// 从会话数据中提取用户信息
-	linuxDoID, ok := session.Data["linux_do_id"].(string)
-	if !ok {
-		s.logger.WithField("session_id", sessionID).Error("Invalid session data: missing linux_do_id")
-		return nil, fmt.Errorf("invalid session data")
+	now := time.Now()
+	lastSeen := time.Unix(sessionInt64(session.Data, "last_seen_at"), 0)
+	ip, _ := session.Data["ip"].(string)
+	if now.Sub(lastSeen) < sessionTouchInterval && ip == meta.IP {
+		return user, nil
+	}
+
+	session.Data["last_seen_at"] = now.Unix()
+	session.Data["ip"] = meta.IP
+	if meta.UserAgent != "" {
+		session.Data["user_agent"] = utils.TruncateString(meta.UserAgent, sessionUserAgentMaxLen, "")
+	}
+
+	// 保留会话剩余有效期
+	if ttl := time.Until(session.ExpiresAt); ttl > 0 {
+		if err := s.sessionRepo.Update(ctx, sessionID, session.Data, ttl); err != nil {
+			s.logger.WithError(err).WithField("linux_do_id", user.LinuxDoID).Warn("Failed to record session activity")
+		}
+	}
+
+	return user, nil
+}
+
+// validateSession 验证会话，返回会话所属用户和会话
+func (s *AuthService) validateSession(ctx context.Context, sessionID string) (*model.User, *model.Session, error) {
+	session, err := s.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 从会话数据中提取内部用户ID
+	userID := int(sessionInt64(session.Data, "user_id"))
+	if userID <= 0 {
+		s.logger.WithField("session_id", sessionID).Error("Invalid session data: missing user_id")
+		return nil, nil, fmt.Errorf("invalid session data")
 	}
 
 	// 获取用户信息
-	user, err := s.userRepo.GetByLinuxDoID(ctx, linuxDoID)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		s.logger.WithError(err).Error("Failed to get user")
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	if user == nil {
-		s.logger.WithField("linux_do_id", linuxDoID).Warn("User not found for valid session")
-		return nil, fmt.Errorf("user not found")
+		s.logger.WithField("user_id", userID).Warn("User not found for valid session")
+		return nil, nil, fmt.Errorf("user not found")
 	}
 
-	return user, nil
+	return user, session, nil
 }
 
-// RefreshSession 刷新会话
-func (s *AuthService) RefreshSession(ctx context.Context, sessionID string) error {
+// RefreshSession 刷新会话：使用新的会话ID重新签发（防止会话固定）并删除旧会话，返回新的会话ID
+func (s *AuthService) RefreshSession(ctx context.Context, sessionID string, meta model.SessionMeta) (string, error) {
 	session, err := s.GetSession(ctx, sessionID)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	// 更新过期时间
-	newExpiry := time.Now().Add(s.sessionTimeout)
-	session.ExpiresAt = newExpiry
+	session.Data["last_seen_at"] = time.Now().Unix()
+	session.Data["ip"] = meta.IP
+	if meta.UserAgent != "" {
+		session.Data["user_agent"] = utils.TruncateString(meta.UserAgent, sessionUserAgentMaxLen, "")
+	}
 
-	if err := s.sessionRepo.Update(ctx, sessionID, session.Data, s.sessionTimeout); err != nil {
-		s.logger.WithError(err).WithField("session_id", sessionID).Error("Failed to refresh session")
-		return fmt.Errorf("failed to refresh session: %w", err)
+	newSessionID, err := s.saveSession(ctx, session.Data)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to refresh session")
+		return "", fmt.Errorf("failed to refresh session: %w", err)
 	}
 
-	s.logger.WithField("session_id", sessionID).Debug("Session refreshed")
-	return nil
+	if err := s.sessionRepo.Delete(ctx, sessionID); err != nil {
+		s.logger.WithError(err).Warn("Failed to delete rotated session")
+	}
+
+	s.logger.WithField("user_id", session.Data["user_id"]).Debug("Session refreshed and rotated")
+	return newSessionID, nil
 }
 
-// DeleteSession 删除会话（登出）
+// ListUserSessions 获取用户的登录设备列表（最近活跃的在前）
+func (s *AuthService) ListUserSessions(ctx context.Context, userID int, currentSessionID string) ([]*model.UserSessionInfo, error) {
+	sessions, err := s.sessionRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]*model.UserSessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		ip, _ := session.Data["ip"].(string)
+		userAgent, _ := session.Data["user_agent"].(string)
+		infos = append(infos, &model.UserSessionInfo{
+			ID:         SessionHandle(session.SessionID),
+			IP:         ip,
+			UserAgent:  userAgent,
+			CreatedAt:  sessionInt64(session.Data, "created_at"),
+			LastSeenAt: sessionInt64(session.Data, "last_seen_at"),
+			ExpiresAt:  session.ExpiresAt.Unix(),
+			Current:    session.SessionID == currentSessionID,
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].LastSeenAt > infos[j].LastSeenAt
+	})
+
+	return infos, nil
+}
+
+// RevokeUserSession 按会话标识删除用户的某个会话，返回被删除的会话ID
+func (s *AuthService) RevokeUserSession(ctx context.Context, userID int, handle string) (string, error) {
+	sessions, err := s.sessionRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, session := range sessions {
+		if SessionHandle(session.SessionID) != handle {
+			continue
+		}
+		if err := s.sessionRepo.Delete(ctx, session.SessionID); err != nil {
+			return "", err
+		}
+		s.logger.WithField("user_id", userID).Info("User session revoked")
+		return session.SessionID, nil
+	}
+
+	return "", fmt.Errorf("session not found")
+}
+
+// RevokeAllUserSessions 删除用户的全部会话（exceptSessionID 非空时保留当前会话），返回删除的会话数
+// 退出全部设备时同时撤销保存的 Linux.do 刷新令牌
+func (s *AuthService) RevokeAllUserSessions(ctx context.Context, user *model.User, exceptSessionID string) (int, error) {
+	revoked, err := s.sessionRepo.DeleteByUser(ctx, user.ID, exceptSessionID)
+	if err != nil {
+		return revoked, err
+	}
+
+	if exceptSessionID == "" {
+		s.verifier.RevokeUserToken(ctx, user.LinuxDoID)
+	}
+
+	return revoked, nil
+}
+
+// SessionHandle 会话对外展示的标识（会话ID的哈希），避免在接口中暴露会话ID本身
+func SessionHandle(sessionID string) string {
+	return utils.HashSHA256(sessionID)[:16]
+}
+
+// sessionInt64 读取会话数据中的整数（从 JSON 恢复的数字为 float64）
+func sessionInt64(data model.JSONMap, key string) int64 {
+	switch v := data[key].(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
+
+// DeleteSession 删除会话（登出），用户最后一个会话退出时撤销保存的 Linux.do 刷新令牌
 func (s *AuthService) DeleteSession(ctx context.Context, sessionID string) error {
+	var userID int
+	var linuxDoID string
+	if session, err := s.sessionRepo.Get(ctx, sessionID); err == nil && session != nil {
+		userID = int(sessionInt64(session.Data, "user_id"))
+		linuxDoID, _ = session.Data["linux_do_id"].(string)
+	}
+
 	if err := s.sessionRepo.Delete(ctx, sessionID); err != nil {
 		s.logger.WithError(err).WithField("session_id", sessionID).Error("Failed to delete session")
 		return fmt.Errorf("failed to delete session: %w", err)
 	}
 
+	if userID > 0 && linuxDoID != "" {
+		if remaining, err := s.sessionRepo.ListByUser(ctx, userID); err == nil && len(remaining) == 0 {
+			s.verifier.RevokeUserToken(ctx, linuxDoID)
+		}
+	}
+
 	s.logger.WithField("session_id", sessionID).Info("Session deleted")
 	return nil
 }
 
+const (
+	// sessionTouchInterval 记录会话活跃时间的最小间隔
+	sessionTouchInterval = 5 * time.Minute
+	// sessionUserAgentMaxLen 会话中保存的 User-Agent 最大长度
+	sessionUserAgentMaxLen = 512
+)
+
+const (
+	// adminTokenTTL 管理员令牌有效期
+	adminTokenTTL = 24 * time.Hour
+	// adminOTPChallengeTTL 两步验证登录挑战有效期
+	adminOTPChallengeTTL = 5 * time.Minute
+	// adminOTPMaxAttempts 每个登录挑战允许的验证码尝试次数
+	adminOTPMaxAttempts = 5
+	// totpSkew 允许的时钟偏差（前后各一个时间步）
+	totpSkew = 1
+	// totpIssuer 验证器应用中显示的发行方
+	totpIssuer = "Kyx Quota Bridge"
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+)
+
+// dummyPasswordHash 管理员不存在时用于比对的哈希，使登录耗时与用户名是否存在无关
+var dummyPasswordHash, _ = utils.HashPassword("kyx-quota-bridge-dummy-password")
+
+// BootstrapAdmin 管理员表为空时使用配置的用户名和密码创建首个 owner 账号
+func (s *AuthService) BootstrapAdmin(ctx context.Context, username, password string) error {
+	count, err := s.adminRepo.Count(ctx)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if username == "" || password == "" {
+		s.logger.Warn("No admin accounts exist and ADMIN_USERNAME/ADMIN_PASSWORD are not set, admin API is unavailable")
+		return nil
+	}
+
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash admin password: %w", err)
+	}
+
+	admin := &model.Admin{
+		Username:     username,
+		PasswordHash: hash,
+		Role:         model.AdminRoleOwner,
+	}
+	if err := s.adminRepo.Create(ctx, admin); err != nil {
+		return err
+	}
+
+	s.logger.WithField("username", username).Info("Bootstrap owner admin created")
+	return nil
+}
+
 // AdminLogin 管理员登录
-func (s *AuthService) AdminLogin(ctx context.Context, password string) (string, error) {
-	if s.adminPassword == "" {
-		s.logger.Error("Admin password not configured")
-		return "", fmt.Errorf("admin authentication not configured")
+// 未启用两步验证时直接返回令牌；已启用时返回登录挑战，需调用 AdminLoginOTP 提交验证码
+func (s *AuthService) AdminLogin(ctx context.Context, username, password string) (*model.AdminLoginResponse, error) {
+	if username == "" {
+		username = s.defaultAdmin
+	}
+
+	admin, err := s.adminRepo.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin: %w", err)
+	}
+
+	if admin == nil {
+		utils.CheckPasswordHash(password, dummyPasswordHash)
+		s.logger.WithField("username", username).Warn("Admin login attempt for unknown username")
+		return nil, fmt.Errorf("invalid username or password")
+	}
+
+	if !utils.CheckPasswordHash(password, admin.PasswordHash) {
+		s.logger.WithField("username", username).Warn("Invalid admin password attempt")
+		return nil, fmt.Errorf("invalid username or password")
+	}
+
+	if admin.Disabled {
+		s.logger.WithField("username", username).Warn("Disabled admin login attempt")
+		return nil, fmt.Errorf("admin account disabled")
+	}
+
+	if admin.TOTPEnabled {
+		challenge, err := s.GenerateState()
+		if err != nil {
+			return nil, err
+		}
+		key := model.CacheKeyAdminOTPChallenge + challenge
+		if err := s.cacheService.Set(ctx, key, strconv.Itoa(admin.ID), adminOTPChallengeTTL); err != nil {
+			s.logger.WithError(err).Error("Failed to save admin otp challenge")
+			return nil, fmt.Errorf("failed to create login challenge: %w", err)
+		}
+
+		s.logger.WithField("admin_id", admin.ID).Info("Admin password verified, awaiting two-factor code")
+		return &model.AdminLoginResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		}, nil
+	}
+
+	return s.completeAdminLogin(ctx, admin)
+}
+
+// AdminLoginOTP 两步验证登录：校验登录挑战和验证码（或恢复码）后返回令牌
+func (s *AuthService) AdminLoginOTP(ctx context.Context, challengeToken, code string) (*model.AdminLoginResponse, error) {
+	key := model.CacheKeyAdminOTPChallenge + challengeToken
+	attemptsKey := key + ":attempts"
+
+	value, err := s.cacheService.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login challenge: %w", err)
+	}
+	adminID, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired login challenge")
+	}
+
+	// 限制单个挑战的尝试次数，超出后挑战作废需重新输入密码
+	attempts, err := s.cacheService.Incr(ctx, attemptsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count otp attempts: %w", err)
+	}
+	if attempts == 1 {
+		_ = s.cacheService.Expire(ctx, attemptsKey, adminOTPChallengeTTL)
+	}
+	if attempts > adminOTPMaxAttempts {
+		_ = s.cacheService.Del(ctx, key, attemptsKey)
+		s.logger.WithField("admin_id", adminID).Warn("Too many admin otp attempts")
+		return nil, fmt.Errorf("too many attempts, please log in again")
 	}
 
-	if password != s.adminPassword {
-		s.logger.Warn("Invalid admin password attempt")
-		return "", fmt.Errorf("invalid password")
+	admin, err := s.adminRepo.GetByID(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if admin == nil || admin.Disabled {
+		_ = s.cacheService.Del(ctx, key, attemptsKey)
+		return nil, fmt.Errorf("admin account not found or disabled")
+	}
+	if !admin.TOTPEnabled {
+		_ = s.cacheService.Del(ctx, key, attemptsKey)
+		return nil, fmt.Errorf("two-factor authentication is no longer enabled, please log in again")
 	}
 
+	if err := s.verifyAdminOTP(ctx, admin, code); err != nil {
+		s.logger.WithError(err).WithField("admin_id", admin.ID).Warn("Invalid admin otp attempt")
+		return nil, err
+	}
+
+	_ = s.cacheService.Del(ctx, key, attemptsKey)
+	return s.completeAdminLogin(ctx, admin)
+}
+
+// completeAdminLogin 签发管理员令牌并记录登录时间
+func (s *AuthService) completeAdminLogin(ctx context.Context, admin *model.Admin) (*model.AdminLoginResponse, error) {
 	// 生成JWT token
-	token, err := s.GenerateAdminToken()
+	token, err := s.GenerateAdminToken(admin)
 	if err != nil {
 		s.logger.WithError(err).Error("Failed to generate admin token")
-		return "", fmt.Errorf("failed to generate token: %w", err)
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	if err := s.adminRepo.UpdateLastLogin(ctx, admin.ID); err != nil {
+		s.logger.WithError(err).Warn("Failed to update admin last login")
 	}
 
-	s.logger.Info("Admin logged in successfully")
-	return token, nil
+	s.logger.WithFields(logrus.Fields{
+		"admin_id": admin.ID,
+		"username": admin.Username,
+		"role":     admin.Role,
+	}).Info("Admin logged in successfully")
+	return &model.AdminLoginResponse{
+		Token: token,
+		Admin: admin,
+	}, nil
+}
+
+// verifyAdminOTP 校验已启用两步验证的管理员提交的验证码或恢复码
+// 验证码的时间步必须大于上次使用的时间步，恢复码使用后即失效
+func (s *AuthService) verifyAdminOTP(ctx context.Context, admin *model.Admin, code string) error {
+	if counter, ok := totp.ValidateAfter(admin.TOTPSecret.String, code, time.Now(), totpSkew, admin.TOTPLastCounter); ok {
+		// 并发提交同一验证码时由数据库条件更新保证只有一次成功
+		used, err := s.adminRepo.UseTOTPCounter(ctx, admin.ID, counter)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return fmt.Errorf("verification code already used")
+		}
+		return nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if normalized != "" {
+		used, err := s.adminRepo.UseRecoveryCode(ctx, admin.ID, utils.HashSHA256(normalized))
+		if err != nil {
+			return err
+		}
+		if used {
+			s.logger.WithField("admin_id", admin.ID).Warn("Admin recovery code used")
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid verification code")
+}
+
+// SetupAdminTOTP 为管理员生成新的 TOTP 密钥（待验证），返回密钥和二维码使用的 URI
+func (s *AuthService) SetupAdminTOTP(ctx context.Context, admin *model.Admin) (*model.TOTPSetupResponse, error) {
+	if admin.TOTPEnabled {
+		return nil, fmt.Errorf("two-factor authentication already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.adminRepo.SetPendingTOTP(ctx, admin.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &model.TOTPSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(totpIssuer, admin.Username, secret),
+	}, nil
+}
+
+// EnableAdminTOTP 使用验证器生成的验证码确认绑定并启用两步验证，返回一次性恢复码
+func (s *AuthService) EnableAdminTOTP(ctx context.Context, adminID int, code string) ([]string, error) {
+	admin, err := s.adminRepo.GetByID(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if admin == nil {
+		return nil, fmt.Errorf("admin not found")
+	}
+	if admin.TOTPEnabled {
+		return nil, fmt.Errorf("two-factor authentication already enabled")
+	}
+	if !admin.TOTPSecret.Valid || admin.TOTPSecret.String == "" {
+		return nil, fmt.Errorf("two-factor authentication setup not started")
+	}
+
+	counter, ok := totp.Validate(admin.TOTPSecret.String, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, fmt.Errorf("invalid verification code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.adminRepo.EnableTOTP(ctx, admin.ID, counter, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，之前的恢复码全部失效
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, adminID int, code string) ([]string, error) {
+	admin, err := s.getTOTPEnabledAdmin(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyAdminOTP(ctx, admin, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.adminRepo.SetRecoveryCodes(ctx, admin.ID, hashes); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithField("admin_id", admin.ID).Info("Admin recovery codes regenerated")
+	return codes, nil
+}
+
+// DisableAdminTOTP 管理员验证后关闭自己的两步验证
+func (s *AuthService) DisableAdminTOTP(ctx context.Context, adminID int, code string) error {
+	admin, err := s.getTOTPEnabledAdmin(ctx, adminID)
+	if err != nil {
+		return err
+	}
+	if err := s.verifyAdminOTP(ctx, admin, code); err != nil {
+		return err
+	}
+
+	return s.adminRepo.DisableTOTP(ctx, admin.ID)
+}
+
+// getTOTPEnabledAdmin 获取已启用两步验证的管理员
+func (s *AuthService) getTOTPEnabledAdmin(ctx context.Context, adminID int) (*model.Admin, error) {
+	admin, err := s.adminRepo.GetByID(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if admin == nil {
+		return nil, fmt.Errorf("admin not found")
+	}
+	if !admin.TOTPEnabled {
+		return nil, fmt.Errorf("two-factor authentication is not enabled")
+	}
+	return admin, nil
 }
 
-// GenerateAdminToken 生成管理员JWT token
-func (s *AuthService) GenerateAdminToken() (string, error) {
+// generateRecoveryCodes 生成恢复码（xxxx-xxxx 格式）及其 SHA-256 哈希
+func generateRecoveryCodes() ([]string, model.JSONArray, error) {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make(model.JSONArray, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		for j := range b {
+			b[j] = alphabet[int(b[j])%len(alphabet)]
+		}
+		codes[i] = string(b[:4]) + "-" + string(b[4:])
+		hashes[i] = utils.HashSHA256(normalizeRecoveryCode(codes[i]))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode 规范化恢复码（忽略大小写、空格和连字符）
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// GenerateAdminToken 生成管理员JWT token，角色写入 role 声明
+func (s *AuthService) GenerateAdminToken(admin *model.Admin) (string, error) {
 	if s.jwtSecret == "" {
 		return "", fmt.Errorf("JWT secret not configured")
 	}
 
 	// 创建token
 	claims := jwt.MapClaims{
-		"role": "admin",
-		"iat":  time.Now().Unix(),
-		"exp":  time.Now().Add(24 * time.Hour).Unix(),
+		"sub":      strconv.Itoa(admin.ID),
+		"username": admin.Username,
+		"role":     admin.Role,
+		"iat":      time.Now().Unix(),
+		"exp":      time.Now().Add(adminTokenTTL).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -351,56 +1070,58 @@ func (s *AuthService) GenerateAdminToken() (string, error) {
 	return tokenString, nil
 }
 
-// ValidateAdminToken 验证管理员JWT token
-func (s *AuthService) ValidateAdminToken(tokenString string) error {
+// ValidateAdminToken 验证管理员JWT token，返回当前的管理员账号
+// 账号被删除、停用或角色变更后，之前签发的令牌立即失效
+func (s *AuthService) ValidateAdminToken(ctx context.Context, tokenString string) (*model.Admin, error) {
 	if s.jwtSecret == "" {
-		return fmt.Errorf("JWT secret not configured")
+		return nil, fmt.Errorf("JWT secret not configured")
 	}
 
-	// 解析token
+	// 解析token（同时校验过期时间）
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		// 验证签名方法
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(s.jwtSecret), nil
-	})
+	}, jwt.WithExpirationRequired())
 
 	if err != nil {
 		s.logger.WithError(err).Warn("Invalid admin token")
-		return fmt.Errorf("invalid token: %w", err)
+		return nil, fmt.Errorf("invalid token: %w", err)
 	}
 
 	// 验证claims
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || !token.Valid {
 		s.logger.Warn("Invalid token claims")
-		return fmt.Errorf("invalid token claims")
+		return nil, fmt.Errorf("invalid token claims")
+	}
+
+	subject, _ := claims["sub"].(string)
+	adminID, err := strconv.Atoi(subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token subject")
 	}
 
-	// 验证角色
 	role, ok := claims["role"].(string)
-	if !ok || role != "admin" {
+	if !ok || !model.IsValidAdminRole(role) {
 		s.logger.Warn("Invalid admin role in token")
-		return fmt.Errorf("invalid role")
+		return nil, fmt.Errorf("invalid role")
 	}
 
-	// 验证过期时间
-	exp, ok := claims["exp"].(float64)
-	if !ok {
-		return fmt.Errorf("invalid expiration time")
+	admin, err := s.adminRepo.GetByID(ctx, adminID)
+	if err != nil {
+		return nil, err
 	}
-
-	if time.Now().Unix() > int64(exp) {
-		return fmt.Errorf("token expired")
+	if admin == nil || admin.Disabled {
+		return nil, fmt.Errorf("admin account not found or disabled")
+	}
+	if admin.Role != role {
+		return nil, fmt.Errorf("admin role changed, please log in again")
 	}
 
-	return nil
-}
-
-// IsAdmin 检查是否为管理员
-func (s *AuthService) IsAdmin(tokenString string) bool {
-	return s.ValidateAdminToken(tokenString) == nil
+	return admin, nil
 }
 
 // CleanExpiredSessions 清理过期会话
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+)
+
+// maxSuspendHours 暂停最长时长（更长的处置应使用封禁）
+const maxSuspendHours = 24 * 365
+
+// BanService 用户封禁、暂停和观察名单服务
+type BanService struct {
+	banRepo      *repository.BanRepository
+	userRepo     *repository.UserRepository
+	sessionRepo  *repository.SessionRepository
+	cacheService *CacheService
+	logger       *logrus.Logger
+}
+
+// NewBanService 创建用户处置服务
+func NewBanService(
+	banRepo *repository.BanRepository,
+	userRepo *repository.UserRepository,
+	sessionRepo *repository.SessionRepository,
+	cacheService *CacheService,
+	logger *logrus.Logger,
+) *BanService {
+	return &BanService{
+		banRepo:      banRepo,
+		userRepo:     userRepo,
+		sessionRepo:  sessionRepo,
+		cacheService: cacheService,
+		logger:       logger,
+	}
+}
+
+// BanMessage 生成展示给用户的处置说明
+func BanMessage(ban *model.UserBan) string {
+	switch ban.Kind {
+	case model.UserBanKindBan:
+		return "account is banned: " + ban.Reason
+	case model.UserBanKindSuspend:
+		return fmt.Sprintf("account is suspended until %s: %s", ban.ExpiresAt.Time.Format(time.RFC3339), ban.Reason)
+	}
+	return ban.Reason
+}
+
+// Restriction 获取限制 Linux.do ID 或公益站用户ID 操作的生效处置（封禁优先），没有时返回 nil
+func (s *BanService) Restriction(ctx context.Context, linuxDoID string, kyxUserID int) (*model.UserBan, error) {
+	bans, err := s.banRepo.ListActive(ctx, linuxDoID, kyxUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ban := range bans {
+		if ban.BlockedReason() != "" {
+			return ban, nil
+		}
+	}
+	return nil, nil
+}
+
+// Check 检查是否允许执行操作：被封禁或暂停时返回以原因开头的错误，在观察名单中时记录告警日志
+func (s *BanService) Check(ctx context.Context, linuxDoID string, kyxUserID int, action string) error {
+	bans, err := s.banRepo.ListActive(ctx, linuxDoID, kyxUserID)
+	if err != nil {
+		return fmt.Errorf("failed to check user bans: %w", err)
+	}
+
+	for _, ban := range bans {
+		fields := logrus.Fields{
+			"linux_do_id": linuxDoID,
+			"kyx_user_id": kyxUserID,
+			"action":      action,
+			"ban_id":      ban.ID,
+			"kind":        ban.Kind,
+		}
+
+		if reason := ban.BlockedReason(); reason != "" {
+			s.logger.WithFields(fields).Warn("Action blocked by user ban")
+			return fmt.Errorf("%s: %s", reason, BanMessage(ban))
+		}
+
+		s.logger.WithFields(fields).WithField("reason", ban.Reason).Warn("Watchlisted user action")
+	}
+
+	return nil
+}
+
+// CreateBan 封禁、暂停用户或加入观察名单，封禁和暂停会清除已存在用户的登录会话
+func (s *BanService) CreateBan(ctx context.Context, admin *model.Admin, req *model.CreateUserBanRequest) (*model.UserBan, error) {
+	linuxDoID := strings.TrimSpace(req.LinuxDoID)
+	if linuxDoID == "" && req.KyxUserID <= 0 {
+		return nil, fmt.Errorf("linux_do_id or kyx_user_id is required")
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("reason is required")
+	}
+
+	ban := &model.UserBan{
+		Kind:        req.Kind,
+		LinuxDoID:   linuxDoID,
+		KyxUserID:   req.KyxUserID,
+		Reason:      reason,
+		CreatedByID: sql.NullInt64{Int64: int64(admin.ID), Valid: true},
+		CreatedBy:   admin.Username,
+	}
+
+	switch req.Kind {
+	case model.UserBanKindSuspend:
+		if req.DurationHours <= 0 || req.DurationHours > maxSuspendHours {
+			return nil, fmt.Errorf("duration_hours must be between 1 and %d for a suspension", maxSuspendHours)
+		}
+	case model.UserBanKindBan, model.UserBanKindWatch:
+	default:
+		return nil, fmt.Errorf("invalid ban kind: %s", req.Kind)
+	}
+	if req.DurationHours > 0 {
+		ban.ExpiresAt = sql.NullTime{Time: time.Now().Add(time.Duration(req.DurationHours) * time.Hour), Valid: true}
+	}
+
+	if err := s.banRepo.Create(ctx, ban); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"ban_id":      ban.ID,
+		"kind":        ban.Kind,
+		"linux_do_id": ban.LinuxDoID,
+		"kyx_user_id": ban.KyxUserID,
+		"admin_id":    admin.ID,
+	}).Warn("User ban applied")
+
+	if ban.BlockedReason() != "" {
+		s.logoutTargets(ctx, ban)
+	}
+
+	return ban, nil
+}
+
+// logoutTargets 清除处置目标用户的登录会话和缓存（用户尚未登录时跳过）
+func (s *BanService) logoutTargets(ctx context.Context, ban *model.UserBan) {
+	users := make([]*model.User, 0, 2)
+	if ban.LinuxDoID != "" {
+		user, err := s.userRepo.GetByLinuxDoID(ctx, ban.LinuxDoID)
+		if err != nil {
+			s.logger.WithError(err).WithField("linux_do_id", ban.LinuxDoID).Warn("Failed to find user for ban")
+		} else if user != nil {
+			users = append(users, user)
+		}
+	}
+	if ban.KyxUserID > 0 {
+		user, err := s.userRepo.GetByKyxUserID(ctx, ban.KyxUserID)
+		if err != nil {
+			s.logger.WithError(err).WithField("kyx_user_id", ban.KyxUserID).Warn("Failed to find user for ban")
+		} else if user != nil && user.LinuxDoID != ban.LinuxDoID {
+			users = append(users, user)
+		}
+	}
+
+	for _, user := range users {
+		count, err := s.sessionRepo.DeleteByUser(ctx, user.ID, "")
+		if err != nil {
+			s.logger.WithError(err).WithField("linux_do_id", user.LinuxDoID).Warn("Failed to revoke sessions of banned user")
+			continue
+		}
+		_ = s.cacheService.ClearUserCache(ctx, user.ID)
+
+		if count > 0 {
+			s.logger.WithFields(logrus.Fields{
+				"linux_do_id": user.LinuxDoID,
+				"sessions":    count,
+			}).Info("Banned user logged out")
+		}
+	}
+}
+
+// LiftBan 解除处置
+func (s *BanService) LiftBan(ctx context.Context, admin *model.Admin, banID int64, reason string) (*model.UserBan, error) {
+	ban, err := s.banRepo.GetByID(ctx, banID)
+	if err != nil {
+		return nil, err
+	}
+	if ban == nil {
+		return nil, fmt.Errorf("ban not found")
+	}
+
+	ban.LiftedByID = sql.NullInt64{Int64: int64(admin.ID), Valid: true}
+	ban.LiftedBy = admin.Username
+	ban.LiftReason = strings.TrimSpace(reason)
+
+	lifted, err := s.banRepo.Lift(ctx, ban)
+	if err != nil {
+		return nil, err
+	}
+	if !lifted {
+		return nil, fmt.Errorf("ban already lifted")
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"ban_id":      ban.ID,
+		"kind":        ban.Kind,
+		"linux_do_id": ban.LinuxDoID,
+		"kyx_user_id": ban.KyxUserID,
+		"admin_id":    admin.ID,
+	}).Info("User ban lifted")
+
+	return ban, nil
+}
+
+// ListBans 分页获取处置记录
+func (s *BanService) ListBans(ctx context.Context, filter model.UserBanFilter, page, pageSize int) ([]*model.UserBan, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	bans, err := s.banRepo.List(ctx, filter, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := s.banRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return bans, total, nil
+}
+
+// GetUserStatus 获取用户处置状态和全部处置历史（用户不存在时按 Linux.do ID 查询）
+// 定期校验产生的暂停（users.suspended_at）同样体现为 suspended
+func (s *BanService) GetUserStatus(ctx context.Context, linuxDoID string) (*model.UserStatus, []*model.UserBan, error) {
+	user, err := s.userRepo.GetByLinuxDoID(ctx, linuxDoID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kyxUserID := 0
+	if user != nil {
+		kyxUserID = user.KyxUserID
+	}
+
+	active, err := s.banRepo.ListActive(ctx, linuxDoID, kyxUserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status := &model.UserStatus{Status: model.UserStatusActive}
+	for _, ban := range active {
+		if ban.Kind == model.UserBanKindWatch {
+			status.Watched = true
+			continue
+		}
+		if status.BanID != 0 {
+			continue
+		}
+
+		status.BanID = ban.ID
+		status.Reason = ban.Reason
+		if ban.Kind == model.UserBanKindBan {
+			status.Status = model.UserStatusBanned
+		} else {
+			status.Status = model.UserStatusSuspended
+		}
+		if ban.ExpiresAt.Valid {
+			until := ban.ExpiresAt.Time
+			status.Until = &until
+		}
+	}
+
+	if status.BanID == 0 && user != nil && user.SuspendedAt.Valid {
+		status.Status = model.UserStatusSuspended
+		status.Reason = user.SuspendReason
+	}
+
+	history, err := s.banRepo.List(ctx, model.UserBanFilter{LinuxDoID: linuxDoID, KyxUserID: kyxUserID}, 100, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return status, history, nil
+}
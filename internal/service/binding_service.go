@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+)
+
+// errUnbindNotPending 解绑申请已被处理或撤回
+var errUnbindNotPending = errors.New("unbind request is not pending")
+
+// BindingService 解绑申请和绑定历史服务
+// 用户提交解绑申请后由管理员批准或拒绝，批准后解除绑定并结束当前绑定记录，之后可以绑定其他公益站账号
+type BindingService struct {
+	bindingRepo  *repository.BindingRepository
+	userRepo     *repository.UserRepository
+	cacheService *CacheService
+	cooldown     time.Duration
+	logger       *logrus.Logger
+}
+
+// NewBindingService 创建解绑申请和绑定历史服务（cooldown 为绑定后多久才能申请解绑）
+func NewBindingService(
+	bindingRepo *repository.BindingRepository,
+	userRepo *repository.UserRepository,
+	cacheService *CacheService,
+	cooldown time.Duration,
+	logger *logrus.Logger,
+) *BindingService {
+	return &BindingService{
+		bindingRepo:  bindingRepo,
+		userRepo:     userRepo,
+		cacheService: cacheService,
+		cooldown:     cooldown,
+		logger:       logger,
+	}
+}
+
+// RequestUnbind 提交解绑申请：需要已绑定公益站账号且绑定时间超过冷却期，同一时间只能有一个待处理的申请
+func (s *BindingService) RequestUnbind(ctx context.Context, linuxDoID, reason string) (*model.UnbindRequest, error) {
+	user, err := s.userRepo.GetByLinuxDoID(ctx, linuxDoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if user.KyxUserID == 0 {
+		return nil, fmt.Errorf("account not bound")
+	}
+
+	binding, err := s.bindingRepo.GetOpenBinding(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if binding != nil && s.cooldown > 0 {
+		if allowedAt := binding.BoundAt.Add(s.cooldown); time.Now().Before(allowedAt) {
+			return nil, fmt.Errorf("unbind is not allowed until %s", allowedAt.Format(time.RFC3339))
+		}
+	}
+
+	req := &model.UnbindRequest{
+		UserID:      user.ID,
+		LinuxDoID:   linuxDoID,
+		KyxUserID:   user.KyxUserID,
+		KyxUsername: user.Username,
+		Reason:      strings.TrimSpace(reason),
+	}
+	if err := s.bindingRepo.CreateUnbindRequest(ctx, req); err != nil {
+		if database.IsUniqueViolation(err) {
+			return nil, fmt.Errorf("unbind request already pending")
+		}
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"request_id":  req.ID,
+		"linux_do_id": linuxDoID,
+		"kyx_user_id": req.KyxUserID,
+	}).Info("Unbind request created")
+
+	return req, nil
+}
+
+// GetUnbindRequest 获取用户最近一次解绑申请，没有时返回 nil
+func (s *BindingService) GetUnbindRequest(ctx context.Context, linuxDoID string) (*model.UnbindRequest, error) {
+	user, err := s.userRepo.GetByLinuxDoID(ctx, linuxDoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	return s.bindingRepo.GetLatestUnbindRequest(ctx, user.ID)
+}
+
+// CancelUnbind 撤回待处理的解绑申请
+func (s *BindingService) CancelUnbind(ctx context.Context, linuxDoID string) error {
+	user, err := s.userRepo.GetByLinuxDoID(ctx, linuxDoID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+
+	cancelled, err := s.bindingRepo.CancelUnbindRequest(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return fmt.Errorf("no pending unbind request")
+	}
+
+	s.logger.WithField("linux_do_id", linuxDoID).Info("Unbind request cancelled")
+	return nil
+}
+
+// ListUnbindRequests 分页获取解绑申请（status 为空时返回全部）
+func (s *BindingService) ListUnbindRequests(ctx context.Context, status string, page, pageSize int) (*model.PaginationResult, error) {
+	switch status {
+	case "", model.UnbindStatusPending, model.UnbindStatusApproved, model.UnbindStatusRejected, model.UnbindStatusCancelled:
+	default:
+		return nil, fmt.Errorf("invalid status: %s", status)
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	reqs, err := s.bindingRepo.ListUnbindRequests(ctx, status, pageSize, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.bindingRepo.CountUnbindRequests(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+
+	return &model.PaginationResult{
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+		TotalPages: totalPages,
+		HasMore:    page < totalPages,
+		Data:       reqs,
+	}, nil
+}
+
+// ListBindingHistory 获取 Linux.do ID 的绑定历史
+func (s *BindingService) ListBindingHistory(ctx context.Context, linuxDoID string) ([]*model.BindingHistory, error) {
+	return s.bindingRepo.ListHistory(ctx, linuxDoID)
+}
+
+// ApproveUnbind 批准解绑申请：在一个事务中更新申请状态、解除绑定并结束当前绑定记录
+// 用户已不再绑定申请时的公益站账号时整体回滚
+func (s *BindingService) ApproveUnbind(ctx context.Context, admin *model.Admin, id int64, note string) (*model.UnbindRequest, error) {
+	req, err := s.getPendingRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.fillReview(req, admin, model.UnbindStatusApproved, note)
+
+	err = s.userRepo.Transaction(ctx, func(tx *sqlx.Tx) error {
+		reviewed, err := s.bindingRepo.ReviewUnbindRequestTx(ctx, tx, req)
+		if err != nil {
+			return err
+		}
+		if !reviewed {
+			return errUnbindNotPending
+		}
+
+		unbound, err := s.userRepo.UnbindTx(ctx, tx, req.UserID, req.KyxUserID)
+		if err != nil {
+			return err
+		}
+		if !unbound {
+			return fmt.Errorf("user is no longer bound to kyx account %d", req.KyxUserID)
+		}
+
+		return s.bindingRepo.CloseBindingTx(ctx, tx, req.UserID, req.ID)
+	})
+	if err != nil {
+		if errors.Is(err, errUnbindNotPending) {
+			return nil, errUnbindNotPending
+		}
+		return nil, fmt.Errorf("failed to approve unbind request: %w", err)
+	}
+
+	_ = s.cacheService.ClearUserCache(ctx, req.UserID)
+
+	s.logger.WithFields(logrus.Fields{
+		"request_id":  req.ID,
+		"linux_do_id": req.LinuxDoID,
+		"kyx_user_id": req.KyxUserID,
+		"admin_id":    admin.ID,
+	}).Info("Unbind request approved")
+
+	return req, nil
+}
+
+// RejectUnbind 拒绝解绑申请
+func (s *BindingService) RejectUnbind(ctx context.Context, admin *model.Admin, id int64, note string) (*model.UnbindRequest, error) {
+	req, err := s.getPendingRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.fillReview(req, admin, model.UnbindStatusRejected, note)
+
+	err = s.userRepo.Transaction(ctx, func(tx *sqlx.Tx) error {
+		reviewed, err := s.bindingRepo.ReviewUnbindRequestTx(ctx, tx, req)
+		if err != nil {
+			return err
+		}
+		if !reviewed {
+			return errUnbindNotPending
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errUnbindNotPending) {
+			return nil, errUnbindNotPending
+		}
+		return nil, fmt.Errorf("failed to reject unbind request: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"request_id":  req.ID,
+		"linux_do_id": req.LinuxDoID,
+		"admin_id":    admin.ID,
+	}).Info("Unbind request rejected")
+
+	return req, nil
+}
+
+// getPendingRequest 获取待处理的解绑申请
+func (s *BindingService) getPendingRequest(ctx context.Context, id int64) (*model.UnbindRequest, error) {
+	req, err := s.bindingRepo.GetUnbindRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req == nil {
+		return nil, fmt.Errorf("unbind request not found")
+	}
+	if req.Status != model.UnbindStatusPending {
+		return nil, errUnbindNotPending
+	}
+	return req, nil
+}
+
+// fillReview 填写申请的处理结果
+func (s *BindingService) fillReview(req *model.UnbindRequest, admin *model.Admin, status, note string) {
+	req.Status = status
+	req.ReviewedByID = sql.NullInt64{Int64: int64(admin.ID), Valid: true}
+	req.ReviewedBy = admin.Username
+	req.ReviewNote = strings.TrimSpace(note)
+}
@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -24,28 +27,27 @@ func NewCacheService(cache *cache.Redis, logger *logrus.Logger) *CacheService {
 	}
 }
 
-// 缓存键生成方法
+// 缓存键生成方法（用户相关的缓存键按内部用户ID区分）
 
 // UserKey 生成用户缓存键
-func (s *CacheService) UserKey(linuxDoID string) string {
-	return model.CacheKeyUser + linuxDoID
+func (s *CacheService) UserKey(userID int) string {
+	return model.CacheKeyUser + strconv.Itoa(userID)
 }
 
 // UserQuotaKey 生成用户额度缓存键
-func (s *CacheService) UserQuotaKey(linuxDoID string) string {
-	return model.CacheKeyUserQuota + linuxDoID
+func (s *CacheService) UserQuotaKey(userID int) string {
+	return model.CacheKeyUserQuota + strconv.Itoa(userID)
 }
 
-// ClaimTodayKey 生成今日领取缓存键
-func (s *CacheService) ClaimTodayKey(linuxDoID string) string {
-	today := time.Now().Format("2006-01-02")
-	return model.CacheKeyClaimToday + linuxDoID + ":" + today
+// ClaimNextKey 生成下次可领取时间缓存键
+func (s *CacheService) ClaimNextKey(userID int) string {
+	return model.CacheKeyClaimNext + strconv.Itoa(userID)
 }
 
 // DonateCountKey 生成投喂计数缓存键
-func (s *CacheService) DonateCountKey(linuxDoID string) string {
+func (s *CacheService) DonateCountKey(userID int) string {
 	today := time.Now().Format("2006-01-02")
-	return model.CacheKeyDonateCount + linuxDoID + ":" + today
+	return model.CacheKeyDonateCount + strconv.Itoa(userID) + ":" + today
 }
 
 // SessionKey 生成会话缓存键
@@ -59,8 +61,8 @@ func (s *CacheService) RateLimitLoginKey(ip string) string {
 }
 
 // RateLimitDonateKey 生成投喂限流缓存键
-func (s *CacheService) RateLimitDonateKey(linuxDoID string) string {
-	return model.RateLimitDonate + linuxDoID
+func (s *CacheService) RateLimitDonateKey(userID int) string {
+	return model.RateLimitDonate + strconv.Itoa(userID)
 }
 
 // RateLimitAPIKey 生成API限流缓存键
@@ -68,6 +70,21 @@ func (s *CacheService) RateLimitAPIKey(identifier string) string {
 	return model.RateLimitAPI + identifier
 }
 
+// ClaimLockKey 生成领取锁缓存键
+func (s *CacheService) ClaimLockKey(userID int) string {
+	return model.CacheKeyLock + "claim:" + strconv.Itoa(userID)
+}
+
+// GrantLockKey 生成额度发放投递锁缓存键（按公益站用户）
+func (s *CacheService) GrantLockKey(kyxUserID int) string {
+	return model.CacheKeyLock + "grant:" + strconv.Itoa(kyxUserID)
+}
+
+// KeyFilterLockKey 生成布隆过滤器重建锁缓存键
+func (s *CacheService) KeyFilterLockKey() string {
+	return model.CacheKeyLock + model.CacheKeyKeysBloom
+}
+
 // 基础缓存操作
 
 // Set 设置缓存
@@ -130,11 +147,41 @@ func (s *CacheService) DecrBy(ctx context.Context, key string, value int64) (int
 	return s.cache.DecrBy(ctx, key, value)
 }
 
+// 分布式锁
+
+// AcquireLock 获取分布式锁，返回用于释放锁的令牌；锁已被持有时返回空令牌
+func (s *CacheService) AcquireLock(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	ok, err := s.cache.SetNX(ctx, key, token, ttl)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", nil
+	}
+	return token, nil
+}
+
+// ReleaseLock 释放分布式锁（仅释放自己持有的锁）
+func (s *CacheService) ReleaseLock(ctx context.Context, key, token string) {
+	if token == "" {
+		return
+	}
+	if _, err := s.cache.DelIfEqual(ctx, key, token); err != nil {
+		s.logger.WithError(err).WithField("key", key).Warn("Failed to release lock")
+	}
+}
+
 // 用户相关缓存
 
 // GetUserQuota 获取用户额度缓存
-func (s *CacheService) GetUserQuota(ctx context.Context, linuxDoID string) (*model.QuotaInfo, error) {
-	key := s.UserQuotaKey(linuxDoID)
+func (s *CacheService) GetUserQuota(ctx context.Context, userID int) (*model.QuotaInfo, error) {
+	key := s.UserQuotaKey(userID)
 	var quota model.QuotaInfo
 	err := s.GetJSON(ctx, key, &quota)
 	if err != nil {
@@ -144,36 +191,50 @@ func (s *CacheService) GetUserQuota(ctx context.Context, linuxDoID string) (*mod
 }
 
 // SetUserQuota 设置用户额度缓存
-func (s *CacheService) SetUserQuota(ctx context.Context, linuxDoID string, quota *model.QuotaInfo, ttl time.Duration) error {
-	key := s.UserQuotaKey(linuxDoID)
+func (s *CacheService) SetUserQuota(ctx context.Context, userID int, quota *model.QuotaInfo, ttl time.Duration) error {
+	key := s.UserQuotaKey(userID)
 	return s.SetJSON(ctx, key, quota, ttl)
 }
 
 // ClearUserQuota 清除用户额度缓存
-func (s *CacheService) ClearUserQuota(ctx context.Context, linuxDoID string) error {
-	key := s.UserQuotaKey(linuxDoID)
+func (s *CacheService) ClearUserQuota(ctx context.Context, userID int) error {
+	key := s.UserQuotaKey(userID)
 	return s.Del(ctx, key)
 }
 
-// HasClaimedToday 检查用户今天是否已领取（缓存）
-func (s *CacheService) HasClaimedToday(ctx context.Context, linuxDoID string) (bool, error) {
-	key := s.ClaimTodayKey(linuxDoID)
-	return s.Exists(ctx, key)
+// GetNextClaimAt 获取缓存的下次可领取时间（未缓存时返回零值）
+func (s *CacheService) GetNextClaimAt(ctx context.Context, userID int) (time.Time, error) {
+	key := s.ClaimNextKey(userID)
+	val, err := s.Get(ctx, key)
+	if err != nil || val == "" {
+		return time.Time{}, err
+	}
+	var unix int64
+	if _, err := fmt.Sscanf(val, "%d", &unix); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(unix, 0), nil
 }
 
-// MarkClaimedToday 标记用户今天已领取
-func (s *CacheService) MarkClaimedToday(ctx context.Context, linuxDoID string) error {
-	key := s.ClaimTodayKey(linuxDoID)
-	// 缓存到明天凌晨
-	now := time.Now()
-	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
-	ttl := tomorrow.Sub(now)
-	return s.Set(ctx, key, "1", ttl)
+// MarkClaimed 标记用户已领取，缓存到下次可领取时间
+func (s *CacheService) MarkClaimed(ctx context.Context, userID int, nextClaimAt time.Time) error {
+	key := s.ClaimNextKey(userID)
+	ttl := time.Until(nextClaimAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.Set(ctx, key, nextClaimAt.Unix(), ttl)
+}
+
+// ClearClaimed 清除已领取标记（领取被释放或重置时）
+func (s *CacheService) ClearClaimed(ctx context.Context, userID int) error {
+	key := s.ClaimNextKey(userID)
+	return s.Del(ctx, key)
 }
 
 // GetDonateCount 获取今日投喂次数
-func (s *CacheService) GetDonateCount(ctx context.Context, linuxDoID string) (int64, error) {
-	key := s.DonateCountKey(linuxDoID)
+func (s *CacheService) GetDonateCount(ctx context.Context, userID int) (int64, error) {
+	key := s.DonateCountKey(userID)
 	val, err := s.Get(ctx, key)
 	if err != nil {
 		return 0, err
@@ -190,8 +251,8 @@ func (s *CacheService) GetDonateCount(ctx context.Context, linuxDoID string) (in
 }
 
 // IncrDonateCount 增加今日投喂次数
-func (s *CacheService) IncrDonateCount(ctx context.Context, linuxDoID string) (int64, error) {
-	key := s.DonateCountKey(linuxDoID)
+func (s *CacheService) IncrDonateCount(ctx context.Context, userID int) (int64, error) {
+	key := s.DonateCountKey(userID)
 	count, err := s.Incr(ctx, key)
 	if err != nil {
 		return 0, err
@@ -206,41 +267,25 @@ func (s *CacheService) IncrDonateCount(ctx context.Context, linuxDoID string) (i
 	return count, nil
 }
 
-// Bloom Filter 相关
+// Key校验结果缓存
 
-// BloomFilterAdd 向布隆过滤器添加Key
-func (s *CacheService) BloomFilterAdd(ctx context.Context, keyHash string) error {
-	return s.cache.BloomAdd(ctx, model.CacheKeyKeysBloom, keyHash)
+// KeyVerifyKey 生成Key校验结果缓存键
+func (s *CacheService) KeyVerifyKey(keyHash string) string {
+	return model.CacheKeyKeyVerify + keyHash
 }
 
-// BloomFilterExists 检查Key是否可能存在于布隆过滤器
-func (s *CacheService) BloomFilterExists(ctx context.Context, keyHash string) (bool, error) {
-	return s.cache.BloomExists(ctx, model.CacheKeyKeysBloom, keyHash)
-}
-
-// BloomFilterAddBatch 批量向布隆过滤器添加Key
-func (s *CacheService) BloomFilterAddBatch(ctx context.Context, keyHashes []string) error {
-	for _, keyHash := range keyHashes {
-		if err := s.BloomFilterAdd(ctx, keyHash); err != nil {
-			s.logger.WithError(err).WithField("key_hash", keyHash).Warn("Failed to add key to bloom filter")
-		}
+// GetKeyVerifyResult 获取Key校验结果缓存
+func (s *CacheService) GetKeyVerifyResult(ctx context.Context, keyHash string) (*KeyVerifyResult, error) {
+	var result KeyVerifyResult
+	if err := s.GetJSON(ctx, s.KeyVerifyKey(keyHash), &result); err != nil {
+		return nil, err
 	}
-	return nil
+	return &result, nil
 }
 
-// BloomFilterExistsBatch 批量检查Key是否可能存在于布隆过滤器
-func (s *CacheService) BloomFilterExistsBatch(ctx context.Context, keyHashes []string) (map[string]bool, error) {
-	result := make(map[string]bool)
-	for _, keyHash := range keyHashes {
-		exists, err := s.BloomFilterExists(ctx, keyHash)
-		if err != nil {
-			s.logger.WithError(err).WithField("key_hash", keyHash).Warn("Failed to check bloom filter")
-			result[keyHash] = false
-			continue
-		}
-		result[keyHash] = exists
-	}
-	return result, nil
+// SetKeyVerifyResult 设置Key校验结果缓存
+func (s *CacheService) SetKeyVerifyResult(ctx context.Context, keyHash string, result *KeyVerifyResult, ttl time.Duration) error {
+	return s.SetJSON(ctx, s.KeyVerifyKey(keyHash), result, ttl)
 }
 
 // 限流相关
@@ -315,12 +360,12 @@ func (s *CacheService) DeletePattern(ctx context.Context, pattern string) (int64
 }
 
 // ClearUserCache 清除用户相关的所有缓存
-func (s *CacheService) ClearUserCache(ctx context.Context, linuxDoID string) error {
+func (s *CacheService) ClearUserCache(ctx context.Context, userID int) error {
 	keys := []string{
-		s.UserKey(linuxDoID),
-		s.UserQuotaKey(linuxDoID),
-		s.ClaimTodayKey(linuxDoID),
-		s.DonateCountKey(linuxDoID),
+		s.UserKey(userID),
+		s.UserQuotaKey(userID),
+		s.ClaimNextKey(userID),
+		s.DonateCountKey(userID),
 	}
 
 	return s.Del(ctx, keys...)
@@ -331,7 +376,7 @@ func (s *CacheService) ClearAllUserCaches(ctx context.Context) error {
 	patterns := []string{
 		model.CacheKeyUser + "*",
 		model.CacheKeyUserQuota + "*",
-		model.CacheKeyClaimToday + "*",
+		model.CacheKeyClaimNext + "*",
 		model.CacheKeyDonateCount + "*",
 	}
 
@@ -370,7 +415,7 @@ func (s *CacheService) Stats(ctx context.Context) (map[string]interface{}, error
 	quotaKeys, _ := s.cache.Keys(ctx, model.CacheKeyUserQuota+"*")
 	stats["quota_cache_count"] = len(quotaKeys)
 
-	claimKeys, _ := s.cache.Keys(ctx, model.CacheKeyClaimToday+"*")
+	claimKeys, _ := s.cache.Keys(ctx, model.CacheKeyClaimNext+"*")
 	stats["claim_cache_count"] = len(claimKeys)
 
 	sessionKeys, _ := s.cache.Keys(ctx, model.CacheKeySession+"*")
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+)
+
+// ClaimPeriod 领取周期（按配置的时区计算）
+// daily: 每个自然日一次；weekly: 每个自然周（周一开始）一次；rolling: 距上次领取满 N 小时后可再次领取
+type ClaimPeriod struct {
+	kind     string
+	location *time.Location
+	rolling  time.Duration
+}
+
+// NewClaimPeriod 创建领取周期
+func NewClaimPeriod(kind, timezone string, rollingHours int) (*ClaimPeriod, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid claim timezone %s: %w", timezone, err)
+	}
+
+	period := &ClaimPeriod{
+		kind:     kind,
+		location: location,
+		rolling:  time.Duration(rollingHours) * time.Hour,
+	}
+
+	switch kind {
+	case model.ClaimPeriodDaily, model.ClaimPeriodWeekly:
+	case model.ClaimPeriodRolling:
+		if period.rolling <= 0 {
+			return nil, fmt.Errorf("claim rolling hours must be positive")
+		}
+	default:
+		return nil, fmt.Errorf("invalid claim period: %s", kind)
+	}
+
+	return period, nil
+}
+
+// Kind 周期类型
+func (p *ClaimPeriod) Kind() string {
+	return p.kind
+}
+
+// Location 领取时区
+func (p *ClaimPeriod) Location() *time.Location {
+	return p.location
+}
+
+// Now 领取时区的当前时间
+func (p *ClaimPeriod) Now() time.Time {
+	return time.Now().In(p.location)
+}
+
+// ClaimDate 领取时区下的日期 (YYYY-MM-DD)
+func (p *ClaimPeriod) ClaimDate(t time.Time) string {
+	return t.In(p.location).Format("2006-01-02")
+}
+
+// StartOfDay 领取时区下 t 所在自然日的零点
+func (p *ClaimPeriod) StartOfDay(t time.Time) time.Time {
+	t = t.In(p.location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, p.location)
+}
+
+// StartOfWeek 领取时区下 t 所在自然周（周一开始）的零点
+func (p *ClaimPeriod) StartOfWeek(t time.Time) time.Time {
+	day := p.StartOfDay(t)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// PeriodKey 领取记录的周期键，与 linux_do_id 组成唯一约束
+// rolling 模式没有固定的周期边界，使用上一条有效领取记录的ID，
+// 并发领取时基于同一条记录生成相同的键，由唯一约束拒绝其中之一
+func (p *ClaimPeriod) PeriodKey(t time.Time, last *model.ClaimRecord) string {
+	switch p.kind {
+	case model.ClaimPeriodWeekly:
+		year, week := t.In(p.location).ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case model.ClaimPeriodRolling:
+		if last == nil {
+			return "after:0"
+		}
+		return fmt.Sprintf("after:%d", last.ID)
+	default:
+		return p.ClaimDate(t)
+	}
+}
+
+// CurrentStart 当前领取周期的开始时间（rolling 模式为最近 N 小时）
+func (p *ClaimPeriod) CurrentStart(now time.Time) time.Time {
+	switch p.kind {
+	case model.ClaimPeriodWeekly:
+		return p.StartOfWeek(now)
+	case model.ClaimPeriodRolling:
+		return now.Add(-p.rolling).In(p.location)
+	default:
+		return p.StartOfDay(now)
+	}
+}
+
+// NextClaimAt 上次领取后下一次可领取的时间
+func (p *ClaimPeriod) NextClaimAt(lastClaimAt time.Time) time.Time {
+	switch p.kind {
+	case model.ClaimPeriodWeekly:
+		return p.StartOfWeek(lastClaimAt).AddDate(0, 0, 7)
+	case model.ClaimPeriodRolling:
+		return lastClaimAt.Add(p.rolling).In(p.location)
+	default:
+		return p.StartOfDay(lastClaimAt).AddDate(0, 0, 1)
+	}
+}
+
+// StreakDeadline 上次领取后保持连续领取的截止时间（需在下一个周期结束前再次领取）
+func (p *ClaimPeriod) StreakDeadline(lastClaimAt time.Time) time.Time {
+	switch p.kind {
+	case model.ClaimPeriodWeekly:
+		return p.StartOfWeek(lastClaimAt).AddDate(0, 0, 14)
+	case model.ClaimPeriodRolling:
+		return lastClaimAt.Add(2 * p.rolling).In(p.location)
+	default:
+		return p.StartOfDay(lastClaimAt).AddDate(0, 0, 2)
+	}
+}
+
+// CurrentStreak 截至 now 仍然有效的连续领取数，连续已中断时返回 0
+func (p *ClaimPeriod) CurrentStreak(last *model.ClaimRecord, now time.Time) int {
+	if last == nil || !now.Before(p.StreakDeadline(last.CreatedAt)) {
+		return 0
+	}
+	return last.Streak
+}
@@ -4,46 +4,121 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
 	"github.com/yourusername/kyx-quota-bridge/internal/model"
 	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+	"github.com/yourusername/kyx-quota-bridge/pkg/cache"
+)
+
+const (
+	// donateJobMaxAttempts 投喂任务最大执行次数
+	donateJobMaxAttempts = 3
+	// donateJobLease 任务租约时长，保存进度时续租，超时未完成的任务会被重新领取
+	donateJobLease = 5 * time.Minute
+	// donateJobPollInterval 工作协程轮询间隔
+	donateJobPollInterval = 5 * time.Second
+	// donateJobRetryBackoff 任务重试间隔（按执行次数线性增长）
+	donateJobRetryBackoff = 30 * time.Second
+
+	// keyFilterRebuildLockTTL 布隆过滤器重建锁时长，避免多个实例同时重建
+	keyFilterRebuildLockTTL = 10 * time.Minute
+	// keyFilterRebuildBatch 重建时每批读取的Key数量
+	keyFilterRebuildBatch = 5000
+	// keyFilterCatchUpWindow 重建完成后补录该时间窗口内新增的Key，覆盖重建期间写入旧位图的Key
+	keyFilterCatchUpWindow = time.Minute
 )
 
 // DonateService 投喂服务
 type DonateService struct {
 	donateRepo      *repository.DonateRepository
+	jobRepo         *repository.DonateJobRepository
 	keyRepo         *repository.KeyRepository
 	userRepo        *repository.UserRepository
 	adminConfigRepo *repository.AdminConfigRepository
 	kyxClient       *KyxClient
+	sessionMonitor  *KyxSessionMonitor
+	grantService    *QuotaGrantService
+	tierService     *GroupTierService
 	cacheService    *CacheService
+	banService      *BanService
+	keyFilter       *cache.BloomFilter
+	donateDefaults  model.DonatePolicy
+	trustDefaults   model.TrustPolicy
+	keyVerifier     KeyVerifier
 	httpClient      *http.Client
 	logger          *logrus.Logger
+
+	verifyConcurrency int
+	verifyTimeout     time.Duration
+
+	workers   int
+	jobNotify chan struct{}
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+}
+
+// KeyVerifyConfig 投喂Key上游校验配置
+type KeyVerifyConfig struct {
+	Verifier    KeyVerifier // 为 nil 时跳过上游校验
+	Concurrency int
+	Timeout     time.Duration
 }
 
 // NewDonateService 创建投喂服务
 func NewDonateService(
 	donateRepo *repository.DonateRepository,
+	jobRepo *repository.DonateJobRepository,
 	keyRepo *repository.KeyRepository,
 	userRepo *repository.UserRepository,
 	adminConfigRepo *repository.AdminConfigRepository,
 	kyxClient *KyxClient,
+	sessionMonitor *KyxSessionMonitor,
+	grantService *QuotaGrantService,
+	tierService *GroupTierService,
 	cacheService *CacheService,
+	banService *BanService,
+	keyFilter *cache.BloomFilter,
+	donateDefaults model.DonatePolicy,
+	trustDefaults model.TrustPolicy,
+	verifyConfig KeyVerifyConfig,
+	workers int,
 	logger *logrus.Logger,
 ) *DonateService {
+	if verifyConfig.Concurrency <= 0 {
+		verifyConfig.Concurrency = 5
+	}
+	if verifyConfig.Timeout == 0 {
+		verifyConfig.Timeout = 10 * time.Second
+	}
+	if workers <= 0 {
+		workers = 2
+	}
+
 	return &DonateService{
 		donateRepo:      donateRepo,
+		jobRepo:         jobRepo,
 		keyRepo:         keyRepo,
 		userRepo:        userRepo,
 		adminConfigRepo: adminConfigRepo,
 		kyxClient:       kyxClient,
+		sessionMonitor:  sessionMonitor,
+		grantService:    grantService,
+		tierService:     tierService,
 		cacheService:    cacheService,
+		banService:      banService,
+		keyFilter:       keyFilter,
+		donateDefaults:  donateDefaults,
+		trustDefaults:   trustDefaults,
+		keyVerifier:     verifyConfig.Verifier,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 			Transport: &http.Transport{
@@ -52,155 +127,605 @@ func NewDonateService(
 				IdleConnTimeout:     90 * time.Second,
 			},
 		},
-		logger: logger,
+		logger:            logger,
+		verifyConcurrency: verifyConfig.Concurrency,
+		verifyTimeout:     verifyConfig.Timeout,
+		workers:           workers,
+		jobNotify:         make(chan struct{}, workers),
+		stopCh:            make(chan struct{}),
 	}
 }
 
-// DonateKeys 投喂Keys
-func (s *DonateService) DonateKeys(ctx context.Context, linuxDoID string, keys []string) (*model.DonateResponse, error) {
+// SubmitDonation 提交投喂任务
+// 只做用户和提交限制的检查，Key的校验、推送和额度发放由后台工作池异步完成
+func (s *DonateService) SubmitDonation(ctx context.Context, userID int, keys []string) (*model.DonateJob, error) {
 	// 检查用户是否存在
-	user, err := s.userRepo.GetByLinuxDoID(ctx, linuxDoID)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		s.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get user")
+		s.logger.WithError(err).WithField("user_id", userID).Error("Failed to get user")
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	if user == nil {
-		s.logger.WithField("linux_do_id", linuxDoID).Warn("User not found for donate")
+		s.logger.WithField("user_id", userID).Warn("User not found for donate")
 		return nil, fmt.Errorf("user not found")
 	}
 
 	// 检查是否已绑定账号
 	if user.KyxUserID == 0 {
-		s.logger.WithField("linux_do_id", linuxDoID).Warn("Attempt to donate without bound account")
+		s.logger.WithField("linux_do_id", user.LinuxDoID).Warn("Attempt to donate without bound account")
 		return nil, fmt.Errorf("account not bound, please bind first")
 	}
 
+	// 检查 Linux.do 信任等级和账号状态
+	trustPolicy, err := s.adminConfigRepo.GetTrustPolicy(ctx, s.trustDefaults)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to get trust policy, using defaults")
+	}
+	if reason := trustPolicy.BlockedReason(user, trustPolicy.MinDonateLevel); reason != "" {
+		s.logger.WithFields(logrus.Fields{
+			"linux_do_id": user.LinuxDoID,
+			"trust_level": user.TrustLevel,
+			"min_level":   trustPolicy.MinDonateLevel,
+			"active":      user.Active,
+			"silenced":    user.Silenced,
+		}).Warn("Donate blocked by trust policy")
+		return nil, fmt.Errorf("%s: linux.do account does not meet donate requirements (trust level %d, required %d)", reason, user.TrustLevel, trustPolicy.MinDonateLevel)
+	}
+
+	// 检查管理员封禁和暂停
+	if err := s.banService.Check(ctx, user.LinuxDoID, user.KyxUserID, "donate"); err != nil {
+		return nil, err
+	}
+
+	// 公益站 Session 失效且无法自动续期时直接拒绝，投喂的额度无法发放
+	if err := s.sessionMonitor.CheckAvailable(); err != nil {
+		return nil, err
+	}
+
+	// 获取投喂策略
+	policy, err := s.GetDonatePolicy(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to get donate policy")
+		return nil, fmt.Errorf("failed to get donate policy: %w", err)
+	}
+
+	// 检查单次提交数量
+	if policy.MaxKeysPerSubmission > 0 && len(keys) > policy.MaxKeysPerSubmission {
+		s.logger.WithFields(logrus.Fields{
+			"linux_do_id": user.LinuxDoID,
+			"keys":        len(keys),
+			"limit":       policy.MaxKeysPerSubmission,
+		}).Warn("Too many keys in one submission")
+		return nil, fmt.Errorf("too many keys in one submission (max %d)", policy.MaxKeysPerSubmission)
+	}
+
 	// 检查投喂限制（每天最多投喂次数）
-	donateCount, err := s.cacheService.GetDonateCount(ctx, linuxDoID)
-	if err == nil && donateCount >= 10 { // 每天最多10次
-		s.logger.WithField("linux_do_id", linuxDoID).Warn("Donate limit exceeded")
-		return nil, fmt.Errorf("daily donate limit exceeded (max 10 times per day)")
+	if policy.MaxSubmissionsPerDay > 0 {
+		donateCount, err := s.cacheService.GetDonateCount(ctx, user.ID)
+		if err == nil && donateCount >= int64(policy.MaxSubmissionsPerDay) {
+			s.logger.WithField("linux_do_id", user.LinuxDoID).Warn("Donate limit exceeded")
+			return nil, fmt.Errorf("daily donate limit exceeded (max %d times per day)", policy.MaxSubmissionsPerDay)
+		}
 	}
 
-	// 验证Keys
-	validationResults, validKeys := s.ValidateKeys(ctx, keys)
+	// 创建任务
+	jobKeys := make(model.JSONArray, 0, len(keys))
+	items := make(model.DonateJobItems, 0, len(keys))
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		jobKeys = append(jobKeys, key)
+		items = append(items, model.DonateJobItem{
+			Key:    maskKey(key),
+			Status: model.DonateItemStatusPending,
+		})
+	}
 
-	if len(validKeys) == 0 {
-		s.logger.WithField("linux_do_id", linuxDoID).Warn("No valid keys to donate")
-		return &model.DonateResponse{
-			ValidKeys:        0,
-			AlreadyExists:    len(keys),
-			DuplicateRemoved: 0,
-			QuotaAdded:       0,
-			Results:          validationResults,
-		}, nil
+	job := &model.DonateJob{
+		UserID:      user.ID,
+		LinuxDoID:   user.LinuxDoID,
+		Username:    user.Username,
+		Keys:        jobKeys,
+		Items:       items,
+		TotalKeys:   len(jobKeys),
+		MaxAttempts: donateJobMaxAttempts,
+	}
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		return nil, err
 	}
 
-	// 推送Keys到公益站
-	pushResult := s.PushKeys(ctx, validKeys)
+	// 增加投喂计数
+	_, _ = s.cacheService.IncrDonateCount(ctx, user.ID)
 
-	// 计算总额度
-	totalQuota := int64(len(pushResult.SuccessKeys)) * 500000 // 每个Key = $1 = 500000
+	// 唤醒空闲的工作协程
+	select {
+	case s.jobNotify <- struct{}{}:
+	default:
+	}
 
-	// 如果有成功的Key，添加额度
-	if len(pushResult.SuccessKeys) > 0 {
-		if err := s.kyxClient.AddQuota(ctx, user.KyxUserID, totalQuota); err != nil {
-			s.logger.WithError(err).WithFields(logrus.Fields{
-				"linux_do_id": linuxDoID,
-				"kyx_user_id": user.KyxUserID,
-				"quota":       totalQuota,
-			}).Error("Failed to add quota for donated keys")
-			// 继续处理，记录错误但不中断
+	s.logger.WithFields(logrus.Fields{
+		"job_id":      job.ID,
+		"linux_do_id": user.LinuxDoID,
+		"total_keys":  job.TotalKeys,
+	}).Info("Donate job submitted")
+
+	return job, nil
+}
+
+// GetDonateJob 获取用户的投喂任务（不属于该用户时返回 nil）
+func (s *DonateService) GetDonateJob(ctx context.Context, userID int, jobID int) (*model.DonateJob, error) {
+	job, err := s.jobRepo.GetByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil || job.UserID != userID {
+		return nil, nil
+	}
+	return job, nil
+}
+
+// errDonateRejected 投喂被拒绝（业务错误，任务直接失败不再重试）
+var errDonateRejected = errors.New("donation rejected")
+
+// Start 启动投喂任务工作池
+// 服务重启后，未完成的任务会在租约到期后被重新领取并从已保存的进度继续执行
+func (s *DonateService) Start(ctx context.Context) {
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go s.runWorker(ctx)
+	}
+
+	// 后台重建已使用Key布隆过滤器，重建完成前校验直接查询数据库
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if _, err := s.RebuildKeyFilter(ctx); err != nil {
+			s.logger.WithError(err).Warn("Failed to rebuild key filter on startup")
+		}
+	}()
+
+	s.logger.WithField("workers", s.workers).Info("Donate job workers started")
+}
+
+// Stop 停止投喂任务工作池，等待进行中的任务结束
+func (s *DonateService) Stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+	s.logger.Info("Donate job workers stopped")
+}
+
+// runWorker 工作协程：有新任务或轮询到期时领取任务执行
+func (s *DonateService) runWorker(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(donateJobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for !s.stopping(ctx) && s.runNextJob(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopCh:
+			return
+		case <-s.jobNotify:
+		case <-ticker.C:
+		}
+	}
+}
+
+// stopping 工作池是否正在停止
+func (s *DonateService) stopping(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	case <-s.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// runNextJob 领取并执行一个任务，没有可执行的任务时返回 false
+func (s *DonateService) runNextJob(ctx context.Context) bool {
+	job, err := s.jobRepo.AcquireNext(ctx, donateJobLease)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to acquire donate job")
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	fields := logrus.Fields{
+		"job_id":      job.ID,
+		"linux_do_id": job.LinuxDoID,
+		"attempt":     job.Attempts,
+	}
+
+	err = s.processJob(ctx, job)
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, errDonateRejected) || job.Attempts >= job.MaxAttempts {
+		if markErr := s.jobRepo.MarkFailed(ctx, job, err.Error()); markErr != nil {
+			s.logger.WithError(markErr).WithFields(fields).Error("Failed to mark donate job failed")
+		}
+		s.releaseUnpushedKeys(ctx, job)
+		s.logger.WithError(err).WithFields(fields).Warn("Donate job failed")
+		return true
+	}
+
+	nextAttemptAt := time.Now().Add(time.Duration(job.Attempts) * donateJobRetryBackoff)
+	if markErr := s.jobRepo.MarkRetry(ctx, job, nextAttemptAt, err.Error()); markErr != nil {
+		s.logger.WithError(markErr).WithFields(fields).Error("Failed to schedule donate job retry")
+	}
+	s.logger.WithError(err).WithFields(fields).WithField("next_attempt_at", nextAttemptAt).Warn("Donate job failed, will retry")
+	return true
+}
+
+// processJob 执行投喂任务：校验 -> 推送 -> 落库 -> 发放额度
+// 每个阶段结束后保存进度，重新执行时跳过已完成的阶段
+func (s *DonateService) processJob(ctx context.Context, job *model.DonateJob) error {
+	if len(job.Keys) != len(job.Items) {
+		return fmt.Errorf("%w: job keys are no longer available", errDonateRejected)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, job.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("%w: user not found", errDonateRejected)
+	}
+	if user.KyxUserID == 0 {
+		return fmt.Errorf("%w: account not bound", errDonateRejected)
+	}
+
+	// 提交后被封禁或暂停的用户不再处理
+	ban, err := s.banService.Restriction(ctx, user.LinuxDoID, user.KyxUserID)
+	if err != nil {
+		return fmt.Errorf("failed to check user bans: %w", err)
+	}
+	if ban != nil {
+		return fmt.Errorf("%w: %s", errDonateRejected, BanMessage(ban))
+	}
+
+	policy, err := s.GetDonatePolicy(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get donate policy: %w", err)
+	}
+
+	// 1. 校验未处理的Key
+	pending := job.ItemIndexes(model.DonateItemStatusPending)
+	if len(pending) > 0 {
+		keys := make([]string, 0, len(pending))
+		for _, i := range pending {
+			keys = append(keys, job.Keys[i])
+		}
+
+		results, _ := s.ValidateKeys(ctx, keys)
+		for j, i := range pending {
+			if results[j].Valid {
+				job.Items[i].Status = model.DonateItemStatusValid
+				continue
+			}
+			job.Items[i].Status = model.DonateItemStatusRejected
+			job.Items[i].Reason = results[j].Reason
+			job.ProcessedKeys++
+		}
+		s.saveJobProgress(ctx, job)
+	}
+
+	// 2. 推送校验通过的Key
+	valid := job.ItemIndexes(model.DonateItemStatusValid)
+	if len(valid) > 0 {
+		// 检查每日Key数量限制（已推送过的任务在首次执行时已检查）
+		if policy.MaxKeysPerDay > 0 && len(job.ItemIndexes(model.DonateItemStatusPushed, model.DonateItemStatusPushFailed)) == 0 {
+			todayKeys, err := s.donateRepo.GetUserTodayKeys(ctx, user.ID)
+			if err != nil {
+				return fmt.Errorf("failed to check daily key limit: %w", err)
+			}
+			remaining := int64(policy.MaxKeysPerDay) - todayKeys
+			if remaining < 0 {
+				remaining = 0
+			}
+			if int64(len(valid)) > remaining {
+				reason := fmt.Sprintf("daily key limit exceeded (max %d keys per day, %d remaining)", policy.MaxKeysPerDay, remaining)
+				for _, i := range valid {
+					job.Items[i].Status = model.DonateItemStatusRejected
+					job.Items[i].Reason = reason
+					job.ProcessedKeys++
+				}
+				return fmt.Errorf("%w: %s", errDonateRejected, reason)
+			}
+		}
+
+		// 推送前预留Key：并发任务或重复提交中已被其他任务预留、已写入 used_keys 的Key不推送
+		hashes := make([]string, 0, len(valid))
+		for _, i := range valid {
+			hashes = append(hashes, repository.HashKey(job.Keys[i]))
+		}
+		reserved, err := s.keyRepo.ReserveKeys(ctx, job.ID, hashes)
+		if err != nil {
+			return fmt.Errorf("failed to reserve keys: %w", err)
+		}
+
+		toPush := make([]int, 0, len(valid))
+		keys := make([]string, 0, len(valid))
+		for j, i := range valid {
+			if !reserved[hashes[j]] {
+				job.Items[i].Status = model.DonateItemStatusDuplicate
+				job.Items[i].Reason = "Key already donated"
+				job.ProcessedKeys++
+				continue
+			}
+			toPush = append(toPush, i)
+			keys = append(keys, job.Keys[i])
+		}
+
+		var pushed map[string]bool
+		if len(keys) > 0 {
+			pushResult := s.PushKeys(ctx, keys)
+			pushed = make(map[string]bool, len(pushResult.SuccessKeys))
+			for _, key := range pushResult.SuccessKeys {
+				pushed[key] = true
+			}
+			for _, key := range pushResult.FailedKeys {
+				delete(pushed, key)
+			}
+		}
+
+		failedHashes := make([]string, 0)
+		for _, i := range toPush {
+			if pushed[job.Keys[i]] {
+				job.Items[i].Status = model.DonateItemStatusPushed
+			} else {
+				job.Items[i].Status = model.DonateItemStatusPushFailed
+				job.Items[i].Reason = "Failed to push key"
+				failedHashes = append(failedHashes, repository.HashKey(job.Keys[i]))
+			}
+			job.ProcessedKeys++
+		}
+		// Key已推送到公益站，尽快保存进度避免重试时重复推送
+		s.saveJobProgress(ctx, job)
+
+		// 推送失败的Key释放预留，之后可以重新投喂
+		if err := s.keyRepo.ReleaseKeys(ctx, job.ID, failedHashes); err != nil {
+			s.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to release reservations of failed keys")
 		}
 	}
 
-	// 保存已使用的Keys
-	usedKeys := make([]*model.UsedKey, 0, len(pushResult.SuccessKeys))
-	for _, key := range pushResult.SuccessKeys {
+	// 3. 落库并发放额度
+	return s.finishJob(ctx, job, user, policy)
+}
+
+// finishJob 在同一事务中写入已使用的Keys、投喂记录、额度发放并完成任务
+func (s *DonateService) finishJob(ctx context.Context, job *model.DonateJob, user *model.User, policy model.DonatePolicy) error {
+	pushedIdx := job.ItemIndexes(model.DonateItemStatusPushed)
+
+	// 已使用的Keys
+	usedKeys := make([]*model.UsedKey, 0, len(pushedIdx))
+	for _, i := range pushedIdx {
 		usedKeys = append(usedKeys, &model.UsedKey{
-			KeyHash:   repository.HashKey(key),
-			FullKey:   key,
-			LinuxDoID: linuxDoID,
+			KeyHash:   repository.HashKey(job.Keys[i]),
+			FullKey:   job.Keys[i],
+			LinuxDoID: job.LinuxDoID,
 			Username:  user.Username,
 			UsedAt:    time.Now(),
 		})
 	}
 
-	if len(usedKeys) > 0 {
-		addedCount, err := s.keyRepo.AddBatch(ctx, usedKeys)
-		if err != nil {
-			s.logger.WithError(err).Warn("Failed to save used keys")
+	var (
+		addedIdx   []int
+		failedIdx  []int
+		totalQuota int64
+		grant      *model.QuotaGrant
+	)
+
+	// 已使用的Keys、投喂记录、额度发放和任务完成状态在同一事务中写入，额度由发件箱投递到公益站
+	// 只按本事务实际写入 used_keys 的Key计算额度，已被其他任务写入的Key标记为重复
+	err := s.userRepo.Transaction(ctx, func(tx *sqlx.Tx) error {
+		added := make(map[string]bool)
+		if len(usedKeys) > 0 {
+			var err error
+			added, err = s.keyRepo.AddBatchTx(ctx, tx, usedKeys)
+			if err != nil {
+				return err
+			}
+			s.logger.WithField("added_count", len(added)).Debug("Used keys saved")
 		}
-		s.logger.WithField("added_count", addedCount).Debug("Used keys saved")
 
-		// 更新布隆过滤器
-		keyHashes := make([]string, 0, len(usedKeys))
-		for _, key := range usedKeys {
-			keyHashes = append(keyHashes, key.KeyHash)
+		addedIdx = make([]int, 0, len(pushedIdx))
+		for j, i := range pushedIdx {
+			if added[usedKeys[j].KeyHash] {
+				addedIdx = append(addedIdx, i)
+				continue
+			}
+			job.Items[i].Status = model.DonateItemStatusDuplicate
+			job.Items[i].Reason = "Key already donated"
 		}
-		_ = s.cacheService.BloomFilterAddBatch(ctx, keyHashes)
-	}
+		failedIdx = job.ItemIndexes(model.DonateItemStatusPushFailed)
 
-	// 创建投喂记录
-	pushStatus := "success"
-	pushMessage := fmt.Sprintf("Successfully pushed %d keys", len(pushResult.SuccessKeys))
-	if len(pushResult.FailedKeys) > 0 {
-		pushStatus = "partial"
-		pushMessage = fmt.Sprintf("Pushed %d keys, %d failed", len(pushResult.SuccessKeys), len(pushResult.FailedKeys))
-	}
-	if len(pushResult.SuccessKeys) == 0 {
-		pushStatus = "failed"
-		pushMessage = "All keys failed to push"
-	}
+		// 推送失败的Key只保存脱敏后的值
+		failedKeys := make(model.JSONArray, 0, len(failedIdx))
+		for _, i := range failedIdx {
+			failedKeys = append(failedKeys, maskKey(job.Keys[i]))
+		}
+
+		// 计算总额度
+		totalQuota = int64(len(addedIdx)) * policy.QuotaPerKey
+		job.ValidKeys = len(addedIdx)
+		job.QuotaAdded = totalQuota
+
+		// 投喂记录（没有Key写入或推送失败时不生成记录）
+		var record *model.DonateRecord
+		if len(addedIdx) > 0 || len(failedIdx) > 0 {
+			pushStatus := "success"
+			pushMessage := fmt.Sprintf("Successfully pushed %d keys", len(addedIdx))
+			if len(failedIdx) > 0 {
+				pushStatus = "partial"
+				pushMessage = fmt.Sprintf("Pushed %d keys, %d failed", len(addedIdx), len(failedIdx))
+			}
+			if len(addedIdx) == 0 {
+				pushStatus = "failed"
+				pushMessage = "All keys failed to push"
+			}
+
+			record = &model.DonateRecord{
+				UserID:          user.ID,
+				LinuxDoID:       job.LinuxDoID,
+				Username:        user.Username,
+				KeysCount:       len(addedIdx),
+				TotalQuotaAdded: totalQuota,
+				PushStatus:      pushStatus,
+				PushMessage:     pushMessage,
+				FailedKeys:      failedKeys,
+			}
+			if err := s.donateRepo.CreateTx(ctx, tx, record); err != nil {
+				return err
+			}
+			job.DonateRecordID.Int64, job.DonateRecordID.Valid = int64(record.ID), true
+		}
+
+		if totalQuota > 0 {
+			grant = &model.QuotaGrant{
+				IdempotencyKey: DonateGrantKey(record.ID),
+				UserID:         user.ID,
+				LinuxDoID:      job.LinuxDoID,
+				KyxUserID:      user.KyxUserID,
+				Quota:          totalQuota,
+				Source:         model.GrantSourceDonate,
+				SourceID:       record.ID,
+			}
+			if err := s.grantService.EnqueueTx(ctx, tx, grant); err != nil {
+				return err
+			}
+		}
 
-	record := &model.DonateRecord{
-		LinuxDoID:       linuxDoID,
-		Username:        user.Username,
-		KeysCount:       len(pushResult.SuccessKeys),
-		TotalQuotaAdded: totalQuota,
-		PushStatus:      pushStatus,
-		PushMessage:     pushMessage,
-		FailedKeys:      pushResult.FailedKeys,
+		// Key已写入 used_keys，预留不再需要
+		if err := s.keyRepo.DeleteReservationsTx(ctx, tx, job.ID); err != nil {
+			return err
+		}
+
+		return s.jobRepo.CompleteTx(ctx, tx, job)
+	})
+	if err != nil {
+		// Keys已推送到公益站但未能落库，任务会重试；重试耗尽后需要人工核对
+		s.logger.WithError(err).WithFields(logrus.Fields{
+			"job_id":       job.ID,
+			"linux_do_id":  job.LinuxDoID,
+			"success_keys": len(pushedIdx),
+		}).Error("Failed to save donate record and quota grant")
+		return fmt.Errorf("failed to save donation: %w", err)
 	}
+	job.Status = model.DonateJobStatusCompleted
 
-	if err := s.donateRepo.Create(ctx, record); err != nil {
-		s.logger.WithError(err).Error("Failed to create donate record")
-		// 不返回错误，因为额度已经添加
+	// 更新布隆过滤器，写入失败只会导致误判为"可能存在"后查询数据库
+	if len(addedIdx) > 0 {
+		keyHashes := make([]string, 0, len(addedIdx))
+		for _, i := range addedIdx {
+			keyHashes = append(keyHashes, repository.HashKey(job.Keys[i]))
+		}
+		if err := s.keyFilter.Add(ctx, keyHashes...); err != nil {
+			s.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to add keys to key filter")
+		}
 	}
 
-	// 增加投喂计数
-	_, _ = s.cacheService.IncrDonateCount(ctx, linuxDoID)
+	// 立即尝试投递，失败时由后台任务重试
+	if grant != nil {
+		if delivered, err := s.grantService.DeliverNow(ctx, grant.ID); err != nil {
+			s.logger.WithError(err).WithField("grant_id", grant.ID).Warn("Failed to deliver donate grant immediately")
+		} else if delivered != nil && delivered.Status != model.GrantStatusDelivered {
+			s.logger.WithFields(logrus.Fields{
+				"grant_id": grant.ID,
+				"status":   delivered.Status,
+			}).Warn("Donate grant queued for retry")
+		}
+	}
 
 	// 清除用户额度缓存
-	_ = s.cacheService.ClearUserQuota(ctx, linuxDoID)
+	_ = s.cacheService.ClearUserQuota(ctx, user.ID)
 
 	s.logger.WithFields(logrus.Fields{
-		"linux_do_id":  linuxDoID,
+		"job_id":       job.ID,
+		"linux_do_id":  job.LinuxDoID,
 		"username":     user.Username,
-		"total_keys":   len(keys),
-		"valid_keys":   len(validKeys),
-		"success_keys": len(pushResult.SuccessKeys),
-		"failed_keys":  len(pushResult.FailedKeys),
+		"total_keys":   job.TotalKeys,
+		"success_keys": len(addedIdx),
+		"failed_keys":  len(failedIdx),
 		"quota_added":  totalQuota,
-		"push_status":  pushStatus,
 	}).Info("Keys donated")
 
-	response := &model.DonateResponse{
-		ValidKeys:        len(pushResult.SuccessKeys),
-		AlreadyExists:    len(keys) - len(validKeys),
-		DuplicateRemoved: len(keys) - len(validKeys),
-		QuotaAdded:       totalQuota,
-		Results:          validationResults,
+	// 累计投喂增加后评估用户组档位，失败时由定期任务重试
+	if len(addedIdx) > 0 {
+		if err := s.tierService.Evaluate(ctx, job.UserID, model.GroupChangeSourceDonate); err != nil {
+			s.logger.WithError(err).WithFields(logrus.Fields{
+				"user_id":     job.UserID,
+				"linux_do_id": job.LinuxDoID,
+			}).Warn("Failed to evaluate group tier after donation")
+		}
+	}
+
+	return nil
+}
+
+// releaseUnpushedKeys 任务最终失败时释放未推送Key的预留，已推送的Key保留预留避免再次推送
+func (s *DonateService) releaseUnpushedKeys(ctx context.Context, job *model.DonateJob) {
+	if len(job.Keys) != len(job.Items) {
+		return
+	}
+
+	hashes := make([]string, 0, len(job.Items))
+	for i, item := range job.Items {
+		if item.Status != model.DonateItemStatusPushed {
+			hashes = append(hashes, repository.HashKey(job.Keys[i]))
+		}
+	}
+	if err := s.keyRepo.ReleaseKeys(ctx, job.ID, hashes); err != nil {
+		s.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to release reservations of unpushed keys")
 	}
+}
+
+// saveJobProgress 保存任务进度（失败只记录日志，不影响任务继续执行）
+func (s *DonateService) saveJobProgress(ctx context.Context, job *model.DonateJob) {
+	if err := s.jobRepo.SaveProgress(ctx, job, donateJobLease); err != nil {
+		s.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to save donate job progress")
+	}
+}
+
+// maskKey 脱敏Key，仅保留前后几位用于展示
+func maskKey(key string) string {
+	if len(key) <= 12 {
+		return "***"
+	}
+	return key[:6] + "..." + key[len(key)-4:]
+}
 
-	return response, nil
+// GetDonatePolicy 获取生效的投喂策略
+func (s *DonateService) GetDonatePolicy(ctx context.Context) (model.DonatePolicy, error) {
+	return s.adminConfigRepo.GetDonatePolicy(ctx, s.donateDefaults)
+}
+
+// MaxSubmissionsPerDay 获取每日最多投喂次数（0 表示不限制，读取失败时使用默认值）
+func (s *DonateService) MaxSubmissionsPerDay(ctx context.Context) int64 {
+	policy, err := s.GetDonatePolicy(ctx)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to get donate policy, using defaults")
+	}
+	return int64(policy.MaxSubmissionsPerDay)
 }
 
 // ValidateKeys 验证Keys的有效性
+// 依次检查格式、本次提交内重复、是否已被使用，最后向上游校验Key是否可用，
+// 只有上游确认可用 (live) 的Key才会被接受
 func (s *DonateService) ValidateKeys(ctx context.Context, keys []string) ([]model.KeyValidationResult, []string) {
 	results := make([]model.KeyValidationResult, 0, len(keys))
-	validKeys := make([]string, 0, len(keys))
+	candidates := make([]string, 0, len(keys))
+	candidateIndex := make(map[string]int)
 	seenKeys := make(map[string]bool)
 
 	for _, key := range keys {
@@ -227,28 +752,45 @@ func (s *DonateService) ValidateKeys(ctx context.Context, keys []string) ([]mode
 		}
 		seenKeys[key] = true
 
-		// 检查布隆过滤器（快速检查）
-		keyHash := repository.HashKey(key)
-		exists, err := s.cacheService.BloomFilterExists(ctx, keyHash)
-		if err == nil && exists {
-			// 布隆过滤器显示可能存在，需要进一步验证
-			dbExists, err := s.keyRepo.Exists(ctx, keyHash)
-			if err == nil && dbExists {
-				results = append(results, model.KeyValidationResult{
-					Key:    key,
-					Valid:  false,
-					Reason: "Key already used",
-				})
-				continue
-			}
+		// 检查是否已被使用（布隆过滤器快速排除，不可用时查询数据库）
+		used, err := s.isKeyUsed(ctx, repository.HashKey(key))
+		if err != nil {
+			results = append(results, model.KeyValidationResult{
+				Key:    key,
+				Valid:  false,
+				Reason: "Failed to check key usage, please try again later",
+			})
+			continue
+		}
+		if used {
+			results = append(results, model.KeyValidationResult{
+				Key:    key,
+				Valid:  false,
+				Reason: "Key already used",
+			})
+			continue
 		}
 
-		// Key有效
-		validKeys = append(validKeys, key)
-		results = append(results, model.KeyValidationResult{
-			Key:   key,
-			Valid: true,
-		})
+		// 待上游校验
+		candidateIndex[key] = len(results)
+		candidates = append(candidates, key)
+		results = append(results, model.KeyValidationResult{Key: key})
+	}
+
+	// 上游校验
+	verifyResults := s.verifyKeys(ctx, candidates)
+
+	validKeys := make([]string, 0, len(candidates))
+	for _, key := range candidates {
+		verify := verifyResults[key]
+		result := &results[candidateIndex[key]]
+		result.Status = verify.Status
+		if verify.Live() {
+			result.Valid = true
+			validKeys = append(validKeys, key)
+			continue
+		}
+		result.Reason = verify.Reason
 	}
 
 	return results, validKeys
@@ -379,7 +921,7 @@ func (s *DonateService) PushKeys(ctx context.Context, keys []string) *PushKeysRe
 }
 
 // GetDonateHistory 获取用户的投喂历史
-func (s *DonateService) GetDonateHistory(ctx context.Context, linuxDoID string, page, pageSize int) ([]*model.DonateRecord, int64, error) {
+func (s *DonateService) GetDonateHistory(ctx context.Context, userID int, page, pageSize int) ([]*model.DonateRecord, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -389,15 +931,15 @@ func (s *DonateService) GetDonateHistory(ctx context.Context, linuxDoID string,
 
 	offset := (page - 1) * pageSize
 
-	records, err := s.donateRepo.GetByLinuxDoID(ctx, linuxDoID, pageSize, offset)
+	records, err := s.donateRepo.GetByUserID(ctx, userID, pageSize, offset)
 	if err != nil {
-		s.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get donate history")
+		s.logger.WithError(err).WithField("user_id", userID).Error("Failed to get donate history")
 		return nil, 0, fmt.Errorf("failed to get donate history: %w", err)
 	}
 
-	total, err := s.donateRepo.CountByLinuxDoID(ctx, linuxDoID)
+	total, err := s.donateRepo.CountByUserID(ctx, userID)
 	if err != nil {
-		s.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to count donate records")
+		s.logger.WithError(err).WithField("user_id", userID).Error("Failed to count donate records")
 		return nil, 0, fmt.Errorf("failed to count donate records: %w", err)
 	}
 
@@ -405,22 +947,22 @@ func (s *DonateService) GetDonateHistory(ctx context.Context, linuxDoID string,
 }
 
 // GetUserDonateStats 获取用户的投喂统计
-func (s *DonateService) GetUserDonateStats(ctx context.Context, linuxDoID string) (totalDonates int64, totalKeys int64, totalQuota int64, err error) {
-	totalDonates, err = s.donateRepo.CountByLinuxDoID(ctx, linuxDoID)
+func (s *DonateService) GetUserDonateStats(ctx context.Context, userID int) (totalDonates int64, totalKeys int64, totalQuota int64, err error) {
+	totalDonates, err = s.donateRepo.CountByUserID(ctx, userID)
 	if err != nil {
-		s.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to count user donates")
+		s.logger.WithError(err).WithField("user_id", userID).Error("Failed to count user donates")
 		return 0, 0, 0, fmt.Errorf("failed to count donates: %w", err)
 	}
 
-	totalKeys, err = s.donateRepo.GetTotalKeys(ctx, linuxDoID)
+	totalKeys, err = s.donateRepo.GetTotalKeys(ctx, userID)
 	if err != nil {
-		s.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get total donated keys")
+		s.logger.WithError(err).WithField("user_id", userID).Error("Failed to get total donated keys")
 		return 0, 0, 0, fmt.Errorf("failed to get total keys: %w", err)
 	}
 
-	totalQuota, err = s.donateRepo.GetTotalQuota(ctx, linuxDoID)
+	totalQuota, err = s.donateRepo.GetTotalQuota(ctx, userID)
 	if err != nil {
-		s.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get total donated quota")
+		s.logger.WithError(err).WithField("user_id", userID).Error("Failed to get total donated quota")
 		return 0, 0, 0, fmt.Errorf("failed to get total quota: %w", err)
 	}
 
@@ -555,17 +1097,62 @@ func (s *DonateService) GetRecentDonates(ctx context.Context, limit int) ([]*mod
 // CheckKeyExists 检查Key是否已被使用
 func (s *DonateService) CheckKeyExists(ctx context.Context, key string) (bool, error) {
 	key = strings.TrimSpace(key)
-	keyHash := repository.HashKey(key)
+	return s.isKeyUsed(ctx, repository.HashKey(key))
+}
 
-	// 先检查布隆过滤器
-	exists, err := s.cacheService.BloomFilterExists(ctx, keyHash)
+// isKeyUsed 检查Key哈希是否已被使用
+// 布隆过滤器已就绪且判定不存在时直接返回；过滤器可能存在、未就绪或 Redis 不可用时以数据库为准
+func (s *DonateService) isKeyUsed(ctx context.Context, keyHash string) (bool, error) {
+	mightContain, ready, err := s.keyFilter.Check(ctx, keyHash)
 	if err != nil {
-		s.logger.WithError(err).Warn("Failed to check bloom filter")
-	} else if !exists {
-		// 布隆过滤器说不存在，则一定不存在
+		s.logger.WithError(err).Warn("Failed to check key filter, falling back to database")
+	} else if ready && !mightContain {
 		return false, nil
 	}
 
-	// 布隆过滤器可能存在，检查数据库
 	return s.keyRepo.Exists(ctx, keyHash)
 }
+
+// RebuildKeyFilter 从 used_keys 重建已使用Key布隆过滤器，返回写入的Key数量
+// 重建期间旧过滤器（如有）继续提供服务，完成后原子替换
+func (s *DonateService) RebuildKeyFilter(ctx context.Context) (int, error) {
+	lockKey := s.cacheService.KeyFilterLockKey()
+	lockToken, err := s.cacheService.AcquireLock(ctx, lockKey, keyFilterRebuildLockTTL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire key filter lock: %w", err)
+	}
+	if lockToken == "" {
+		return 0, fmt.Errorf("key filter rebuild already in progress")
+	}
+	defer s.cacheService.ReleaseLock(ctx, lockKey, lockToken)
+
+	startedAt := time.Now()
+	count := 0
+	err = s.keyFilter.Rebuild(ctx, func(add func(members ...string) error) error {
+		return s.keyRepo.ForEachHash(ctx, keyFilterRebuildBatch, func(keyHashes []string) error {
+			count += len(keyHashes)
+			return add(keyHashes...)
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// 重建期间完成的投喂写入的是旧位图，替换后补录
+	recent, err := s.keyRepo.ListHashesSince(ctx, startedAt.Add(-keyFilterCatchUpWindow))
+	if err != nil {
+		return 0, err
+	}
+	if err := s.keyFilter.Add(ctx, recent...); err != nil {
+		return 0, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"keys":     count,
+		"bits":     s.keyFilter.Bits(),
+		"hashes":   s.keyFilter.Hashes(),
+		"duration": time.Since(startedAt),
+	}).Info("Key filter rebuilt")
+
+	return count, nil
+}
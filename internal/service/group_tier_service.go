@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+)
+
+// groupTierBatchSize 定期评估时每批读取的用户数量
+const groupTierBatchSize = 200
+
+// GroupTierService 按投喂量自动调整公益站用户组
+// 每次投喂成功后评估投喂用户，后台任务定期评估全部已绑定用户（处理降级和失败重试），
+// 档位变化时调用公益站接口调整用户组，并在 group_changes 中记录每次调整
+type GroupTierService struct {
+	tierRepo        *repository.GroupTierRepository
+	adminConfigRepo *repository.AdminConfigRepository
+	kyxClient       *KyxClient
+	defaults        model.GroupTierPolicy
+	interval        time.Duration
+	logger          *logrus.Logger
+	stopCh          chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewGroupTierService 创建用户组档位服务
+func NewGroupTierService(
+	tierRepo *repository.GroupTierRepository,
+	adminConfigRepo *repository.AdminConfigRepository,
+	kyxClient *KyxClient,
+	defaults model.GroupTierPolicy,
+	interval time.Duration,
+	logger *logrus.Logger,
+) *GroupTierService {
+	return &GroupTierService{
+		tierRepo:        tierRepo,
+		adminConfigRepo: adminConfigRepo,
+		kyxClient:       kyxClient,
+		defaults:        defaults,
+		interval:        interval,
+		logger:          logger,
+		stopCh:          make(chan struct{}),
+	}
+}
+
+// policy 获取生效的用户组档位策略
+func (s *GroupTierService) policy(ctx context.Context) model.GroupTierPolicy {
+	policy, err := s.adminConfigRepo.GetGroupTierPolicy(ctx, s.defaults)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to get group tier policy, using defaults")
+	}
+	return policy
+}
+
+// targetTier 计算用户应处于的档位，未达到任何档位或超过降级天数没有投喂时返回 nil
+func targetTier(policy model.GroupTierPolicy, donor *model.DonorProgress, now time.Time) *model.GroupTier {
+	idx := policy.Tiers.Match(donor.KeysDonated, donor.DonateQuota)
+	if idx < 0 {
+		return nil
+	}
+	if policy.DemoteInactiveDays > 0 {
+		cutoff := now.AddDate(0, 0, -policy.DemoteInactiveDays)
+		if !donor.LastDonateAt.Valid || donor.LastDonateAt.Time.Before(cutoff) {
+			return nil
+		}
+	}
+	return &policy.Tiers[idx]
+}
+
+// Evaluate 评估单个用户的档位，档位变化时调整公益站用户组（未配置档位或用户未绑定时跳过）
+func (s *GroupTierService) Evaluate(ctx context.Context, userID int, source string) error {
+	policy := s.policy(ctx)
+	if len(policy.Tiers) == 0 {
+		return nil
+	}
+
+	donor, err := s.tierRepo.GetDonor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if donor == nil {
+		return nil
+	}
+
+	return s.apply(ctx, policy, donor, source)
+}
+
+// EvaluateAll 评估全部已绑定用户，返回调整成功的用户数量
+func (s *GroupTierService) EvaluateAll(ctx context.Context) (int, error) {
+	policy := s.policy(ctx)
+	if len(policy.Tiers) == 0 {
+		return 0, nil
+	}
+
+	changed := 0
+	afterID := 0
+	for {
+		donors, err := s.tierRepo.ListDonors(ctx, afterID, groupTierBatchSize)
+		if err != nil {
+			return changed, err
+		}
+
+		for _, donor := range donors {
+			target := targetTier(policy, donor, time.Now())
+			if tierName(target) == donor.CurrentTier {
+				continue
+			}
+			if err := s.apply(ctx, policy, donor, model.GroupChangeSourceScheduled); err != nil {
+				s.logger.WithError(err).WithField("linux_do_id", donor.LinuxDoID).Warn("Failed to apply group tier")
+				continue
+			}
+			changed++
+		}
+
+		if len(donors) < groupTierBatchSize {
+			return changed, nil
+		}
+		afterID = donors[len(donors)-1].UserID
+
+		select {
+		case <-ctx.Done():
+			return changed, ctx.Err()
+		case <-s.stopCh:
+			return changed, nil
+		default:
+		}
+	}
+}
+
+// apply 用户应处于的档位与当前档位不同时调整公益站用户组并记录调整结果
+// 调用失败时当前档位不变，下一轮定期评估会重试
+func (s *GroupTierService) apply(ctx context.Context, policy model.GroupTierPolicy, donor *model.DonorProgress, source string) error {
+	target := targetTier(policy, donor, time.Now())
+	if tierName(target) == donor.CurrentTier {
+		return nil
+	}
+
+	groupID := policy.DefaultGroupID
+	if target != nil {
+		groupID = target.GroupID
+	}
+
+	change := &model.GroupChange{
+		UserID:      sql.NullInt64{Int64: int64(donor.UserID), Valid: true},
+		LinuxDoID:   donor.LinuxDoID,
+		KyxUserID:   donor.KyxUserID,
+		FromTier:    donor.CurrentTier,
+		ToTier:      tierName(target),
+		GroupID:     groupID,
+		Source:      source,
+		Status:      model.GroupChangeStatusSuccess,
+		KeysDonated: donor.KeysDonated,
+		DonateQuota: donor.DonateQuota,
+	}
+
+	updateErr := s.kyxClient.UpdateGroup(ctx, donor.KyxUserID, groupID)
+	if updateErr != nil {
+		change.Status = model.GroupChangeStatusFailed
+		change.Error = updateErr.Error()
+	}
+
+	if err := s.tierRepo.CreateChange(ctx, change); err != nil {
+		return err
+	}
+
+	fields := logrus.Fields{
+		"linux_do_id": donor.LinuxDoID,
+		"kyx_user_id": donor.KyxUserID,
+		"from_tier":   change.FromTier,
+		"to_tier":     change.ToTier,
+		"group_id":    groupID,
+		"source":      source,
+	}
+	if updateErr != nil {
+		s.logger.WithError(updateErr).WithFields(fields).Warn("Failed to update kyx user group")
+		return fmt.Errorf("failed to update kyx user group: %w", updateErr)
+	}
+
+	s.logger.WithFields(fields).Info("Kyx user group updated")
+	return nil
+}
+
+// tierName 档位名称，nil 表示默认用户组
+func tierName(tier *model.GroupTier) string {
+	if tier == nil {
+		return ""
+	}
+	return tier.Name
+}
+
+// GetProgress 获取用户当前档位和升级到下一档位的进度，用户未绑定时返回 nil
+func (s *GroupTierService) GetProgress(ctx context.Context, userID int) (*model.GroupTierProgress, error) {
+	donor, err := s.tierRepo.GetDonor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if donor == nil {
+		return nil, nil
+	}
+
+	policy := s.policy(ctx)
+
+	progress := &model.GroupTierProgress{
+		CurrentTier:    donor.CurrentTier,
+		GroupID:        policy.DefaultGroupID,
+		KeysDonated:    donor.KeysDonated,
+		DonateQuota:    donor.DonateQuota,
+		DonateQuotaUSD: model.QuotaToDollar(donor.DonateQuota),
+	}
+	if tier := policy.Tiers.Find(donor.CurrentTier); tier != nil {
+		progress.GroupID = tier.GroupID
+	}
+	if donor.LastDonateAt.Valid {
+		lastDonateAt := donor.LastDonateAt.Time
+		progress.LastDonateAt = &lastDonateAt
+		if donor.CurrentTier != "" && policy.DemoteInactiveDays > 0 {
+			demoteAt := lastDonateAt.AddDate(0, 0, policy.DemoteInactiveDays)
+			progress.DemoteAt = &demoteAt
+		}
+	}
+
+	// 下一档位：累计投喂已达到的最高档位之后的一档
+	next := policy.Tiers.Match(donor.KeysDonated, donor.DonateQuota) + 1
+	if next < len(policy.Tiers) {
+		tier := policy.Tiers[next]
+		progress.NextTier = &tier
+		if tier.MinKeys > 0 {
+			progress.KeysToNext = tier.MinKeys - donor.KeysDonated
+		}
+		if tier.MinDonateQuota > 0 {
+			progress.QuotaToNext = tier.MinDonateQuota - donor.DonateQuota
+		}
+	}
+
+	return progress, nil
+}
+
+// ListChanges 分页获取用户组调整记录（linux_do_id 为空时返回全部）
+func (s *GroupTierService) ListChanges(ctx context.Context, linuxDoID string, page, pageSize int) (*model.PaginationResult, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	changes, err := s.tierRepo.ListChanges(ctx, linuxDoID, pageSize, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.tierRepo.CountChanges(ctx, linuxDoID)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+
+	return &model.PaginationResult{
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+		TotalPages: totalPages,
+		HasMore:    page < totalPages,
+		Data:       changes,
+	}, nil
+}
+
+// Start 启动后台定期评估任务
+func (s *GroupTierService) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		s.logger.WithField("interval", s.interval.String()).Info("Group tier evaluator started")
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.stopCh:
+				return
+			case <-ticker.C:
+				changed, err := s.EvaluateAll(ctx)
+				if err != nil {
+					s.logger.WithError(err).Error("Failed to evaluate group tiers")
+				} else if changed > 0 {
+					s.logger.WithField("changed", changed).Info("Group tiers evaluated")
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止后台定期评估任务
+func (s *GroupTierService) Stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+	s.logger.Info("Group tier evaluator stopped")
+}
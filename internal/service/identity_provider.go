@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+)
+
+// IdentityProvider 身份提供方（OAuth2 授权码流程）
+type IdentityProvider interface {
+	// Name 提供方名称，用于路由参数和 user_identities.provider
+	Name() string
+	// DisplayName 展示给用户的名称
+	DisplayName() string
+	// AuthorizationURL 生成授权跳转地址
+	AuthorizationURL(ctx context.Context, state string) (string, error)
+	// ExchangeCode 使用授权码换取令牌
+	ExchangeCode(ctx context.Context, code string) (*model.OAuthToken, error)
+	// UserInfo 获取令牌对应的用户身份
+	UserInfo(ctx context.Context, token *model.OAuthToken) (*model.ExternalIdentity, error)
+}
+
+// LinuxDoProvider Linux.do 身份提供方
+type LinuxDoProvider struct {
+	client *LinuxDoClient
+}
+
+// NewLinuxDoProvider 创建 Linux.do 身份提供方
+func NewLinuxDoProvider(client *LinuxDoClient) *LinuxDoProvider {
+	return &LinuxDoProvider{client: client}
+}
+
+// Name 提供方名称
+func (p *LinuxDoProvider) Name() string {
+	return model.IdentityProviderLinuxDo
+}
+
+// DisplayName 展示名称
+func (p *LinuxDoProvider) DisplayName() string {
+	return "Linux.do"
+}
+
+// AuthorizationURL 生成授权跳转地址
+func (p *LinuxDoProvider) AuthorizationURL(ctx context.Context, state string) (string, error) {
+	return p.client.GetAuthorizationURL(state), nil
+}
+
+// ExchangeCode 使用授权码换取令牌
+func (p *LinuxDoProvider) ExchangeCode(ctx context.Context, code string) (*model.OAuthToken, error) {
+	tokenResp, err := p.client.ExchangeCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.OAuthToken{
+		AccessToken:  tokenResp.AccessToken,
+		TokenType:    tokenResp.TokenType,
+		ExpiresIn:    tokenResp.ExpiresIn,
+		RefreshToken: tokenResp.RefreshToken,
+		Scope:        tokenResp.Scope,
+	}, nil
+}
+
+// UserInfo 获取 Linux.do 用户信息（包含信任等级和账号状态）
+func (p *LinuxDoProvider) UserInfo(ctx context.Context, token *model.OAuthToken) (*model.ExternalIdentity, error) {
+	userInfo, err := p.client.GetUserInfo(ctx, token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if userInfo.ID == 0 {
+		return nil, fmt.Errorf("linux.do user info missing id")
+	}
+
+	return &model.ExternalIdentity{
+		Provider: model.IdentityProviderLinuxDo,
+		Subject:  strconv.Itoa(userInfo.ID),
+		Username: userInfo.Username,
+		LinuxDo:  userInfo,
+	}, nil
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+)
+
+// KeyVerifyResult Key上游校验结果
+type KeyVerifyResult struct {
+	Status string `json:"status"` // live, invalid, quota_exhausted, rate_limited, unknown
+	Reason string `json:"reason,omitempty"`
+}
+
+// Live 是否为可用Key
+func (r KeyVerifyResult) Live() bool {
+	return r.Status == model.KeyStatusLive
+}
+
+// KeyVerifier Key可用性校验接口
+type KeyVerifier interface {
+	// Verify 校验单个Key，网络错误等无法判定的情况返回 unknown
+	Verify(ctx context.Context, key string) KeyVerifyResult
+}
+
+// keyVerifyCacheTTL 各校验状态的缓存时长，0 表示不缓存
+var keyVerifyCacheTTL = map[string]time.Duration{
+	model.KeyStatusLive:           10 * time.Minute,
+	model.KeyStatusInvalid:        24 * time.Hour,
+	model.KeyStatusQuotaExhausted: time.Hour,
+	model.KeyStatusRateLimited:    0,
+	model.KeyStatusUnknown:        0,
+}
+
+// ModelScopeKeyVerifier 基于 ModelScope API 的Key校验器
+type ModelScopeKeyVerifier struct {
+	baseURL    string
+	model      string
+	httpClient *http.Client
+	logger     *logrus.Logger
+}
+
+// NewModelScopeKeyVerifier 创建 ModelScope Key校验器，model 为校验时调用的模型
+func NewModelScopeKeyVerifier(baseURL, model string, timeout time.Duration, logger *logrus.Logger) *ModelScopeKeyVerifier {
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &ModelScopeKeyVerifier{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		httpClient: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+		logger: logger,
+	}
+}
+
+// Verify 通过 max_tokens=1 的 POST /chat/completions 校验Key
+// GET /models 是公开接口，不校验Key，也无法得知额度耗尽或被限流；
+// 推理接口会校验Key并扣除额度，每次校验只消耗一个输出 token
+func (v *ModelScopeKeyVerifier) Verify(ctx context.Context, key string) KeyVerifyResult {
+	payload, err := json.Marshal(map[string]interface{}{
+		"model":      v.model,
+		"messages":   []map[string]string{{"role": "user", "content": "hi"}},
+		"max_tokens": 1,
+		"stream":     false,
+	})
+	if err != nil {
+		return KeyVerifyResult{Status: model.KeyStatusUnknown, Reason: fmt.Sprintf("failed to marshal request: %v", err)}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", v.baseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return KeyVerifyResult{Status: model.KeyStatusUnknown, Reason: fmt.Sprintf("failed to create request: %v", err)}
+	}
+
+	req.Header.Set("Authorization", "Bearer "+key)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "KyxQuotaBridge/1.0")
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		v.logger.WithError(err).Debug("ModelScope key verification request failed")
+		return KeyVerifyResult{Status: model.KeyStatusUnknown, Reason: "upstream unreachable"}
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	result := classifyKeyResponse(resp.StatusCode, string(body))
+	if result.Status == model.KeyStatusUnknown {
+		// 模型不存在等配置问题也会落到这里，记录响应便于排查
+		v.logger.WithFields(logrus.Fields{
+			"status_code": resp.StatusCode,
+			"response":    string(body),
+			"model":       v.model,
+		}).Warn("Unexpected key verification response")
+	}
+	return result
+}
+
+// classifyKeyResponse 将上游响应归类为校验状态
+func classifyKeyResponse(statusCode int, body string) KeyVerifyResult {
+	lowerBody := strings.ToLower(body)
+
+	switch {
+	case statusCode == http.StatusOK:
+		return KeyVerifyResult{Status: model.KeyStatusLive}
+	case statusCode == http.StatusTooManyRequests:
+		if strings.Contains(lowerBody, "quota") || strings.Contains(lowerBody, "insufficient") {
+			return KeyVerifyResult{Status: model.KeyStatusQuotaExhausted, Reason: "Key quota exhausted"}
+		}
+		return KeyVerifyResult{Status: model.KeyStatusRateLimited, Reason: "Key is rate limited, please try again later"}
+	case statusCode == http.StatusPaymentRequired:
+		return KeyVerifyResult{Status: model.KeyStatusQuotaExhausted, Reason: "Key quota exhausted"}
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return KeyVerifyResult{Status: model.KeyStatusInvalid, Reason: "Key is invalid or revoked"}
+	default:
+		return KeyVerifyResult{Status: model.KeyStatusUnknown, Reason: fmt.Sprintf("unexpected upstream status %d", statusCode)}
+	}
+}
+
+// verifyKeys 并发校验Keys（限制并发数和单Key超时），结果按Key哈希缓存
+func (s *DonateService) verifyKeys(ctx context.Context, keys []string) map[string]KeyVerifyResult {
+	results := make(map[string]KeyVerifyResult, len(keys))
+	// 只有显式关闭校验 (KEY_VERIFY_DISABLED=true) 时 keyVerifier 才为 nil
+	if s.keyVerifier == nil || len(keys) == 0 {
+		for _, key := range keys {
+			results[key] = KeyVerifyResult{Status: model.KeyStatusLive}
+		}
+		return results
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, s.verifyConcurrency)
+
+	for _, key := range keys {
+		keyHash := repository.HashKey(key)
+
+		// 先查缓存
+		if cached, err := s.cacheService.GetKeyVerifyResult(ctx, keyHash); err == nil && cached != nil {
+			results[key] = *cached
+			continue
+		}
+
+		wg.Add(1)
+		go func(key, keyHash string) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			verifyCtx, cancel := context.WithTimeout(ctx, s.verifyTimeout)
+			defer cancel()
+
+			result := s.keyVerifier.Verify(verifyCtx, key)
+			if verifyCtx.Err() == context.DeadlineExceeded && result.Status == model.KeyStatusUnknown {
+				result.Reason = "verification timed out"
+			}
+
+			if ttl := keyVerifyCacheTTL[result.Status]; ttl > 0 {
+				_ = s.cacheService.SetKeyVerifyResult(ctx, keyHash, &result, ttl)
+			}
+
+			mu.Lock()
+			results[key] = result
+			mu.Unlock()
+		}(key, keyHash)
+	}
+
+	wg.Wait()
+	return results
+}
@@ -8,18 +8,28 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/yourusername/kyx-quota-bridge/internal/model"
 )
 
+const (
+	// kyxSearchPageSize 搜索用户每页数量
+	kyxSearchPageSize = 100
+	// kyxSearchMaxPages 搜索用户最多遍历的页数
+	kyxSearchMaxPages = 20
+)
+
 // KyxClient 公益站API客户端
 type KyxClient struct {
 	baseURL    string
 	httpClient *http.Client
-	session    string
 	logger     *logrus.Logger
+
+	mu      sync.RWMutex
+	session string
 }
 
 // KyxClientConfig 公益站客户端配置
@@ -52,18 +62,83 @@ func NewKyxClient(config KyxClientConfig, logger *logrus.Logger) *KyxClient {
 
 // UpdateSession 更新Session
 func (c *KyxClient) UpdateSession(session string) {
+	c.mu.Lock()
 	c.session = session
+	c.mu.Unlock()
 	c.logger.Info("Kyx client session updated")
 }
 
-// SearchUser 搜索用户
+// Session 当前使用的Session
+func (c *KyxClient) Session() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.session
+}
+
+// SearchUser 按 Linux.do ID 查找公益站用户
+// keyword 搜索是模糊匹配，遍历所有结果页并只接受 linux_do_id 完全相同的用户；
+// 没有匹配时返回 nil，匹配到多个不同账号时返回错误
 func (c *KyxClient) SearchUser(ctx context.Context, linuxDoID string) (*model.KyxUser, error) {
-	if c.session == "" {
+	if c.Session() == "" {
 		return nil, fmt.Errorf("session not configured")
 	}
 
+	seen := make(map[int]bool)
+	var match *model.KyxUser
+
+	for page := 0; page < kyxSearchMaxPages; page++ {
+		users, err := c.searchPage(ctx, linuxDoID, page)
+		if err != nil {
+			return nil, err
+		}
+
+		added := 0
+		for i := range users {
+			user := users[i]
+			if seen[user.ID] {
+				continue
+			}
+			seen[user.ID] = true
+			added++
+
+			if user.LinuxDoID != linuxDoID {
+				continue
+			}
+			if match != nil {
+				c.logger.WithFields(logrus.Fields{
+					"linux_do_id":  linuxDoID,
+					"kyx_user_ids": []int{match.ID, user.ID},
+				}).Warn("Multiple Kyx users match linux_do_id")
+				return nil, fmt.Errorf("multiple Kyx accounts are linked to this linux.do account")
+			}
+			match = &user
+		}
+
+		// 最后一页（部分版本不支持分页时，后续页与首页相同，不会有新用户）
+		if len(users) < kyxSearchPageSize || added == 0 {
+			break
+		}
+	}
+
+	if match == nil {
+		c.logger.WithField("linux_do_id", linuxDoID).Warn("User not found in search results")
+		return nil, nil
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"linux_do_id": linuxDoID,
+		"username":    match.Username,
+		"kyx_user_id": match.ID,
+	}).Info("User found in Kyx API")
+
+	return match, nil
+}
+
+// searchPage 获取一页搜索结果（页码从 0 开始，以 1 起始计页的版本会把 0 当作第 1 页）
+func (c *KyxClient) searchPage(ctx context.Context, keyword string, page int) ([]model.KyxUser, error) {
 	// 构建搜索URL
-	searchURL := fmt.Sprintf("%s/api/user?keyword=%s", c.baseURL, url.QueryEscape(linuxDoID))
+	searchURL := fmt.Sprintf("%s/api/user?keyword=%s&p=%d&page_size=%d",
+		c.baseURL, url.QueryEscape(keyword), page, kyxSearchPageSize)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
 	if err != nil {
@@ -72,14 +147,14 @@ func (c *KyxClient) SearchUser(ctx context.Context, linuxDoID string) (*model.Ky
 	}
 
 	// 设置请求头
-	req.Header.Set("Cookie", fmt.Sprintf("session=%s", c.session))
+	req.Header.Set("Cookie", fmt.Sprintf("session=%s", c.Session()))
 	req.Header.Set("User-Agent", "KyxQuotaBridge/1.0")
 	req.Header.Set("Accept", "application/json")
 
 	// 发送请求
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		c.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to search user")
+		c.logger.WithError(err).WithField("keyword", keyword).Error("Failed to search user")
 		return nil, fmt.Errorf("failed to search user: %w", err)
 	}
 	defer resp.Body.Close()
@@ -109,31 +184,19 @@ func (c *KyxClient) SearchUser(ctx context.Context, linuxDoID string) (*model.Ky
 
 	if !searchResp.Success {
 		c.logger.WithFields(logrus.Fields{
-			"linux_do_id": linuxDoID,
-			"message":     searchResp.Message,
+			"keyword": keyword,
+			"page":    page,
+			"message": searchResp.Message,
 		}).Warn("Search user returned unsuccessful")
 		return nil, fmt.Errorf("search failed: %s", searchResp.Message)
 	}
 
-	// 查找匹配的用户
-	for _, user := range searchResp.Data {
-		if user.LinuxDoID == linuxDoID {
-			c.logger.WithFields(logrus.Fields{
-				"linux_do_id": linuxDoID,
-				"username":    user.Username,
-				"kyx_user_id": user.ID,
-			}).Info("User found in Kyx API")
-			return &user, nil
-		}
-	}
-
-	c.logger.WithField("linux_do_id", linuxDoID).Warn("User not found in search results")
-	return nil, fmt.Errorf("user not found")
+	return searchResp.Data, nil
 }
 
 // GetUserByID 根据ID获取用户信息
 func (c *KyxClient) GetUserByID(ctx context.Context, kyxUserID int) (*model.KyxUser, error) {
-	if c.session == "" {
+	if c.Session() == "" {
 		return nil, fmt.Errorf("session not configured")
 	}
 
@@ -147,7 +210,7 @@ func (c *KyxClient) GetUserByID(ctx context.Context, kyxUserID int) (*model.KyxU
 	}
 
 	// 设置请求头
-	req.Header.Set("Cookie", fmt.Sprintf("session=%s", c.session))
+	req.Header.Set("Cookie", fmt.Sprintf("session=%s", c.Session()))
 	req.Header.Set("User-Agent", "KyxQuotaBridge/1.0")
 	req.Header.Set("Accept", "application/json")
 
@@ -191,8 +254,9 @@ func (c *KyxClient) GetUserByID(ctx context.Context, kyxUserID int) (*model.KyxU
 }
 
 // AddQuota 为用户增加额度
+// 公益站不支持幂等去重，重复投递由 QuotaGrantService 串行投递并在重试前对账避免
 func (c *KyxClient) AddQuota(ctx context.Context, kyxUserID int, quota int64) error {
-	if c.session == "" {
+	if c.Session() == "" {
 		return fmt.Errorf("session not configured")
 	}
 
@@ -216,7 +280,7 @@ func (c *KyxClient) AddQuota(ctx context.Context, kyxUserID int, quota int64) er
 	}
 
 	// 设置请求头
-	req.Header.Set("Cookie", fmt.Sprintf("session=%s", c.session))
+	req.Header.Set("Cookie", fmt.Sprintf("session=%s", c.Session()))
 	req.Header.Set("User-Agent", "KyxQuotaBridge/1.0")
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
@@ -270,7 +334,7 @@ func (c *KyxClient) GetQuota(ctx context.Context, kyxUserID int) (quota int64, u
 
 // ValidateSession 验证Session是否有效
 func (c *KyxClient) ValidateSession(ctx context.Context) error {
-	if c.session == "" {
+	if c.Session() == "" {
 		return fmt.Errorf("session not configured")
 	}
 
@@ -282,7 +346,7 @@ func (c *KyxClient) ValidateSession(ctx context.Context) error {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Cookie", fmt.Sprintf("session=%s", c.session))
+	req.Header.Set("Cookie", fmt.Sprintf("session=%s", c.Session()))
 	req.Header.Set("User-Agent", "KyxQuotaBridge/1.0")
 	req.Header.Set("Accept", "application/json")
 
@@ -308,7 +372,7 @@ func (c *KyxClient) ValidateSession(ctx context.Context) error {
 
 // UpdateGroup 更新用户组
 func (c *KyxClient) UpdateGroup(ctx context.Context, kyxUserID int, groupID int) error {
-	if c.session == "" {
+	if c.Session() == "" {
 		return fmt.Errorf("session not configured")
 	}
 
@@ -332,7 +396,7 @@ func (c *KyxClient) UpdateGroup(ctx context.Context, kyxUserID int, groupID int)
 	}
 
 	// 设置请求头
-	req.Header.Set("Cookie", fmt.Sprintf("session=%s", c.session))
+	req.Header.Set("Cookie", fmt.Sprintf("session=%s", c.Session()))
 	req.Header.Set("User-Agent", "KyxQuotaBridge/1.0")
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
@@ -372,6 +436,68 @@ func (c *KyxClient) UpdateGroup(ctx context.Context, kyxUserID int, groupID int)
 	return nil
 }
 
+// Login 使用公益站管理员账号密码登录，返回新的Session
+// 账号开启两步验证时无法自动登录
+func (c *KyxClient) Login(ctx context.Context, username, password string) (string, error) {
+	loginURL := fmt.Sprintf("%s/api/user/login", c.baseURL)
+
+	jsonData, err := json.Marshal(map[string]string{
+		"username": username,
+		"password": password,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", loginURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to create login request")
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "KyxQuotaBridge/1.0")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to login to kyx")
+		return "", fmt.Errorf("failed to login: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		c.logger.WithField("status_code", resp.StatusCode).Warn("Kyx login request failed")
+		return "", fmt.Errorf("login failed with status %d", resp.StatusCode)
+	}
+
+	var loginResp struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &loginResp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if !loginResp.Success {
+		c.logger.WithField("message", loginResp.Message).Warn("Kyx login returned unsuccessful")
+		return "", fmt.Errorf("login failed: %s", loginResp.Message)
+	}
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "session" && cookie.Value != "" {
+			c.logger.WithField("username", username).Info("Logged in to kyx")
+			return cookie.Value, nil
+		}
+	}
+
+	return "", fmt.Errorf("login response did not set a session cookie")
+}
+
 // Ping 测试连接
 func (c *KyxClient) Ping(ctx context.Context) error {
 	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL, nil)
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+)
+
+// kyxSessionCheckTimeout 单次校验（包括重新登录）的超时时间
+const kyxSessionCheckTimeout = time.Minute
+
+// KyxSessionMonitorConfig 公益站 Session 健康检查配置
+type KyxSessionMonitorConfig struct {
+	Interval      time.Duration // 校验间隔，0 表示不启动后台校验
+	LoginUsername string        // 公益站管理员账号，与密码都配置时 Session 失效后自动重新登录
+	LoginPassword string
+}
+
+// KyxSessionMonitor 公益站 Session 健康检查
+// 定期校验 Session，失效时先加载数据库中的 Session（可能已被其他实例或管理员更新），
+// 仍然无效时使用管理员账号重新登录并保存新的 Session；都失败时产生告警，
+// 告警期间领取和投喂直接返回公益站不可用，恢复后自动解决告警
+type KyxSessionMonitor struct {
+	kyxClient       *KyxClient
+	adminConfigRepo *repository.AdminConfigRepository
+	alertService    *AlertService
+	config          KyxSessionMonitorConfig
+	autoRenewal     bool
+	logger          *logrus.Logger
+
+	mu     sync.RWMutex
+	status model.KyxSessionStatus
+
+	checkNow chan struct{}
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewKyxSessionMonitor 创建公益站 Session 健康检查服务
+func NewKyxSessionMonitor(
+	kyxClient *KyxClient,
+	adminConfigRepo *repository.AdminConfigRepository,
+	alertService *AlertService,
+	config KyxSessionMonitorConfig,
+	logger *logrus.Logger,
+) *KyxSessionMonitor {
+	autoRenewal := config.LoginUsername != "" && config.LoginPassword != ""
+
+	return &KyxSessionMonitor{
+		kyxClient:       kyxClient,
+		adminConfigRepo: adminConfigRepo,
+		alertService:    alertService,
+		config:          config,
+		autoRenewal:     autoRenewal,
+		logger:          logger,
+		status:          model.KyxSessionStatus{AutoRenewal: autoRenewal},
+		checkNow:        make(chan struct{}, 1),
+		stopCh:          make(chan struct{}),
+	}
+}
+
+// Check 校验 Session，失效时尝试续期，结果用于产生或解决告警
+func (m *KyxSessionMonitor) Check(ctx context.Context) error {
+	renewed := false
+	err := m.kyxClient.ValidateSession(ctx)
+	if err != nil {
+		renewed, err = m.renew(ctx, err)
+	}
+
+	m.record(err, renewed)
+
+	if err != nil {
+		if alertErr := m.alertService.Raise(ctx, model.AlertTypeKyxSession, err.Error()); alertErr != nil {
+			m.logger.WithError(alertErr).Error("Failed to raise kyx session alert")
+		}
+		return err
+	}
+
+	if alertErr := m.alertService.Resolve(ctx, model.AlertTypeKyxSession); alertErr != nil {
+		m.logger.WithError(alertErr).Error("Failed to resolve kyx session alert")
+	}
+	return nil
+}
+
+// renew Session 失效时续期，renewed 表示是否通过重新登录获得了新的 Session
+func (m *KyxSessionMonitor) renew(ctx context.Context, cause error) (renewed bool, err error) {
+	// 数据库中的 Session 与当前使用的不同时先尝试（启动时、其他实例续期后或管理员更新后）
+	stored, err := m.adminConfigRepo.GetSession(ctx)
+	if err != nil {
+		m.logger.WithError(err).Warn("Failed to load stored kyx session")
+	} else if stored != "" && stored != m.kyxClient.Session() {
+		m.kyxClient.UpdateSession(stored)
+		if err := m.kyxClient.ValidateSession(ctx); err == nil {
+			m.logger.Info("Stored kyx session loaded")
+			return false, nil
+		}
+	}
+
+	if !m.autoRenewal {
+		return false, fmt.Errorf("kyx session check failed: %w", cause)
+	}
+
+	session, err := m.kyxClient.Login(ctx, m.config.LoginUsername, m.config.LoginPassword)
+	if err != nil {
+		return false, fmt.Errorf("kyx session check failed (%v) and re-login failed: %w", cause, err)
+	}
+
+	m.kyxClient.UpdateSession(session)
+	if err := m.kyxClient.ValidateSession(ctx); err != nil {
+		return false, fmt.Errorf("kyx session from re-login is invalid: %w", err)
+	}
+
+	// 保存失败时当前实例仍可使用新的 Session，其他实例会各自重新登录
+	if err := m.adminConfigRepo.UpdateSession(ctx, session); err != nil {
+		m.logger.WithError(err).Error("Failed to save renewed kyx session")
+	}
+
+	m.logger.Info("Kyx session renewed by re-login")
+	return true, nil
+}
+
+// record 记录最近一次校验结果
+func (m *KyxSessionMonitor) record(err error, renewed bool) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.status.Checked = true
+	m.status.Valid = err == nil
+	m.status.CheckedAt = &now
+	m.status.Error = ""
+	if err != nil {
+		m.status.Error = err.Error()
+	}
+	if renewed {
+		m.status.RenewedAt = &now
+	}
+}
+
+// Status 获取最近一次校验结果
+func (m *KyxSessionMonitor) Status() model.KyxSessionStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// CheckAvailable 最近一次校验失败时返回公益站不可用错误（尚未校验或未启动后台校验时视为可用）
+func (m *KyxSessionMonitor) CheckAvailable() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.status.Checked && !m.status.Valid {
+		return fmt.Errorf("%s: kyx upstream is unavailable, please try again later", model.UpstreamUnavailable)
+	}
+	return nil
+}
+
+// Recheck 唤醒后台任务立即重新校验（管理员更新 Session 后调用）
+func (m *KyxSessionMonitor) Recheck() {
+	select {
+	case m.checkNow <- struct{}{}:
+	default:
+	}
+}
+
+// Start 启动后台校验任务，启动时立即校验一次
+func (m *KyxSessionMonitor) Start(ctx context.Context) {
+	if m.config.Interval <= 0 {
+		m.logger.Info("Kyx session monitor disabled")
+		return
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(m.config.Interval)
+		defer ticker.Stop()
+
+		m.logger.WithFields(logrus.Fields{
+			"interval":     m.config.Interval.String(),
+			"auto_renewal": m.autoRenewal,
+		}).Info("Kyx session monitor started")
+
+		for {
+			checkCtx, cancel := context.WithTimeout(ctx, kyxSessionCheckTimeout)
+			if err := m.Check(checkCtx); err != nil {
+				m.logger.WithError(err).Warn("Kyx session is unavailable")
+			}
+			cancel()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.stopCh:
+				return
+			case <-ticker.C:
+			case <-m.checkNow:
+			}
+		}
+	}()
+}
+
+// Stop 停止后台校验任务
+func (m *KyxSessionMonitor) Stop() {
+	close(m.stopCh)
+	m.wg.Wait()
+	m.logger.Info("Kyx session monitor stopped")
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,6 +15,15 @@ import (
 	"github.com/yourusername/kyx-quota-bridge/internal/model"
 )
 
+var (
+	// ErrLinuxDoTokenRejected 刷新令牌被拒绝（已过期、被撤销或用户取消了授权）
+	ErrLinuxDoTokenRejected = errors.New("linux.do refresh token rejected")
+	// ErrLinuxDoUnauthorized 访问令牌无效或已过期
+	ErrLinuxDoUnauthorized = errors.New("access token is invalid or expired")
+	// ErrLinuxDoUserNotFound 用户不存在或无法访问（账号已删除）
+	ErrLinuxDoUserNotFound = errors.New("linux.do user not found")
+)
+
 // LinuxDoClient Linux Do OAuth客户端
 type LinuxDoClient struct {
 	clientID     string
@@ -177,7 +187,12 @@ func (c *LinuxDoClient) RefreshToken(ctx context.Context, refreshToken string) (
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	// 检查状态码
+	// 检查状态码（400 invalid_grant / 401 表示刷新令牌已失效）
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
+		c.logger.WithField("status_code", resp.StatusCode).Warn("Refresh token rejected")
+		return nil, fmt.Errorf("%w: status %d: %s", ErrLinuxDoTokenRejected, resp.StatusCode, string(body))
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		c.logger.WithFields(logrus.Fields{
 			"status_code": resp.StatusCode,
@@ -231,7 +246,12 @@ func (c *LinuxDoClient) GetUserInfo(ctx context.Context, accessToken string) (*m
 	// 检查状态码
 	if resp.StatusCode == http.StatusUnauthorized {
 		c.logger.Warn("Access token is invalid or expired")
-		return nil, fmt.Errorf("access token is invalid or expired")
+		return nil, ErrLinuxDoUnauthorized
+	}
+
+	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound {
+		c.logger.WithField("status_code", resp.StatusCode).Warn("Linux.do user not found")
+		return nil, fmt.Errorf("%w: status %d", ErrLinuxDoUserNotFound, resp.StatusCode)
 	}
 
 	if resp.StatusCode != http.StatusOK {
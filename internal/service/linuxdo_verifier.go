@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+)
+
+const (
+	// verifyPollInterval 后台校验任务轮询间隔
+	verifyPollInterval = time.Minute
+	// verifyBatchSize 每轮最多校验的用户数量
+	verifyBatchSize = 20
+	// verifyLeaseDuration 校验租约时长，超时未完成的校验会被重新领取
+	verifyLeaseDuration = 5 * time.Minute
+	// verifyRetryDelay Linux.do 暂时不可用时的重试间隔
+	verifyRetryDelay = 30 * time.Minute
+	// verifyRequestTimeout 单个用户校验的超时时间
+	verifyRequestTimeout = 30 * time.Second
+)
+
+// LinuxDoVerifierConfig Linux.do 账号定期校验配置
+type LinuxDoVerifierConfig struct {
+	Interval     time.Duration // 校验间隔，0 表示不启动后台校验
+	ActiveWindow time.Duration // 只校验该时间内登录或领取过的用户
+}
+
+// LinuxDoVerifier Linux.do 账号定期校验：使用保存的刷新令牌重新获取用户信息，
+// 账号已删除、被停用或禁言、信任等级下降时暂停用户
+type LinuxDoVerifier struct {
+	linuxDoClient *LinuxDoClient
+	tokenRepo     *repository.LinuxDoTokenRepository
+	userRepo      *repository.UserRepository
+	sessionRepo   *repository.SessionRepository
+	cacheService  *CacheService
+	config        LinuxDoVerifierConfig
+	logger        *logrus.Logger
+	stopCh        chan struct{}
+	wg            sync.WaitGroup
+}
+
+// NewLinuxDoVerifier 创建 Linux.do 账号校验服务
+func NewLinuxDoVerifier(
+	linuxDoClient *LinuxDoClient,
+	tokenRepo *repository.LinuxDoTokenRepository,
+	userRepo *repository.UserRepository,
+	sessionRepo *repository.SessionRepository,
+	cacheService *CacheService,
+	config LinuxDoVerifierConfig,
+	logger *logrus.Logger,
+) *LinuxDoVerifier {
+	if config.ActiveWindow <= 0 {
+		config.ActiveWindow = 30 * 24 * time.Hour
+	}
+
+	return &LinuxDoVerifier{
+		linuxDoClient: linuxDoClient,
+		tokenRepo:     tokenRepo,
+		userRepo:      userRepo,
+		sessionRepo:   sessionRepo,
+		cacheService:  cacheService,
+		config:        config,
+		logger:        logger,
+		stopCh:        make(chan struct{}),
+	}
+}
+
+// SaveLoginToken 登录时保存刷新令牌（Linux.do 未返回刷新令牌时跳过）
+func (v *LinuxDoVerifier) SaveLoginToken(ctx context.Context, linuxDoID, refreshToken string) {
+	if refreshToken == "" {
+		return
+	}
+
+	if err := v.tokenRepo.Save(ctx, linuxDoID, refreshToken, v.nextVerifyAt()); err != nil {
+		v.logger.WithError(err).WithField("linux_do_id", linuxDoID).Warn("Failed to save linux.do refresh token")
+	}
+}
+
+// RevokeUserToken 在 Linux.do 撤销并删除用户保存的刷新令牌（退出登录、删除用户时调用）
+func (v *LinuxDoVerifier) RevokeUserToken(ctx context.Context, linuxDoID string) {
+	token, err := v.tokenRepo.Get(ctx, linuxDoID)
+	if err != nil || token == nil {
+		return
+	}
+
+	if err := v.linuxDoClient.RevokeToken(ctx, token.RefreshToken); err != nil {
+		v.logger.WithError(err).WithField("linux_do_id", linuxDoID).Warn("Failed to revoke linux.do refresh token")
+	}
+
+	if err := v.tokenRepo.Delete(ctx, linuxDoID); err != nil {
+		return
+	}
+
+	v.logger.WithField("linux_do_id", linuxDoID).Info("Linux.do refresh token revoked")
+}
+
+// ProcessDue 校验一批到期的用户，返回处理数量
+func (v *LinuxDoVerifier) ProcessDue(ctx context.Context) (int, error) {
+	activeSince := time.Now().Add(-v.config.ActiveWindow)
+	tokens, err := v.tokenRepo.AcquireDue(ctx, verifyBatchSize, verifyLeaseDuration, activeSince)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, token := range tokens {
+		verifyCtx, cancel := context.WithTimeout(ctx, verifyRequestTimeout)
+		v.verify(verifyCtx, token)
+		cancel()
+	}
+
+	return len(tokens), nil
+}
+
+// verify 刷新令牌并重新获取用户信息
+func (v *LinuxDoVerifier) verify(ctx context.Context, token *model.LinuxDoToken) {
+	fields := logrus.Fields{
+		"linux_do_id": token.LinuxDoID,
+		"failures":    token.Failures,
+	}
+
+	user, err := v.userRepo.GetByLinuxDoID(ctx, token.LinuxDoID)
+	if err != nil {
+		v.retryLater(ctx, token, err)
+		return
+	}
+	if user == nil {
+		_ = v.tokenRepo.Delete(ctx, token.LinuxDoID)
+		return
+	}
+
+	tokenResp, err := v.linuxDoClient.RefreshToken(ctx, token.RefreshToken)
+	if err != nil {
+		if errors.Is(err, ErrLinuxDoTokenRejected) {
+			// 刷新令牌已失效，无法继续校验，需要用户重新登录
+			_ = v.tokenRepo.Delete(ctx, token.LinuxDoID)
+			v.suspend(ctx, user, model.SuspendReasonLinuxDoRevoked)
+			return
+		}
+		v.retryLater(ctx, token, err)
+		return
+	}
+
+	// 刷新令牌可能轮换，先保存新的刷新令牌
+	if tokenResp.RefreshToken != "" && tokenResp.RefreshToken != token.RefreshToken {
+		if err := v.tokenRepo.UpdateRefreshToken(ctx, token.LinuxDoID, tokenResp.RefreshToken); err != nil {
+			v.logger.WithError(err).WithFields(fields).Error("Failed to store rotated linux.do refresh token")
+		}
+	}
+
+	userInfo, err := v.linuxDoClient.GetUserInfo(ctx, tokenResp.AccessToken)
+	if err != nil {
+		if errors.Is(err, ErrLinuxDoUnauthorized) || errors.Is(err, ErrLinuxDoUserNotFound) {
+			_ = v.tokenRepo.Delete(ctx, token.LinuxDoID)
+			v.suspend(ctx, user, model.SuspendReasonLinuxDoGone)
+			return
+		}
+		v.retryLater(ctx, token, err)
+		return
+	}
+
+	if strconv.Itoa(userInfo.ID) != user.LinuxDoID {
+		v.logger.WithFields(fields).WithField("user_info_id", userInfo.ID).Error("Linux.do user info does not match stored token")
+		_ = v.tokenRepo.Delete(ctx, token.LinuxDoID)
+		return
+	}
+
+	previousLevel := user.TrustLevel
+	changed := user.Username != userInfo.Username || user.TrustLevel != userInfo.TrustLevel ||
+		user.Active != userInfo.Active || user.Silenced != userInfo.Silenced
+	user.Username = userInfo.Username
+	user.TrustLevel = userInfo.TrustLevel
+	user.Active = userInfo.Active
+	user.Silenced = userInfo.Silenced
+
+	switch {
+	case !userInfo.Active || userInfo.Silenced:
+		v.suspend(ctx, user, model.SuspendReasonLinuxDoRestricted)
+	case userInfo.TrustLevel < previousLevel:
+		v.suspend(ctx, user, model.SuspendReasonLinuxDoDemoted)
+	case changed:
+		if err := v.userRepo.UpdateLinuxDoInfo(ctx, user); err != nil {
+			v.logger.WithError(err).WithFields(fields).Warn("Failed to update Linux.do user info")
+		}
+		v.clearUserCache(ctx, user.ID)
+	}
+
+	if err := v.tokenRepo.MarkVerified(ctx, token.LinuxDoID, v.nextVerifyAt()); err != nil {
+		return
+	}
+
+	v.logger.WithFields(fields).WithField("trust_level", userInfo.TrustLevel).Debug("Linux.do account verified")
+}
+
+// suspend 暂停用户并清除其登录会话
+func (v *LinuxDoVerifier) suspend(ctx context.Context, user *model.User, reason string) {
+	fields := logrus.Fields{
+		"linux_do_id": user.LinuxDoID,
+		"username":    user.Username,
+		"reason":      reason,
+	}
+
+	if err := v.userRepo.Suspend(ctx, user, reason); err != nil {
+		v.logger.WithError(err).WithFields(fields).Error("Failed to suspend user")
+		return
+	}
+
+	if _, err := v.sessionRepo.DeleteByUser(ctx, user.ID, ""); err != nil {
+		v.logger.WithError(err).WithFields(fields).Warn("Failed to delete sessions of suspended user")
+	}
+	v.clearUserCache(ctx, user.ID)
+
+	v.logger.WithFields(fields).Warn("User suspended after Linux.do account verification")
+}
+
+// retryLater 校验因临时错误失败，稍后重试
+func (v *LinuxDoVerifier) retryLater(ctx context.Context, token *model.LinuxDoToken, cause error) {
+	delay := verifyRetryDelay
+	if v.config.Interval > 0 && v.config.Interval < delay {
+		delay = v.config.Interval
+	}
+
+	_ = v.tokenRepo.MarkFailed(ctx, token.LinuxDoID, time.Now().Add(delay), cause.Error())
+	v.logger.WithError(cause).WithFields(logrus.Fields{
+		"linux_do_id": token.LinuxDoID,
+		"failures":    token.Failures + 1,
+	}).Warn("Linux.do account verification failed, will retry")
+}
+
+// clearUserCache 清除用户信息缓存
+func (v *LinuxDoVerifier) clearUserCache(ctx context.Context, userID int) {
+	_ = v.cacheService.Del(ctx, v.cacheService.UserKey(userID), v.cacheService.UserQuotaKey(userID))
+}
+
+// nextVerifyAt 下一次校验时间
+func (v *LinuxDoVerifier) nextVerifyAt() time.Time {
+	interval := v.config.Interval
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	return time.Now().Add(interval)
+}
+
+// IsVerifierSuspension 是否为定期校验产生的暂停（重新登录且账号正常后自动解除）
+func IsVerifierSuspension(user *model.User) bool {
+	return user.SuspendedAt.Valid && strings.HasPrefix(user.SuspendReason, "linuxdo_")
+}
+
+// Start 启动后台校验任务（未配置校验间隔时不启动）
+func (v *LinuxDoVerifier) Start(ctx context.Context) {
+	if v.config.Interval <= 0 {
+		v.logger.Info("Linux.do account verification disabled")
+		return
+	}
+
+	v.wg.Add(1)
+	go func() {
+		defer v.wg.Done()
+
+		ticker := time.NewTicker(verifyPollInterval)
+		defer ticker.Stop()
+
+		v.logger.WithField("interval", v.config.Interval.String()).Info("Linux.do account verifier started")
+
+		for {
+			v.drain(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-v.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// drain 校验所有到期的用户，直到没有更多记录
+func (v *LinuxDoVerifier) drain(ctx context.Context) {
+	for {
+		processed, err := v.ProcessDue(ctx)
+		if err != nil {
+			v.logger.WithError(err).Error("Failed to verify Linux.do accounts")
+			return
+		}
+		if processed < verifyBatchSize {
+			return
+		}
+	}
+}
+
+// Stop 停止后台校验任务
+func (v *LinuxDoVerifier) Stop() {
+	close(v.stopCh)
+	v.wg.Wait()
+	v.logger.Info("Linux.do account verifier stopped")
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+)
+
+// OIDCProviderConfig 通用 OIDC 身份提供方配置
+type OIDCProviderConfig struct {
+	Name         string // 提供方名称，默认 oidc
+	DisplayName  string
+	Issuer       string // 用于发现 {issuer}/.well-known/openid-configuration
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+	Scopes       []string
+	Timeout      time.Duration
+}
+
+// oidcDiscovery OIDC 发现文档中使用的端点
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// oidcUserInfo OIDC userinfo 端点返回的标准声明
+type oidcUserInfo struct {
+	Subject           string `json:"sub"`
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+	Email             string `json:"email"`
+}
+
+// OIDCProvider 通用 OIDC 身份提供方
+// 用户身份取自 userinfo 端点（使用授权码换得的访问令牌通过 TLS 获取），不解析 ID Token
+type OIDCProvider struct {
+	config     OIDCProviderConfig
+	httpClient *http.Client
+	logger     *logrus.Logger
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+}
+
+// NewOIDCProvider 创建通用 OIDC 身份提供方
+func NewOIDCProvider(config OIDCProviderConfig, logger *logrus.Logger) *OIDCProvider {
+	if config.Name == "" {
+		config.Name = "oidc"
+	}
+	if config.DisplayName == "" {
+		config.DisplayName = "OIDC"
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "profile", "email"}
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+	config.Issuer = strings.TrimRight(config.Issuer, "/")
+
+	return &OIDCProvider{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+		logger:     logger,
+	}
+}
+
+// Name 提供方名称
+func (p *OIDCProvider) Name() string {
+	return p.config.Name
+}
+
+// DisplayName 展示名称
+func (p *OIDCProvider) DisplayName() string {
+	return p.config.DisplayName
+}
+
+// getDiscovery 获取 OIDC 发现文档（成功后缓存，失败时下次请求重试）
+func (p *OIDCProvider) getDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	body, err := p.doRequest(ctx, "GET", p.config.Issuer+"/.well-known/openid-configuration", nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover oidc endpoints: %w", err)
+	}
+
+	var discovery oidcDiscovery
+	if err := json.Unmarshal(body, &discovery); err != nil {
+		return nil, fmt.Errorf("failed to parse oidc discovery document: %w", err)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.UserinfoEndpoint == "" {
+		return nil, fmt.Errorf("oidc discovery document is missing required endpoints")
+	}
+
+	p.discovery = &discovery
+	p.logger.WithFields(logrus.Fields{
+		"provider": p.config.Name,
+		"issuer":   discovery.Issuer,
+	}).Info("OIDC endpoints discovered")
+
+	return p.discovery, nil
+}
+
+// AuthorizationURL 生成授权跳转地址
+func (p *OIDCProvider) AuthorizationURL(ctx context.Context, state string) (string, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.config.RedirectURI)
+	params.Set("response_type", "code")
+	params.Set("scope", strings.Join(p.config.Scopes, " "))
+	params.Set("state", state)
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// ExchangeCode 使用授权码换取令牌
+func (p *OIDCProvider) ExchangeCode(ctx context.Context, code string) (*model.OAuthToken, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data := url.Values{}
+	data.Set("grant_type", "authorization_code")
+	data.Set("code", code)
+	data.Set("redirect_uri", p.config.RedirectURI)
+	data.Set("client_id", p.config.ClientID)
+	data.Set("client_secret", p.config.ClientSecret)
+
+	body, err := p.doRequest(ctx, "POST", discovery.TokenEndpoint, strings.NewReader(data.Encode()), "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+
+	var token model.OAuthToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("token response missing access_token")
+	}
+
+	return &token, nil
+}
+
+// UserInfo 从 userinfo 端点获取用户身份
+func (p *OIDCProvider) UserInfo(ctx context.Context, token *model.OAuthToken) (*model.ExternalIdentity, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.doRequest(ctx, "GET", discovery.UserinfoEndpoint, nil, token.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+
+	var info oidcUserInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse user info: %w", err)
+	}
+	if info.Subject == "" {
+		return nil, fmt.Errorf("oidc user info missing sub claim")
+	}
+
+	username := info.PreferredUsername
+	if username == "" {
+		username = info.Name
+	}
+	if username == "" {
+		username = info.Email
+	}
+
+	return &model.ExternalIdentity{
+		Provider: p.config.Name,
+		Subject:  info.Subject,
+		Username: username,
+		Email:    info.Email,
+	}, nil
+}
+
+// doRequest 发送请求并返回响应体（非 200 响应返回错误）
+func (p *OIDCProvider) doRequest(ctx context.Context, method, endpoint string, form io.Reader, accessToken string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, form)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "KyxQuotaBridge/1.0")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		p.logger.WithFields(logrus.Fields{
+			"provider":    p.config.Name,
+			"endpoint":    endpoint,
+			"status_code": resp.StatusCode,
+		}).Error("OIDC request failed")
+		return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
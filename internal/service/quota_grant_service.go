@@ -0,0 +1,415 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+)
+
+const (
+	// grantMaxAttempts 单笔发放最大尝试次数，超过后进入死信
+	grantMaxAttempts = 8
+	// grantBaseBackoff 首次重试间隔，之后按指数增长
+	grantBaseBackoff = 30 * time.Second
+	// grantMaxBackoff 最大重试间隔
+	grantMaxBackoff = time.Hour
+	// grantLeaseDuration 投递租约时长，超时未完成的投递会被重新领取
+	grantLeaseDuration = 2 * time.Minute
+	// grantPollInterval 后台任务轮询间隔
+	grantPollInterval = 15 * time.Second
+	// grantBatchSize 每轮最多处理的发放数量
+	grantBatchSize = 20
+	// grantLockTTL 同一公益站用户投递锁的最长持有时间（覆盖对账、投递和状态更新）
+	grantLockTTL = grantLeaseDuration
+	// grantLockWait 等待其他投递释放锁的最长时间
+	grantLockWait = 10 * time.Second
+	// grantLockPollInterval 等待投递锁的轮询间隔
+	grantLockPollInterval = 200 * time.Millisecond
+)
+
+// errGrantUncertain 无法确认上次投递是否已到账
+var errGrantUncertain = errors.New("previous delivery may have been applied")
+
+// QuotaGrantService 额度发放服务（事务发件箱投递）
+// 公益站不支持按幂等键去重，同一公益站用户的投递按 Redis 锁串行执行，每次投递前记录公益站累计额度，
+// 重试前扣除期间其他发放的额度后对账，确认上次投递未到账才重新投递，无法确认时转为人工核对，避免重复加额度
+type QuotaGrantService struct {
+	grantRepo    *repository.QuotaGrantRepository
+	claimRepo    *repository.ClaimRepository
+	kyxClient    *KyxClient
+	cacheService *CacheService
+	logger       *logrus.Logger
+	stopCh       chan struct{}
+	wg           sync.WaitGroup
+}
+
+// NewQuotaGrantService 创建额度发放服务
+func NewQuotaGrantService(
+	grantRepo *repository.QuotaGrantRepository,
+	claimRepo *repository.ClaimRepository,
+	kyxClient *KyxClient,
+	cacheService *CacheService,
+	logger *logrus.Logger,
+) *QuotaGrantService {
+	return &QuotaGrantService{
+		grantRepo:    grantRepo,
+		claimRepo:    claimRepo,
+		kyxClient:    kyxClient,
+		cacheService: cacheService,
+		logger:       logger,
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// ClaimGrantKey 领取发放的幂等键（每条领取记录唯一）
+func ClaimGrantKey(claimRecordID int) string {
+	return fmt.Sprintf("%s:%d", model.GrantSourceClaim, claimRecordID)
+}
+
+// DonateGrantKey 投喂发放的幂等键（每条投喂记录唯一）
+func DonateGrantKey(donateRecordID int) string {
+	return fmt.Sprintf("%s:%d", model.GrantSourceDonate, donateRecordID)
+}
+
+// BindBonusGrantKey 首次绑定奖励的幂等键（每条绑定记录唯一）
+func BindBonusGrantKey(bindingID int64) string {
+	return fmt.Sprintf("%s:%d", model.GrantSourceBindBonus, bindingID)
+}
+
+// EnqueueTx 在业务事务中写入待投递的额度发放
+func (s *QuotaGrantService) EnqueueTx(ctx context.Context, tx *sqlx.Tx, grant *model.QuotaGrant) error {
+	if grant.MaxAttempts <= 0 {
+		grant.MaxAttempts = grantMaxAttempts
+	}
+	return s.grantRepo.CreateTx(ctx, tx, grant)
+}
+
+// DeliverNow 立即尝试投递指定的发放（事务提交后调用）
+// 投递失败时记录会按退避策略留给后台任务重试，返回发放的最新状态
+func (s *QuotaGrantService) DeliverNow(ctx context.Context, grantID int) (*model.QuotaGrant, error) {
+	grant, err := s.grantRepo.AcquireByID(ctx, grantID, grantLeaseDuration)
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil {
+		// 已被后台任务领取或已完成
+		return s.grantRepo.GetByID(ctx, grantID)
+	}
+
+	s.deliver(ctx, grant)
+	return grant, nil
+}
+
+// ProcessDue 投递一批到期的发放，返回处理数量
+func (s *QuotaGrantService) ProcessDue(ctx context.Context) (int, error) {
+	grants, err := s.grantRepo.AcquireDue(ctx, grantBatchSize, grantLeaseDuration)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, grant := range grants {
+		s.deliver(ctx, grant)
+	}
+
+	return len(grants), nil
+}
+
+// deliver 投递单笔发放并更新状态
+func (s *QuotaGrantService) deliver(ctx context.Context, grant *model.QuotaGrant) {
+	fields := logrus.Fields{
+		"grant_id":        grant.ID,
+		"idempotency_key": grant.IdempotencyKey,
+		"user_id":         grant.UserID,
+		"kyx_user_id":     grant.KyxUserID,
+		"quota":           grant.Quota,
+		"attempt":         grant.Attempts,
+	}
+
+	// 对账依赖同一公益站用户的投递串行执行，锁持有到状态更新完成
+	lockKey := s.cacheService.GrantLockKey(grant.KyxUserID)
+	lockToken, err := s.acquireDeliveryLock(ctx, lockKey)
+	if err == nil {
+		defer s.cacheService.ReleaseLock(ctx, lockKey, lockToken)
+		err = s.attempt(ctx, grant)
+	}
+	if err == nil {
+		if err := s.grantRepo.MarkDelivered(ctx, grant.ID); err != nil {
+			// 额度已到账但状态未更新，租约到期后重新领取时对账会发现累计额度已增加，转为人工核对
+			s.logger.WithError(err).WithFields(fields).Error("Quota delivered but failed to mark grant delivered")
+			return
+		}
+		grant.Status = model.GrantStatusDelivered
+		if grant.Source == model.GrantSourceClaim {
+			// 领取预留转为已发放
+			if err := s.claimRepo.MarkGranted(ctx, grant.SourceID); err != nil {
+				s.logger.WithError(err).WithFields(fields).Warn("Failed to finalize claim reservation")
+			}
+		}
+		_ = s.cacheService.ClearUserQuota(ctx, grant.UserID)
+		s.logger.WithFields(fields).Info("Quota grant delivered")
+		return
+	}
+
+	grant.LastError.String, grant.LastError.Valid = err.Error(), true
+
+	if errors.Is(err, errGrantUncertain) {
+		if markErr := s.grantRepo.MarkReview(ctx, grant.ID, err.Error()); markErr != nil {
+			s.logger.WithError(markErr).WithFields(fields).Error("Failed to mark quota grant for review")
+			return
+		}
+		grant.Status = model.GrantStatusReview
+		s.logger.WithError(err).WithFields(fields).Error("Quota grant needs manual review")
+		return
+	}
+
+	if grant.Attempts >= grant.MaxAttempts {
+		if markErr := s.grantRepo.MarkDead(ctx, grant.ID, err.Error()); markErr != nil {
+			s.logger.WithError(markErr).WithFields(fields).Error("Failed to dead-letter quota grant")
+			return
+		}
+		grant.Status = model.GrantStatusDead
+		if grant.Source == model.GrantSourceClaim {
+			// 释放领取预留，用户可以重新领取
+			if err := s.claimRepo.Release(ctx, grant.SourceID); err != nil {
+				s.logger.WithError(err).WithFields(fields).Error("Failed to release claim reservation")
+			} else {
+				_ = s.cacheService.ClearClaimed(ctx, grant.UserID)
+			}
+		}
+		s.logger.WithError(err).WithFields(fields).Error("Quota grant dead-lettered")
+		return
+	}
+
+	nextAttemptAt := time.Now().Add(grantBackoff(grant.Attempts))
+	if markErr := s.grantRepo.MarkRetry(ctx, grant.ID, nextAttemptAt, err.Error()); markErr != nil {
+		s.logger.WithError(markErr).WithFields(fields).Error("Failed to schedule quota grant retry")
+		return
+	}
+	grant.Status = model.GrantStatusPending
+	grant.NextAttemptAt = nextAttemptAt
+	s.logger.WithError(err).WithFields(fields).WithField("next_attempt_at", nextAttemptAt).Warn("Quota grant delivery failed, will retry")
+}
+
+// acquireDeliveryLock 获取公益站用户的投递锁，锁被其他投递持有时等待
+// Redis 不可用或等待超时时不投递，按失败处理留给后台任务重试
+func (s *QuotaGrantService) acquireDeliveryLock(ctx context.Context, key string) (string, error) {
+	deadline := time.Now().Add(grantLockWait)
+	for {
+		token, err := s.cacheService.AcquireLock(ctx, key, grantLockTTL)
+		if err != nil {
+			return "", fmt.Errorf("failed to acquire grant delivery lock: %w", err)
+		}
+		if token != "" {
+			return token, nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("another delivery for this kyx user is in progress")
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(grantLockPollInterval):
+		}
+	}
+}
+
+// attempt 对账后投递一次（调用方持有公益站用户的投递锁）
+// 上次投递前记录的累计额度之后，扣除期间同一公益站用户其他已投递发放的额度，
+// 剩余增加量已达到本笔额度时，上次投递可能已经到账（投递超时、进程崩溃或到账后未能更新状态），
+// 返回 errGrantUncertain 而不再投递
+func (s *QuotaGrantService) attempt(ctx context.Context, grant *model.QuotaGrant) error {
+	quota, usedQuota, err := s.kyxClient.GetQuota(ctx, grant.KyxUserID)
+	if err != nil {
+		return fmt.Errorf("failed to read kyx quota before delivery: %w", err)
+	}
+	total := quota + usedQuota
+
+	if grant.KyxTotalBefore.Valid {
+		var others int64
+		if grant.KyxTotalAt.Valid {
+			others, err = s.grantRepo.SumDeliveredSince(ctx, grant.KyxUserID, grant.ID, grant.KyxTotalAt.Time)
+			if err != nil {
+				return err
+			}
+		}
+		if total-grant.KyxTotalBefore.Int64-others >= grant.Quota {
+			return fmt.Errorf("%w: kyx total quota increased from %d to %d since last attempt (%d from other grants)",
+				errGrantUncertain, grant.KyxTotalBefore.Int64, total, others)
+		}
+	}
+
+	if err := s.grantRepo.SaveKyxTotal(ctx, grant.ID, total); err != nil {
+		return err
+	}
+	grant.KyxTotalBefore.Int64, grant.KyxTotalBefore.Valid = total, true
+
+	return s.kyxClient.AddQuota(ctx, grant.KyxUserID, grant.Quota)
+}
+
+// grantBackoff 计算第 attempts 次失败后的重试间隔
+func grantBackoff(attempts int) time.Duration {
+	backoff := grantBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= grantMaxBackoff {
+			return grantMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// Start 启动后台投递任务
+func (s *QuotaGrantService) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(grantPollInterval)
+		defer ticker.Stop()
+
+		s.logger.Info("Quota grant worker started")
+
+		for {
+			s.drain(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// drain 处理所有到期的发放，直到没有更多记录
+func (s *QuotaGrantService) drain(ctx context.Context) {
+	for {
+		processed, err := s.ProcessDue(ctx)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to process quota grants")
+			return
+		}
+		if processed < grantBatchSize {
+			return
+		}
+	}
+}
+
+// Stop 停止后台投递任务
+func (s *QuotaGrantService) Stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+	s.logger.Info("Quota grant worker stopped")
+}
+
+// ListGrants 获取额度发放记录（管理员）
+func (s *QuotaGrantService) ListGrants(ctx context.Context, status string, page, pageSize int) ([]*model.QuotaGrant, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+
+	grants, err := s.grantRepo.List(ctx, status, pageSize, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list quota grants: %w", err)
+	}
+
+	total, err := s.grantRepo.Count(ctx, status)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count quota grants: %w", err)
+	}
+
+	return grants, total, nil
+}
+
+// ReplayGrant 重放死信、待核对或待投递的发放并立即尝试投递（管理员）
+// 待核对的发放只应在管理员确认公益站未到账后重放
+func (s *QuotaGrantService) ReplayGrant(ctx context.Context, grantID int) (*model.QuotaGrant, error) {
+	grant, err := s.grantRepo.GetByID(ctx, grantID)
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil {
+		return nil, fmt.Errorf("grant not found")
+	}
+
+	// 死信的领取发放已释放了领取预留，重放前需要重新预留
+	if grant.Source == model.GrantSourceClaim && grant.Status == model.GrantStatusDead {
+		if err := s.claimRepo.Reserve(ctx, grant.SourceID); err != nil {
+			if database.IsUniqueViolation(err) {
+				return nil, fmt.Errorf("user has claimed again for this day, grant cannot be replayed")
+			}
+			return nil, err
+		}
+	}
+
+	replayed, err := s.grantRepo.Replay(ctx, grantID)
+	if err != nil {
+		return nil, err
+	}
+	if !replayed {
+		return nil, fmt.Errorf("grant in status %s cannot be replayed", grant.Status)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"grant_id":        grant.ID,
+		"idempotency_key": grant.IdempotencyKey,
+		"previous_status": grant.Status,
+	}).Info("Quota grant replayed by admin")
+
+	return s.DeliverNow(ctx, grantID)
+}
+
+// ConfirmGrant 管理员在公益站核对已到账后，将待核对的发放标记为已投递
+func (s *QuotaGrantService) ConfirmGrant(ctx context.Context, grantID int) (*model.QuotaGrant, error) {
+	grant, err := s.grantRepo.GetByID(ctx, grantID)
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil {
+		return nil, fmt.Errorf("grant not found")
+	}
+
+	confirmed, err := s.grantRepo.ConfirmDelivered(ctx, grantID)
+	if err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		return nil, fmt.Errorf("grant in status %s cannot be confirmed", grant.Status)
+	}
+
+	if grant.Source == model.GrantSourceClaim {
+		// 领取预留转为已发放
+		if err := s.claimRepo.MarkGranted(ctx, grant.SourceID); err != nil {
+			s.logger.WithError(err).WithField("grant_id", grantID).Warn("Failed to finalize claim reservation")
+		}
+	}
+	_ = s.cacheService.ClearUserQuota(ctx, grant.UserID)
+
+	s.logger.WithFields(logrus.Fields{
+		"grant_id":        grant.ID,
+		"idempotency_key": grant.IdempotencyKey,
+	}).Info("Quota grant confirmed delivered by admin")
+
+	return s.grantRepo.GetByID(ctx, grantID)
+}
+
+// GetGrantStats 获取各状态的发放数量
+func (s *QuotaGrantService) GetGrantStats(ctx context.Context) (map[string]int64, error) {
+	return s.grantRepo.CountByStatus(ctx)
+}
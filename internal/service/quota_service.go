@@ -5,18 +5,29 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
 	"github.com/yourusername/kyx-quota-bridge/internal/model"
 	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
 )
 
+// claimLockTTL 领取锁的最长持有时间（覆盖一次立即投递的耗时）
+const claimLockTTL = 30 * time.Second
+
 // QuotaService 额度服务
 type QuotaService struct {
 	claimRepo       *repository.ClaimRepository
 	userRepo        *repository.UserRepository
 	adminConfigRepo *repository.AdminConfigRepository
 	kyxClient       *KyxClient
+	sessionMonitor  *KyxSessionMonitor
+	grantService    *QuotaGrantService
 	cacheService    *CacheService
+	banService      *BanService
+	gateDefaults    model.ClaimGatePolicy
+	trustDefaults   model.TrustPolicy
+	claimPeriod     *ClaimPeriod
 	logger          *logrus.Logger
 }
 
@@ -26,7 +37,13 @@ func NewQuotaService(
 	userRepo *repository.UserRepository,
 	adminConfigRepo *repository.AdminConfigRepository,
 	kyxClient *KyxClient,
+	sessionMonitor *KyxSessionMonitor,
+	grantService *QuotaGrantService,
 	cacheService *CacheService,
+	banService *BanService,
+	gateDefaults model.ClaimGatePolicy,
+	trustDefaults model.TrustPolicy,
+	claimPeriod *ClaimPeriod,
 	logger *logrus.Logger,
 ) *QuotaService {
 	return &QuotaService{
@@ -34,40 +51,93 @@ func NewQuotaService(
 		userRepo:        userRepo,
 		adminConfigRepo: adminConfigRepo,
 		kyxClient:       kyxClient,
+		sessionMonitor:  sessionMonitor,
+		grantService:    grantService,
 		cacheService:    cacheService,
+		banService:      banService,
+		gateDefaults:    gateDefaults,
+		trustDefaults:   trustDefaults,
+		claimPeriod:     claimPeriod,
 		logger:          logger,
 	}
 }
 
 // ClaimQuota 领取每日额度
-func (s *QuotaService) ClaimQuota(ctx context.Context, linuxDoID string) (*model.ClaimRecord, error) {
+func (s *QuotaService) ClaimQuota(ctx context.Context, userID int) (*model.ClaimRecord, error) {
 	// 检查用户是否存在
-	user, err := s.userRepo.GetByLinuxDoID(ctx, linuxDoID)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		s.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get user")
+		s.logger.WithError(err).WithField("user_id", userID).Error("Failed to get user")
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	if user == nil {
-		s.logger.WithField("linux_do_id", linuxDoID).Warn("User not found for claim")
+		s.logger.WithField("user_id", userID).Warn("User not found for claim")
 		return nil, fmt.Errorf("user not found")
 	}
 
 	// 检查是否已绑定账号
 	if user.KyxUserID == 0 {
-		s.logger.WithField("linux_do_id", linuxDoID).Warn("Attempt to claim without bound account")
+		s.logger.WithField("linux_do_id", user.LinuxDoID).Warn("Attempt to claim without bound account")
 		return nil, fmt.Errorf("account not bound, please bind first")
 	}
 
-	// 检查今天是否已领取
-	claimed, err := s.CanClaim(ctx, linuxDoID)
+	// 检查 Linux.do 信任等级和账号状态
+	trustPolicy, err := s.adminConfigRepo.GetTrustPolicy(ctx, s.trustDefaults)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to get trust policy, using defaults")
+	}
+	if reason := trustPolicy.BlockedReason(user, trustPolicy.MinClaimLevel); reason != "" {
+		s.logger.WithFields(logrus.Fields{
+			"linux_do_id": user.LinuxDoID,
+			"trust_level": user.TrustLevel,
+			"min_level":   trustPolicy.MinClaimLevel,
+			"active":      user.Active,
+			"silenced":    user.Silenced,
+		}).Warn("Claim blocked by trust policy")
+		return nil, fmt.Errorf("%s: linux.do account does not meet claim requirements (trust level %d, required %d)", reason, user.TrustLevel, trustPolicy.MinClaimLevel)
+	}
+
+	// 检查管理员封禁和暂停
+	if err := s.banService.Check(ctx, user.LinuxDoID, user.KyxUserID, "claim"); err != nil {
+		return nil, err
+	}
+
+	// 公益站 Session 失效且无法自动续期时直接拒绝，避免领取记录长时间停留在待发放状态
+	if err := s.sessionMonitor.CheckAvailable(); err != nil {
+		return nil, err
+	}
+
+	// 同一用户的领取请求串行执行，避免并发请求重复走完领取流程
+	// Redis 不可用时仍由领取记录的唯一约束保证每天只能领取一次
+	lockKey := s.cacheService.ClaimLockKey(user.ID)
+	lockToken, err := s.cacheService.AcquireLock(ctx, lockKey, claimLockTTL)
+	if err != nil {
+		s.logger.WithError(err).WithField("linux_do_id", user.LinuxDoID).Warn("Failed to acquire claim lock, relying on DB reservation")
+	} else if lockToken == "" {
+		s.logger.WithField("linux_do_id", user.LinuxDoID).Warn("Concurrent claim in progress")
+		return nil, fmt.Errorf("claim already in progress, please try again later")
+	}
+	defer s.cacheService.ReleaseLock(ctx, lockKey, lockToken)
+
+	// 检查当前周期是否已领取
+	canClaim, nextClaimAt, err := s.CanClaim(ctx, user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check claim status: %w", err)
 	}
 
-	if !claimed {
-		s.logger.WithField("linux_do_id", linuxDoID).Warn("Already claimed today")
-		return nil, fmt.Errorf("already claimed today, please try again tomorrow")
+	if !canClaim {
+		s.logger.WithFields(logrus.Fields{
+			"linux_do_id":   user.LinuxDoID,
+			"next_claim_at": nextClaimAt,
+		}).Warn("Already claimed in current period")
+		return nil, fmt.Errorf("already claimed, next claim available at %s", nextClaimAt.Format(time.RFC3339))
+	}
+
+	// 上一条有效领取记录（rolling 周期的周期键依赖它）
+	lastClaim, err := s.claimRepo.GetLastActive(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check claim status: %w", err)
 	}
 
 	// 获取领取额度配置
@@ -77,77 +147,161 @@ func (s *QuotaService) ClaimQuota(ctx context.Context, linuxDoID string) (*model
 		return nil, fmt.Errorf("failed to get claim quota: %w", err)
 	}
 
+	// 按信任等级的领取额度（未配置对应档位时使用统一领取额度）
+	claimQuota = trustPolicy.ClaimQuotas.Quota(user.TrustLevel, claimQuota)
+
 	if claimQuota <= 0 {
 		s.logger.Warn("Claim quota not configured")
 		return nil, fmt.Errorf("claim quota not configured")
 	}
 
-	// 调用公益站API添加额度
-	err = s.kyxClient.AddQuota(ctx, user.KyxUserID, claimQuota)
+	// 按公益站剩余额度限制领取：超过阈值时拒绝或按比例缩减
+	gatePolicy, err := s.adminConfigRepo.GetClaimGatePolicy(ctx, s.gateDefaults)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to get claim gate policy, using defaults")
+	}
+	if gatePolicy.QuotaThreshold > 0 {
+		balance, _, err := s.kyxClient.GetQuota(ctx, user.KyxUserID)
+		if err != nil {
+			s.logger.WithError(err).WithField("kyx_user_id", user.KyxUserID).Error("Failed to get current quota for claim")
+			return nil, fmt.Errorf("%s: failed to check current quota: %w", model.UpstreamUnavailable, err)
+		}
+
+		gatedQuota, reason := gatePolicy.Apply(balance, claimQuota)
+		if reason != "" {
+			s.logger.WithFields(logrus.Fields{
+				"linux_do_id": user.LinuxDoID,
+				"balance":     balance,
+				"threshold":   gatePolicy.QuotaThreshold,
+			}).Warn("Claim blocked by quota threshold")
+			return nil, fmt.Errorf("%s: remaining quota exceeds claim threshold", reason)
+		}
+		if gatedQuota < claimQuota {
+			s.logger.WithFields(logrus.Fields{
+				"linux_do_id":  user.LinuxDoID,
+				"balance":      balance,
+				"threshold":    gatePolicy.QuotaThreshold,
+				"scaled_quota": gatedQuota,
+			}).Info("Claim quota scaled down by quota threshold")
+		}
+		claimQuota = gatedQuota
+	}
+
+	// 连续领取奖励：按本次领取后的连续周期数匹配奖励档位
+	now := s.claimPeriod.Now()
+	streak := s.claimPeriod.CurrentStreak(lastClaim, now) + 1
+
+	streakBonuses, err := s.adminConfigRepo.GetStreakBonuses(ctx)
 	if err != nil {
-		s.logger.WithError(err).WithFields(logrus.Fields{
-			"linux_do_id": linuxDoID,
-			"kyx_user_id": user.KyxUserID,
-			"quota":       claimQuota,
-		}).Error("Failed to add quota via Kyx API")
-		return nil, fmt.Errorf("failed to add quota: %w", err)
+		s.logger.WithError(err).Warn("Failed to get streak bonuses, using defaults")
 	}
+	bonusPercent := streakBonuses.Percent(streak)
+	bonusQuota := claimQuota * int64(bonusPercent) / 100
 
-	// 创建领取记录
+	// 先预留领取记录（占住当前周期的唯一约束），再与额度发放在同一事务中写入，
+	// 额度由发件箱投递到公益站：投递成功后预留转为已发放，最终失败时释放预留
 	record := &model.ClaimRecord{
-		LinuxDoID:  linuxDoID,
+		UserID:     user.ID,
+		LinuxDoID:  user.LinuxDoID,
 		Username:   user.Username,
-		QuotaAdded: claimQuota,
+		QuotaAdded: claimQuota + bonusQuota,
+		BaseQuota:  claimQuota,
+		BonusQuota: bonusQuota,
+		Streak:     streak,
+		ClaimDate:  s.claimPeriod.ClaimDate(now),
+		PeriodKey:  s.claimPeriod.PeriodKey(now, lastClaim),
+	}
+	var grant *model.QuotaGrant
+
+	err = s.userRepo.Transaction(ctx, func(tx *sqlx.Tx) error {
+		if err := s.claimRepo.CreateTx(ctx, tx, record); err != nil {
+			return err
+		}
+
+		grant = &model.QuotaGrant{
+			IdempotencyKey: ClaimGrantKey(record.ID),
+			UserID:         user.ID,
+			LinuxDoID:      user.LinuxDoID,
+			KyxUserID:      user.KyxUserID,
+			Quota:          record.QuotaAdded,
+			Source:         model.GrantSourceClaim,
+			SourceID:       record.ID,
+		}
+		return s.grantService.EnqueueTx(ctx, tx, grant)
+	})
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			s.logger.WithField("linux_do_id", user.LinuxDoID).Warn("Concurrent claim rejected by unique constraint")
+			return nil, fmt.Errorf("already claimed in current period")
+		}
+		s.logger.WithError(err).WithField("linux_do_id", user.LinuxDoID).Error("Failed to save claim record")
+		return nil, fmt.Errorf("failed to save claim: %w", err)
 	}
 
-	if err := s.claimRepo.Create(ctx, record); err != nil {
-		s.logger.WithError(err).Error("Failed to create claim record")
-		// 即使记录创建失败，额度已经添加，不应该返回错误
-		// 只记录警告
-		s.logger.Warn("Quota added but failed to save record")
+	// 立即尝试投递，失败时由后台任务重试
+	if delivered, err := s.grantService.DeliverNow(ctx, grant.ID); err != nil {
+		s.logger.WithError(err).WithField("grant_id", grant.ID).Warn("Failed to deliver claim grant immediately")
+	} else if delivered != nil && delivered.Status == model.GrantStatusDelivered {
+		record.Status = model.ClaimStatusGranted
+	} else if delivered != nil {
+		s.logger.WithFields(logrus.Fields{
+			"grant_id": grant.ID,
+			"status":   delivered.Status,
+		}).Warn("Claim grant queued for retry")
 	}
 
-	// 标记今天已领取（缓存）
-	if err := s.cacheService.MarkClaimedToday(ctx, linuxDoID); err != nil {
+	// 标记已领取（缓存到下次可领取时间）
+	if err := s.cacheService.MarkClaimed(ctx, user.ID, s.claimPeriod.NextClaimAt(record.CreatedAt)); err != nil {
 		s.logger.WithError(err).Warn("Failed to mark claimed in cache")
 	}
 
 	// 清除用户额度缓存
-	_ = s.cacheService.ClearUserQuota(ctx, linuxDoID)
+	_ = s.cacheService.ClearUserQuota(ctx, user.ID)
 
 	s.logger.WithFields(logrus.Fields{
-		"linux_do_id": linuxDoID,
+		"linux_do_id": user.LinuxDoID,
 		"username":    user.Username,
 		"kyx_user_id": user.KyxUserID,
-		"quota":       claimQuota,
+		"quota":       record.QuotaAdded,
+		"bonus_quota": bonusQuota,
+		"streak":      streak,
 		"record_id":   record.ID,
 	}).Info("Quota claimed successfully")
 
 	return record, nil
 }
 
-// CanClaim 检查用户是否可以领取
-func (s *QuotaService) CanClaim(ctx context.Context, linuxDoID string) (bool, error) {
+// CanClaim 检查用户在当前领取周期是否可以领取，不能领取时同时返回下次可领取时间
+func (s *QuotaService) CanClaim(ctx context.Context, user *model.User) (bool, time.Time, error) {
+	now := time.Now()
+
 	// 先检查缓存
-	claimed, err := s.cacheService.HasClaimedToday(ctx, linuxDoID)
-	if err == nil {
-		if claimed {
-			return false, nil
-		}
+	nextClaimAt, err := s.cacheService.GetNextClaimAt(ctx, user.ID)
+	if err == nil && nextClaimAt.After(now) {
+		return false, nextClaimAt.In(s.claimPeriod.Location()), nil
 	}
 
 	// 检查数据库
-	claimed, err = s.claimRepo.HasClaimedToday(ctx, linuxDoID)
+	lastClaim, err := s.claimRepo.GetLastActive(ctx, user.ID)
 	if err != nil {
-		s.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to check claim status")
-		return false, fmt.Errorf("failed to check claim status: %w", err)
+		s.logger.WithError(err).WithField("linux_do_id", user.LinuxDoID).Error("Failed to check claim status")
+		return false, time.Time{}, fmt.Errorf("failed to check claim status: %w", err)
+	}
+
+	if lastClaim == nil {
+		return true, time.Time{}, nil
+	}
+
+	nextClaimAt = s.claimPeriod.NextClaimAt(lastClaim.CreatedAt)
+	if nextClaimAt.After(now) {
+		return false, nextClaimAt, nil
 	}
 
-	return !claimed, nil
+	return true, time.Time{}, nil
 }
 
 // GetClaimHistory 获取用户的领取历史
-func (s *QuotaService) GetClaimHistory(ctx context.Context, linuxDoID string, page, pageSize int) ([]*model.ClaimRecord, int64, error) {
+func (s *QuotaService) GetClaimHistory(ctx context.Context, userID int, page, pageSize int) ([]*model.ClaimRecord, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -157,15 +311,15 @@ func (s *QuotaService) GetClaimHistory(ctx context.Context, linuxDoID string, pa
 
 	offset := (page - 1) * pageSize
 
-	records, err := s.claimRepo.GetByLinuxDoID(ctx, linuxDoID, pageSize, offset)
+	records, err := s.claimRepo.GetByUserID(ctx, userID, pageSize, offset)
 	if err != nil {
-		s.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get claim history")
+		s.logger.WithError(err).WithField("user_id", userID).Error("Failed to get claim history")
 		return nil, 0, fmt.Errorf("failed to get claim history: %w", err)
 	}
 
-	total, err := s.claimRepo.CountByLinuxDoID(ctx, linuxDoID)
+	total, err := s.claimRepo.CountAllByUserID(ctx, userID)
 	if err != nil {
-		s.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to count claim records")
+		s.logger.WithError(err).WithField("user_id", userID).Error("Failed to count claim records")
 		return nil, 0, fmt.Errorf("failed to count claim records: %w", err)
 	}
 
@@ -173,25 +327,30 @@ func (s *QuotaService) GetClaimHistory(ctx context.Context, linuxDoID string, pa
 }
 
 // GetUserClaimStats 获取用户的领取统计
-func (s *QuotaService) GetUserClaimStats(ctx context.Context, linuxDoID string) (totalClaims int64, totalQuota int64, err error) {
-	totalClaims, err = s.claimRepo.CountByLinuxDoID(ctx, linuxDoID)
+func (s *QuotaService) GetUserClaimStats(ctx context.Context, userID int) (totalClaims int64, totalQuota int64, err error) {
+	totalClaims, err = s.claimRepo.CountByUserID(ctx, userID)
 	if err != nil {
-		s.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to count user claims")
+		s.logger.WithError(err).WithField("user_id", userID).Error("Failed to count user claims")
 		return 0, 0, fmt.Errorf("failed to count claims: %w", err)
 	}
 
-	totalQuota, err = s.claimRepo.GetTotalQuota(ctx, linuxDoID)
+	totalQuota, err = s.claimRepo.GetTotalQuota(ctx, userID)
 	if err != nil {
-		s.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get total claimed quota")
+		s.logger.WithError(err).WithField("user_id", userID).Error("Failed to get total claimed quota")
 		return 0, 0, fmt.Errorf("failed to get total quota: %w", err)
 	}
 
 	return totalClaims, totalQuota, nil
 }
 
-// GetTodayStats 获取今日领取统计
+// GetTodayStats 获取今日领取统计（按领取时区）
 func (s *QuotaService) GetTodayStats(ctx context.Context) (count int64, totalQuota int64, err error) {
-	return s.claimRepo.GetTodayStats(ctx)
+	return s.claimRepo.GetTodayStats(ctx, s.claimPeriod.ClaimDate(time.Now()))
+}
+
+// GetCurrentPeriodStats 获取当前领取周期的统计
+func (s *QuotaService) GetCurrentPeriodStats(ctx context.Context) (count int64, totalQuota int64, err error) {
+	return s.claimRepo.GetStatsSince(ctx, s.claimPeriod.CurrentStart(time.Now()))
 }
 
 // GetDateRangeStats 获取日期范围内的领取统计
@@ -298,10 +457,21 @@ func (s *QuotaService) GetAllClaimStats(ctx context.Context) (map[string]interfa
 		stats["total_count"] = totalCount
 	}
 
-	// 本周统计
-	now := time.Now()
-	weekStart := now.AddDate(0, 0, -int(now.Weekday()))
-	weekEnd := weekStart.AddDate(0, 0, 7)
+	// 当前领取周期统计
+	periodCount, periodQuota, err := s.GetCurrentPeriodStats(ctx)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to get current period stats")
+	} else {
+		stats["claim_period"] = s.claimPeriod.Kind()
+		stats["period_count"] = periodCount
+		stats["period_quota"] = periodQuota
+		stats["period_quota_usd"] = model.QuotaToDollar(periodQuota)
+	}
+
+	// 本周统计（按领取时区，周一开始）
+	now := s.claimPeriod.Now()
+	weekStart := s.claimPeriod.StartOfWeek(now)
+	weekEnd := weekStart.AddDate(0, 0, 6)
 	weekCount, weekQuota, err := s.GetDateRangeStats(
 		ctx,
 		weekStart.Format("2006-01-02"),
@@ -317,7 +487,7 @@ func (s *QuotaService) GetAllClaimStats(ctx context.Context) (map[string]interfa
 
 	// 本月统计
 	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-	monthEnd := monthStart.AddDate(0, 1, 0)
+	monthEnd := monthStart.AddDate(0, 1, -1)
 	monthCount, monthQuota, err := s.GetDateRangeStats(
 		ctx,
 		monthStart.Format("2006-01-02"),
@@ -336,9 +506,16 @@ func (s *QuotaService) GetAllClaimStats(ctx context.Context) (map[string]interfa
 
 // ResetDailyClaim 重置每日领取状态（用于测试或手动重置）
 func (s *QuotaService) ResetDailyClaim(ctx context.Context, linuxDoID string) error {
-	// 清除缓存中的今日领取标记
-	key := s.cacheService.ClaimTodayKey(linuxDoID)
-	if err := s.cacheService.Del(ctx, key); err != nil {
+	user, err := s.userRepo.GetByLinuxDoID(ctx, linuxDoID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+
+	// 清除缓存中的已领取标记
+	if err := s.cacheService.ClearClaimed(ctx, user.ID); err != nil {
 		s.logger.WithError(err).Warn("Failed to clear claim cache")
 	}
 
@@ -0,0 +1,244 @@
+//go:build integration
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+	"github.com/yourusername/kyx-quota-bridge/pkg/cache"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+)
+
+// 集成测试需要已执行全部迁移的 PostgreSQL 和 Redis（例如 docker compose 启动的服务）：
+//
+//	TEST_DB_HOST=localhost TEST_DB_USER=kyxuser TEST_DB_PASSWORD=... TEST_DB_NAME=kyxquota \
+//	TEST_REDIS_HOST=localhost make test-integration
+//
+// 未配置 TEST_DB_HOST 时跳过
+
+// testEnv 读取环境变量，未设置时使用默认值
+func testEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// testEnvInt 读取整数环境变量，未设置或格式错误时使用默认值
+func testEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// newIntegrationDeps 连接测试数据库和 Redis
+func newIntegrationDeps(t *testing.T, logger *logrus.Logger) (*database.DB, *cache.Redis) {
+	t.Helper()
+
+	if os.Getenv("TEST_DB_HOST") == "" {
+		t.Skip("TEST_DB_HOST not set, skipping integration test")
+	}
+
+	db, err := database.New(&database.Config{
+		Host:            os.Getenv("TEST_DB_HOST"),
+		Port:            testEnvInt("TEST_DB_PORT", 5432),
+		User:            testEnv("TEST_DB_USER", "kyxuser"),
+		Password:        os.Getenv("TEST_DB_PASSWORD"),
+		DBName:          testEnv("TEST_DB_NAME", "kyxquota"),
+		SSLMode:         testEnv("TEST_DB_SSLMODE", "disable"),
+		MaxOpenConns:    50,
+		MaxIdleConns:    10,
+		ConnMaxLifetime: 5,
+	}, logger)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	redisClient, err := cache.New(&cache.Config{
+		Host:       testEnv("TEST_REDIS_HOST", "localhost"),
+		Port:       testEnvInt("TEST_REDIS_PORT", 6379),
+		Password:   os.Getenv("TEST_REDIS_PASSWORD"),
+		DB:         testEnvInt("TEST_REDIS_DB", 15),
+		PoolSize:   50,
+		MaxRetries: 1,
+	}, logger)
+	if err != nil {
+		t.Fatalf("failed to connect to test redis: %v", err)
+	}
+	t.Cleanup(func() { redisClient.Close() })
+
+	return db, redisClient
+}
+
+// fakeKyxServer 模拟公益站：记录加额度请求次数，查询用户时返回累计额度
+func fakeKyxServer(t *testing.T, kyxUserID int) (*httptest.Server, *int64) {
+	t.Helper()
+
+	var addCalls, balance int64
+	userPath := fmt.Sprintf("/api/user/%d", kyxUserID)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == userPath+"/quota":
+			var body struct {
+				Quota int64 `json:"quota"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			// 放大并发窗口
+			time.Sleep(50 * time.Millisecond)
+			atomic.AddInt64(&addCalls, 1)
+			atomic.AddInt64(&balance, body.Quota)
+			fmt.Fprint(w, `{"success":true}`)
+		case r.Method == http.MethodGet && r.URL.Path == userPath:
+			fmt.Fprintf(w, `{"id":%d,"username":"itest","quota":%d,"used_quota":0}`, kyxUserID, atomic.LoadInt64(&balance))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/user":
+			fmt.Fprint(w, `{"success":true,"data":[]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &addCalls
+}
+
+// TestClaimQuotaConcurrent 同一用户并发领取时只能产生一条领取记录和一笔额度发放
+func TestClaimQuotaConcurrent(t *testing.T) {
+	const parallel = 20
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	db, redisClient := newIntegrationDeps(t, logger)
+	ctx := context.Background()
+
+	userRepo := repository.NewUserRepository(db, logger)
+	claimRepo := repository.NewClaimRepository(db, logger)
+	adminConfigRepo := repository.NewAdminConfigRepository(db, redisClient, nil, logger)
+	grantRepo := repository.NewQuotaGrantRepository(db, logger)
+	banRepo := repository.NewBanRepository(db, logger)
+	sessionRepo := repository.NewSessionRepository(db, redisClient, logger)
+	alertRepo := repository.NewAlertRepository(db, logger)
+
+	// 每次运行使用新的用户，避免与其他数据冲突
+	suffix := time.Now().UnixNano()
+	kyxUserID := int(suffix%1_000_000_000) + 1_000_000_000
+	user := &model.User{
+		LinuxDoID:  fmt.Sprintf("itest-claim-%d", suffix),
+		Username:   fmt.Sprintf("itest_%d", suffix),
+		KyxUserID:  kyxUserID,
+		TrustLevel: 4,
+		Active:     true,
+	}
+	if err := userRepo.Create(ctx, user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	server, addCalls := fakeKyxServer(t, kyxUserID)
+
+	cacheService := NewCacheService(redisClient, logger)
+	t.Cleanup(func() {
+		_ = cacheService.ClearClaimed(ctx, user.ID)
+		_ = cacheService.ClearUserQuota(ctx, user.ID)
+		db.ExecContext(ctx, `DELETE FROM quota_grants WHERE user_id = $1`, user.ID)
+		db.ExecContext(ctx, `DELETE FROM claim_records WHERE user_id = $1`, user.ID)
+		db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, user.ID)
+	})
+
+	kyxClient := NewKyxClient(KyxClientConfig{
+		BaseURL: server.URL,
+		Session: "itest-session",
+		Timeout: 10 * time.Second,
+	}, logger)
+	alertService := NewAlertService(alertRepo, "", logger)
+	sessionMonitor := NewKyxSessionMonitor(kyxClient, adminConfigRepo, alertService, KyxSessionMonitorConfig{}, logger)
+	banService := NewBanService(banRepo, userRepo, sessionRepo, cacheService, logger)
+	grantService := NewQuotaGrantService(grantRepo, claimRepo, kyxClient, cacheService, logger)
+
+	claimPeriod, err := NewClaimPeriod(model.ClaimPeriodDaily, "Asia/Shanghai", 24)
+	if err != nil {
+		t.Fatalf("failed to create claim period: %v", err)
+	}
+
+	quotaService := NewQuotaService(
+		claimRepo,
+		userRepo,
+		adminConfigRepo,
+		kyxClient,
+		sessionMonitor,
+		grantService,
+		cacheService,
+		banService,
+		model.ClaimGatePolicy{},
+		model.TrustPolicy{},
+		claimPeriod,
+		logger,
+	)
+
+	var (
+		wg        sync.WaitGroup
+		start     = make(chan struct{})
+		succeeded int64
+	)
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, err := quotaService.ClaimQuota(ctx, user.ID); err == nil {
+				atomic.AddInt64(&succeeded, 1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("expected exactly 1 successful claim, got %d", succeeded)
+	}
+
+	var claims, activeClaims int
+	if err := db.GetContext(ctx, &claims, `SELECT COUNT(*) FROM claim_records WHERE user_id = $1`, user.ID); err != nil {
+		t.Fatalf("failed to count claim records: %v", err)
+	}
+	if err := db.GetContext(ctx, &activeClaims, `SELECT COUNT(*) FROM claim_records WHERE user_id = $1 AND status <> $2`, user.ID, model.ClaimStatusReleased); err != nil {
+		t.Fatalf("failed to count active claim records: %v", err)
+	}
+	if claims != 1 || activeClaims != 1 {
+		t.Errorf("expected exactly 1 claim record not released, got %d records (%d not released)", claims, activeClaims)
+	}
+
+	var grants int
+	if err := db.GetContext(ctx, &grants, `SELECT COUNT(*) FROM quota_grants WHERE user_id = $1 AND source = $2 AND status <> $3`, user.ID, model.GrantSourceClaim, model.GrantStatusDead); err != nil {
+		t.Fatalf("failed to count quota grants: %v", err)
+	}
+	if grants != 1 {
+		t.Errorf("expected exactly 1 claim quota grant, got %d", grants)
+	}
+
+	if calls := atomic.LoadInt64(addCalls); calls != 1 {
+		t.Errorf("expected exactly 1 add quota request to kyx, got %d", calls)
+	}
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/kyx-quota-bridge/internal/model"
+	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+)
+
+const (
+	// purgePollInterval 后台清除任务轮询间隔
+	purgePollInterval = time.Hour
+	// purgeBatchSize 每轮最多清除的用户数量
+	purgeBatchSize = 50
+)
+
+// errPurgeBusy 用户仍有未完成的投喂任务或额度发放，稍后重试
+var errPurgeBusy = errors.New("user has unfinished donate jobs or quota grants")
+
+// UserPurgerConfig 已删除用户清除配置
+type UserPurgerConfig struct {
+	Retention time.Duration // 软删除后的保留时长，0 表示不启动后台清除
+}
+
+// UserPurger 在一个事务中彻底清除用户及其领取、投喂、投喂任务和额度发放记录，
+// 已使用的 Key 只清除明文和归属信息，保留哈希防止重复投喂
+type UserPurger struct {
+	userRepo    *repository.UserRepository
+	claimRepo   *repository.ClaimRepository
+	donateRepo  *repository.DonateRepository
+	jobRepo     *repository.DonateJobRepository
+	grantRepo   *repository.QuotaGrantRepository
+	keyRepo     *repository.KeyRepository
+	bindingRepo *repository.BindingRepository
+	config      UserPurgerConfig
+	logger      *logrus.Logger
+	stopCh      chan struct{}
+	wg          sync.WaitGroup
+}
+
+// NewUserPurger 创建已删除用户清除服务
+func NewUserPurger(
+	userRepo *repository.UserRepository,
+	claimRepo *repository.ClaimRepository,
+	donateRepo *repository.DonateRepository,
+	jobRepo *repository.DonateJobRepository,
+	grantRepo *repository.QuotaGrantRepository,
+	keyRepo *repository.KeyRepository,
+	bindingRepo *repository.BindingRepository,
+	config UserPurgerConfig,
+	logger *logrus.Logger,
+) *UserPurger {
+	return &UserPurger{
+		userRepo:    userRepo,
+		claimRepo:   claimRepo,
+		donateRepo:  donateRepo,
+		jobRepo:     jobRepo,
+		grantRepo:   grantRepo,
+		keyRepo:     keyRepo,
+		bindingRepo: bindingRepo,
+		config:      config,
+		logger:      logger,
+		stopCh:      make(chan struct{}),
+	}
+}
+
+// Purge 彻底清除用户，任一步骤失败时整体回滚
+// 仍有排队或执行中的投喂任务、待投递的额度发放时返回错误，避免处理结果写入已删除的用户
+func (p *UserPurger) Purge(ctx context.Context, user *model.User) error {
+	var claims, donates, jobs, grants, keys int64
+
+	err := p.userRepo.Transaction(ctx, func(tx *sqlx.Tx) error {
+		pendingJobs, err := p.jobRepo.CountUnfinishedByUserIDTx(ctx, tx, user.ID)
+		if err != nil {
+			return err
+		}
+		pendingGrants, err := p.grantRepo.CountUndeliveredByUserIDTx(ctx, tx, user.ID)
+		if err != nil {
+			return err
+		}
+		if pendingJobs > 0 || pendingGrants > 0 {
+			return errPurgeBusy
+		}
+
+		if claims, err = p.claimRepo.DeleteByUserIDTx(ctx, tx, user.ID); err != nil {
+			return err
+		}
+		if donates, err = p.donateRepo.DeleteByUserIDTx(ctx, tx, user.ID); err != nil {
+			return err
+		}
+		if jobs, err = p.jobRepo.DeleteByUserIDTx(ctx, tx, user.ID); err != nil {
+			return err
+		}
+		if grants, err = p.grantRepo.DeleteByUserIDTx(ctx, tx, user.ID); err != nil {
+			return err
+		}
+		if keys, err = p.keyRepo.ReleaseByLinuxDoIDTx(ctx, tx, user.LinuxDoID); err != nil {
+			return err
+		}
+		// 绑定历史保留，只结束当前绑定
+		if err := p.bindingRepo.CloseBindingTx(ctx, tx, user.ID, 0); err != nil {
+			return err
+		}
+		return p.userRepo.DeleteTx(ctx, tx, user.ID)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to purge user: %w", err)
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"user_id":        user.ID,
+		"linux_do_id":    user.LinuxDoID,
+		"claim_records":  claims,
+		"donate_records": donates,
+		"donate_jobs":    jobs,
+		"quota_grants":   grants,
+		"released_keys":  keys,
+	}).Info("User purged")
+
+	return nil
+}
+
+// PurgeExpired 清除一批超过保留期的已删除用户，返回处理的数量
+func (p *UserPurger) PurgeExpired(ctx context.Context) (int, error) {
+	users, err := p.userRepo.ListDeletedBefore(ctx, time.Now().Add(-p.config.Retention), purgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, user := range users {
+		if err := p.Purge(ctx, user); err != nil {
+			p.logger.WithError(err).WithField("linux_do_id", user.LinuxDoID).Warn("Failed to purge deleted user")
+		}
+	}
+
+	return len(users), nil
+}
+
+// Start 启动后台清除任务（未配置保留期时不启动）
+func (p *UserPurger) Start(ctx context.Context) {
+	if p.config.Retention <= 0 {
+		p.logger.Info("Deleted user purge disabled")
+		return
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(purgePollInterval)
+		defer ticker.Stop()
+
+		p.logger.WithField("retention", p.config.Retention.String()).Info("Deleted user purger started")
+
+		for {
+			if _, err := p.PurgeExpired(ctx); err != nil {
+				p.logger.WithError(err).Error("Failed to purge deleted users")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 停止后台清除任务
+func (p *UserPurger) Stop() {
+	close(p.stopCh)
+	p.wg.Wait()
+	p.logger.Info("Deleted user purger stopped")
+}
@@ -2,23 +2,50 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
 	"github.com/yourusername/kyx-quota-bridge/internal/model"
 	"github.com/yourusername/kyx-quota-bridge/internal/repository"
+	"github.com/yourusername/kyx-quota-bridge/pkg/database"
+	"github.com/yourusername/kyx-quota-bridge/pkg/utils"
 )
 
+const (
+	// bindChallengeCodePrefix 绑定验证码前缀，便于用户在显示名称中识别
+	bindChallengeCodePrefix = "KQB-"
+	// bindChallengeMaxAttempts 每个绑定挑战允许的验证次数
+	bindChallengeMaxAttempts = 10
+)
+
+// BindChallengeConfig 绑定挑战配置
+type BindChallengeConfig struct {
+	Enabled bool          // 绑定前要求在公益站显示名称中放置一次性验证码
+	TTL     time.Duration // 验证码有效期
+}
+
 // UserService 用户服务
 type UserService struct {
 	userRepo        *repository.UserRepository
 	claimRepo       *repository.ClaimRepository
 	donateRepo      *repository.DonateRepository
+	bindingRepo     *repository.BindingRepository
 	adminConfigRepo *repository.AdminConfigRepository
 	kyxClient       *KyxClient
 	linuxDoClient   *LinuxDoClient
+	grantService    *QuotaGrantService
 	cacheService    *CacheService
+	banService      *BanService
+	gateDefaults    model.ClaimGatePolicy
+	trustDefaults   model.TrustPolicy
+	bonusDefaults   model.BindBonusPolicy
+	claimPeriod     *ClaimPeriod
+	bindChallenge   BindChallengeConfig
 	logger          *logrus.Logger
 }
 
@@ -27,39 +54,55 @@ func NewUserService(
 	userRepo *repository.UserRepository,
 	claimRepo *repository.ClaimRepository,
 	donateRepo *repository.DonateRepository,
+	bindingRepo *repository.BindingRepository,
 	adminConfigRepo *repository.AdminConfigRepository,
 	kyxClient *KyxClient,
 	linuxDoClient *LinuxDoClient,
+	grantService *QuotaGrantService,
 	cacheService *CacheService,
+	banService *BanService,
+	gateDefaults model.ClaimGatePolicy,
+	trustDefaults model.TrustPolicy,
+	bonusDefaults model.BindBonusPolicy,
+	claimPeriod *ClaimPeriod,
+	bindChallenge BindChallengeConfig,
 	logger *logrus.Logger,
 ) *UserService {
 	return &UserService{
 		userRepo:        userRepo,
 		claimRepo:       claimRepo,
 		donateRepo:      donateRepo,
+		bindingRepo:     bindingRepo,
 		adminConfigRepo: adminConfigRepo,
 		kyxClient:       kyxClient,
 		linuxDoClient:   linuxDoClient,
+		grantService:    grantService,
 		cacheService:    cacheService,
+		banService:      banService,
+		gateDefaults:    gateDefaults,
+		trustDefaults:   trustDefaults,
+		bonusDefaults:   bonusDefaults,
+		claimPeriod:     claimPeriod,
+		bindChallenge:   bindChallenge,
 		logger:          logger,
 	}
 }
 
 // GetUser 获取用户信息
-func (s *UserService) GetUser(ctx context.Context, linuxDoID string) (*model.User, error) {
+func (s *UserService) GetUser(ctx context.Context, userID int) (*model.User, error) {
 	// 先从缓存获取
-	cacheKey := s.cacheService.UserKey(linuxDoID)
+	cacheKey := s.cacheService.UserKey(userID)
 	var user model.User
 	err := s.cacheService.GetJSON(ctx, cacheKey, &user)
 	if err == nil && user.ID > 0 {
-		s.logger.WithField("linux_do_id", linuxDoID).Debug("User retrieved from cache")
+		s.logger.WithField("user_id", userID).Debug("User retrieved from cache")
 		return &user, nil
 	}
 
 	// 从数据库获取
-	dbUser, err := s.userRepo.GetByLinuxDoID(ctx, linuxDoID)
+	dbUser, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		s.logger.WithError(err).WithField("linux_do_id", linuxDoID).Error("Failed to get user")
+		s.logger.WithError(err).WithField("user_id", userID).Error("Failed to get user")
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
@@ -73,26 +116,21 @@ func (s *UserService) GetUser(ctx context.Context, linuxDoID string) (*model.Use
 	return dbUser, nil
 }
 
-// GetUserByID 根据ID获取用户信息
-func (s *UserService) GetUserByID(ctx context.Context, userID int) (*model.User, error) {
-	return s.userRepo.GetByID(ctx, userID)
-}
-
 // GetUserByUsername 根据用户名获取用户信息
 func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
 	return s.userRepo.GetByUsername(ctx, username)
 }
 
 // BindAccount 绑定公益站账号
-func (s *UserService) BindAccount(ctx context.Context, linuxDoID, username string) (*model.BindAccountResponse, error) {
+func (s *UserService) BindAccount(ctx context.Context, userID int, username string) (*model.BindAccountResponse, error) {
 	// 获取用户
-	user, err := s.GetUser(ctx, linuxDoID)
+	user, err := s.GetUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
 	if user == nil {
-		s.logger.WithField("linux_do_id", linuxDoID).Error("User not found for binding")
+		s.logger.WithField("user_id", userID).Error("User not found for binding")
 		return nil, fmt.Errorf("user not found")
 	}
 
@@ -101,7 +139,7 @@ func (s *UserService) BindAccount(ctx context.Context, linuxDoID, username strin
 		// 已绑定，验证用户名是否匹配
 		if user.Username != username {
 			s.logger.WithFields(logrus.Fields{
-				"linux_do_id":    linuxDoID,
+				"linux_do_id":    user.LinuxDoID,
 				"current_user":   user.Username,
 				"requested_user": username,
 			}).Warn("Username mismatch for bound account")
@@ -120,7 +158,7 @@ func (s *UserService) BindAccount(ctx context.Context, linuxDoID, username strin
 		}
 
 		s.logger.WithFields(logrus.Fields{
-			"linux_do_id": linuxDoID,
+			"linux_do_id": user.LinuxDoID,
 			"kyx_user_id": user.KyxUserID,
 			"username":    username,
 		}).Info("Account already bound")
@@ -131,64 +169,119 @@ func (s *UserService) BindAccount(ctx context.Context, linuxDoID, username strin
 		}, nil
 	}
 
-	// 未绑定，搜索公益站用户
-	kyxUser, err := s.kyxClient.SearchUser(ctx, linuxDoID)
-	if err != nil {
-		s.logger.WithError(err).WithFields(logrus.Fields{
-			"linux_do_id": linuxDoID,
-			"username":    username,
-		}).Error("Failed to search user in Kyx API")
-		return nil, fmt.Errorf("failed to search user in Kyx: %w", err)
+	// 检查 Linux.do 信任等级、账号状态和管理员处置
+	if err := s.checkBindAllowed(ctx, user); err != nil {
+		return nil, err
 	}
 
-	if kyxUser == nil {
-		s.logger.WithFields(logrus.Fields{
-			"linux_do_id": linuxDoID,
-			"username":    username,
-		}).Warn("User not found in Kyx API")
-		return nil, fmt.Errorf("user not found in Kyx, please register first")
+	// 未绑定，按 Linux.do ID 精确查找公益站用户
+	kyxUser, err := s.findKyxUser(ctx, user)
+	if err != nil {
+		return nil, err
 	}
 
 	// 验证用户名是否匹配
 	if kyxUser.Username != username {
 		s.logger.WithFields(logrus.Fields{
-			"linux_do_id":    linuxDoID,
+			"linux_do_id":    user.LinuxDoID,
 			"kyx_username":   kyxUser.Username,
 			"requested_user": username,
 		}).Warn("Username mismatch")
 		return nil, fmt.Errorf("username mismatch: expected %s, got %s", kyxUser.Username, username)
 	}
 
-	// 更新用户绑定信息
+	// 挑战模式下验证公益站显示名称中的一次性验证码
+	if s.bindChallenge.Enabled {
+		if err := s.verifyBindChallenge(ctx, user, kyxUser.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 首次绑定奖励：按奖励策略计算，同一个 Linux.do ID 或公益站账号只发放一次，解绑后重新绑定不再发放
+	bonusPolicy, err := s.adminConfigRepo.GetBindBonusPolicy(ctx, s.bonusDefaults)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to get bind bonus policy, using defaults")
+	}
+	bonus := bonusPolicy.Amount(user)
+	if bonus > 0 {
+		granted, err := s.bindingRepo.HasBindBonus(ctx, user.LinuxDoID, kyxUser.ID)
+		if err != nil {
+			return nil, err
+		}
+		if granted {
+			bonus = 0
+		}
+	}
+
+	// 更新用户绑定信息并记录绑定历史，奖励与绑定记录在同一事务中写入发件箱
+	// （kyx_user_id 唯一约束兜底并发绑定）
 	user.KyxUserID = kyxUser.ID
 	user.Username = kyxUser.Username
-	if err := s.userRepo.Update(ctx, user); err != nil {
+	var grant *model.QuotaGrant
+	err = s.userRepo.Transaction(ctx, func(tx *sqlx.Tx) error {
+		if err := s.userRepo.UpdateTx(ctx, tx, user); err != nil {
+			return err
+		}
+
+		history := &model.BindingHistory{
+			UserID:       sql.NullInt64{Int64: int64(user.ID), Valid: true},
+			LinuxDoID:    user.LinuxDoID,
+			KyxUserID:    kyxUser.ID,
+			KyxUsername:  kyxUser.Username,
+			BonusGranted: bonus > 0,
+		}
+		if err := s.bindingRepo.CreateHistoryTx(ctx, tx, history); err != nil {
+			return err
+		}
+		if bonus <= 0 {
+			return nil
+		}
+
+		grant = &model.QuotaGrant{
+			IdempotencyKey: BindBonusGrantKey(history.ID),
+			UserID:         user.ID,
+			LinuxDoID:      user.LinuxDoID,
+			KyxUserID:      kyxUser.ID,
+			Quota:          bonus,
+			Source:         model.GrantSourceBindBonus,
+			SourceID:       int(history.ID),
+		}
+		return s.grantService.EnqueueTx(ctx, tx, grant)
+	})
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			s.logger.WithFields(logrus.Fields{
+				"linux_do_id": user.LinuxDoID,
+				"kyx_user_id": kyxUser.ID,
+			}).Warn("Kyx account bind rejected by unique constraint")
+			return nil, fmt.Errorf("%s: kyx account is already bound to another linux.do account", model.BindBlockedAccountTaken)
+		}
 		s.logger.WithError(err).Error("Failed to update user binding")
 		return nil, fmt.Errorf("failed to update user binding: %w", err)
 	}
 
+	if s.bindChallenge.Enabled {
+		key := bindChallengeKey(user.ID)
+		_ = s.cacheService.Del(ctx, key, key+":attempts")
+	}
+
 	// 清除用户缓存
-	_ = s.cacheService.ClearUserCache(ctx, linuxDoID)
-
-	// 首次绑定奖励
-	var bonus int64 = 0
-	claimQuota, err := s.adminConfigRepo.GetClaimQuota(ctx)
-	if err == nil && claimQuota > 0 {
-		bonus = claimQuota
-		// 添加首次绑定奖励
-		if err := s.kyxClient.AddQuota(ctx, kyxUser.ID, bonus); err != nil {
-			s.logger.WithError(err).Warn("Failed to add first bind bonus")
-		} else {
+	_ = s.cacheService.ClearUserCache(ctx, user.ID)
+
+	// 立即投递首次绑定奖励，失败时由后台任务重试
+	if grant != nil {
+		if delivered, err := s.grantService.DeliverNow(ctx, grant.ID); err != nil {
+			s.logger.WithError(err).WithField("grant_id", grant.ID).Warn("Failed to deliver bind bonus immediately")
+		} else if delivered != nil && delivered.Status != model.GrantStatusDelivered {
 			s.logger.WithFields(logrus.Fields{
-				"linux_do_id": linuxDoID,
-				"kyx_user_id": kyxUser.ID,
-				"bonus":       bonus,
-			}).Info("First bind bonus added")
+				"grant_id": grant.ID,
+				"status":   delivered.Status,
+			}).Warn("Bind bonus grant queued for retry")
 		}
 	}
 
 	s.logger.WithFields(logrus.Fields{
-		"linux_do_id": linuxDoID,
+		"linux_do_id": user.LinuxDoID,
 		"kyx_user_id": kyxUser.ID,
 		"username":    username,
 		"bonus":       bonus,
@@ -202,17 +295,179 @@ func (s *UserService) BindAccount(ctx context.Context, linuxDoID, username strin
 	}, nil
 }
 
+// CreateBindChallenge 为未绑定的用户生成绑定验证码（挑战模式）
+// 用户把验证码加入公益站显示名称后提交绑定，重新生成时旧验证码作废
+func (s *UserService) CreateBindChallenge(ctx context.Context, userID int) (*model.BindChallengeResponse, error) {
+	if !s.bindChallenge.Enabled {
+		return nil, fmt.Errorf("bind challenge is not enabled")
+	}
+
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if user.KyxUserID > 0 {
+		return nil, fmt.Errorf("account already bound")
+	}
+
+	if err := s.checkBindAllowed(ctx, user); err != nil {
+		return nil, err
+	}
+
+	kyxUser, err := s.findKyxUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	random, err := utils.GenerateRandomString(8)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate bind challenge: %w", err)
+	}
+
+	challenge := model.BindChallenge{
+		Code:      bindChallengeCodePrefix + strings.ToUpper(random),
+		KyxUserID: kyxUser.ID,
+		ExpiresAt: time.Now().Add(s.bindChallenge.TTL),
+	}
+
+	key := bindChallengeKey(user.ID)
+	if err := s.cacheService.SetJSON(ctx, key, challenge, s.bindChallenge.TTL); err != nil {
+		s.logger.WithError(err).Error("Failed to save bind challenge")
+		return nil, fmt.Errorf("failed to create bind challenge: %w", err)
+	}
+	_ = s.cacheService.Del(ctx, key+":attempts")
+
+	s.logger.WithFields(logrus.Fields{
+		"linux_do_id": user.LinuxDoID,
+		"kyx_user_id": kyxUser.ID,
+	}).Info("Bind challenge issued")
+
+	return &model.BindChallengeResponse{
+		Code:        challenge.Code,
+		KyxUsername: kyxUser.Username,
+		ExpiresAt:   challenge.ExpiresAt,
+	}, nil
+}
+
+// bindChallengeKey 绑定挑战缓存键
+func bindChallengeKey(userID int) string {
+	return model.CacheKeyBindChallenge + strconv.Itoa(userID)
+}
+
+// checkBindAllowed 检查未绑定用户是否允许绑定（Linux.do 信任等级、账号状态和管理员处置）
+func (s *UserService) checkBindAllowed(ctx context.Context, user *model.User) error {
+	trustPolicy, err := s.adminConfigRepo.GetTrustPolicy(ctx, s.trustDefaults)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to get trust policy, using defaults")
+	}
+	if reason := trustPolicy.BlockedReason(user, trustPolicy.MinBindLevel); reason != "" {
+		s.logger.WithFields(logrus.Fields{
+			"linux_do_id": user.LinuxDoID,
+			"trust_level": user.TrustLevel,
+			"min_level":   trustPolicy.MinBindLevel,
+			"active":      user.Active,
+			"silenced":    user.Silenced,
+		}).Warn("Bind blocked by trust policy")
+		return fmt.Errorf("%s: linux.do account does not meet bind requirements (trust level %d, required %d)", reason, user.TrustLevel, trustPolicy.MinBindLevel)
+	}
+
+	// 检查管理员封禁和暂停
+	return s.banService.Check(ctx, user.LinuxDoID, 0, "bind")
+}
+
+// findKyxUser 查找 linux_do_id 完全匹配的公益站用户，并检查该账号是否可以绑定：
+// 未被封禁，且没有绑定其他 Linux.do 账号
+func (s *UserService) findKyxUser(ctx context.Context, user *model.User) (*model.KyxUser, error) {
+	kyxUser, err := s.kyxClient.SearchUser(ctx, user.LinuxDoID)
+	if err != nil {
+		s.logger.WithError(err).WithField("linux_do_id", user.LinuxDoID).Error("Failed to search user in Kyx API")
+		return nil, fmt.Errorf("failed to search user in Kyx: %w", err)
+	}
+
+	if kyxUser == nil {
+		s.logger.WithField("linux_do_id", user.LinuxDoID).Warn("User not found in Kyx API")
+		return nil, fmt.Errorf("user not found in Kyx, please register first")
+	}
+
+	// 被封禁的公益站账号不能绑定到其他 Linux.do 账号
+	if err := s.banService.Check(ctx, "", kyxUser.ID, "bind"); err != nil {
+		return nil, err
+	}
+
+	bound, err := s.userRepo.GetByKyxUserID(ctx, kyxUser.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check kyx account binding: %w", err)
+	}
+	if bound != nil && bound.ID != user.ID {
+		s.logger.WithFields(logrus.Fields{
+			"linux_do_id":       user.LinuxDoID,
+			"kyx_user_id":       kyxUser.ID,
+			"bound_linux_do_id": bound.LinuxDoID,
+		}).Warn("Kyx account already bound to another user")
+		return nil, fmt.Errorf("%s: kyx account is already bound to another linux.do account", model.BindBlockedAccountTaken)
+	}
+
+	return kyxUser, nil
+}
+
+// verifyBindChallenge 验证绑定挑战：公益站显示名称中需要包含为该账号生成的验证码
+func (s *UserService) verifyBindChallenge(ctx context.Context, user *model.User, kyxUserID int) error {
+	key := bindChallengeKey(user.ID)
+	attemptsKey := key + ":attempts"
+
+	var challenge model.BindChallenge
+	if err := s.cacheService.GetJSON(ctx, key, &challenge); err != nil || challenge.Code == "" {
+		return fmt.Errorf("%s: bind challenge not found or expired, request a new code", model.BindBlockedChallenge)
+	}
+	if challenge.KyxUserID != kyxUserID {
+		return fmt.Errorf("%s: bind challenge was issued for another kyx account, request a new code", model.BindBlockedChallenge)
+	}
+
+	// 限制单个挑战的验证次数，超出后需要重新获取验证码
+	attempts, err := s.cacheService.Incr(ctx, attemptsKey)
+	if err != nil {
+		return fmt.Errorf("failed to count bind challenge attempts: %w", err)
+	}
+	if attempts == 1 {
+		_ = s.cacheService.Expire(ctx, attemptsKey, s.bindChallenge.TTL)
+	}
+	if attempts > bindChallengeMaxAttempts {
+		_ = s.cacheService.Del(ctx, key, attemptsKey)
+		s.logger.WithField("linux_do_id", user.LinuxDoID).Warn("Too many bind challenge attempts")
+		return fmt.Errorf("%s: too many attempts, request a new code", model.BindBlockedChallenge)
+	}
+
+	kyxUser, err := s.kyxClient.GetUserByID(ctx, kyxUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get Kyx user info: %w", err)
+	}
+
+	if !strings.Contains(strings.ToUpper(kyxUser.DisplayName), challenge.Code) {
+		s.logger.WithFields(logrus.Fields{
+			"linux_do_id": user.LinuxDoID,
+			"kyx_user_id": kyxUserID,
+			"attempts":    attempts,
+		}).Warn("Bind challenge code not found in Kyx display name")
+		return fmt.Errorf("%s: code %s not found in kyx display name", model.BindBlockedChallenge, challenge.Code)
+	}
+
+	return nil
+}
+
 // GetQuotaInfo 获取用户额度信息
-func (s *UserService) GetQuotaInfo(ctx context.Context, linuxDoID string) (*model.QuotaInfo, error) {
+func (s *UserService) GetQuotaInfo(ctx context.Context, userID int) (*model.QuotaInfo, error) {
 	// 先从缓存获取
-	cachedQuota, err := s.cacheService.GetUserQuota(ctx, linuxDoID)
+	cachedQuota, err := s.cacheService.GetUserQuota(ctx, userID)
 	if err == nil && cachedQuota != nil {
-		s.logger.WithField("linux_do_id", linuxDoID).Debug("Quota info retrieved from cache")
+		s.logger.WithField("user_id", userID).Debug("Quota info retrieved from cache")
 		return cachedQuota, nil
 	}
 
 	// 获取用户信息
-	user, err := s.GetUser(ctx, linuxDoID)
+	user, err := s.GetUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -232,11 +487,19 @@ func (s *UserService) GetQuotaInfo(ctx context.Context, linuxDoID string) (*mode
 		return nil, fmt.Errorf("failed to get quota info: %w", err)
 	}
 
-	// 检查今天是否已领取
-	claimedToday, err := s.claimRepo.HasClaimedToday(ctx, linuxDoID)
-	if err != nil {
-		s.logger.WithError(err).Warn("Failed to check claim status")
-		claimedToday = false
+	// 检查当前领取周期是否已领取
+	claimedToday := false
+	var nextClaimAt *int64
+	lastClaim, lastClaimErr := s.claimRepo.GetLastActive(ctx, user.ID)
+	if lastClaimErr != nil {
+		s.logger.WithError(lastClaimErr).Warn("Failed to check claim status")
+	} else if lastClaim != nil {
+		next := s.claimPeriod.NextClaimAt(lastClaim.CreatedAt)
+		if next.After(time.Now()) {
+			claimedToday = true
+			nextClaimUnix := next.Unix()
+			nextClaimAt = &nextClaimUnix
+		}
 	}
 
 	// 构建额度信息
@@ -250,17 +513,88 @@ func (s *UserService) GetQuotaInfo(ctx context.Context, linuxDoID string) (*mode
 		Total:        kyxUser.Quota + kyxUser.UsedQuota,
 		CanClaim:     !claimedToday,
 		ClaimedToday: claimedToday,
+		NextClaimAt:  nextClaimAt,
+	}
+
+	trustPolicy, err := s.adminConfigRepo.GetTrustPolicy(ctx, s.trustDefaults)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to get trust policy, using defaults")
+	}
+
+	if claimedToday {
+		quotaInfo.ClaimBlockedReason = model.ClaimBlockedAlreadyClaimed
+	} else if reason := trustPolicy.BlockedReason(user, trustPolicy.MinClaimLevel); reason != "" {
+		// 信任等级不足或账号受限时不能领取
+		quotaInfo.CanClaim = false
+		quotaInfo.ClaimBlockedReason = reason
+	} else {
+		// 剩余额度超过阈值时（block 模式）不能领取
+		gatePolicy, err := s.adminConfigRepo.GetClaimGatePolicy(ctx, s.gateDefaults)
+		if err != nil {
+			s.logger.WithError(err).Warn("Failed to get claim gate policy, using defaults")
+		}
+		if reason := gatePolicy.BlockedReason(kyxUser.Quota); reason != "" {
+			quotaInfo.CanClaim = false
+			quotaInfo.ClaimBlockedReason = reason
+		}
+	}
+
+	if lastClaimErr == nil {
+		streak, err := s.getClaimStreak(ctx, user.ID, lastClaim)
+		if err != nil {
+			s.logger.WithError(err).Warn("Failed to get claim streak")
+		} else {
+			quotaInfo.CurrentStreak = streak.CurrentStreak
+			quotaInfo.LongestStreak = streak.LongestStreak
+			quotaInfo.LastClaimDate = streak.LastClaimDate
+		}
 	}
 
 	// 缓存额度信息（5分钟）
-	_ = s.cacheService.SetUserQuota(ctx, linuxDoID, quotaInfo, 5*time.Minute)
+	_ = s.cacheService.SetUserQuota(ctx, userID, quotaInfo, 5*time.Minute)
 
 	return quotaInfo, nil
 }
 
 // GetStatistics 获取用户统计信息
-func (s *UserService) GetStatistics(ctx context.Context, linuxDoID string) (*model.UserStatistics, error) {
-	return s.userRepo.GetStatistics(ctx, linuxDoID)
+func (s *UserService) GetStatistics(ctx context.Context, userID int) (*model.UserStatistics, error) {
+	stats, err := s.userRepo.GetStatistics(ctx, userID)
+	if err != nil || stats == nil {
+		return stats, err
+	}
+
+	lastClaim, err := s.claimRepo.GetLastActive(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last claim: %w", err)
+	}
+
+	streak, err := s.getClaimStreak(ctx, userID, lastClaim)
+	if err != nil {
+		return nil, err
+	}
+	stats.CurrentStreak = streak.CurrentStreak
+	stats.LongestStreak = streak.LongestStreak
+	stats.LastClaimDate = streak.LastClaimDate
+
+	return stats, nil
+}
+
+// getClaimStreak 计算用户的连续领取信息，lastClaim 为最近一条有效领取记录
+func (s *UserService) getClaimStreak(ctx context.Context, userID int, lastClaim *model.ClaimRecord) (*model.ClaimStreak, error) {
+	streak := &model.ClaimStreak{}
+	if lastClaim == nil {
+		return streak, nil
+	}
+
+	longest, err := s.claimRepo.GetLongestStreak(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get longest streak: %w", err)
+	}
+
+	streak.CurrentStreak = s.claimPeriod.CurrentStreak(lastClaim, time.Now())
+	streak.LongestStreak = longest
+	streak.LastClaimDate = lastClaim.ClaimDate
+	return streak, nil
 }
 
 // ListUsers 获取用户列表
@@ -294,33 +628,9 @@ func (s *UserService) GetAllStatistics(ctx context.Context) ([]*model.UserStatis
 	return s.userRepo.GetAllStatistics(ctx)
 }
 
-// DeleteUser 删除用户
-func (s *UserService) DeleteUser(ctx context.Context, linuxDoID string) error {
-	// 删除用户的所有记录
-	if err := s.claimRepo.DeleteByLinuxDoID(ctx, linuxDoID); err != nil {
-		s.logger.WithError(err).Warn("Failed to delete claim records")
-	}
-
-	if err := s.donateRepo.DeleteByLinuxDoID(ctx, linuxDoID); err != nil {
-		s.logger.WithError(err).Warn("Failed to delete donate records")
-	}
-
-	// 删除用户
-	if err := s.userRepo.Delete(ctx, linuxDoID); err != nil {
-		s.logger.WithError(err).Error("Failed to delete user")
-		return fmt.Errorf("failed to delete user: %w", err)
-	}
-
-	// 清除缓存
-	_ = s.cacheService.ClearUserCache(ctx, linuxDoID)
-
-	s.logger.WithField("linux_do_id", linuxDoID).Info("User deleted successfully")
-	return nil
-}
-
 // UpdateUsername 更新用户名
-func (s *UserService) UpdateUsername(ctx context.Context, linuxDoID, newUsername string) error {
-	user, err := s.GetUser(ctx, linuxDoID)
+func (s *UserService) UpdateUsername(ctx context.Context, userID int, newUsername string) error {
+	user, err := s.GetUser(ctx, userID)
 	if err != nil {
 		return err
 	}
@@ -336,19 +646,19 @@ func (s *UserService) UpdateUsername(ctx context.Context, linuxDoID, newUsername
 	}
 
 	// 清除缓存
-	_ = s.cacheService.ClearUserCache(ctx, linuxDoID)
+	_ = s.cacheService.ClearUserCache(ctx, userID)
 
 	s.logger.WithFields(logrus.Fields{
-		"linux_do_id": linuxDoID,
-		"username":    newUsername,
+		"user_id":  userID,
+		"username": newUsername,
 	}).Info("Username updated")
 
 	return nil
 }
 
 // IsAccountBound 检查账号是否已绑定
-func (s *UserService) IsAccountBound(ctx context.Context, linuxDoID string) (bool, error) {
-	user, err := s.GetUser(ctx, linuxDoID)
+func (s *UserService) IsAccountBound(ctx context.Context, userID int) (bool, error) {
+	user, err := s.GetUser(ctx, userID)
 	if err != nil {
 		return false, err
 	}
@@ -361,8 +671,8 @@ func (s *UserService) IsAccountBound(ctx context.Context, linuxDoID string) (boo
 }
 
 // GetBoundKyxUserID 获取绑定的公益站用户ID
-func (s *UserService) GetBoundKyxUserID(ctx context.Context, linuxDoID string) (int, error) {
-	user, err := s.GetUser(ctx, linuxDoID)
+func (s *UserService) GetBoundKyxUserID(ctx context.Context, userID int) (int, error) {
+	user, err := s.GetUser(ctx, userID)
 	if err != nil {
 		return 0, err
 	}
@@ -379,14 +689,14 @@ func (s *UserService) GetBoundKyxUserID(ctx context.Context, linuxDoID string) (
 }
 
 // RefreshQuotaCache 刷新用户额度缓存
-func (s *UserService) RefreshQuotaCache(ctx context.Context, linuxDoID string) error {
+func (s *UserService) RefreshQuotaCache(ctx context.Context, userID int) error {
 	// 清除现有缓存
-	if err := s.cacheService.ClearUserQuota(ctx, linuxDoID); err != nil {
+	if err := s.cacheService.ClearUserQuota(ctx, userID); err != nil {
 		s.logger.WithError(err).Warn("Failed to clear quota cache")
 	}
 
 	// 重新获取并缓存
-	_, err := s.GetQuotaInfo(ctx, linuxDoID)
+	_, err := s.GetQuotaInfo(ctx, userID)
 	return err
 }
 
@@ -415,8 +725,8 @@ func (s *UserService) GetBoundUserCount(ctx context.Context) (int64, error) {
 }
 
 // SyncUserInfo 同步用户信息（从公益站）
-func (s *UserService) SyncUserInfo(ctx context.Context, linuxDoID string) error {
-	user, err := s.GetUser(ctx, linuxDoID)
+func (s *UserService) SyncUserInfo(ctx context.Context, userID int) error {
+	user, err := s.GetUser(ctx, userID)
 	if err != nil {
 		return err
 	}
@@ -444,10 +754,10 @@ func (s *UserService) SyncUserInfo(ctx context.Context, linuxDoID string) error
 	}
 
 	// 清除缓存
-	_ = s.cacheService.ClearUserCache(ctx, linuxDoID)
+	_ = s.cacheService.ClearUserCache(ctx, user.ID)
 
 	s.logger.WithFields(logrus.Fields{
-		"linux_do_id": linuxDoID,
+		"linux_do_id": user.LinuxDoID,
 		"kyx_user_id": user.KyxUserID,
 		"username":    user.Username,
 	}).Info("User info synced")
@@ -0,0 +1,75 @@
+-- ========================================
+-- 额度发放事务发件箱 (quota_grants)
+-- ========================================
+-- 说明: 领取/投喂记录与待发放额度在同一事务中写入，
+--       由后台任务异步投递到公益站，支持重试、死信和人工重放
+-- ========================================
+
+CREATE TABLE IF NOT EXISTS quota_grants (
+    id SERIAL PRIMARY KEY,
+    idempotency_key VARCHAR(128) UNIQUE NOT NULL,
+    linux_do_id VARCHAR(100) NOT NULL,
+    kyx_user_id INTEGER NOT NULL,
+    quota BIGINT NOT NULL CHECK (quota > 0),
+    source VARCHAR(20) NOT NULL CHECK (source IN ('claim', 'donate')),
+    source_id INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivering', 'delivered', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 8,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_quota_grants_status_next ON quota_grants(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_quota_grants_linux_do_id ON quota_grants(linux_do_id);
+CREATE INDEX IF NOT EXISTS idx_quota_grants_source ON quota_grants(source, source_id);
+CREATE INDEX IF NOT EXISTS idx_quota_grants_created_at ON quota_grants(created_at);
+
+-- 自动更新 updated_at
+DROP TRIGGER IF EXISTS update_quota_grants_updated_at ON quota_grants;
+CREATE TRIGGER update_quota_grants_updated_at
+    BEFORE UPDATE ON quota_grants
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- 添加注释
+COMMENT ON TABLE quota_grants IS '额度发放发件箱，记录每一笔待投递到公益站的额度';
+COMMENT ON COLUMN quota_grants.idempotency_key IS '幂等键，同一来源只会生成一笔发放';
+COMMENT ON COLUMN quota_grants.linux_do_id IS 'Linux Do 用户ID';
+COMMENT ON COLUMN quota_grants.kyx_user_id IS '公益站用户ID';
+COMMENT ON COLUMN quota_grants.quota IS '待发放额度';
+COMMENT ON COLUMN quota_grants.source IS '来源：claim/donate';
+COMMENT ON COLUMN quota_grants.source_id IS '来源记录ID（claim_records.id 或 donate_records.id）';
+COMMENT ON COLUMN quota_grants.status IS '状态：pending/delivering/delivered/dead';
+COMMENT ON COLUMN quota_grants.attempts IS '已尝试投递次数';
+COMMENT ON COLUMN quota_grants.max_attempts IS '最大尝试次数，超过后进入死信';
+COMMENT ON COLUMN quota_grants.next_attempt_at IS '下次投递时间（delivering 状态下为租约到期时间）';
+COMMENT ON COLUMN quota_grants.last_error IS '最近一次投递失败原因';
+COMMENT ON COLUMN quota_grants.delivered_at IS '投递成功时间';
+
+-- ========================================
+-- 放宽 donate_records 约束
+-- ========================================
+-- 投喂记录现在与发放记录在同一事务中写入，
+-- 部分成功 (partial) 和全部失败 (keys_count = 0) 的记录也必须能够落库
+ALTER TABLE donate_records DROP CONSTRAINT IF EXISTS donate_records_push_status_check;
+ALTER TABLE donate_records ADD CONSTRAINT donate_records_push_status_check
+    CHECK (push_status IN ('success', 'partial', 'failed'));
+
+ALTER TABLE donate_records DROP CONSTRAINT IF EXISTS donate_records_keys_count_check;
+ALTER TABLE donate_records ADD CONSTRAINT donate_records_keys_count_check
+    CHECK (keys_count >= 0);
+
+COMMENT ON COLUMN donate_records.push_status IS '推送状态：success/partial/failed';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'quota_grants 发件箱表已创建';
+    RAISE NOTICE '========================================';
+END $$;
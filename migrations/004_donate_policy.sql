@@ -0,0 +1,28 @@
+-- ========================================
+-- 投喂策略配置
+-- ========================================
+-- 说明: 将投喂单价和限制移入 admin_config，支持运行时修改
+--       字段为 NULL 时使用环境变量中的默认值
+--       (DONATE_QUOTA_PER_KEY / MAX_DONATE_KEYS_PER_DAY /
+--        MAX_DONATE_SUBMISSIONS_PER_DAY / MAX_KEYS_PER_SUBMISSION)
+--       限制类字段为 0 表示不限制
+--       环境变量默认值与升级前一致（每个Key 500000，不限制每日Key数，每天最多投喂 10 次）
+-- ========================================
+
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS donate_quota_per_key BIGINT CHECK (donate_quota_per_key > 0);
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS max_donate_keys_per_day INTEGER CHECK (max_donate_keys_per_day >= 0);
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS max_donate_submissions_per_day INTEGER CHECK (max_donate_submissions_per_day >= 0);
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS max_keys_per_submission INTEGER CHECK (max_keys_per_submission >= 0);
+
+COMMENT ON COLUMN admin_config.donate_quota_per_key IS '每个投喂Key奖励的额度（NULL 使用环境变量默认值）';
+COMMENT ON COLUMN admin_config.max_donate_keys_per_day IS '每个用户每天最多投喂的Key数量，0 表示不限制';
+COMMENT ON COLUMN admin_config.max_donate_submissions_per_day IS '每个用户每天最多投喂次数，0 表示不限制';
+COMMENT ON COLUMN admin_config.max_keys_per_submission IS '单次投喂最多提交的Key数量，0 表示不限制';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'admin_config 投喂策略字段已添加';
+    RAISE NOTICE '========================================';
+END $$;
@@ -0,0 +1,65 @@
+-- ========================================
+-- 异步投喂任务 (donate_jobs)
+-- ========================================
+-- 说明: 投喂请求只创建任务并立即返回任务ID，
+--       由后台工作池完成校验、推送、落库和额度发放，
+--       processing 状态的任务租约到期后会被重新领取（服务重启后自动恢复）
+-- ========================================
+
+CREATE TABLE IF NOT EXISTS donate_jobs (
+    id SERIAL PRIMARY KEY,
+    linux_do_id VARCHAR(100) NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'processing', 'completed', 'failed')),
+    keys JSONB NOT NULL DEFAULT '[]',
+    items JSONB NOT NULL DEFAULT '[]',
+    total_keys INTEGER NOT NULL CHECK (total_keys > 0),
+    processed_keys INTEGER NOT NULL DEFAULT 0,
+    valid_keys INTEGER NOT NULL DEFAULT 0,
+    quota_added BIGINT NOT NULL DEFAULT 0,
+    donate_record_id INTEGER REFERENCES donate_records(id) ON DELETE SET NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 3,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_donate_jobs_status_next ON donate_jobs(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_donate_jobs_linux_do_id ON donate_jobs(linux_do_id);
+CREATE INDEX IF NOT EXISTS idx_donate_jobs_created_at ON donate_jobs(created_at);
+
+-- 自动更新 updated_at
+DROP TRIGGER IF EXISTS update_donate_jobs_updated_at ON donate_jobs;
+CREATE TRIGGER update_donate_jobs_updated_at
+    BEFORE UPDATE ON donate_jobs
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- 添加注释
+COMMENT ON TABLE donate_jobs IS '异步投喂任务';
+COMMENT ON COLUMN donate_jobs.linux_do_id IS 'Linux Do 用户ID';
+COMMENT ON COLUMN donate_jobs.status IS '状态：queued/processing/completed/failed';
+COMMENT ON COLUMN donate_jobs.keys IS '待处理的完整Key（任务结束后清空）';
+COMMENT ON COLUMN donate_jobs.items IS '每个Key的处理进度（脱敏Key、状态、原因），与 keys 按下标对应';
+COMMENT ON COLUMN donate_jobs.total_keys IS '提交的Key数量';
+COMMENT ON COLUMN donate_jobs.processed_keys IS '已得出结果的Key数量';
+COMMENT ON COLUMN donate_jobs.valid_keys IS '推送成功的Key数量';
+COMMENT ON COLUMN donate_jobs.quota_added IS '奖励额度';
+COMMENT ON COLUMN donate_jobs.donate_record_id IS '生成的投喂记录ID';
+COMMENT ON COLUMN donate_jobs.attempts IS '已执行次数';
+COMMENT ON COLUMN donate_jobs.max_attempts IS '最大执行次数，超过后任务失败';
+COMMENT ON COLUMN donate_jobs.next_attempt_at IS '下次执行时间（processing 状态下为租约到期时间）';
+COMMENT ON COLUMN donate_jobs.last_error IS '最近一次失败原因';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'donate_jobs 异步投喂任务表已创建';
+    RAISE NOTICE '========================================';
+END $$;
@@ -0,0 +1,27 @@
+-- ========================================
+-- 领取预留 (claim_records.status)
+-- ========================================
+-- 说明: 领取时先写入 reserved 状态的领取记录占住 (linux_do_id, claim_date)，
+--       额度投递成功后转为 granted；投递最终失败（死信）时转为 released，
+--       释放当日领取资格，用户可以重新领取
+-- ========================================
+
+-- 已有记录均已发放
+ALTER TABLE claim_records ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'granted'
+    CHECK (status IN ('reserved', 'granted', 'released'));
+ALTER TABLE claim_records ALTER COLUMN status SET DEFAULT 'reserved';
+
+-- 唯一约束只作用于未释放的记录
+DROP INDEX IF EXISTS idx_claim_records_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_claim_records_unique ON claim_records(linux_do_id, claim_date)
+    WHERE status <> 'released';
+
+COMMENT ON COLUMN claim_records.status IS '状态：reserved（已预留，额度投递中）/granted（已发放）/released（投递失败，已释放）';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'claim_records 领取预留状态已添加';
+    RAISE NOTICE '========================================';
+END $$;
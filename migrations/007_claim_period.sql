@@ -0,0 +1,29 @@
+-- ========================================
+-- 可配置的领取周期 (claim_records.period_key)
+-- ========================================
+-- 说明: 领取周期支持 daily / weekly / rolling（CLAIM_PERIOD），
+--       并按 CLAIM_TIMEZONE 计算日期边界；
+--       唯一约束从 (linux_do_id, claim_date) 改为 (linux_do_id, period_key)
+--       daily: YYYY-MM-DD，weekly: YYYY-Www，rolling: after:<上一条领取记录ID>
+-- ========================================
+
+ALTER TABLE claim_records ADD COLUMN IF NOT EXISTS period_key VARCHAR(32);
+UPDATE claim_records SET period_key = to_char(claim_date, 'YYYY-MM-DD') WHERE period_key IS NULL;
+ALTER TABLE claim_records ALTER COLUMN period_key SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_claim_records_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_claim_records_unique ON claim_records(linux_do_id, period_key)
+    WHERE status <> 'released';
+
+CREATE INDEX IF NOT EXISTS idx_claim_records_linux_do_id_created_at ON claim_records(linux_do_id, created_at DESC);
+
+COMMENT ON COLUMN claim_records.claim_date IS '领取日期（按 CLAIM_TIMEZONE 计算）';
+COMMENT ON COLUMN claim_records.period_key IS '领取周期键，同一用户同一周期只能有一条有效记录';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'claim_records 领取周期字段已添加';
+    RAISE NOTICE '========================================';
+END $$;
@@ -0,0 +1,50 @@
+-- ========================================
+-- 连续领取与连续奖励 (claim_records.streak / admin_config.streak_bonuses)
+-- ========================================
+-- 说明: 每条领取记录保存领取时的连续周期数（streak），
+--       以及基础额度与连续奖励额度的拆分（quota_added = base_quota + bonus_quota）；
+--       admin_config.streak_bonuses 为连续奖励表，NULL 时使用内置默认值
+--       （连续 7 次 +10%，连续 30 次 +25%），空数组表示关闭连续奖励
+-- ========================================
+
+ALTER TABLE claim_records ADD COLUMN IF NOT EXISTS streak INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE claim_records ADD COLUMN IF NOT EXISTS base_quota BIGINT;
+ALTER TABLE claim_records ADD COLUMN IF NOT EXISTS bonus_quota BIGINT NOT NULL DEFAULT 0;
+
+UPDATE claim_records SET base_quota = quota_added WHERE base_quota IS NULL;
+ALTER TABLE claim_records ALTER COLUMN base_quota SET NOT NULL;
+
+-- 按自然日回填历史记录的连续领取数（历史数据均为按天领取）
+WITH ordered AS (
+    SELECT
+        id,
+        linux_do_id,
+        claim_date,
+        claim_date - (ROW_NUMBER() OVER (PARTITION BY linux_do_id ORDER BY claim_date))::int AS grp
+    FROM claim_records
+    WHERE status <> 'released'
+),
+numbered AS (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY linux_do_id, grp ORDER BY claim_date) AS streak
+    FROM ordered
+)
+UPDATE claim_records cr
+SET streak = numbered.streak
+FROM numbered
+WHERE cr.id = numbered.id;
+
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS streak_bonuses JSONB;
+
+COMMENT ON COLUMN claim_records.streak IS '领取时的连续领取周期数';
+COMMENT ON COLUMN claim_records.base_quota IS '基础领取额度';
+COMMENT ON COLUMN claim_records.bonus_quota IS '连续领取奖励额度（quota_added = base_quota + bonus_quota）';
+COMMENT ON COLUMN admin_config.streak_bonuses IS '连续领取奖励表 [{"days":7,"percent":10}]，NULL 使用默认值';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'claim_records 连续领取字段已添加';
+    RAISE NOTICE 'admin_config.streak_bonuses 已添加';
+    RAISE NOTICE '========================================';
+END $$;
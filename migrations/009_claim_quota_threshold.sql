@@ -0,0 +1,22 @@
+-- ========================================
+-- 按剩余额度限制领取
+-- ========================================
+-- 说明: 领取前查询用户在公益站的剩余额度，超过阈值时按策略处理
+--       block: 拒绝领取；scale: 按 阈值/剩余额度 的比例缩减本次领取额度
+--       字段为 NULL 时使用环境变量中的默认值
+--       (MIN_QUOTA_THRESHOLD / CLAIM_THRESHOLD_MODE)，阈值为 0 表示不限制
+-- ========================================
+
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS claim_quota_threshold BIGINT CHECK (claim_quota_threshold >= 0);
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS claim_threshold_mode VARCHAR(16) CHECK (claim_threshold_mode IN ('block', 'scale'));
+
+COMMENT ON COLUMN admin_config.claim_quota_threshold IS '剩余额度超过该值时限制领取，0 表示不限制（NULL 使用环境变量默认值）';
+COMMENT ON COLUMN admin_config.claim_threshold_mode IS '超过阈值时的处理方式: block 拒绝领取, scale 按比例缩减';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'admin_config 领取额度阈值字段已添加';
+    RAISE NOTICE '========================================';
+END $$;
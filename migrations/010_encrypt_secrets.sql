@@ -0,0 +1,44 @@
+-- ========================================
+-- 敏感数据加密存储
+-- ========================================
+-- 说明: used_keys.full_key、admin_config.session / keys_authorization
+--       使用 AES-256-GCM 信封加密（主密钥来自 ENCRYPTION_MASTER_KEYS）
+--       key_version 记录加密使用的主密钥版本，0 表示历史明文数据
+--       执行本迁移后运行 `go run ./cmd/reencrypt` 加密已有数据；
+--       轮换主密钥时加入新版本并切换 ENCRYPTION_KEY_VERSION 后再次运行
+-- ========================================
+
+ALTER TABLE used_keys ADD COLUMN IF NOT EXISTS key_version INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_used_keys_key_version ON used_keys(key_version);
+
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS secrets_key_version INTEGER NOT NULL DEFAULT 0;
+
+-- 推送失败的Key只保留脱敏后的值（与 maskKey 一致）
+UPDATE donate_records
+SET failed_keys = (
+    SELECT COALESCE(jsonb_agg(
+        CASE
+            WHEN length(k) <= 12 THEN '***'
+            WHEN k LIKE '%...%' THEN k
+            ELSE left(k, 6) || '...' || right(k, 4)
+        END
+    ), '[]'::jsonb)
+    FROM jsonb_array_elements_text(failed_keys) AS k
+)
+WHERE failed_keys IS NOT NULL AND jsonb_typeof(failed_keys) = 'array' AND jsonb_array_length(failed_keys) > 0;
+
+COMMENT ON COLUMN used_keys.full_key IS '完整的Key（信封加密，key_version = 0 时为历史明文）';
+COMMENT ON COLUMN used_keys.key_version IS '加密使用的主密钥版本，0 表示明文';
+COMMENT ON COLUMN admin_config.session IS '公益站Session（信封加密）';
+COMMENT ON COLUMN admin_config.keys_authorization IS 'Keys API Authorization（信封加密）';
+COMMENT ON COLUMN admin_config.secrets_key_version IS 'session / keys_authorization 加密使用的主密钥版本，0 表示明文';
+COMMENT ON COLUMN donate_records.failed_keys IS '推送失败的Keys（脱敏后的JSON数组）';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE '敏感数据加密字段已添加';
+    RAISE NOTICE '请运行 go run ./cmd/reencrypt 加密已有数据';
+    RAISE NOTICE '========================================';
+END $$;
@@ -0,0 +1,37 @@
+-- ========================================
+-- Linux.do 信任等级与账号状态限制
+-- ========================================
+-- 说明: 登录时保存 Linux.do 用户信息中的 trust_level / active / silenced，每次登录刷新；
+--       admin_config 中配置绑定、领取、投喂所需的最低信任等级，以及按信任等级的领取额度
+--       最低等级字段为 NULL 时使用环境变量中的默认值
+--       (MIN_BIND_TRUST_LEVEL / MIN_CLAIM_TRUST_LEVEL / MIN_DONATE_TRUST_LEVEL)，0 表示不限制；
+--       trust_level_claim_quotas 为 NULL 时所有等级使用 claim_quota
+--       未激活 (active = false) 或被禁言 (silenced = true) 的账号不能绑定、领取和投喂
+--       已有用户在下次登录前信任等级为 0
+-- ========================================
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS trust_level INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS silenced BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS min_bind_trust_level INTEGER CHECK (min_bind_trust_level BETWEEN 0 AND 4);
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS min_claim_trust_level INTEGER CHECK (min_claim_trust_level BETWEEN 0 AND 4);
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS min_donate_trust_level INTEGER CHECK (min_donate_trust_level BETWEEN 0 AND 4);
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS trust_level_claim_quotas JSONB;
+
+COMMENT ON COLUMN users.trust_level IS 'Linux.do 信任等级 (0-4)，登录时刷新';
+COMMENT ON COLUMN users.active IS 'Linux.do 账号是否已激活，登录时刷新';
+COMMENT ON COLUMN users.silenced IS 'Linux.do 账号是否被禁言，登录时刷新';
+COMMENT ON COLUMN admin_config.min_bind_trust_level IS '绑定公益站账号所需的最低信任等级（NULL 使用环境变量默认值）';
+COMMENT ON COLUMN admin_config.min_claim_trust_level IS '领取额度所需的最低信任等级（NULL 使用环境变量默认值）';
+COMMENT ON COLUMN admin_config.min_donate_trust_level IS '投喂Key所需的最低信任等级（NULL 使用环境变量默认值）';
+COMMENT ON COLUMN admin_config.trust_level_claim_quotas IS '按信任等级的领取额度 [{"level":2,"quota":1000000}]，NULL 时使用 claim_quota';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'users 信任等级与账号状态字段已添加';
+    RAISE NOTICE 'admin_config 信任等级策略字段已添加';
+    RAISE NOTICE '========================================';
+END $$;
@@ -0,0 +1,46 @@
+-- ========================================
+-- 管理员账号与角色 (admins)
+-- ========================================
+-- 说明: 每个管理员使用独立账号登录，密码以 bcrypt 哈希保存
+--       角色: viewer   查看统计和列表
+--             operator 维护操作、清除缓存、重置领取状态（包含 viewer 权限）
+--             owner    修改配置、删除数据、管理管理员账号（包含全部权限）
+--       表为空时服务启动会使用 ADMIN_USERNAME / ADMIN_PASSWORD 创建首个 owner 账号
+-- ========================================
+
+CREATE TABLE IF NOT EXISTS admins (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(64) NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('viewer', 'operator', 'owner')),
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    last_login_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_admins_role ON admins(role);
+
+-- 自动更新 updated_at
+DROP TRIGGER IF EXISTS update_admins_updated_at ON admins;
+CREATE TRIGGER update_admins_updated_at
+    BEFORE UPDATE ON admins
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- 添加注释
+COMMENT ON TABLE admins IS '管理员账号';
+COMMENT ON COLUMN admins.username IS '登录用户名';
+COMMENT ON COLUMN admins.password_hash IS 'bcrypt 密码哈希';
+COMMENT ON COLUMN admins.role IS '角色：viewer/operator/owner';
+COMMENT ON COLUMN admins.disabled IS '是否已停用，停用后已签发的令牌立即失效';
+COMMENT ON COLUMN admins.last_login_at IS '最近登录时间';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'admins 表已创建';
+    RAISE NOTICE '========================================';
+END $$;
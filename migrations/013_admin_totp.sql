@@ -0,0 +1,29 @@
+-- ========================================
+-- 管理员两步验证 (TOTP)
+-- ========================================
+-- 说明: 管理员可绑定 RFC 6238 TOTP 验证器，启用后登录需先验证密码、再提交验证码换取令牌
+--       totp_secret 使用 ENCRYPTION_MASTER_KEYS 加密保存（totp_key_version 为主密钥版本，0 表示明文）；
+--       totp_enabled 为 false 且 totp_secret 非空时表示已生成密钥、等待首次验证；
+--       totp_last_counter 记录最近一次使用的时间步，防止验证码重放；
+--       totp_recovery_codes 保存恢复码的 SHA-256 哈希，每个恢复码只能使用一次
+-- ========================================
+
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS totp_key_version INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS totp_last_counter BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS totp_recovery_codes JSONB NOT NULL DEFAULT '[]';
+
+COMMENT ON COLUMN admins.totp_secret IS 'TOTP 密钥（加密保存）';
+COMMENT ON COLUMN admins.totp_key_version IS 'TOTP 密钥加密使用的主密钥版本，0 表示明文';
+COMMENT ON COLUMN admins.totp_enabled IS '是否已启用两步验证';
+COMMENT ON COLUMN admins.totp_last_counter IS '最近一次通过验证的 TOTP 时间步，用于防重放';
+COMMENT ON COLUMN admins.totp_recovery_codes IS '未使用的恢复码 SHA-256 哈希';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'admins 两步验证字段已添加';
+    RAISE NOTICE '========================================';
+END $$;
@@ -0,0 +1,76 @@
+-- ========================================
+-- 管理员审计日志 (admin_audit_log)
+-- ========================================
+-- 说明: 记录每次管理后台操作的操作人、动作、目标、变更前后内容（敏感字段已脱敏）、IP、User-Agent 和结果
+--       每条记录的 hash = SHA-256(prev_hash + 记录内容)，prev_hash 为上一条记录的 hash，
+--       修改或删除中间任意一条记录都会使之后的哈希链校验失败（GET /api/admin/audit/verify）
+--       表上的触发器禁止 UPDATE / DELETE / TRUNCATE，日志只能追加
+-- ========================================
+
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    admin_id INTEGER,
+    admin_username VARCHAR(64) NOT NULL DEFAULT '',
+    action VARCHAR(64) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    path VARCHAR(255) NOT NULL,
+    target VARCHAR(255) NOT NULL DEFAULT '',
+    before JSONB,
+    after JSONB,
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    status_code INTEGER NOT NULL,
+    success BOOLEAN NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    prev_hash VARCHAR(64) NOT NULL DEFAULT '',
+    hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_admin_id ON admin_audit_log(admin_id);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_admin_username ON admin_audit_log(admin_username);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_action ON admin_audit_log(action);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_created_at ON admin_audit_log(created_at DESC);
+
+-- 禁止修改和删除审计日志
+CREATE OR REPLACE FUNCTION prevent_admin_audit_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'admin_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS admin_audit_log_immutable ON admin_audit_log;
+CREATE TRIGGER admin_audit_log_immutable
+    BEFORE UPDATE OR DELETE ON admin_audit_log
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_admin_audit_log_change();
+
+DROP TRIGGER IF EXISTS admin_audit_log_no_truncate ON admin_audit_log;
+CREATE TRIGGER admin_audit_log_no_truncate
+    BEFORE TRUNCATE ON admin_audit_log
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION prevent_admin_audit_log_change();
+
+-- 添加注释
+COMMENT ON TABLE admin_audit_log IS '管理员审计日志（只追加，哈希链防篡改）';
+COMMENT ON COLUMN admin_audit_log.admin_id IS '操作管理员ID，未认证的请求为 NULL';
+COMMENT ON COLUMN admin_audit_log.admin_username IS '操作管理员用户名（登录请求为提交的用户名）';
+COMMENT ON COLUMN admin_audit_log.action IS '动作，如 update_config、delete_user';
+COMMENT ON COLUMN admin_audit_log.target IS '操作目标，如 linux_do_id=123';
+COMMENT ON COLUMN admin_audit_log.before IS '变更前内容（敏感字段已脱敏）';
+COMMENT ON COLUMN admin_audit_log.after IS '变更后内容或请求参数（敏感字段已脱敏）';
+COMMENT ON COLUMN admin_audit_log.status_code IS 'HTTP 响应状态码';
+COMMENT ON COLUMN admin_audit_log.success IS '操作是否成功（状态码 < 400）';
+COMMENT ON COLUMN admin_audit_log.error IS '失败原因';
+COMMENT ON COLUMN admin_audit_log.prev_hash IS '上一条记录的 hash，第一条为空';
+COMMENT ON COLUMN admin_audit_log.hash IS 'SHA-256(prev_hash + 记录内容)';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'admin_audit_log 表已创建';
+    RAISE NOTICE '========================================';
+END $$;
@@ -0,0 +1,47 @@
+-- ========================================
+-- 用户个人访问令牌 (access_tokens)
+-- ========================================
+-- 说明: 用户为脚本（如定时领取）签发的访问令牌，通过 Authorization: Bearer 使用
+--       只保存令牌的 SHA-256 哈希，明文仅在创建时返回一次
+--       scopes 限定令牌可访问的接口：quota:read 查询额度和记录，claim 领取，donate 投喂
+--       rate_limit 为单个令牌每小时的请求上限，叠加在用户级限流之上
+-- ========================================
+
+CREATE TABLE IF NOT EXISTS access_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    linux_do_id VARCHAR(100) NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    token_prefix VARCHAR(16) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes JSONB NOT NULL DEFAULT '[]'::jsonb,
+    rate_limit INTEGER NOT NULL DEFAULT 60 CHECK (rate_limit > 0),
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    last_used_ip VARCHAR(64) NOT NULL DEFAULT '',
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_access_tokens_linux_do_id ON access_tokens(linux_do_id) WHERE revoked_at IS NULL;
+
+-- 添加注释
+COMMENT ON TABLE access_tokens IS '用户个人访问令牌';
+COMMENT ON COLUMN access_tokens.linux_do_id IS '令牌所属用户';
+COMMENT ON COLUMN access_tokens.name IS '令牌名称（用户填写，用于区分用途）';
+COMMENT ON COLUMN access_tokens.token_prefix IS '令牌开头几位，用于在列表中识别令牌';
+COMMENT ON COLUMN access_tokens.token_hash IS '令牌 SHA-256 哈希';
+COMMENT ON COLUMN access_tokens.scopes IS '授权范围：quota:read / claim / donate';
+COMMENT ON COLUMN access_tokens.rate_limit IS '每小时请求上限';
+COMMENT ON COLUMN access_tokens.expires_at IS '过期时间，为空表示永不过期';
+COMMENT ON COLUMN access_tokens.last_used_at IS '最近使用时间';
+COMMENT ON COLUMN access_tokens.last_used_ip IS '最近使用的客户端IP';
+COMMENT ON COLUMN access_tokens.revoked_at IS '撤销时间，撤销后令牌立即失效';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'access_tokens 表已创建';
+    RAISE NOTICE '========================================';
+END $$;
@@ -0,0 +1,58 @@
+-- ========================================
+-- Linux.do 账号定期校验 (linuxdo_tokens)
+-- ========================================
+-- 说明: 登录时加密保存 Linux.do OAuth 刷新令牌（与其他敏感数据使用相同的主密钥）
+--       后台任务按 LINUX_DO_VERIFY_INTERVAL_HOURS 定期刷新令牌并重新获取用户信息，
+--       只校验最近 LINUX_DO_VERIFY_ACTIVE_DAYS 天内登录或领取过的用户
+--       账号已删除、被停用或禁言、信任等级下降、授权被撤销时暂停用户（users.suspended_at）
+--       暂停的用户不能绑定、领取和投喂，登录会话被清除；重新登录且账号正常后自动解除
+--       用户在最后一个设备上退出登录、退出全部设备或被删除时撤销并删除保存的刷新令牌
+-- ========================================
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspend_reason VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS linuxdo_tokens (
+    linux_do_id VARCHAR(100) PRIMARY KEY,
+    refresh_token TEXT NOT NULL,
+    key_version INTEGER NOT NULL DEFAULT 0,
+    last_login_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    verified_at TIMESTAMP,
+    next_verify_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_linuxdo_tokens_next_verify_at ON linuxdo_tokens(next_verify_at);
+CREATE INDEX IF NOT EXISTS idx_users_suspended_at ON users(suspended_at) WHERE suspended_at IS NOT NULL;
+
+-- 自动更新 updated_at
+DROP TRIGGER IF EXISTS update_linuxdo_tokens_updated_at ON linuxdo_tokens;
+CREATE TRIGGER update_linuxdo_tokens_updated_at
+    BEFORE UPDATE ON linuxdo_tokens
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- 添加注释
+COMMENT ON TABLE linuxdo_tokens IS 'Linux.do OAuth 刷新令牌，用于定期校验账号状态';
+COMMENT ON COLUMN linuxdo_tokens.refresh_token IS '加密后的刷新令牌（key_version 为 0 时为明文）';
+COMMENT ON COLUMN linuxdo_tokens.key_version IS '加密使用的主密钥版本';
+COMMENT ON COLUMN linuxdo_tokens.last_login_at IS '最近一次 OAuth 登录时间';
+COMMENT ON COLUMN linuxdo_tokens.verified_at IS '最近一次校验成功时间';
+COMMENT ON COLUMN linuxdo_tokens.next_verify_at IS '下次校验时间（校验进行中时为租约到期时间）';
+COMMENT ON COLUMN linuxdo_tokens.failures IS '连续校验失败次数（Linux.do 不可用等临时错误）';
+COMMENT ON COLUMN linuxdo_tokens.last_error IS '最近一次校验失败原因';
+COMMENT ON COLUMN users.suspended_at IS '因 Linux.do 账号异常被暂停的时间，NULL 表示正常';
+COMMENT ON COLUMN users.suspend_reason IS '暂停原因：linuxdo_account_gone / linuxdo_account_restricted / linuxdo_trust_level_demoted / linuxdo_authorization_revoked';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'linuxdo_tokens 表已创建';
+    RAISE NOTICE 'users 暂停状态字段已添加';
+    RAISE NOTICE '========================================';
+END $$;
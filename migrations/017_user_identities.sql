@@ -0,0 +1,84 @@
+-- ========================================
+-- 多身份提供方与账号关联 (user_identities)
+-- ========================================
+-- 说明: 登录支持多个身份提供方（Linux.do、通用 OIDC），一个用户可以关联多个提供方的身份
+--       新用户通过 Linux.do 注册（公益站绑定和信任等级依赖 Linux.do 账号），
+--       其他提供方的身份需要在登录后关联，之后可直接用于登录
+--       领取记录和投喂记录改为按内部用户ID（users.id）归属，
+--       唯一约束从 (linux_do_id, period_key) 改为 (user_id, period_key)
+-- ========================================
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(32) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    username VARCHAR(255) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    last_login_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, subject),
+    UNIQUE (user_id, provider)
+);
+
+-- 已有用户的 Linux.do 身份
+INSERT INTO user_identities (user_id, provider, subject, username, created_at)
+SELECT id, 'linuxdo', linux_do_id, username, created_at FROM users
+ON CONFLICT (provider, subject) DO NOTHING;
+
+-- 领取记录和投喂记录按内部用户ID归属（用户已删除的历史记录保持为空）
+ALTER TABLE claim_records ADD COLUMN IF NOT EXISTS user_id INTEGER;
+ALTER TABLE donate_records ADD COLUMN IF NOT EXISTS user_id INTEGER;
+
+UPDATE claim_records c SET user_id = u.id FROM users u
+WHERE c.user_id IS NULL AND u.linux_do_id = c.linux_do_id;
+UPDATE donate_records d SET user_id = u.id FROM users u
+WHERE d.user_id IS NULL AND u.linux_do_id = d.linux_do_id;
+
+DROP INDEX IF EXISTS idx_claim_records_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_claim_records_unique ON claim_records(user_id, period_key)
+    WHERE status <> 'released';
+
+CREATE INDEX IF NOT EXISTS idx_claim_records_user_id_created_at ON claim_records(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_donate_records_user_id_created_at ON donate_records(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+-- 用户统计视图按内部用户ID汇总
+DROP VIEW IF EXISTS user_statistics;
+CREATE VIEW user_statistics AS
+SELECT
+    u.id as user_id,
+    u.linux_do_id,
+    u.username,
+    u.created_at as register_time,
+    COUNT(DISTINCT cr.id) as total_claims,
+    COALESCE(SUM(cr.quota_added), 0) as total_claim_quota,
+    COUNT(DISTINCT dr.id) as total_donates,
+    COALESCE(SUM(dr.keys_count), 0) as total_keys_donated,
+    COALESCE(SUM(dr.total_quota_added), 0) as total_donate_quota,
+    COALESCE(SUM(cr.quota_added), 0) + COALESCE(SUM(dr.total_quota_added), 0) as total_quota
+FROM users u
+LEFT JOIN claim_records cr ON u.id = cr.user_id
+LEFT JOIN donate_records dr ON u.id = dr.user_id
+GROUP BY u.id, u.linux_do_id, u.username, u.created_at;
+
+-- 添加注释
+COMMENT ON TABLE user_identities IS '用户关联的身份提供方账号';
+COMMENT ON COLUMN user_identities.user_id IS '所属用户（users.id）';
+COMMENT ON COLUMN user_identities.provider IS '身份提供方：linuxdo / 配置的 OIDC 提供方名称';
+COMMENT ON COLUMN user_identities.subject IS '提供方内的用户唯一标识（Linux.do 用户ID / OIDC sub）';
+COMMENT ON COLUMN user_identities.username IS '提供方内的用户名';
+COMMENT ON COLUMN user_identities.email IS '提供方返回的邮箱';
+COMMENT ON COLUMN user_identities.last_login_at IS '最近一次使用该身份登录的时间';
+COMMENT ON COLUMN claim_records.user_id IS '领取用户（users.id）';
+COMMENT ON COLUMN donate_records.user_id IS '投喂用户（users.id）';
+COMMENT ON VIEW user_statistics IS '用户统计视图，包含领取和投喂的汇总数据';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'user_identities 表已创建';
+    RAISE NOTICE 'claim_records / donate_records 已添加 user_id 字段';
+    RAISE NOTICE '========================================';
+END $$;
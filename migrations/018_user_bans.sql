@@ -0,0 +1,56 @@
+-- ========================================
+-- 用户封禁、暂停和观察名单 (user_bans)
+-- ========================================
+-- 说明: 管理员对用户的处置记录，按 Linux.do ID 或公益站用户ID 匹配，
+--       可在用户首次登录或绑定前预先设置，删除用户后记录仍然保留
+--       kind: ban 永久封禁（解除前一直有效），suspend 暂停到 expires_at，
+--             watch 观察名单（不限制操作，只在登录、绑定、领取、投喂时记录告警日志）
+--       封禁和暂停的用户不能登录、访问用户接口、绑定、领取和投喂，施加时清除其登录会话
+--       解除时记录解除时间、操作人和原因，全部记录作为处置历史保留
+--       与 users.suspended_at（Linux.do 账号定期校验产生的暂停）相互独立
+-- ========================================
+
+CREATE TABLE IF NOT EXISTS user_bans (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('ban', 'suspend', 'watch')),
+    linux_do_id VARCHAR(100) NOT NULL DEFAULT '',
+    kyx_user_id INTEGER NOT NULL DEFAULT 0,
+    reason TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    created_by_id INTEGER REFERENCES admins(id) ON DELETE SET NULL,
+    created_by VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    lifted_at TIMESTAMP,
+    lifted_by_id INTEGER REFERENCES admins(id) ON DELETE SET NULL,
+    lifted_by VARCHAR(64) NOT NULL DEFAULT '',
+    lift_reason TEXT NOT NULL DEFAULT '',
+    CHECK (linux_do_id <> '' OR kyx_user_id > 0),
+    CHECK (kind <> 'suspend' OR expires_at IS NOT NULL)
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_user_bans_linux_do_id ON user_bans(linux_do_id) WHERE lifted_at IS NULL AND linux_do_id <> '';
+CREATE INDEX IF NOT EXISTS idx_user_bans_kyx_user_id ON user_bans(kyx_user_id) WHERE lifted_at IS NULL AND kyx_user_id > 0;
+CREATE INDEX IF NOT EXISTS idx_user_bans_created_at ON user_bans(created_at DESC);
+
+-- 添加注释
+COMMENT ON TABLE user_bans IS '用户封禁、暂停和观察名单记录（含已解除的历史）';
+COMMENT ON COLUMN user_bans.kind IS '类型：ban 封禁 / suspend 暂停 / watch 观察名单';
+COMMENT ON COLUMN user_bans.linux_do_id IS '目标 Linux.do ID，为空表示按公益站用户ID 匹配';
+COMMENT ON COLUMN user_bans.kyx_user_id IS '目标公益站用户ID，0 表示按 Linux.do ID 匹配';
+COMMENT ON COLUMN user_bans.reason IS '处置原因';
+COMMENT ON COLUMN user_bans.expires_at IS '到期时间，暂停必填，为空表示解除前一直有效';
+COMMENT ON COLUMN user_bans.created_by_id IS '执行处置的管理员ID';
+COMMENT ON COLUMN user_bans.created_by IS '执行处置的管理员用户名（管理员删除后保留）';
+COMMENT ON COLUMN user_bans.lifted_at IS '解除时间，为空表示未解除';
+COMMENT ON COLUMN user_bans.lifted_by_id IS '解除处置的管理员ID';
+COMMENT ON COLUMN user_bans.lifted_by IS '解除处置的管理员用户名';
+COMMENT ON COLUMN user_bans.lift_reason IS '解除原因';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'user_bans 表已创建';
+    RAISE NOTICE '========================================';
+END $$;
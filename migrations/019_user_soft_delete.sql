@@ -0,0 +1,49 @@
+-- ========================================
+-- 用户软删除 (users.deleted_at)
+-- ========================================
+-- 说明: 管理员删除用户时默认软删除：记录删除时间和操作人，用户从列表和统计中隐藏，
+--       登录会话、个人访问令牌和保存的 Linux.do 刷新令牌被清除，不能再登录
+--       保留期内可以恢复；超过 DELETED_USER_RETENTION_DAYS 天后由后台任务彻底清除
+--       彻底清除在一个事务中删除领取记录、投喂记录、投喂任务和用户，
+--       已使用的 Key 只清除明文和归属信息，保留 key_hash 防止同一个 Key 被重复投喂
+-- ========================================
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_by VARCHAR(64) NOT NULL DEFAULT '';
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- 统计视图排除已删除的用户
+DROP VIEW IF EXISTS user_statistics;
+CREATE VIEW user_statistics AS
+SELECT
+    u.id as user_id,
+    u.linux_do_id,
+    u.username,
+    u.created_at as register_time,
+    COUNT(DISTINCT cr.id) as total_claims,
+    COALESCE(SUM(cr.quota_added), 0) as total_claim_quota,
+    COUNT(DISTINCT dr.id) as total_donates,
+    COALESCE(SUM(dr.keys_count), 0) as total_keys_donated,
+    COALESCE(SUM(dr.total_quota_added), 0) as total_donate_quota,
+    COALESCE(SUM(cr.quota_added), 0) + COALESCE(SUM(dr.total_quota_added), 0) as total_quota
+FROM users u
+LEFT JOIN claim_records cr ON u.id = cr.user_id
+LEFT JOIN donate_records dr ON u.id = dr.user_id
+WHERE u.deleted_at IS NULL
+GROUP BY u.id, u.linux_do_id, u.username, u.created_at;
+
+-- 添加注释
+COMMENT ON COLUMN users.deleted_at IS '软删除时间，NULL 表示正常';
+COMMENT ON COLUMN users.deleted_by IS '执行删除的管理员用户名';
+COMMENT ON VIEW user_statistics IS '用户统计视图，包含领取和投喂的汇总数据（不含已删除的用户）';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'users 软删除字段已添加';
+    RAISE NOTICE 'user_statistics 视图已更新';
+    RAISE NOTICE '========================================';
+END $$;
@@ -0,0 +1,41 @@
+-- ========================================
+-- 公益站账号唯一绑定 (users.kyx_user_id)
+-- ========================================
+-- 说明: 一个公益站账号只能绑定一个 Linux.do 账号（kyx_user_id = 0 表示未绑定，不受限制）
+--       迁移前已存在的重复绑定保留最早创建的用户，其余用户解除绑定后需要重新绑定
+--       软删除的用户在彻底清除前仍占用其绑定的公益站账号
+-- ========================================
+
+-- 解除重复绑定
+DO $$
+DECLARE
+    dup RECORD;
+BEGIN
+    FOR dup IN
+        SELECT id, linux_do_id, kyx_user_id
+        FROM (
+            SELECT id, linux_do_id, kyx_user_id,
+                   ROW_NUMBER() OVER (PARTITION BY kyx_user_id ORDER BY id) AS rn
+            FROM users
+            WHERE kyx_user_id > 0
+        ) ranked
+        WHERE rn > 1
+    LOOP
+        RAISE NOTICE '解除重复绑定: linux_do_id=%, kyx_user_id=%', dup.linux_do_id, dup.kyx_user_id;
+        UPDATE users SET kyx_user_id = 0, updated_at = NOW() WHERE id = dup.id;
+    END LOOP;
+END $$;
+
+-- 创建唯一索引
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_kyx_user_id_unique ON users(kyx_user_id) WHERE kyx_user_id > 0;
+
+-- 添加注释
+COMMENT ON COLUMN users.kyx_user_id IS '绑定的公益站用户ID，0 表示未绑定（已绑定的值唯一）';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'users.kyx_user_id 唯一索引已创建';
+    RAISE NOTICE '========================================';
+END $$;
@@ -0,0 +1,72 @@
+-- ========================================
+-- 绑定历史和解绑申请 (binding_history / unbind_requests)
+-- ========================================
+-- 说明: binding_history 每次绑定公益站账号记录一行，解绑时记录解绑时间和对应的解绑申请，
+--       更换公益站账号后新旧绑定都会保留；用户被彻底清除后历史仍然保留（user_id 置空），
+--       同一个 Linux.do ID 或公益站账号只能获得一次首次绑定奖励
+--       unbind_requests 用户自助提交的解绑申请，需要管理员批准后才会解除绑定，
+--       每个用户同一时间只能有一个待处理的申请，绑定后 UNBIND_COOLDOWN_HOURS 小时内不能申请
+-- ========================================
+
+CREATE TABLE IF NOT EXISTS unbind_requests (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    linux_do_id VARCHAR(100) NOT NULL,
+    kyx_user_id INTEGER NOT NULL,
+    kyx_username VARCHAR(255) NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'cancelled')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reviewed_at TIMESTAMP,
+    reviewed_by_id INTEGER REFERENCES admins(id) ON DELETE SET NULL,
+    reviewed_by VARCHAR(64) NOT NULL DEFAULT '',
+    review_note TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS binding_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    linux_do_id VARCHAR(100) NOT NULL,
+    kyx_user_id INTEGER NOT NULL,
+    kyx_username VARCHAR(255) NOT NULL DEFAULT '',
+    bonus_granted BOOLEAN NOT NULL DEFAULT FALSE,
+    bound_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    unbound_at TIMESTAMP,
+    unbind_request_id BIGINT REFERENCES unbind_requests(id) ON DELETE SET NULL
+);
+
+-- 创建索引
+CREATE UNIQUE INDEX IF NOT EXISTS idx_unbind_requests_pending ON unbind_requests(user_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_unbind_requests_status ON unbind_requests(status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_binding_history_linux_do_id ON binding_history(linux_do_id);
+CREATE INDEX IF NOT EXISTS idx_binding_history_kyx_user_id ON binding_history(kyx_user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_binding_history_open ON binding_history(user_id) WHERE unbound_at IS NULL;
+
+-- 补齐迁移前已存在的绑定（此前首次绑定都会发放奖励，视为已领取）
+INSERT INTO binding_history (user_id, linux_do_id, kyx_user_id, kyx_username, bonus_granted, bound_at)
+SELECT u.id, u.linux_do_id, u.kyx_user_id, u.username, TRUE, u.updated_at
+FROM users u
+WHERE u.kyx_user_id > 0
+  AND NOT EXISTS (SELECT 1 FROM binding_history h WHERE h.user_id = u.id AND h.unbound_at IS NULL);
+
+-- 添加注释
+COMMENT ON TABLE unbind_requests IS '用户自助解绑申请，管理员批准后解除公益站账号绑定';
+COMMENT ON COLUMN unbind_requests.kyx_user_id IS '申请时绑定的公益站用户ID';
+COMMENT ON COLUMN unbind_requests.reason IS '用户填写的解绑原因';
+COMMENT ON COLUMN unbind_requests.status IS '状态：pending 待处理 / approved 已批准 / rejected 已拒绝 / cancelled 用户已撤回';
+COMMENT ON COLUMN unbind_requests.reviewed_by IS '处理申请的管理员用户名';
+COMMENT ON COLUMN unbind_requests.review_note IS '管理员处理备注';
+COMMENT ON TABLE binding_history IS '公益站账号绑定历史，每次绑定一行';
+COMMENT ON COLUMN binding_history.user_id IS '用户ID，用户被彻底清除后为空';
+COMMENT ON COLUMN binding_history.bonus_granted IS '本次绑定是否发放了首次绑定奖励';
+COMMENT ON COLUMN binding_history.unbound_at IS '解绑时间，为空表示当前绑定';
+COMMENT ON COLUMN binding_history.unbind_request_id IS '解除本次绑定的解绑申请';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'unbind_requests 表已创建';
+    RAISE NOTICE 'binding_history 表已创建并补齐现有绑定';
+    RAISE NOTICE '========================================';
+END $$;
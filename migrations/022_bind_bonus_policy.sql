@@ -0,0 +1,84 @@
+-- ========================================
+-- 首次绑定奖励策略 (admin_config.bind_bonus_*)
+-- ========================================
+-- 说明: 首次绑定奖励不再使用 claim_quota，改为独立策略：是否启用、奖励额度、
+--       只奖励指定时间之后创建的账号；启用和额度为 NULL 时使用环境变量 FIRST_BIND_BONUS_QUOTA
+--       每笔奖励与绑定记录在同一事务中写入 quota_grants (source = 'bind_bonus')，
+--       由发件箱投递到公益站，失败时自动重试，并计入用户统计和系统统计
+--       已有用户的安装按升级前的行为写入策略（启用，奖励额度等于当前领取额度 claim_quota），
+--       避免升级后奖励额度变为环境变量默认值
+-- ========================================
+
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS bind_bonus_enabled BOOLEAN;
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS bind_bonus_quota BIGINT CHECK (bind_bonus_quota >= 0);
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS bind_bonus_accounts_after TIMESTAMP;
+
+-- 已有安装保持升级前的首次绑定奖励（等于领取额度）
+UPDATE admin_config
+SET bind_bonus_enabled = TRUE, bind_bonus_quota = claim_quota
+WHERE id = 1
+  AND bind_bonus_enabled IS NULL
+  AND bind_bonus_quota IS NULL
+  AND EXISTS (SELECT 1 FROM users);
+
+-- 额度发放增加首次绑定奖励来源
+ALTER TABLE quota_grants DROP CONSTRAINT IF EXISTS quota_grants_source_check;
+ALTER TABLE quota_grants ADD CONSTRAINT quota_grants_source_check
+    CHECK (source IN ('claim', 'donate', 'bind_bonus'));
+
+-- 统计视图增加首次绑定奖励（死信的发放不计入）
+-- 领取、投喂和首次绑定奖励分别在 LATERAL 子查询中汇总，避免多表 JOIN 后行数相乘重复累加；
+-- 已释放的领取预留不计入；首次绑定奖励按 binding_history.user_id 匹配用户
+DROP VIEW IF EXISTS user_statistics;
+CREATE VIEW user_statistics AS
+SELECT
+    u.id as user_id,
+    u.linux_do_id,
+    u.username,
+    u.created_at as register_time,
+    c.claims as total_claims,
+    c.quota as total_claim_quota,
+    d.donates as total_donates,
+    d.keys_count as total_keys_donated,
+    d.quota as total_donate_quota,
+    bb.quota as total_bind_bonus_quota,
+    c.quota + d.quota + bb.quota as total_quota
+FROM users u
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) as claims, COALESCE(SUM(cr.quota_added), 0) as quota
+    FROM claim_records cr
+    WHERE cr.user_id = u.id AND cr.status <> 'released'
+) c
+CROSS JOIN LATERAL (
+    SELECT
+        COUNT(*) as donates,
+        COALESCE(SUM(dr.keys_count), 0) as keys_count,
+        COALESCE(SUM(dr.total_quota_added), 0) as quota
+    FROM donate_records dr
+    WHERE dr.user_id = u.id
+) d
+CROSS JOIN LATERAL (
+    SELECT COALESCE(SUM(g.quota), 0) as quota
+    FROM quota_grants g
+    JOIN binding_history bh ON bh.id = g.source_id
+    WHERE bh.user_id = u.id AND g.source = 'bind_bonus' AND g.status <> 'dead'
+) bb
+WHERE u.deleted_at IS NULL;
+
+-- 添加注释
+COMMENT ON COLUMN admin_config.bind_bonus_enabled IS '是否发放首次绑定奖励（NULL 时 FIRST_BIND_BONUS_QUOTA > 0 即启用）';
+COMMENT ON COLUMN admin_config.bind_bonus_quota IS '首次绑定奖励额度（NULL 使用环境变量 FIRST_BIND_BONUS_QUOTA）';
+COMMENT ON COLUMN admin_config.bind_bonus_accounts_after IS '只奖励此时间之后首次登录创建的账号，NULL 表示不限制';
+COMMENT ON COLUMN quota_grants.source IS '来源：claim/donate/bind_bonus';
+COMMENT ON COLUMN quota_grants.source_id IS '来源记录ID（claim_records.id、donate_records.id 或 binding_history.id）';
+COMMENT ON VIEW user_statistics IS '用户统计视图，领取（不含已释放的预留）、投喂和首次绑定奖励分别汇总（不含已删除的用户）';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'admin_config 首次绑定奖励策略字段已添加';
+    RAISE NOTICE 'quota_grants 已支持 bind_bonus 来源';
+    RAISE NOTICE 'user_statistics 视图已更新';
+    RAISE NOTICE '========================================';
+END $$;
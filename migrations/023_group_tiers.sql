@@ -0,0 +1,55 @@
+-- ========================================
+-- 按投喂量自动调整公益站用户组 (admin_config.group_tiers / group_changes)
+-- ========================================
+-- 说明: admin_config.group_tiers 按从低到高的顺序配置档位，累计投喂Key数或投喂额度任一达到门槛即进入该档位，
+--       每次投喂成功后和后台任务定期评估，档位变化时调用公益站接口调整用户组；
+--       超过 group_demote_inactive_days 天没有投喂的用户降回 admin_config.group_id（0 或 NULL 时不降级，
+--       NULL 使用环境变量 GROUP_DEMOTE_INACTIVE_DAYS）
+--       group_changes 记录每次调整（包括失败的调整），用户当前档位为最近一次成功调整的目标档位
+-- ========================================
+
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS group_tiers JSONB;
+ALTER TABLE admin_config ADD COLUMN IF NOT EXISTS group_demote_inactive_days INTEGER CHECK (group_demote_inactive_days >= 0);
+
+CREATE TABLE IF NOT EXISTS group_changes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    linux_do_id VARCHAR(100) NOT NULL,
+    kyx_user_id INTEGER NOT NULL,
+    from_tier VARCHAR(64) NOT NULL DEFAULT '',
+    to_tier VARCHAR(64) NOT NULL DEFAULT '',
+    group_id INTEGER NOT NULL,
+    source VARCHAR(16) NOT NULL CHECK (source IN ('donate', 'scheduled')),
+    status VARCHAR(16) NOT NULL CHECK (status IN ('success', 'failed')),
+    error TEXT NOT NULL DEFAULT '',
+    keys_donated BIGINT NOT NULL DEFAULT 0,
+    donate_quota BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_group_changes_user ON group_changes(user_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_group_changes_linux_do_id ON group_changes(linux_do_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_group_changes_created_at ON group_changes(created_at DESC);
+
+-- 添加注释
+COMMENT ON COLUMN admin_config.group_tiers IS '用户组档位 [{"name":"vip","group_id":2,"min_keys":20,"min_donate_quota":25000000}]，按从低到高排列，NULL 或空数组表示不自动调整';
+COMMENT ON COLUMN admin_config.group_demote_inactive_days IS '超过多少天没有投喂降回默认用户组，0 表示不降级（NULL 使用环境变量默认值）';
+COMMENT ON TABLE group_changes IS '公益站用户组自动调整记录';
+COMMENT ON COLUMN group_changes.user_id IS '用户ID，用户被彻底清除后为空';
+COMMENT ON COLUMN group_changes.from_tier IS '调整前的档位，空字符串表示默认用户组';
+COMMENT ON COLUMN group_changes.to_tier IS '调整后的档位，空字符串表示默认用户组';
+COMMENT ON COLUMN group_changes.group_id IS '调整后的公益站用户组ID';
+COMMENT ON COLUMN group_changes.source IS '触发来源：donate 投喂后 / scheduled 定期任务';
+COMMENT ON COLUMN group_changes.status IS '调用公益站接口的结果：success / failed';
+COMMENT ON COLUMN group_changes.keys_donated IS '评估时的累计投喂Key数';
+COMMENT ON COLUMN group_changes.donate_quota IS '评估时的累计投喂额度';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'admin_config 用户组档位字段已添加';
+    RAISE NOTICE 'group_changes 表已创建';
+    RAISE NOTICE '========================================';
+END $$;
@@ -0,0 +1,39 @@
+-- ========================================
+-- 系统告警 (system_alerts)
+-- ========================================
+-- 说明: 后台任务发现需要管理员处理的问题时记录告警，目前用于公益站 Session 失效：
+--       定期校验 Session 失败且自动重新登录也失败时产生告警，之后每次失败累加 failures，
+--       Session 恢复有效（自动续期或管理员更新）后记录 resolved_at
+--       同一类型同一时间只有一条未解决的告警，未解决的告警显示在 /api/admin/health，
+--       产生和解决时发送 Webhook（配置 ALERT_WEBHOOK_URL 时）
+-- ========================================
+
+CREATE TABLE IF NOT EXISTS system_alerts (
+    id BIGSERIAL PRIMARY KEY,
+    alert_type VARCHAR(32) NOT NULL,
+    message TEXT NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP
+);
+
+-- 创建索引
+CREATE UNIQUE INDEX IF NOT EXISTS idx_system_alerts_open ON system_alerts(alert_type) WHERE resolved_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_system_alerts_created_at ON system_alerts(created_at DESC);
+
+-- 添加注释
+COMMENT ON TABLE system_alerts IS '系统告警记录（含已解决的历史）';
+COMMENT ON COLUMN system_alerts.alert_type IS '告警类型：kyx_session 公益站 Session 失效';
+COMMENT ON COLUMN system_alerts.message IS '最近一次失败的错误信息';
+COMMENT ON COLUMN system_alerts.failures IS '告警期间连续失败的次数';
+COMMENT ON COLUMN system_alerts.updated_at IS '最近一次失败时间';
+COMMENT ON COLUMN system_alerts.resolved_at IS '解决时间，为空表示未解决';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'system_alerts 表已创建';
+    RAISE NOTICE '========================================';
+END $$;
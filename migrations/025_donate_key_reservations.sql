@@ -0,0 +1,30 @@
+-- ========================================
+-- 投喂Key预留 (donate_key_reservations)
+-- ========================================
+-- 说明: 投喂任务推送Key前先按 key_hash 预留，同一个Key同一时间只能被一个任务推送，
+--       并发的工作协程或同一用户重复提交时，后到的任务将该Key标记为重复且不推送、不发放额度
+--       推送失败的Key释放预留；任务完成时Key写入 used_keys 后删除预留；
+--       已推送但任务最终失败的Key保留预留，避免再次推送
+-- ========================================
+
+CREATE TABLE IF NOT EXISTS donate_key_reservations (
+    key_hash VARCHAR(64) PRIMARY KEY,
+    job_id INTEGER NOT NULL REFERENCES donate_jobs(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_donate_key_reservations_job ON donate_key_reservations(job_id);
+
+-- 添加注释
+COMMENT ON TABLE donate_key_reservations IS '投喂任务推送前的Key预留，防止同一个Key被多个任务推送和计费';
+COMMENT ON COLUMN donate_key_reservations.key_hash IS 'Key的SHA256哈希值';
+COMMENT ON COLUMN donate_key_reservations.job_id IS '持有预留的投喂任务ID';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'donate_key_reservations 表已创建';
+    RAISE NOTICE '========================================';
+END $$;
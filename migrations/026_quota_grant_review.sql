@@ -0,0 +1,34 @@
+-- ========================================
+-- 额度发放对账与人工核对 (quota_grants)
+-- ========================================
+-- 说明: 公益站不支持按幂等键去重，重复投递同一笔发放会重复加额度
+--       同一公益站用户的投递通过 Redis 锁串行执行，每次投递前记录公益站用户的累计额度
+--       （quota + used_quota）和记录时间，再次投递前（上次投递超时、进程崩溃租约到期或结果未知）
+--       重新读取累计额度，扣除上次记录之后同一公益站用户其他已投递发放的额度：
+--       剩余增加量小于本笔额度说明上次未到账，可以安全重试；
+--       否则无法确认是否已到账，进入 review 状态等待管理员核对，
+--       管理员确认已到账后标记为已投递，确认未到账后重放
+-- ========================================
+
+ALTER TABLE quota_grants ADD COLUMN IF NOT EXISTS kyx_total_before BIGINT;
+ALTER TABLE quota_grants ADD COLUMN IF NOT EXISTS kyx_total_at TIMESTAMP;
+
+-- 对账时按公益站用户汇总其他发放
+CREATE INDEX IF NOT EXISTS idx_quota_grants_kyx_user_total_at ON quota_grants(kyx_user_id, kyx_total_at);
+
+ALTER TABLE quota_grants DROP CONSTRAINT IF EXISTS quota_grants_status_check;
+ALTER TABLE quota_grants ADD CONSTRAINT quota_grants_status_check
+    CHECK (status IN ('pending', 'delivering', 'delivered', 'review', 'dead'));
+
+-- 添加注释
+COMMENT ON COLUMN quota_grants.kyx_total_before IS '最近一次投递前公益站用户的累计额度（quota + used_quota），用于重试前对账';
+COMMENT ON COLUMN quota_grants.kyx_total_at IS '记录 kyx_total_before 的时间，之后同一公益站用户投递的其他发放在对账时扣除';
+COMMENT ON COLUMN quota_grants.status IS '状态：pending/delivering/delivered/review（待人工核对）/dead';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'quota_grants 已支持投递前对账和人工核对状态';
+    RAISE NOTICE '========================================';
+END $$;
@@ -0,0 +1,42 @@
+-- ========================================
+-- 用户统计视图排除已释放的领取 (user_statistics)
+-- ========================================
+-- 说明: 领取先写入预留记录，额度发放最终失败时预留转为 released，
+--       released 的记录没有发放额度，不计入领取次数和领取额度
+-- ========================================
+
+DROP VIEW IF EXISTS user_statistics;
+CREATE VIEW user_statistics AS
+SELECT
+    u.id as user_id,
+    u.linux_do_id,
+    u.username,
+    u.created_at as register_time,
+    COUNT(DISTINCT cr.id) as total_claims,
+    COALESCE(SUM(cr.quota_added), 0) as total_claim_quota,
+    COUNT(DISTINCT dr.id) as total_donates,
+    COALESCE(SUM(dr.keys_count), 0) as total_keys_donated,
+    COALESCE(SUM(dr.total_quota_added), 0) as total_donate_quota,
+    bb.quota as total_bind_bonus_quota,
+    COALESCE(SUM(cr.quota_added), 0) + COALESCE(SUM(dr.total_quota_added), 0) + bb.quota as total_quota
+FROM users u
+LEFT JOIN claim_records cr ON u.id = cr.user_id AND cr.status <> 'released'
+LEFT JOIN donate_records dr ON u.id = dr.user_id
+CROSS JOIN LATERAL (
+    SELECT COALESCE(SUM(g.quota), 0) as quota
+    FROM quota_grants g
+    WHERE g.linux_do_id = u.linux_do_id AND g.source = 'bind_bonus' AND g.status <> 'dead'
+) bb
+WHERE u.deleted_at IS NULL
+GROUP BY u.id, u.linux_do_id, u.username, u.created_at, bb.quota;
+
+-- 添加注释
+COMMENT ON VIEW user_statistics IS '用户统计视图，包含领取（不含已释放的预留）、投喂和首次绑定奖励的汇总数据（不含已删除的用户）';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'user_statistics 视图已排除已释放的领取';
+    RAISE NOTICE '========================================';
+END $$;
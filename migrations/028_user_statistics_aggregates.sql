@@ -0,0 +1,55 @@
+-- ========================================
+-- 用户统计视图按来源分别汇总 (user_statistics)
+-- ========================================
+-- 说明: 之前的视图同时 LEFT JOIN claim_records 和 donate_records 后再 SUM，
+--       用户同时有多条领取和投喂记录时行数相乘，领取额度和投喂额度被重复累加
+--       领取、投喂和首次绑定奖励分别在 LATERAL 子查询中汇总，每个用户只有一行
+--       首次绑定奖励按 binding_history.user_id 匹配用户，不再依赖 Linux.do ID
+-- ========================================
+
+DROP VIEW IF EXISTS user_statistics;
+CREATE VIEW user_statistics AS
+SELECT
+    u.id as user_id,
+    u.linux_do_id,
+    u.username,
+    u.created_at as register_time,
+    c.claims as total_claims,
+    c.quota as total_claim_quota,
+    d.donates as total_donates,
+    d.keys_count as total_keys_donated,
+    d.quota as total_donate_quota,
+    bb.quota as total_bind_bonus_quota,
+    c.quota + d.quota + bb.quota as total_quota
+FROM users u
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) as claims, COALESCE(SUM(cr.quota_added), 0) as quota
+    FROM claim_records cr
+    WHERE cr.user_id = u.id AND cr.status <> 'released'
+) c
+CROSS JOIN LATERAL (
+    SELECT
+        COUNT(*) as donates,
+        COALESCE(SUM(dr.keys_count), 0) as keys_count,
+        COALESCE(SUM(dr.total_quota_added), 0) as quota
+    FROM donate_records dr
+    WHERE dr.user_id = u.id
+) d
+CROSS JOIN LATERAL (
+    SELECT COALESCE(SUM(g.quota), 0) as quota
+    FROM quota_grants g
+    JOIN binding_history bh ON bh.id = g.source_id
+    WHERE bh.user_id = u.id AND g.source = 'bind_bonus' AND g.status <> 'dead'
+) bb
+WHERE u.deleted_at IS NULL;
+
+-- 添加注释
+COMMENT ON VIEW user_statistics IS '用户统计视图，领取（不含已释放的预留）、投喂和首次绑定奖励分别汇总（不含已删除的用户）';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'user_statistics 视图已改为按来源分别汇总';
+    RAISE NOTICE '========================================';
+END $$;
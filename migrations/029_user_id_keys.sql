@@ -0,0 +1,147 @@
+-- ========================================
+-- 用户数据按内部用户ID归属 (user_id)
+-- ========================================
+-- 说明: 额度发放、投喂任务、个人访问令牌和会话增加 user_id（users.id 外键），
+--       查询和删除改为按 user_id，linux_do_id 只作为冗余展示字段保留
+--       回填按 linux_do_id 匹配用户（会话按会话数据中的 linux_do_id），领取记录和投喂记录同样补全
+--       会话和个人访问令牌的 user_id 非空，找不到用户的被删除，随用户级联删除
+--       额度发放、投喂任务、领取记录和投喂记录的 user_id 可以为空（用户已删除的历史记录），
+--       外键为 ON DELETE SET NULL，不会因为删除用户而丢失历史或未完成的额度发放
+-- ========================================
+
+-- 新增字段
+ALTER TABLE quota_grants ADD COLUMN IF NOT EXISTS user_id INTEGER;
+ALTER TABLE donate_jobs ADD COLUMN IF NOT EXISTS user_id INTEGER;
+ALTER TABLE access_tokens ADD COLUMN IF NOT EXISTS user_id INTEGER;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS user_id INTEGER;
+
+-- 按 linux_do_id 回填
+UPDATE quota_grants g SET user_id = u.id FROM users u
+WHERE g.user_id IS NULL AND u.linux_do_id = g.linux_do_id;
+UPDATE donate_jobs j SET user_id = u.id FROM users u
+WHERE j.user_id IS NULL AND u.linux_do_id = j.linux_do_id;
+UPDATE access_tokens t SET user_id = u.id FROM users u
+WHERE t.user_id IS NULL AND u.linux_do_id = t.linux_do_id;
+UPDATE sessions s SET user_id = u.id FROM users u
+WHERE s.user_id IS NULL AND u.linux_do_id = s.data->>'linux_do_id';
+UPDATE claim_records c SET user_id = u.id FROM users u
+WHERE c.user_id IS NULL AND u.linux_do_id = c.linux_do_id;
+UPDATE donate_records d SET user_id = u.id FROM users u
+WHERE d.user_id IS NULL AND u.linux_do_id = d.linux_do_id;
+
+-- 找不到用户的会话和个人访问令牌无法再使用，直接删除；
+-- 额度发放、投喂任务和历史记录保留，user_id 保持为空
+DO $$
+DECLARE
+    removed INTEGER;
+    orphaned INTEGER;
+BEGIN
+    DELETE FROM access_tokens WHERE user_id IS NULL;
+    GET DIAGNOSTICS removed = ROW_COUNT;
+    IF removed > 0 THEN
+        RAISE NOTICE '已删除 % 个找不到用户的个人访问令牌', removed;
+    END IF;
+
+    DELETE FROM sessions WHERE user_id IS NULL;
+    GET DIAGNOSTICS removed = ROW_COUNT;
+    IF removed > 0 THEN
+        RAISE NOTICE '已删除 % 个找不到用户的会话', removed;
+    END IF;
+
+    SELECT COUNT(*) INTO orphaned FROM quota_grants WHERE user_id IS NULL;
+    IF orphaned > 0 THEN
+        RAISE NOTICE '% 条额度发放记录找不到用户，已保留（未完成的仍会按公益站用户ID投递）', orphaned;
+    END IF;
+
+    SELECT COUNT(*) INTO orphaned FROM donate_jobs WHERE user_id IS NULL;
+    IF orphaned > 0 THEN
+        RAISE NOTICE '% 个投喂任务找不到用户，已保留', orphaned;
+    END IF;
+END $$;
+
+-- 会话和个人访问令牌必须属于用户，随用户删除
+ALTER TABLE access_tokens ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE sessions ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE access_tokens DROP CONSTRAINT IF EXISTS access_tokens_user_id_fkey;
+ALTER TABLE access_tokens ADD CONSTRAINT access_tokens_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS sessions_user_id_fkey;
+ALTER TABLE sessions ADD CONSTRAINT sessions_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+-- 额度发放、投喂任务、领取记录和投喂记录在用户删除后保留（user_id 置空），
+-- 用户清除任务会显式删除这些记录
+ALTER TABLE quota_grants DROP CONSTRAINT IF EXISTS quota_grants_user_id_fkey;
+ALTER TABLE quota_grants ADD CONSTRAINT quota_grants_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE donate_jobs DROP CONSTRAINT IF EXISTS donate_jobs_user_id_fkey;
+ALTER TABLE donate_jobs ADD CONSTRAINT donate_jobs_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE claim_records DROP CONSTRAINT IF EXISTS claim_records_user_id_fkey;
+ALTER TABLE claim_records ADD CONSTRAINT claim_records_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE donate_records DROP CONSTRAINT IF EXISTS donate_records_user_id_fkey;
+ALTER TABLE donate_records ADD CONSTRAINT donate_records_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_quota_grants_user_id ON quota_grants(user_id);
+CREATE INDEX IF NOT EXISTS idx_donate_jobs_user_id ON donate_jobs(user_id);
+CREATE INDEX IF NOT EXISTS idx_access_tokens_user_id ON access_tokens(user_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+-- 首次绑定奖励直接按额度发放记录的 user_id 汇总
+DROP VIEW IF EXISTS user_statistics;
+CREATE VIEW user_statistics AS
+SELECT
+    u.id as user_id,
+    u.linux_do_id,
+    u.username,
+    u.created_at as register_time,
+    c.claims as total_claims,
+    c.quota as total_claim_quota,
+    d.donates as total_donates,
+    d.keys_count as total_keys_donated,
+    d.quota as total_donate_quota,
+    bb.quota as total_bind_bonus_quota,
+    c.quota + d.quota + bb.quota as total_quota
+FROM users u
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) as claims, COALESCE(SUM(cr.quota_added), 0) as quota
+    FROM claim_records cr
+    WHERE cr.user_id = u.id AND cr.status <> 'released'
+) c
+CROSS JOIN LATERAL (
+    SELECT
+        COUNT(*) as donates,
+        COALESCE(SUM(dr.keys_count), 0) as keys_count,
+        COALESCE(SUM(dr.total_quota_added), 0) as quota
+    FROM donate_records dr
+    WHERE dr.user_id = u.id
+) d
+CROSS JOIN LATERAL (
+    SELECT COALESCE(SUM(g.quota), 0) as quota
+    FROM quota_grants g
+    WHERE g.user_id = u.id AND g.source = 'bind_bonus' AND g.status <> 'dead'
+) bb
+WHERE u.deleted_at IS NULL;
+
+-- 添加注释
+COMMENT ON COLUMN quota_grants.user_id IS '发放对象（users.id），用户已删除时为空';
+COMMENT ON COLUMN quota_grants.linux_do_id IS '发放对象的 Linux Do 用户ID（仅用于展示）';
+COMMENT ON COLUMN donate_jobs.user_id IS '投喂用户（users.id），用户已删除时为空';
+COMMENT ON COLUMN donate_jobs.linux_do_id IS 'Linux Do 用户ID（仅用于展示）';
+COMMENT ON COLUMN access_tokens.user_id IS '令牌所属用户（users.id）';
+COMMENT ON COLUMN access_tokens.linux_do_id IS '令牌所属用户的 Linux Do 用户ID（仅用于展示）';
+COMMENT ON COLUMN sessions.user_id IS '会话所属用户（users.id）';
+COMMENT ON VIEW user_statistics IS '用户统计视图，领取（不含已释放的预留）、投喂和首次绑定奖励分别汇总（不含已删除的用户）';
+
+-- 提示信息
+DO $$
+BEGIN
+    RAISE NOTICE '========================================';
+    RAISE NOTICE 'quota_grants / donate_jobs / access_tokens / sessions 已添加 user_id 外键';
+    RAISE NOTICE 'claim_records / donate_records 的 user_id 已添加外键（用户删除时置空）';
+    RAISE NOTICE '========================================';
+END $$;
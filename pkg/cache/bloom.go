@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// bloomMaxBits Redis 字符串最大 512MB，即 2^32 位
+const bloomMaxBits = uint64(1) << 32
+
+// BloomFilter 基于 Redis 位图 (SETBIT/GETBIT) 的布隆过滤器
+// 位数组与哈希函数个数由容量和误判率计算；过滤器只有在完整重建后才会被标记为就绪，
+// 未就绪（从未重建、Redis 被清空或键被淘汰、参数变更）时调用方应回退到数据库查询
+type BloomFilter struct {
+	redis    *Redis
+	key      string
+	readyKey string
+	bits     uint64
+	hashes   int
+}
+
+// NewBloomFilter 创建布隆过滤器，capacity 为预计元素数量，fpRate 为期望误判率
+func NewBloomFilter(r *Redis, key string, capacity int64, fpRate float64) *BloomFilter {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.001
+	}
+
+	// m = -n·ln(p) / (ln2)^2, k = m/n·ln2
+	bits := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if bits > bloomMaxBits {
+		bits = bloomMaxBits
+	}
+	if bits < 64 {
+		bits = 64
+	}
+	hashes := int(math.Round(float64(bits) / float64(capacity) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+
+	return &BloomFilter{
+		redis:    r,
+		key:      key,
+		readyKey: key + ":ready",
+		bits:     bits,
+		hashes:   hashes,
+	}
+}
+
+// Bits 位数组大小
+func (b *BloomFilter) Bits() uint64 {
+	return b.bits
+}
+
+// Hashes 哈希函数个数
+func (b *BloomFilter) Hashes() int {
+	return b.hashes
+}
+
+// params 就绪标记的值，参数变化后旧的位图自动失效
+func (b *BloomFilter) params() string {
+	return fmt.Sprintf("%d:%d", b.bits, b.hashes)
+}
+
+// offsets 计算元素对应的位（双重哈希）
+func (b *BloomFilter) offsets(member string) []int64 {
+	sum := sha256.Sum256([]byte(member))
+	h1 := binary.BigEndian.Uint64(sum[0:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16]) | 1
+
+	offsets := make([]int64, b.hashes)
+	for i := 0; i < b.hashes; i++ {
+		offsets[i] = int64((h1 + uint64(i)*h2) % b.bits)
+	}
+	return offsets
+}
+
+// Add 向过滤器添加元素
+func (b *BloomFilter) Add(ctx context.Context, members ...string) error {
+	return b.addTo(ctx, b.key, members)
+}
+
+// addTo 向指定键的位图添加元素
+func (b *BloomFilter) addTo(ctx context.Context, key string, members []string) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	_, err := b.redis.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, member := range members {
+			for _, offset := range b.offsets(member) {
+				pipe.SetBit(ctx, key, offset, 1)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add to bloom filter %s: %w", key, err)
+	}
+	return nil
+}
+
+// Check 检查元素是否可能存在
+// ready 为 false 时过滤器不可信（未重建或已丢失），mightContain 没有意义
+func (b *BloomFilter) Check(ctx context.Context, member string) (mightContain bool, ready bool, err error) {
+	var (
+		readyCmd  *redis.StringCmd
+		existsCmd *redis.IntCmd
+		bitCmds   []*redis.IntCmd
+	)
+
+	_, err = b.redis.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		readyCmd = pipe.Get(ctx, b.readyKey)
+		existsCmd = pipe.Exists(ctx, b.key)
+		for _, offset := range b.offsets(member) {
+			bitCmds = append(bitCmds, pipe.GetBit(ctx, b.key, offset))
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return false, false, fmt.Errorf("failed to check bloom filter %s: %w", b.key, err)
+	}
+
+	if readyCmd.Val() != b.params() || existsCmd.Val() == 0 {
+		return false, false, nil
+	}
+
+	for _, cmd := range bitCmds {
+		if cmd.Val() == 0 {
+			return false, true, nil
+		}
+	}
+	return true, true, nil
+}
+
+// Ready 过滤器是否已完整重建且仍然可用
+func (b *BloomFilter) Ready(ctx context.Context) (bool, error) {
+	var (
+		readyCmd  *redis.StringCmd
+		existsCmd *redis.IntCmd
+	)
+
+	_, err := b.redis.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		readyCmd = pipe.Get(ctx, b.readyKey)
+		existsCmd = pipe.Exists(ctx, b.key)
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return false, fmt.Errorf("failed to check bloom filter %s: %w", b.key, err)
+	}
+
+	return readyCmd.Val() == b.params() && existsCmd.Val() > 0, nil
+}
+
+// Rebuild 重建过滤器：load 通过 add 写入全部元素到临时位图，完成后原子替换并标记为就绪
+func (b *BloomFilter) Rebuild(ctx context.Context, load func(add func(members ...string) error) error) error {
+	tmpKey := b.key + ":rebuild"
+	if err := b.redis.Del(ctx, tmpKey); err != nil {
+		return err
+	}
+
+	err := load(func(members ...string) error {
+		return b.addTo(ctx, tmpKey, members)
+	})
+	if err != nil {
+		_ = b.redis.Del(ctx, tmpKey)
+		return fmt.Errorf("failed to load bloom filter %s: %w", b.key, err)
+	}
+
+	// 预分配完整位图，空集合时也能完成替换
+	_, err = b.redis.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SetBit(ctx, tmpKey, int64(b.bits-1), 0)
+		pipe.Rename(ctx, tmpKey, b.key)
+		pipe.Set(ctx, b.readyKey, b.params(), 0)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to swap bloom filter %s: %w", b.key, err)
+	}
+
+	return nil
+}
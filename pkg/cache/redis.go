@@ -204,6 +204,14 @@ func (r *Redis) SAdd(ctx context.Context, key string, members ...interface{}) er
 	return nil
 }
 
+// SRem 从集合删除成员
+func (r *Redis) SRem(ctx context.Context, key string, members ...interface{}) error {
+	if err := r.client.SRem(ctx, key, members...).Err(); err != nil {
+		return fmt.Errorf("failed to remove members from set %s: %w", key, err)
+	}
+	return nil
+}
+
 // SIsMember 检查是否是集合成员
 func (r *Redis) SIsMember(ctx context.Context, key string, member interface{}) (bool, error) {
 	result, err := r.client.SIsMember(ctx, key, member).Result()
@@ -335,6 +343,23 @@ func (r *Redis) SetNX(ctx context.Context, key string, value interface{}, expira
 	return result, nil
 }
 
+// releaseLockScript 仅当值匹配时删除键，避免释放他人持有的锁
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// DelIfEqual 仅当键的值等于 value 时删除（用于释放分布式锁）
+func (r *Redis) DelIfEqual(ctx context.Context, key string, value string) (bool, error) {
+	result, err := releaseLockScript.Run(ctx, r.client, []string{key}, value).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete key %s: %w", key, err)
+	}
+	return result > 0, nil
+}
+
 // GetSet 设置新值并返回旧值
 func (r *Redis) GetSet(ctx context.Context, key string, value interface{}) (string, error) {
 	oldVal, err := r.client.GetSet(ctx, key, value).Result()
@@ -440,22 +465,3 @@ func (r *Redis) Ping(ctx context.Context) error {
 	}
 	return nil
 }
-
-// BloomAdd 向布隆过滤器添加元素（使用Set实现简单版本）
-// 注意：这不是真正的布隆过滤器，如果需要真正的布隆过滤器，请安装RedisBloom模块
-func (r *Redis) BloomAdd(ctx context.Context, key string, member string) error {
-	if err := r.client.SAdd(ctx, key, member).Err(); err != nil {
-		return fmt.Errorf("failed to add to bloom filter %s: %w", key, err)
-	}
-	return nil
-}
-
-// BloomExists 检查元素是否可能存在于布隆过滤器（使用Set实现简单版本）
-// 注意：这不是真正的布隆过滤器，如果需要真正的布隆过滤器，请安装RedisBloom模块
-func (r *Redis) BloomExists(ctx context.Context, key string, member string) (bool, error) {
-	result, err := r.client.SIsMember(ctx, key, member).Result()
-	if err != nil {
-		return false, fmt.Errorf("failed to check bloom filter %s: %w", key, err)
-	}
-	return result, nil
-}
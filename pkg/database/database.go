@@ -2,11 +2,12 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
@@ -178,8 +179,8 @@ func IsUniqueViolation(err error) bool {
 		return false
 	}
 	// PostgreSQL unique violation error code is 23505
-	return err.Error() == "pq: duplicate key value violates unique constraint" ||
-		err.Error() == "ERROR: duplicate key value violates unique constraint"
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
 }
 
 // IsForeignKeyViolation 检查是否是外键约束违反错误
@@ -188,6 +189,6 @@ func IsForeignKeyViolation(err error) bool {
 		return false
 	}
 	// PostgreSQL foreign key violation error code is 23503
-	return err.Error() == "pq: insert or update on table violates foreign key constraint" ||
-		err.Error() == "ERROR: insert or update on table violates foreign key constraint"
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
 }
@@ -0,0 +1,240 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// encryptedPrefix 密文前缀，完整格式: enc:v<版本>:<加密后的数据密钥>:<加密后的数据>
+const encryptedPrefix = "enc:v"
+
+// masterKeySize 主密钥长度（AES-256）
+const masterKeySize = 32
+
+// Keyring 信封加密密钥环
+// 每个值使用随机生成的数据密钥（AES-256-GCM）加密，数据密钥再由指定版本的主密钥加密后与密文一起保存；
+// 轮换主密钥时加入新版本并切换当前版本，旧版本主密钥保留到所有数据重新加密完成
+type Keyring struct {
+	keys    map[int][]byte
+	current int
+}
+
+// NewKeyring 创建密钥环，current 为加密使用的主密钥版本
+func NewKeyring(keys map[int][]byte, current int) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no master keys provided")
+	}
+	for version, key := range keys {
+		if version <= 0 {
+			return nil, fmt.Errorf("invalid master key version: %d", version)
+		}
+		if len(key) != masterKeySize {
+			return nil, fmt.Errorf("master key v%d must be %d bytes", version, masterKeySize)
+		}
+	}
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("current master key version %d not found", current)
+	}
+
+	return &Keyring{
+		keys:    keys,
+		current: current,
+	}, nil
+}
+
+// ParseKeyring 解析主密钥配置 "1:<base64>,2:<base64>"
+// current 为 0 时使用最大的版本号；spec 为空时返回 nil（不加密）
+func ParseKeyring(spec string, current int) (*Keyring, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	keys := make(map[int][]byte)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid master key entry, expected <version>:<base64 key>")
+		}
+
+		version, err := strconv.Atoi(strings.TrimPrefix(parts[0], "v"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid master key version %q: %w", parts[0], err)
+		}
+		if _, exists := keys[version]; exists {
+			return nil, fmt.Errorf("duplicate master key version: %d", version)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid master key v%d: %w", version, err)
+		}
+		keys[version] = key
+	}
+
+	if current == 0 {
+		versions := make([]int, 0, len(keys))
+		for version := range keys {
+			versions = append(versions, version)
+		}
+		sort.Ints(versions)
+		if len(versions) > 0 {
+			current = versions[len(versions)-1]
+		}
+	}
+
+	return NewKeyring(keys, current)
+}
+
+// CurrentVersion 当前加密使用的主密钥版本，未启用加密时为 0
+func (k *Keyring) CurrentVersion() int {
+	if k == nil {
+		return 0
+	}
+	return k.current
+}
+
+// Encrypt 使用当前主密钥加密，返回密文和主密钥版本
+// 未启用加密或值为空时原样返回，版本为 0
+func (k *Keyring) Encrypt(plaintext string) (string, int, error) {
+	if k == nil || plaintext == "" {
+		return plaintext, 0, nil
+	}
+
+	dataKey := make([]byte, masterKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", 0, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	wrappedKey, err := seal(k.keys[k.current], dataKey, []byte(encryptedPrefix+strconv.Itoa(k.current)))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	ciphertext, err := seal(dataKey, []byte(plaintext), nil)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to encrypt value: %w", err)
+	}
+
+	return fmt.Sprintf("%s%d:%s:%s",
+		encryptedPrefix,
+		k.current,
+		base64.StdEncoding.EncodeToString(wrappedKey),
+		base64.StdEncoding.EncodeToString(ciphertext),
+	), k.current, nil
+}
+
+// Decrypt 解密 Encrypt 生成的密文，未加密的历史明文原样返回
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 4 {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+
+	version := VersionOf(value)
+	if k == nil {
+		return "", fmt.Errorf("value encrypted with master key v%d but encryption is not configured", version)
+	}
+	masterKey, ok := k.keys[version]
+	if !ok {
+		return "", fmt.Errorf("master key v%d not found", version)
+	}
+
+	wrappedKey, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("malformed data key: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return "", fmt.Errorf("malformed ciphertext: %w", err)
+	}
+
+	dataKey, err := open(masterKey, wrappedKey, []byte(encryptedPrefix+strconv.Itoa(version)))
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	plaintext, err := open(dataKey, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// IsEncrypted 判断值是否为 Encrypt 生成的密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// VersionOf 密文使用的主密钥版本，明文返回 0
+func VersionOf(value string) int {
+	if !IsEncrypted(value) {
+		return 0
+	}
+	rest := strings.TrimPrefix(value, encryptedPrefix)
+	end := strings.Index(rest, ":")
+	if end < 0 {
+		return 0
+	}
+	version, err := strconv.Atoi(rest[:end])
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// seal AES-GCM 加密，输出 nonce || ciphertext
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open AES-GCM 解密 seal 的输出
+func open(key, data, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// newGCM 创建 AES-GCM
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,157 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// testKey 生成测试用的主密钥（base64 编码）
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, masterKeySize))
+}
+
+// TestKeyringRotation 旧主密钥加密的值在轮换后仍可解密，新值使用新版本加密
+func TestKeyringRotation(t *testing.T) {
+	const plaintext = "sk-donated-key"
+
+	oldRing, err := ParseKeyring("1:"+testKey(1), 0)
+	if err != nil {
+		t.Fatalf("ParseKeyring v1 error: %v", err)
+	}
+
+	encrypted, version, err := oldRing.Encrypt(plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	if version != 1 || VersionOf(encrypted) != 1 || !IsEncrypted(encrypted) {
+		t.Fatalf("Encrypt = (%q, %d), want v1 ciphertext", encrypted, version)
+	}
+	if strings.Contains(encrypted, plaintext) {
+		t.Fatal("ciphertext contains plaintext")
+	}
+
+	// 轮换：加入 v2 并切换为当前版本，v1 保留用于解密
+	rotated, err := ParseKeyring("1:"+testKey(1)+",2:"+testKey(2), 0)
+	if err != nil {
+		t.Fatalf("ParseKeyring v2 error: %v", err)
+	}
+	if rotated.CurrentVersion() != 2 {
+		t.Fatalf("CurrentVersion = %d, want 2", rotated.CurrentVersion())
+	}
+
+	decrypted, err := rotated.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("Decrypt after rotation error: %v", err)
+	}
+	if decrypted != plaintext {
+		t.Fatalf("Decrypt after rotation = %q, want %q", decrypted, plaintext)
+	}
+
+	reencrypted, version, err := rotated.Encrypt(decrypted)
+	if err != nil {
+		t.Fatalf("Encrypt after rotation error: %v", err)
+	}
+	if version != 2 || VersionOf(reencrypted) != 2 {
+		t.Fatalf("Encrypt after rotation version = %d, want 2", version)
+	}
+
+	// 移除 v1 后旧密文无法解密，新密文不受影响
+	newOnly, err := ParseKeyring("2:"+testKey(2), 0)
+	if err != nil {
+		t.Fatalf("ParseKeyring v2 only error: %v", err)
+	}
+	if _, err := newOnly.Decrypt(encrypted); err == nil {
+		t.Error("Decrypt with retired master key should fail")
+	}
+	if decrypted, err := newOnly.Decrypt(reencrypted); err != nil || decrypted != plaintext {
+		t.Errorf("Decrypt re-encrypted value = (%q, %v), want %q", decrypted, err, plaintext)
+	}
+}
+
+// TestKeyringRejectsTampering 篡改数据密钥、密文或版本号时解密失败
+func TestKeyringRejectsTampering(t *testing.T) {
+	ring, err := ParseKeyring("1:"+testKey(1)+",2:"+testKey(2), 1)
+	if err != nil {
+		t.Fatalf("ParseKeyring error: %v", err)
+	}
+
+	encrypted, _, err := ring.Encrypt("secret")
+	if err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	parts := strings.Split(encrypted, ":")
+
+	// flip 翻转 base64 字段解码后的最后一个字节
+	flip := func(field string) string {
+		raw, err := base64.StdEncoding.DecodeString(field)
+		if err != nil {
+			t.Fatalf("decode field error: %v", err)
+		}
+		raw[len(raw)-1] ^= 0x01
+		return base64.StdEncoding.EncodeToString(raw)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"wrapped key", strings.Join([]string{parts[0], parts[1], flip(parts[2]), parts[3]}, ":")},
+		{"ciphertext", strings.Join([]string{parts[0], parts[1], parts[2], flip(parts[3])}, ":")},
+		{"version", strings.Join([]string{parts[0], "v2", parts[2], parts[3]}, ":")},
+		{"truncated", strings.Join(parts[:3], ":")},
+	}
+
+	for _, tt := range tests {
+		if _, err := ring.Decrypt(tt.value); err == nil {
+			t.Errorf("Decrypt with tampered %s should fail", tt.name)
+		}
+	}
+}
+
+// TestKeyringPlaintextPassthrough 未启用加密或历史明文原样返回
+func TestKeyringPlaintextPassthrough(t *testing.T) {
+	var disabled *Keyring
+
+	value, version, err := disabled.Encrypt("plain")
+	if err != nil || value != "plain" || version != 0 {
+		t.Errorf("nil Encrypt = (%q, %d, %v), want plaintext", value, version, err)
+	}
+	if value, err := disabled.Decrypt("plain"); err != nil || value != "plain" {
+		t.Errorf("nil Decrypt = (%q, %v), want plaintext", value, err)
+	}
+
+	ring, err := ParseKeyring("1:"+testKey(1), 0)
+	if err != nil {
+		t.Fatalf("ParseKeyring error: %v", err)
+	}
+	encrypted, _, _ := ring.Encrypt("secret")
+	if _, err := disabled.Decrypt(encrypted); err == nil {
+		t.Error("nil Decrypt of ciphertext should fail")
+	}
+}
+
+// TestParseKeyringInvalid 无效的主密钥配置
+func TestParseKeyringInvalid(t *testing.T) {
+	tests := []string{
+		"1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+		"0:" + testKey(1),
+		"1:" + testKey(1) + ",1:" + testKey(2),
+		"x:" + testKey(1),
+		testKey(1),
+	}
+
+	for _, spec := range tests {
+		if _, err := ParseKeyring(spec, 0); err == nil {
+			t.Errorf("ParseKeyring(%q) should fail", spec)
+		}
+	}
+
+	if _, err := ParseKeyring("1:"+testKey(1), 2); err == nil {
+		t.Error("ParseKeyring with unknown current version should fail")
+	}
+	if ring, err := ParseKeyring("  ", 0); ring != nil || err != nil {
+		t.Errorf("ParseKeyring empty = (%v, %v), want (nil, nil)", ring, err)
+	}
+}
@@ -0,0 +1,119 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret RFC 6238 附录 B 的 SHA1 测试密钥 "12345678901234567890"（Base32 编码）
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestCodeRFC6238 RFC 6238 附录 B SHA1 测试向量（取 8 位验证码的后 6 位）
+func TestCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Counter(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d) error: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+// TestCodeSecretFormat 密钥忽略大小写、空格和填充
+func TestCodeSecretFormat(t *testing.T) {
+	secret := strings.ToLower(rfcSecret[:16]) + " " + rfcSecret[16:] + "===="
+	got, err := Code(secret, Counter(time.Unix(59, 0)))
+	if err != nil {
+		t.Fatalf("Code error: %v", err)
+	}
+	if got != "287082" {
+		t.Errorf("Code = %s, want 287082", got)
+	}
+
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code with invalid secret should fail")
+	}
+}
+
+// TestValidateSkew 只接受前后 skew 个时间步内的验证码
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Counter(now)
+
+	for offset := int64(-2); offset <= 2; offset++ {
+		code, err := Code(rfcSecret, current+offset)
+		if err != nil {
+			t.Fatalf("Code error: %v", err)
+		}
+
+		counter, ok := Validate(rfcSecret, code, now, 1)
+		wantOK := offset >= -1 && offset <= 1
+		if ok != wantOK {
+			t.Errorf("offset %d: Validate ok = %v, want %v", offset, ok, wantOK)
+			continue
+		}
+		if ok && counter != current+offset {
+			t.Errorf("offset %d: Validate counter = %d, want %d", offset, counter, current+offset)
+		}
+	}
+
+	code, _ := Code(rfcSecret, current)
+	if _, ok := Validate(rfcSecret, " "+code+" ", now, 0); !ok {
+		t.Error("Validate should trim surrounding spaces")
+	}
+	if _, ok := Validate(rfcSecret, code[:5], now, 1); ok {
+		t.Error("Validate should reject codes with wrong length")
+	}
+}
+
+// TestValidateAfterRejectsReusedCounter 已使用的时间步（及更早的时间步）不能再次通过校验
+func TestValidateAfterRejectsReusedCounter(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Counter(now)
+
+	code, err := Code(rfcSecret, current)
+	if err != nil {
+		t.Fatalf("Code error: %v", err)
+	}
+
+	last, ok := ValidateAfter(rfcSecret, code, now, 1, 0)
+	if !ok || last != current {
+		t.Fatalf("first ValidateAfter = (%d, %v), want (%d, true)", last, ok, current)
+	}
+
+	// 同一验证码在同一时间步和下一个时间步内重放
+	if _, ok := ValidateAfter(rfcSecret, code, now, 1, last); ok {
+		t.Error("ValidateAfter should reject a reused counter")
+	}
+	if _, ok := ValidateAfter(rfcSecret, code, now.Add(Period*time.Second), 1, last); ok {
+		t.Error("ValidateAfter should reject a reused counter within the skew window")
+	}
+
+	// 偏差窗口内更早的时间步同样拒绝
+	previous, _ := Code(rfcSecret, current-1)
+	if _, ok := ValidateAfter(rfcSecret, previous, now, 1, last); ok {
+		t.Error("ValidateAfter should reject counters older than the last used one")
+	}
+
+	// 下一个时间步的新验证码可以通过
+	next, _ := Code(rfcSecret, current+1)
+	counter, ok := ValidateAfter(rfcSecret, next, now.Add(Period*time.Second), 1, last)
+	if !ok || counter != current+1 {
+		t.Errorf("ValidateAfter next = (%d, %v), want (%d, true)", counter, ok, current+1)
+	}
+}